@@ -2,7 +2,8 @@ package main
 
 import (
 	"context"
-	"log"
+	"fmt"
+	"log/slog"
 
 	"github.com/amrrdev/trawl/services/auth/internal/config"
 	"github.com/amrrdev/trawl/services/auth/internal/database"
@@ -11,7 +12,10 @@ import (
 	"github.com/amrrdev/trawl/services/auth/internal/server"
 	"github.com/amrrdev/trawl/services/auth/internal/services"
 	"github.com/amrrdev/trawl/services/shared/jwt"
+	"github.com/amrrdev/trawl/services/shared/logging"
 	"github.com/amrrdev/trawl/services/shared/middleware"
+	"github.com/amrrdev/trawl/services/shared/revocation"
+	"github.com/amrrdev/trawl/services/shared/tracing"
 )
 
 func main() {
@@ -19,31 +23,96 @@ func main() {
 
 	config, err := config.Load()
 	if err != nil {
-		log.Fatal(err)
+		logging.Fatal("Invalid configuration", "error", err)
 	}
+	logging.Setup(config.Logging)
+	shutdownTracing, err := tracing.Setup(ctx, tracing.Config{
+		ServiceName: "trawl-auth",
+		Endpoint:    config.TracingEndpoint,
+	})
+	if err != nil {
+		logging.Fatal("Failed to set up tracing", "error", err)
+	}
+	defer shutdownTracing(context.Background())
 
 	database, err := database.Connect(ctx, config.DatabaseUrl, database.DefaultConfig())
 	if err != nil {
-		log.Fatalf("Failed to connect: %v", err)
+		logging.Fatal("Failed to connect to the database", "error", err)
 	}
 
 	if err := database.HealthCheck(ctx); err != nil {
-		log.Printf("Health check failed: %v", err)
+		slog.Warn("Database health check failed", "error", err)
 	}
 
-	stats := database.Stats()
-	log.Printf("Active connections: %d", stats.AcquiredConns())
+	slog.Info("Connected to the database", "active_connections", database.Stats().AcquiredConns())
 
 	repo := repository.NewUserRepository(database.Pool)
-	jwtService := jwt.NewService(config.JWTSecretKey, config.AccessTokenTTL)
+	refreshRepo := repository.NewRefreshTokenRepository(database.Pool)
+	resetRepo := repository.NewPasswordResetTokenRepository(database.Pool)
+	jwtService, err := newJWTService(config)
+	if err != nil {
+		logging.Fatal("Failed to initialize JWT", "error", err)
+	}
+	jwtService.UseRevocationStore(revocation.NewStore(database.Pool))
 	hashingService := services.NewHashingService()
-	authService := services.NewAuthService(repo, hashingService, jwtService)
+	authService := services.NewAuthService(
+		repo,
+		refreshRepo,
+		resetRepo,
+		hashingService,
+		jwtService,
+		newPasswordResetSender(config),
+		services.NewLoginLimiter(config.LoginMaxFailures, config.LoginLockout),
+		config.RefreshTokenTTL,
+		config.PasswordResetTTL,
+	)
 	authHandler := handler.NewAuthHandler(authService)
 	authMiddleware := middleware.NewAuthMiddleware(jwtService)
 
-	g := server.NewServer(authHandler, authMiddleware)
+	g, err := server.NewServer(authHandler, authMiddleware, config.CORS, config.TrustedProxies)
+	if err != nil {
+		logging.Fatal("Failed to create server", "error", err)
+	}
 
 	if err := g.Run(); err != nil {
-		log.Fatal(err)
+		logging.Fatal("Server stopped with error", "error", err)
+	}
+}
+
+// newPasswordResetSender returns the sender config.PasswordResetSender names.
+func newPasswordResetSender(config *config.Config) services.PasswordResetSender {
+	if config.PasswordResetSender == "log" {
+		return services.LogPasswordResetSender{}
+	}
+	return services.SMTPPasswordResetSender{
+		Addr:     config.SMTP.Addr,
+		From:     config.SMTP.From,
+		Username: config.SMTP.Username,
+		Password: config.SMTP.Password,
+		ResetURL: config.SMTP.ResetURL,
 	}
 }
+
+// newJWTService builds the token service for config.JWTAlgorithm. With RS256
+// the JWKS endpoint publishes the current and retired public keys.
+func newJWTService(config *config.Config) (*jwt.Service, error) {
+	if config.JWTAlgorithm != jwt.AlgorithmRS256 {
+		return jwt.NewService(config.JWTSecretKey, config.AccessTokenTTL), nil
+	}
+
+	privateKey, err := jwt.LoadRSAPrivateKey(config.JWTPrivateKeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := jwt.NewKeySet()
+	for kid, path := range config.JWTRetiredPublicKeys {
+		publicKey, err := jwt.LoadRSAPublicKey(path)
+		if err != nil {
+			return nil, fmt.Errorf("retired key %q: %w", kid, err)
+		}
+		keys.Add(kid, publicKey)
+	}
+
+	return jwt.NewRS256Service(privateKey, config.JWTKeyID, keys, config.AccessTokenTTL), nil
+}
@@ -0,0 +1,78 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLoadPasswordResetSender(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		want    string
+		wantErr string
+	}{
+		{
+			name:    "unset",
+			env:     map[string]string{},
+			wantErr: "invalid PASSWORD_RESET_SENDER",
+		},
+		{
+			name: "log in development",
+			env:  map[string]string{"PASSWORD_RESET_SENDER": "log"},
+			want: "log",
+		},
+		{
+			name: "log in production",
+			env: map[string]string{
+				"ENV":                   "production",
+				"DATABASE_URL":          "postgres://prod",
+				"JWT_SECRET_KEY":        "prod-secret",
+				"PASSWORD_RESET_SENDER": "log",
+			},
+			wantErr: "PASSWORD_RESET_SENDER=log is for development",
+		},
+		{
+			name:    "smtp without server",
+			env:     map[string]string{"PASSWORD_RESET_SENDER": "smtp"},
+			wantErr: "requires SMTP_ADDR",
+		},
+		{
+			name: "smtp in production",
+			env: map[string]string{
+				"ENV":                   "production",
+				"DATABASE_URL":          "postgres://prod",
+				"JWT_SECRET_KEY":        "prod-secret",
+				"PASSWORD_RESET_SENDER": "smtp",
+				"SMTP_ADDR":             "smtp.example.com:587",
+				"SMTP_FROM":             "no-reply@example.com",
+				"PASSWORD_RESET_URL":    "https://example.com/reset",
+			},
+			want: "smtp",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, key := range []string{"ENV", "PASSWORD_RESET_SENDER", "SMTP_ADDR", "SMTP_FROM", "PASSWORD_RESET_URL"} {
+				t.Setenv(key, "")
+			}
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+
+			config, err := Load()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if config.PasswordResetSender != tt.want {
+				t.Errorf("PasswordResetSender = %q, want %q", config.PasswordResetSender, tt.want)
+			}
+		})
+	}
+}
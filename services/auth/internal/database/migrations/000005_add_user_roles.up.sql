@@ -0,0 +1,4 @@
+-- Grant admin with: UPDATE users SET role = 'admin' WHERE email = '...';
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS role VARCHAR(32) NOT NULL DEFAULT 'user'
+    CHECK (role IN ('user', 'admin'));
@@ -0,0 +1,3 @@
+-- Access tokens issued before tokens_valid_after are rejected; NULL rejects none.
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS tokens_valid_after TIMESTAMPTZ;
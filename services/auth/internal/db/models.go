@@ -8,12 +8,39 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
-type User struct {
+type PasswordResetToken struct {
+	TokenID   pgtype.UUID      `db:"token_id" json:"token_id"`
+	UserID    pgtype.UUID      `db:"user_id" json:"user_id"`
+	TokenHash string           `db:"token_hash" json:"token_hash"`
+	ExpiresAt pgtype.Timestamp `db:"expires_at" json:"expires_at"`
+	CreatedAt pgtype.Timestamp `db:"created_at" json:"created_at"`
+	UsedAt    pgtype.Timestamp `db:"used_at" json:"used_at"`
+}
+
+type RefreshToken struct {
+	TokenID   pgtype.UUID      `db:"token_id" json:"token_id"`
 	UserID    pgtype.UUID      `db:"user_id" json:"user_id"`
-	Email     string           `db:"email" json:"email"`
-	Password  string           `db:"password" json:"password"`
-	Name      pgtype.Text      `db:"name" json:"name"`
+	TokenHash string           `db:"token_hash" json:"token_hash"`
+	FamilyID  pgtype.UUID      `db:"family_id" json:"family_id"`
+	ExpiresAt pgtype.Timestamp `db:"expires_at" json:"expires_at"`
 	CreatedAt pgtype.Timestamp `db:"created_at" json:"created_at"`
-	UpdatedAt pgtype.Timestamp `db:"updated_at" json:"updated_at"`
-	IsActive  pgtype.Bool      `db:"is_active" json:"is_active"`
+	RevokedAt pgtype.Timestamp `db:"revoked_at" json:"revoked_at"`
+}
+
+type RevokedToken struct {
+	Jti       string           `db:"jti" json:"jti"`
+	ExpiresAt pgtype.Timestamp `db:"expires_at" json:"expires_at"`
+	RevokedAt pgtype.Timestamp `db:"revoked_at" json:"revoked_at"`
+}
+
+type User struct {
+	UserID           pgtype.UUID        `db:"user_id" json:"user_id"`
+	Email            string             `db:"email" json:"email"`
+	Password         string             `db:"password" json:"password"`
+	Name             pgtype.Text        `db:"name" json:"name"`
+	CreatedAt        pgtype.Timestamp   `db:"created_at" json:"created_at"`
+	UpdatedAt        pgtype.Timestamp   `db:"updated_at" json:"updated_at"`
+	IsActive         pgtype.Bool        `db:"is_active" json:"is_active"`
+	Role             string             `db:"role" json:"role"`
+	TokensValidAfter pgtype.Timestamptz `db:"tokens_valid_after" json:"tokens_valid_after"`
 }
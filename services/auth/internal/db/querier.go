@@ -20,7 +20,17 @@ type Querier interface {
 	// ============================================
 	BulkDeactivateUsers(ctx context.Context, dollar_1 []pgtype.UUID) error
 	CheckUserExists(ctx context.Context, email string) (bool, error)
+	ConsumePasswordResetToken(ctx context.Context, tokenHash string) (pgtype.UUID, error)
 	CountUsers(ctx context.Context, arg CountUsersParams) (int64, error)
+	CountUsersByDateRange(ctx context.Context, arg CountUsersByDateRangeParams) (int64, error)
+	// ============================================
+	// PASSWORD RESET TOKENS
+	// ============================================
+	CreatePasswordResetToken(ctx context.Context, arg CreatePasswordResetTokenParams) error
+	// ============================================
+	// REFRESH TOKENS
+	// ============================================
+	CreateRefreshToken(ctx context.Context, arg CreateRefreshTokenParams) (RefreshToken, error)
 	// ============================================
 	// USER CREATION
 	// ============================================
@@ -29,14 +39,17 @@ type Querier interface {
 	// USER STATUS MANAGEMENT
 	// ============================================
 	DeactivateUser(ctx context.Context, userID pgtype.UUID) error
+	DeleteExpiredPasswordResetTokens(ctx context.Context, expiresAt pgtype.Timestamp) error
 	// ============================================
 	// DATA INTEGRITY
 	// ============================================
 	GetDuplicateEmails(ctx context.Context) ([]GetDuplicateEmailsRow, error)
+	GetRefreshTokenByHash(ctx context.Context, tokenHash string) (RefreshToken, error)
 	// ============================================
 	// USER AUTHENTICATION QUERIES
 	// ============================================
 	GetUserByEmail(ctx context.Context, email string) (User, error)
+	GetUserByEmailAnyStatus(ctx context.Context, email string) (User, error)
 	GetUserByID(ctx context.Context, userID pgtype.UUID) (User, error)
 	GetUserForValidation(ctx context.Context, userID pgtype.UUID) (GetUserForValidationRow, error)
 	// ============================================
@@ -44,18 +57,23 @@ type Querier interface {
 	// ============================================
 	GetUserStats(ctx context.Context) (GetUserStatsRow, error)
 	GetUsersByDateRange(ctx context.Context, arg GetUsersByDateRangeParams) ([]GetUsersByDateRangeRow, error)
+	InvalidateUserPasswordResetTokens(ctx context.Context, userID pgtype.UUID) error
 	ListActiveUsers(ctx context.Context, arg ListActiveUsersParams) ([]ListActiveUsersRow, error)
 	// ============================================
 	// LISTING & PAGINATION
 	// ============================================
 	ListUsers(ctx context.Context, arg ListUsersParams) ([]ListUsersRow, error)
 	ReactivateUser(ctx context.Context, userID pgtype.UUID) error
+	RevokeRefreshToken(ctx context.Context, tokenID pgtype.UUID) (int64, error)
+	RevokeRefreshTokenFamily(ctx context.Context, familyID pgtype.UUID) error
+	RevokeUserRefreshTokens(ctx context.Context, userID pgtype.UUID) error
 	UpdateUserEmail(ctx context.Context, arg UpdateUserEmailParams) (UpdateUserEmailRow, error)
 	UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) error
 	// ============================================
 	// USER PROFILE UPDATES
 	// ============================================
 	UpdateUserProfile(ctx context.Context, arg UpdateUserProfileParams) (UpdateUserProfileRow, error)
+	UpdateUserRole(ctx context.Context, arg UpdateUserRoleParams) (int64, error)
 }
 
 var _ Querier = (*Queries)(nil)
@@ -30,7 +30,8 @@ const bulkDeactivateUsers = `-- name: BulkDeactivateUsers :exec
 UPDATE users
 SET
     is_active = false,
-    updated_at = CURRENT_TIMESTAMP
+    updated_at = CURRENT_TIMESTAMP,
+    tokens_valid_after = NOW()
 WHERE user_id = ANY($1::UUID[])
 `
 
@@ -61,15 +62,15 @@ FROM users
 WHERE
     ($1::BOOLEAN IS NULL OR is_active = $1)
     AND (
-        $2 IS NULL OR
-        email ILIKE '%' || $2 || '%' OR
-        name ILIKE '%' || $2 || '%'
+        $2::TEXT IS NULL OR
+        email ILIKE '%' || $2::TEXT || '%' OR
+        name ILIKE '%' || $2::TEXT || '%'
     )
 `
 
 type CountUsersParams struct {
 	IsActive pgtype.Bool `db:"is_active" json:"is_active"`
-	Search   interface{} `db:"search" json:"search"`
+	Search   pgtype.Text `db:"search" json:"search"`
 }
 
 func (q *Queries) CountUsers(ctx context.Context, arg CountUsersParams) (int64, error) {
@@ -79,6 +80,24 @@ func (q *Queries) CountUsers(ctx context.Context, arg CountUsersParams) (int64,
 	return count, err
 }
 
+const countUsersByDateRange = `-- name: CountUsersByDateRange :one
+SELECT COUNT(*)
+FROM users
+WHERE created_at BETWEEN $1 AND $2
+`
+
+type CountUsersByDateRangeParams struct {
+	CreatedAt   pgtype.Timestamp `db:"created_at" json:"created_at"`
+	CreatedAt_2 pgtype.Timestamp `db:"created_at_2" json:"created_at_2"`
+}
+
+func (q *Queries) CountUsersByDateRange(ctx context.Context, arg CountUsersByDateRangeParams) (int64, error) {
+	row := q.db.QueryRow(ctx, countUsersByDateRange, arg.CreatedAt, arg.CreatedAt_2)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createUser = `-- name: CreateUser :one
 
 INSERT INTO users (
@@ -93,7 +112,8 @@ RETURNING
     email,
     name,
     created_at,
-    is_active
+    is_active,
+    role
 `
 
 type CreateUserParams struct {
@@ -108,6 +128,7 @@ type CreateUserRow struct {
 	Name      pgtype.Text      `db:"name" json:"name"`
 	CreatedAt pgtype.Timestamp `db:"created_at" json:"created_at"`
 	IsActive  pgtype.Bool      `db:"is_active" json:"is_active"`
+	Role      string           `db:"role" json:"role"`
 }
 
 // ============================================
@@ -122,6 +143,7 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (CreateU
 		&i.Name,
 		&i.CreatedAt,
 		&i.IsActive,
+		&i.Role,
 	)
 	return i, err
 }
@@ -131,7 +153,8 @@ const deactivateUser = `-- name: DeactivateUser :exec
 UPDATE users
 SET
     is_active = false,
-    updated_at = CURRENT_TIMESTAMP
+    updated_at = CURRENT_TIMESTAMP,
+    tokens_valid_after = NOW()
 WHERE user_id = $1
 `
 
@@ -190,7 +213,9 @@ SELECT
     name,
     created_at,
     updated_at,
-    is_active
+    is_active,
+    role,
+    tokens_valid_after
 FROM users
 WHERE email = $1
   AND is_active = true
@@ -211,6 +236,41 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.IsActive,
+		&i.Role,
+		&i.TokensValidAfter,
+	)
+	return i, err
+}
+
+const getUserByEmailAnyStatus = `-- name: GetUserByEmailAnyStatus :one
+SELECT
+    user_id,
+    email,
+    password,
+    name,
+    created_at,
+    updated_at,
+    is_active,
+    role,
+    tokens_valid_after
+FROM users
+WHERE email = $1
+LIMIT 1
+`
+
+func (q *Queries) GetUserByEmailAnyStatus(ctx context.Context, email string) (User, error) {
+	row := q.db.QueryRow(ctx, getUserByEmailAnyStatus, email)
+	var i User
+	err := row.Scan(
+		&i.UserID,
+		&i.Email,
+		&i.Password,
+		&i.Name,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.IsActive,
+		&i.Role,
+		&i.TokensValidAfter,
 	)
 	return i, err
 }
@@ -223,7 +283,9 @@ SELECT
     name,
     created_at,
     updated_at,
-    is_active
+    is_active,
+    role,
+    tokens_valid_after
 FROM users
 WHERE user_id = $1
   AND is_active = true
@@ -241,6 +303,8 @@ func (q *Queries) GetUserByID(ctx context.Context, userID pgtype.UUID) (User, er
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.IsActive,
+		&i.Role,
+		&i.TokensValidAfter,
 	)
 	return i, err
 }
@@ -318,11 +382,14 @@ SELECT
 FROM users
 WHERE created_at BETWEEN $1 AND $2
 ORDER BY created_at DESC
+LIMIT $3 OFFSET $4
 `
 
 type GetUsersByDateRangeParams struct {
 	CreatedAt   pgtype.Timestamp `db:"created_at" json:"created_at"`
 	CreatedAt_2 pgtype.Timestamp `db:"created_at_2" json:"created_at_2"`
+	Limit       int32            `db:"limit" json:"limit"`
+	Offset      int32            `db:"offset" json:"offset"`
 }
 
 type GetUsersByDateRangeRow struct {
@@ -334,7 +401,12 @@ type GetUsersByDateRangeRow struct {
 }
 
 func (q *Queries) GetUsersByDateRange(ctx context.Context, arg GetUsersByDateRangeParams) ([]GetUsersByDateRangeRow, error) {
-	rows, err := q.db.Query(ctx, getUsersByDateRange, arg.CreatedAt, arg.CreatedAt_2)
+	rows, err := q.db.Query(ctx, getUsersByDateRange,
+		arg.CreatedAt,
+		arg.CreatedAt_2,
+		arg.Limit,
+		arg.Offset,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -415,14 +487,15 @@ SELECT
     email,
     name,
     created_at,
-    is_active
+    is_active,
+    role
 FROM users
 WHERE
     ($3::BOOLEAN IS NULL OR is_active = $3)
     AND (
-        $4 IS NULL OR
-        email ILIKE '%' || $4 || '%' OR
-        name ILIKE '%' || $4 || '%'
+        $4::TEXT IS NULL OR
+        email ILIKE '%' || $4::TEXT || '%' OR
+        name ILIKE '%' || $4::TEXT || '%'
     )
 ORDER BY created_at DESC
 LIMIT $1 OFFSET $2
@@ -432,7 +505,7 @@ type ListUsersParams struct {
 	Limit    int32       `db:"limit" json:"limit"`
 	Offset   int32       `db:"offset" json:"offset"`
 	IsActive pgtype.Bool `db:"is_active" json:"is_active"`
-	Search   interface{} `db:"search" json:"search"`
+	Search   pgtype.Text `db:"search" json:"search"`
 }
 
 type ListUsersRow struct {
@@ -441,6 +514,7 @@ type ListUsersRow struct {
 	Name      pgtype.Text      `db:"name" json:"name"`
 	CreatedAt pgtype.Timestamp `db:"created_at" json:"created_at"`
 	IsActive  pgtype.Bool      `db:"is_active" json:"is_active"`
+	Role      string           `db:"role" json:"role"`
 }
 
 // ============================================
@@ -466,6 +540,7 @@ func (q *Queries) ListUsers(ctx context.Context, arg ListUsersParams) ([]ListUse
 			&i.Name,
 			&i.CreatedAt,
 			&i.IsActive,
+			&i.Role,
 		); err != nil {
 			return nil, err
 		}
@@ -525,7 +600,8 @@ const updateUserPassword = `-- name: UpdateUserPassword :exec
 UPDATE users
 SET
     password = $2,
-    updated_at = CURRENT_TIMESTAMP
+    updated_at = CURRENT_TIMESTAMP,
+    tokens_valid_after = NOW()
 WHERE user_id = $1
   AND is_active = true
 `
@@ -581,3 +657,24 @@ func (q *Queries) UpdateUserProfile(ctx context.Context, arg UpdateUserProfilePa
 	)
 	return i, err
 }
+
+const updateUserRole = `-- name: UpdateUserRole :execrows
+UPDATE users
+SET
+    role = $2,
+    updated_at = CURRENT_TIMESTAMP
+WHERE user_id = $1
+`
+
+type UpdateUserRoleParams struct {
+	UserID pgtype.UUID `db:"user_id" json:"user_id"`
+	Role   string      `db:"role" json:"role"`
+}
+
+func (q *Queries) UpdateUserRole(ctx context.Context, arg UpdateUserRoleParams) (int64, error) {
+	result, err := q.db.Exec(ctx, updateUserRole, arg.UserID, arg.Role)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
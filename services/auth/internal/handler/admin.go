@@ -0,0 +1,174 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/amrrdev/trawl/services/auth/internal/services"
+	"github.com/amrrdev/trawl/services/shared/apierror"
+	"github.com/amrrdev/trawl/services/shared/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+// ListUsers lists users for admins. Query parameters: limit, offset, search
+// and active (true or false).
+func (h *AuthHandler) ListUsers(c *gin.Context) {
+	opts := services.ListUsersOptions{Search: c.Query("search")}
+
+	var err error
+	if opts.Limit, err = strconv.Atoi(c.DefaultQuery("limit", "0")); err != nil {
+		apierror.Write(c, http.StatusBadRequest, "limit must be an integer")
+		return
+	}
+	if opts.Offset, err = strconv.Atoi(c.DefaultQuery("offset", "0")); err != nil {
+		apierror.Write(c, http.StatusBadRequest, "offset must be an integer")
+		return
+	}
+	if value := c.Query("active"); value != "" {
+		active, err := strconv.ParseBool(value)
+		if err != nil {
+			apierror.Write(c, http.StatusBadRequest, "active must be a boolean")
+			return
+		}
+		opts.IsActive = &active
+	}
+
+	resp, err := h.authService.ListUsers(c, opts)
+	if err != nil {
+		apierror.Respond(c, err, "Failed to list users")
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// ListUsersByDateRange lists the users who registered between the from and
+// to query parameters (RFC 3339), by default over the last 30 days, with
+// limit and offset.
+func (h *AuthHandler) ListUsersByDateRange(c *gin.Context) {
+	to := time.Now()
+	if value := c.Query("to"); value != "" {
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			apierror.Write(c, http.StatusBadRequest, "to must be an RFC 3339 timestamp")
+			return
+		}
+		to = parsed
+	}
+	from := to.AddDate(0, 0, -30)
+	if value := c.Query("from"); value != "" {
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			apierror.Write(c, http.StatusBadRequest, "from must be an RFC 3339 timestamp")
+			return
+		}
+		from = parsed
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "0"))
+	if err != nil {
+		apierror.Write(c, http.StatusBadRequest, "limit must be an integer")
+		return
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil {
+		apierror.Write(c, http.StatusBadRequest, "offset must be an integer")
+		return
+	}
+
+	resp, err := h.authService.ListUsersByDateRange(c, from, to, limit, offset)
+	if err != nil {
+		apierror.Respond(c, err, "Failed to list users")
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *AuthHandler) GetDuplicateEmails(c *gin.Context) {
+	rows, err := h.authService.GetDuplicateEmails(c)
+	if err != nil {
+		apierror.Respond(c, err, "Failed to find duplicate emails")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"duplicates": rows})
+}
+
+func (h *AuthHandler) GetUserStats(c *gin.Context) {
+	stats, err := h.authService.GetUserStats(c)
+	if err != nil {
+		apierror.Respond(c, err, "Failed to get user stats")
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
+
+type SetUserRoleBody struct {
+	Role string `json:"role" binding:"required"`
+}
+
+func (h *AuthHandler) SetUserRole(c *gin.Context) {
+	body := &SetUserRoleBody{}
+
+	if err := c.ShouldBindJSON(body); err != nil {
+		apierror.Write(c, http.StatusBadRequest, "Invalid request data")
+		return
+	}
+
+	err := h.authService.SetUserRole(c, middleware.GetUserID(c), c.Param("userID"), body.Role)
+	if err != nil {
+		apierror.Respond(c, err, "Failed to update role")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "role updated"})
+}
+
+func (h *AuthHandler) AdminDeactivateUser(c *gin.Context) {
+	if err := h.authService.AdminDeactivateUser(c, middleware.GetUserID(c), c.Param("userID")); err != nil {
+		apierror.Respond(c, err, "Failed to deactivate user")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+type BulkDeactivateBody struct {
+	UserIDs []string `json:"user_ids" binding:"required"`
+}
+
+func (h *AuthHandler) AdminBulkDeactivateUsers(c *gin.Context) {
+	body := &BulkDeactivateBody{}
+
+	if err := c.ShouldBindJSON(body); err != nil {
+		apierror.Write(c, http.StatusBadRequest, "Invalid request data")
+		return
+	}
+
+	if err := h.authService.AdminBulkDeactivateUsers(c, middleware.GetUserID(c), body.UserIDs); err != nil {
+		apierror.Respond(c, err, "Failed to deactivate users")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *AuthHandler) AdminReactivateUser(c *gin.Context) {
+	if err := h.authService.AdminReactivateUser(c, middleware.GetUserID(c), c.Param("userID")); err != nil {
+		apierror.Respond(c, err, "Failed to reactivate user")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *AuthHandler) AdminDeleteUser(c *gin.Context) {
+	if err := h.authService.AdminDeleteUser(c, middleware.GetUserID(c), c.Param("userID")); err != nil {
+		apierror.Respond(c, err, "Failed to delete user")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -1,11 +1,14 @@
 package handler
 
 import (
-	"fmt"
+	"errors"
+	"math"
 	"net/http"
-	"strings"
+	"strconv"
 
 	"github.com/amrrdev/trawl/services/auth/internal/services"
+	"github.com/amrrdev/trawl/services/shared/apierror"
+	"github.com/amrrdev/trawl/services/shared/middleware"
 	"github.com/gin-gonic/gin"
 )
 
@@ -29,27 +32,13 @@ func (h *AuthHandler) Register(c *gin.Context) {
 	body := &RegisterBody{}
 
 	if err := c.ShouldBindJSON(body); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid request data",
-		})
+		apierror.Write(c, http.StatusBadRequest, "Invalid request data")
 		return
 	}
 
 	resp, err := h.authService.Register(c, body.Name, body.Email, body.Password)
 	if err != nil {
-		fmt.Println(err)
-		statusCode := http.StatusInternalServerError
-		message := "Failed to register user"
-
-		errMsg := err.Error()
-		if strings.Contains(errMsg, "already exists") {
-			statusCode = http.StatusConflict
-			message = "User already exists"
-		}
-
-		c.JSON(statusCode, gin.H{
-			"error": message,
-		})
+		apierror.Respond(c, err, "Failed to register user")
 		return
 	}
 
@@ -65,31 +54,233 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	body := &LoginBody{}
 
 	if err := c.ShouldBindJSON(body); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid request data",
-		})
+		apierror.Write(c, http.StatusBadRequest, "Invalid request data")
+		return
+	}
+
+	resp, err := h.authService.Login(c, body.Email, body.Password, c.ClientIP())
+	if err != nil {
+		var locked *services.LoginLockedError
+		if errors.As(err, &locked) {
+			retryAfter := int(math.Ceil(locked.RetryAfter.Seconds()))
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+			apierror.Write(c, http.StatusTooManyRequests, "Too many failed login attempts")
+			return
+		}
+
+		apierror.Respond(c, err, "Login failed")
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+type RefreshBody struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	body := &RefreshBody{}
+
+	if err := c.ShouldBindJSON(body); err != nil {
+		apierror.Write(c, http.StatusBadRequest, "Invalid request data")
+		return
+	}
+
+	resp, err := h.authService.Refresh(c, body.RefreshToken)
+	if err != nil {
+		apierror.Respond(c, err, "Failed to refresh token")
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+type LogoutBody struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+func (h *AuthHandler) Logout(c *gin.Context) {
+	body := &LogoutBody{}
+
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(body); err != nil {
+			apierror.Write(c, http.StatusBadRequest, "Invalid request data")
+			return
+		}
+	}
+
+	claims := middleware.GetClaims(c)
+	if claims == nil {
+		apierror.Write(c, http.StatusUnauthorized, "Authentication required")
+		return
+	}
+
+	if err := h.authService.Logout(c, claims, body.RefreshToken); err != nil {
+		apierror.Respond(c, err, "Logout failed")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "logged out"})
+}
+
+type ForgotPasswordBody struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+func (h *AuthHandler) ForgotPassword(c *gin.Context) {
+	body := &ForgotPasswordBody{}
+
+	if err := c.ShouldBindJSON(body); err != nil {
+		apierror.Write(c, http.StatusBadRequest, "Invalid request data")
+		return
+	}
+
+	if err := h.authService.ForgotPassword(c, body.Email); err != nil {
+		apierror.Write(c, http.StatusInternalServerError, "Failed to request password reset")
+		return
+	}
+
+	// Same response whether or not the account exists
+	c.JSON(http.StatusOK, gin.H{"message": "if the account exists, a password reset has been sent"})
+}
+
+type ResetPasswordBody struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required,min=8"`
+}
+
+func (h *AuthHandler) ResetPassword(c *gin.Context) {
+	body := &ResetPasswordBody{}
+
+	if err := c.ShouldBindJSON(body); err != nil {
+		apierror.Write(c, http.StatusBadRequest, "Invalid request data")
+		return
+	}
+
+	if err := h.authService.ResetPassword(c, body.Token, body.Password); err != nil {
+		apierror.Respond(c, err, "Failed to reset password")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "password has been reset"})
+}
+
+type ChangePasswordBody struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required,min=8"`
+}
+
+func (h *AuthHandler) ChangePassword(c *gin.Context) {
+	body := &ChangePasswordBody{}
+
+	if err := c.ShouldBindJSON(body); err != nil {
+		apierror.Write(c, http.StatusBadRequest, "Invalid request data")
+		return
+	}
+
+	claims := middleware.GetClaims(c)
+	if claims == nil {
+		apierror.Write(c, http.StatusUnauthorized, "Authentication required")
+		return
+	}
+
+	err := h.authService.ChangePassword(c, claims, body.CurrentPassword, body.NewPassword, c.ClientIP())
+	if err != nil {
+		var locked *services.LoginLockedError
+		if errors.As(err, &locked) {
+			retryAfter := int(math.Ceil(locked.RetryAfter.Seconds()))
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+			apierror.Write(c, http.StatusTooManyRequests, "Too many failed attempts")
+			return
+		}
+
+		apierror.Respond(c, err, "Failed to change password")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "password has been changed, please log in again"})
+}
+
+func (h *AuthHandler) DeactivateAccount(c *gin.Context) {
+	claims := middleware.GetClaims(c)
+	if claims == nil {
+		apierror.Write(c, http.StatusUnauthorized, "Authentication required")
+		return
+	}
+
+	if err := h.authService.DeactivateAccount(c, claims); err != nil {
+		apierror.Write(c, http.StatusInternalServerError, "Failed to deactivate account")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ReactivateAccount takes the same body as Login.
+func (h *AuthHandler) ReactivateAccount(c *gin.Context) {
+	body := &LoginBody{}
+
+	if err := c.ShouldBindJSON(body); err != nil {
+		apierror.Write(c, http.StatusBadRequest, "Invalid request data")
 		return
 	}
 
-	resp, err := h.authService.Login(c, body.Email, body.Password)
+	resp, err := h.authService.ReactivateAccount(c, body.Email, body.Password, c.ClientIP())
 	if err != nil {
-		statusCode := http.StatusInternalServerError
-		message := "Login failed"
-
-		errMsg := err.Error()
-		if strings.Contains(errMsg, "invalid credentials") {
-			statusCode = http.StatusUnauthorized
-			message = "Invalid credentials"
-		} else if strings.Contains(errMsg, "deactivated") {
-			statusCode = http.StatusForbidden
-			message = "Account is deactivated"
+		var locked *services.LoginLockedError
+		if errors.As(err, &locked) {
+			retryAfter := int(math.Ceil(locked.RetryAfter.Seconds()))
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+			apierror.Write(c, http.StatusTooManyRequests, "Too many failed login attempts")
+			return
 		}
 
-		c.JSON(statusCode, gin.H{
-			"error": message,
-		})
+		apierror.Respond(c, err, "Failed to reactivate account")
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *AuthHandler) GetProfile(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+
+	resp, err := h.authService.GetProfile(c, userID)
+	if err != nil {
+		apierror.Respond(c, err, "Failed to get profile")
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+type UpdateProfileBody struct {
+	Name  *string `json:"name" binding:"omitempty,min=2"`
+	Email *string `json:"email" binding:"omitempty,email"`
+}
+
+func (h *AuthHandler) UpdateProfile(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+	body := &UpdateProfileBody{}
+
+	if err := c.ShouldBindJSON(body); err != nil {
+		apierror.Write(c, http.StatusBadRequest, "Invalid request data")
+		return
+	}
+
+	resp, err := h.authService.UpdateProfile(c, userID, services.UpdateProfileRequest{
+		Name:  body.Name,
+		Email: body.Email,
+	})
+	if err != nil {
+		apierror.Respond(c, err, "Failed to update profile")
 		return
 	}
 
 	c.JSON(http.StatusOK, resp)
 }
+
+func (h *AuthHandler) JWKS(c *gin.Context) {
+	c.JSON(http.StatusOK, h.authService.JWKS())
+}
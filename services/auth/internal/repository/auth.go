@@ -15,6 +15,8 @@ type UserRepository interface {
 	UpdateUserPassword(ctx context.Context, arg db.UpdateUserPasswordParams) error
 
 	GetUserByEmail(ctx context.Context, email string) (db.User, error)
+	// GetUserByEmailAnyStatus also finds deactivated users.
+	GetUserByEmailAnyStatus(ctx context.Context, email string) (db.User, error)
 	GetUserByID(ctx context.Context, userID pgtype.UUID) (db.User, error)
 	GetUserForValidation(ctx context.Context, userID pgtype.UUID) (db.GetUserForValidationRow, error)
 	CheckUserExists(ctx context.Context, email string) (bool, error)
@@ -27,12 +29,14 @@ type UserRepository interface {
 	ListActiveUsers(ctx context.Context, arg db.ListActiveUsersParams) ([]db.ListActiveUsersRow, error)
 	CountUsers(ctx context.Context, arg db.CountUsersParams) (int64, error)
 	GetUsersByDateRange(ctx context.Context, arg db.GetUsersByDateRangeParams) ([]db.GetUsersByDateRangeRow, error)
+	CountUsersByDateRange(ctx context.Context, arg db.CountUsersByDateRangeParams) (int64, error)
 
 	GetUserStats(ctx context.Context) (db.GetUserStatsRow, error)
 
 	GetDuplicateEmails(ctx context.Context) ([]db.GetDuplicateEmailsRow, error)
 
 	AdminHardDeleteUser(ctx context.Context, userID pgtype.UUID) error
+	UpdateUserRole(ctx context.Context, arg db.UpdateUserRoleParams) (int64, error)
 }
 
 type userRepository struct {
@@ -49,6 +53,10 @@ func (r *userRepository) GetUserByEmail(ctx context.Context, email string) (db.U
 	return r.queries.GetUserByEmail(ctx, email)
 }
 
+func (r *userRepository) GetUserByEmailAnyStatus(ctx context.Context, email string) (db.User, error) {
+	return r.queries.GetUserByEmailAnyStatus(ctx, email)
+}
+
 func (r *userRepository) GetUserByID(ctx context.Context, userID pgtype.UUID) (db.User, error) {
 	return r.queries.GetUserByID(ctx, userID)
 }
@@ -105,6 +113,10 @@ func (r *userRepository) GetUsersByDateRange(ctx context.Context, arg db.GetUser
 	return r.queries.GetUsersByDateRange(ctx, arg)
 }
 
+func (r *userRepository) CountUsersByDateRange(ctx context.Context, arg db.CountUsersByDateRangeParams) (int64, error) {
+	return r.queries.CountUsersByDateRange(ctx, arg)
+}
+
 func (r *userRepository) GetUserStats(ctx context.Context) (db.GetUserStatsRow, error) {
 	return r.queries.GetUserStats(ctx)
 }
@@ -116,3 +128,7 @@ func (r *userRepository) GetDuplicateEmails(ctx context.Context) ([]db.GetDuplic
 func (r *userRepository) AdminHardDeleteUser(ctx context.Context, userID pgtype.UUID) error {
 	return r.queries.AdminHardDeleteUser(ctx, userID)
 }
+
+func (r *userRepository) UpdateUserRole(ctx context.Context, arg db.UpdateUserRoleParams) (int64, error) {
+	return r.queries.UpdateUserRole(ctx, arg)
+}
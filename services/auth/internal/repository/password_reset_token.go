@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/amrrdev/trawl/services/auth/internal/db"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type PasswordResetTokenRepository interface {
+	CreatePasswordResetToken(ctx context.Context, arg db.CreatePasswordResetTokenParams) error
+	ConsumePasswordResetToken(ctx context.Context, tokenHash string) (pgtype.UUID, error)
+	InvalidateUserPasswordResetTokens(ctx context.Context, userID pgtype.UUID) error
+	DeleteExpiredPasswordResetTokens(ctx context.Context, expiresAt pgtype.Timestamp) error
+}
+
+type passwordResetTokenRepository struct {
+	queries *db.Queries
+}
+
+func NewPasswordResetTokenRepository(pool *pgxpool.Pool) PasswordResetTokenRepository {
+	return &passwordResetTokenRepository{
+		queries: db.New(pool),
+	}
+}
+
+func (r *passwordResetTokenRepository) CreatePasswordResetToken(ctx context.Context, arg db.CreatePasswordResetTokenParams) error {
+	return r.queries.CreatePasswordResetToken(ctx, arg)
+}
+
+func (r *passwordResetTokenRepository) ConsumePasswordResetToken(ctx context.Context, tokenHash string) (pgtype.UUID, error) {
+	return r.queries.ConsumePasswordResetToken(ctx, tokenHash)
+}
+
+func (r *passwordResetTokenRepository) InvalidateUserPasswordResetTokens(ctx context.Context, userID pgtype.UUID) error {
+	return r.queries.InvalidateUserPasswordResetTokens(ctx, userID)
+}
+
+func (r *passwordResetTokenRepository) DeleteExpiredPasswordResetTokens(ctx context.Context, expiresAt pgtype.Timestamp) error {
+	return r.queries.DeleteExpiredPasswordResetTokens(ctx, expiresAt)
+}
@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/amrrdev/trawl/services/auth/internal/db"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type RefreshTokenRepository interface {
+	CreateRefreshToken(ctx context.Context, arg db.CreateRefreshTokenParams) (db.RefreshToken, error)
+	GetRefreshTokenByHash(ctx context.Context, tokenHash string) (db.RefreshToken, error)
+	RevokeRefreshToken(ctx context.Context, tokenID pgtype.UUID) (int64, error)
+	RevokeRefreshTokenFamily(ctx context.Context, familyID pgtype.UUID) error
+	RevokeUserRefreshTokens(ctx context.Context, userID pgtype.UUID) error
+}
+
+type refreshTokenRepository struct {
+	queries *db.Queries
+}
+
+func NewRefreshTokenRepository(pool *pgxpool.Pool) RefreshTokenRepository {
+	return &refreshTokenRepository{
+		queries: db.New(pool),
+	}
+}
+
+func (r *refreshTokenRepository) CreateRefreshToken(ctx context.Context, arg db.CreateRefreshTokenParams) (db.RefreshToken, error) {
+	return r.queries.CreateRefreshToken(ctx, arg)
+}
+
+func (r *refreshTokenRepository) GetRefreshTokenByHash(ctx context.Context, tokenHash string) (db.RefreshToken, error) {
+	return r.queries.GetRefreshTokenByHash(ctx, tokenHash)
+}
+
+func (r *refreshTokenRepository) RevokeRefreshToken(ctx context.Context, tokenID pgtype.UUID) (int64, error) {
+	return r.queries.RevokeRefreshToken(ctx, tokenID)
+}
+
+func (r *refreshTokenRepository) RevokeRefreshTokenFamily(ctx context.Context, familyID pgtype.UUID) error {
+	return r.queries.RevokeRefreshTokenFamily(ctx, familyID)
+}
+
+func (r *refreshTokenRepository) RevokeUserRefreshTokens(ctx context.Context, userID pgtype.UUID) error {
+	return r.queries.RevokeUserRefreshTokens(ctx, userID)
+}
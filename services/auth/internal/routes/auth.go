@@ -2,6 +2,7 @@ package routes
 
 import (
 	"github.com/amrrdev/trawl/services/auth/internal/handler"
+	"github.com/amrrdev/trawl/services/shared/jwt"
 	"github.com/amrrdev/trawl/services/shared/middleware"
 	"github.com/gin-gonic/gin"
 )
@@ -12,14 +13,40 @@ func RegisterRoutes(router *gin.RouterGroup, authHandlers *handler.AuthHandler,
 		// Public routes - no authentication required
 		auth.POST("/register", authHandlers.Register)
 		auth.POST("/login", authHandlers.Login)
+		auth.POST("/refresh", authHandlers.Refresh)
+		auth.POST("/forgot-password", authHandlers.ForgotPassword)
+		auth.POST("/reset-password", authHandlers.ResetPassword)
+		// Re-authenticates with email/password, as a deactivated account
+		// has no valid token
+		auth.POST("/reactivate", authHandlers.ReactivateAccount)
+		auth.GET("/jwks", authHandlers.JWKS)
 	}
 
+	// Logout revokes the caller's access token, so it needs one
+	auth.POST("/logout", authMiddleware.RequireAuth(), authHandlers.Logout)
+
 	// Protected routes - authentication required
 	protected := router.Group("/protected")
 	protected.Use(authMiddleware.RequireAuth())
 	{
-		// Example: Add protected endpoints here
-		// protected.GET("/profile", profileHandler.GetProfile)
-		// protected.PUT("/profile", profileHandler.UpdateProfile)
+		protected.GET("/profile", authHandlers.GetProfile)
+		protected.PUT("/profile", authHandlers.UpdateProfile)
+		protected.POST("/change-password", authHandlers.ChangePassword)
+		protected.DELETE("/account", authHandlers.DeactivateAccount)
+	}
+
+	// Admin routes - the admin role is required
+	admin := router.Group("/admin")
+	admin.Use(authMiddleware.RequireAuth(), middleware.RequireRole(jwt.RoleAdmin))
+	{
+		admin.GET("/users", authHandlers.ListUsers)
+		admin.GET("/users/registered", authHandlers.ListUsersByDateRange)
+		admin.GET("/users/duplicate-emails", authHandlers.GetDuplicateEmails)
+		admin.POST("/users/deactivate", authHandlers.AdminBulkDeactivateUsers)
+		admin.GET("/stats", authHandlers.GetUserStats)
+		admin.PUT("/users/:userID/role", authHandlers.SetUserRole)
+		admin.POST("/users/:userID/deactivate", authHandlers.AdminDeactivateUser)
+		admin.POST("/users/:userID/reactivate", authHandlers.AdminReactivateUser)
+		admin.DELETE("/users/:userID", authHandlers.AdminDeleteUser)
 	}
 }
@@ -1,16 +1,31 @@
 package server
 
 import (
+	"fmt"
+
 	"github.com/amrrdev/trawl/services/auth/internal/handler"
 	"github.com/amrrdev/trawl/services/auth/internal/routes"
+	"github.com/amrrdev/trawl/services/shared/metrics"
 	"github.com/amrrdev/trawl/services/shared/middleware"
+	"github.com/amrrdev/trawl/services/shared/tracing"
 	"github.com/gin-gonic/gin"
 )
 
-func NewServer(authHandlers *handler.AuthHandler, authMiddleware *middleware.AuthMiddleware) *gin.Engine {
-	g := gin.Default()
+// NewServer builds the auth API. Client IPs, which the login lockout is
+// keyed on, are only taken from X-Forwarded-For and similar headers when the
+// request comes from one of trustedProxies; with none, the peer address is
+// used, so clients can't pick the IP they're counted under.
+func NewServer(authHandlers *handler.AuthHandler, authMiddleware *middleware.AuthMiddleware, corsConfig middleware.CORSConfig, trustedProxies []string) (*gin.Engine, error) {
+	g := gin.New()
+	if err := g.SetTrustedProxies(trustedProxies); err != nil {
+		return nil, fmt.Errorf("invalid trusted proxies: %w", err)
+	}
+	g.Use(tracing.Middleware("trawl-auth"))
+	g.Use(middleware.RequestID(), middleware.Logger(), gin.Recovery())
+	g.Use(middleware.CORS(corsConfig))
+	g.GET("/metrics", gin.WrapH(metrics.Handler()))
 	api := g.Group("/api/v1")
 	routes.RegisterRoutes(api, authHandlers, authMiddleware)
 
-	return g
+	return g, nil
 }
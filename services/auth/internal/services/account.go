@@ -0,0 +1,53 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/amrrdev/trawl/services/shared/jwt"
+)
+
+// DeactivateAccount soft-deletes the account of the user described by
+// claims: it can no longer log in or refresh tokens, every refresh token is
+// revoked, and deactivation moves the user's tokens_valid_after cutoff, so
+// every access token issued so far is rejected too. The access token making
+// the request is also revoked by jti, as in ChangePassword. The data is kept,
+// so the account can be reactivated with ReactivateAccount.
+func (s *AuthService) DeactivateAccount(ctx context.Context, claims *jwt.Claims) error {
+	id, err := parseUserID(claims.UserID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.DeactivateUser(ctx, id); err != nil {
+		return fmt.Errorf("failed to deactivate user: %w", err)
+	}
+	if err := s.refreshRepo.RevokeUserRefreshTokens(ctx, id); err != nil {
+		return fmt.Errorf("failed to revoke refresh tokens: %w", err)
+	}
+	if err := s.jwtService.Revoke(ctx, claims); err != nil {
+		return fmt.Errorf("failed to revoke access token: %w", err)
+	}
+
+	slog.InfoContext(ctx, "Account deactivated", "user_id", claims.UserID)
+	return nil
+}
+
+// ReactivateAccount reactivates a deactivated account and logs its owner in.
+// The caller has no valid token left, so it re-authenticates with
+// email/password, under the same lockout as Login.
+func (s *AuthService) ReactivateAccount(ctx context.Context, email, password, clientIP string) (*LoginResponse, error) {
+	user, err := s.authenticate(ctx, email, password, clientIP)
+	if err != nil {
+		return nil, err
+	}
+
+	if !user.IsActive.Bool {
+		if err := s.repo.ReactivateUser(ctx, user.UserID); err != nil {
+			return nil, fmt.Errorf("failed to reactivate user: %w", err)
+		}
+		slog.InfoContext(ctx, "Account reactivated", "user_id", user.UserID.String())
+	}
+	return s.startSession(ctx, user)
+}
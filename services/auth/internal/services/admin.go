@@ -0,0 +1,298 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/amrrdev/trawl/services/auth/internal/db"
+	"github.com/amrrdev/trawl/services/shared/apierror"
+	"github.com/amrrdev/trawl/services/shared/jwt"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Bounds of an admin user listing page.
+const (
+	DefaultUserListLimit = 50
+	MaxUserListLimit     = 200
+	// MaxBulkUsers bounds the users a bulk operation takes at once.
+	MaxBulkUsers = 1000
+)
+
+// AdminUser is a user as admins see it.
+type AdminUser struct {
+	UserID    string    `json:"user_id"`
+	Email     string    `json:"email"`
+	Name      string    `json:"name"`
+	Role      string    `json:"role,omitempty"`
+	IsActive  bool      `json:"is_active"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type UserListResponse struct {
+	Users  []AdminUser `json:"users"`
+	Total  int64       `json:"total"`
+	Limit  int         `json:"limit"`
+	Offset int         `json:"offset"`
+}
+
+// ListUsersOptions filters an admin user listing. Search matches email or
+// name; a nil IsActive lists users in either state.
+type ListUsersOptions struct {
+	Limit    int
+	Offset   int
+	Search   string
+	IsActive *bool
+}
+
+// ListUsers returns a page of users, newest first.
+func (s *AuthService) ListUsers(ctx context.Context, opts ListUsersOptions) (*UserListResponse, error) {
+	var err error
+	if opts.Limit, err = resolvePage(opts.Limit, opts.Offset); err != nil {
+		return nil, err
+	}
+
+	var isActive pgtype.Bool
+	if opts.IsActive != nil {
+		isActive = pgtype.Bool{Bool: *opts.IsActive, Valid: true}
+	}
+	var search pgtype.Text
+	if term := strings.TrimSpace(opts.Search); term != "" {
+		search = pgtype.Text{String: term, Valid: true}
+	}
+
+	rows, err := s.repo.ListUsers(ctx, db.ListUsersParams{
+		Limit:    int32(opts.Limit),
+		Offset:   int32(opts.Offset),
+		IsActive: isActive,
+		Search:   search,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+	total, err := s.repo.CountUsers(ctx, db.CountUsersParams{
+		IsActive: isActive,
+		Search:   search,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to count users: %w", err)
+	}
+
+	users := make([]AdminUser, 0, len(rows))
+	for _, row := range rows {
+		users = append(users, AdminUser{
+			UserID:    row.UserID.String(),
+			Email:     row.Email,
+			Name:      row.Name.String,
+			Role:      row.Role,
+			IsActive:  row.IsActive.Bool,
+			CreatedAt: row.CreatedAt.Time,
+		})
+	}
+	return &UserListResponse{
+		Users:  users,
+		Total:  total,
+		Limit:  opts.Limit,
+		Offset: opts.Offset,
+	}, nil
+}
+
+// ListUsersByDateRange returns a page of the users who registered within
+// [from, to], newest first. The query doesn't select roles, so Role is empty.
+func (s *AuthService) ListUsersByDateRange(ctx context.Context, from, to time.Time, limit, offset int) (*UserListResponse, error) {
+	limit, err := resolvePage(limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	if from.After(to) {
+		return nil, apierror.InvalidInput("invalid date range: from is after to")
+	}
+
+	start := pgtype.Timestamp{Time: from.UTC(), Valid: true}
+	end := pgtype.Timestamp{Time: to.UTC(), Valid: true}
+	rows, err := s.repo.GetUsersByDateRange(ctx, db.GetUsersByDateRangeParams{
+		CreatedAt:   start,
+		CreatedAt_2: end,
+		Limit:       int32(limit),
+		Offset:      int32(offset),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+	total, err := s.repo.CountUsersByDateRange(ctx, db.CountUsersByDateRangeParams{
+		CreatedAt:   start,
+		CreatedAt_2: end,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to count users: %w", err)
+	}
+
+	users := make([]AdminUser, 0, len(rows))
+	for _, row := range rows {
+		users = append(users, AdminUser{
+			UserID:    row.UserID.String(),
+			Email:     row.Email,
+			Name:      row.Name.String,
+			IsActive:  row.IsActive.Bool,
+			CreatedAt: row.CreatedAt.Time,
+		})
+	}
+	return &UserListResponse{
+		Users:  users,
+		Total:  total,
+		Limit:  limit,
+		Offset: offset,
+	}, nil
+}
+
+// resolvePage validates a listing page, returning the limit with the default
+// applied.
+func resolvePage(limit, offset int) (int, error) {
+	if limit == 0 {
+		limit = DefaultUserListLimit
+	}
+	if limit < 0 || limit > MaxUserListLimit {
+		return 0, apierror.InvalidInput("invalid limit %d: must be between 1 and %d", limit, MaxUserListLimit)
+	}
+	if offset < 0 {
+		return 0, apierror.InvalidInput("invalid offset %d: must not be negative", offset)
+	}
+	return limit, nil
+}
+
+// GetDuplicateEmails lists emails held by more than one account, which the
+// unique index should prevent; anything listed needs cleaning up.
+func (s *AuthService) GetDuplicateEmails(ctx context.Context) ([]db.GetDuplicateEmailsRow, error) {
+	rows, err := s.repo.GetDuplicateEmails(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find duplicate emails: %w", err)
+	}
+	return rows, nil
+}
+
+func (s *AuthService) GetUserStats(ctx context.Context) (*db.GetUserStatsRow, error) {
+	stats, err := s.repo.GetUserStats(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user stats: %w", err)
+	}
+	return &stats, nil
+}
+
+// SetUserRole gives userID role. Admins can't change their own role, so the
+// last admin can't demote themselves by mistake. The new role shows in the
+// user's tokens from their next login or refresh.
+func (s *AuthService) SetUserRole(ctx context.Context, adminID, userID, role string) error {
+	if role != jwt.RoleUser && role != jwt.RoleAdmin {
+		return apierror.InvalidInput("invalid role %q", role)
+	}
+	if userID == adminID {
+		return apierror.InvalidInput("invalid user: admins can't change their own role")
+	}
+	id, err := parseUserID(userID)
+	if err != nil {
+		return err
+	}
+
+	updated, err := s.repo.UpdateUserRole(ctx, db.UpdateUserRoleParams{
+		UserID: id,
+		Role:   role,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update role: %w", err)
+	}
+	if updated == 0 {
+		return apierror.NotFound("user not found")
+	}
+
+	slog.InfoContext(ctx, "User role changed", "admin_id", adminID, "user_id", userID, "role", role)
+	return nil
+}
+
+// AdminDeactivateUser deactivates userID and signs them out: their refresh
+// tokens are revoked, and deactivation moves their tokens_valid_after cutoff,
+// which rejects their access tokens.
+func (s *AuthService) AdminDeactivateUser(ctx context.Context, adminID, userID string) error {
+	id, err := parseUserID(userID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.DeactivateUser(ctx, id); err != nil {
+		return fmt.Errorf("failed to deactivate user: %w", err)
+	}
+	if err := s.refreshRepo.RevokeUserRefreshTokens(ctx, id); err != nil {
+		return fmt.Errorf("failed to revoke refresh tokens: %w", err)
+	}
+
+	slog.InfoContext(ctx, "User deactivated by admin", "admin_id", adminID, "user_id", userID)
+	return nil
+}
+
+// AdminBulkDeactivateUsers deactivates userIDs and signs them out as
+// AdminDeactivateUser does. The admin's own account can't be among them.
+func (s *AuthService) AdminBulkDeactivateUsers(ctx context.Context, adminID string, userIDs []string) error {
+	if len(userIDs) == 0 {
+		return apierror.InvalidInput("user_ids is required")
+	}
+	if len(userIDs) > MaxBulkUsers {
+		return apierror.InvalidInput("invalid user_ids: at most %d at once", MaxBulkUsers)
+	}
+	ids := make([]pgtype.UUID, 0, len(userIDs))
+	for _, userID := range userIDs {
+		if userID == adminID {
+			return apierror.InvalidInput("invalid user_ids: admins can't deactivate themselves")
+		}
+		id, err := parseUserID(userID)
+		if err != nil {
+			return apierror.InvalidInput("invalid user_ids: %q is not a user id", userID)
+		}
+		ids = append(ids, id)
+	}
+
+	if err := s.repo.BulkDeactivateUsers(ctx, ids); err != nil {
+		return fmt.Errorf("failed to deactivate users: %w", err)
+	}
+	for _, id := range ids {
+		if err := s.refreshRepo.RevokeUserRefreshTokens(ctx, id); err != nil {
+			return fmt.Errorf("failed to revoke refresh tokens: %w", err)
+		}
+	}
+
+	slog.InfoContext(ctx, "Users deactivated by admin", "admin_id", adminID, "users", len(ids))
+	return nil
+}
+
+func (s *AuthService) AdminReactivateUser(ctx context.Context, adminID, userID string) error {
+	id, err := parseUserID(userID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.ReactivateUser(ctx, id); err != nil {
+		return fmt.Errorf("failed to reactivate user: %w", err)
+	}
+
+	slog.InfoContext(ctx, "User reactivated by admin", "admin_id", adminID, "user_id", userID)
+	return nil
+}
+
+// AdminDeleteUser permanently deletes userID along with their tokens. Admins
+// can't delete themselves this way.
+func (s *AuthService) AdminDeleteUser(ctx context.Context, adminID, userID string) error {
+	if userID == adminID {
+		return apierror.InvalidInput("invalid user: admins can't delete themselves")
+	}
+	id, err := parseUserID(userID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.AdminHardDeleteUser(ctx, id); err != nil {
+		return fmt.Errorf("failed to delete user: %w", err)
+	}
+
+	slog.InfoContext(ctx, "User deleted by admin", "admin_id", adminID, "user_id", userID)
+	return nil
+}
@@ -0,0 +1,117 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/amrrdev/trawl/services/auth/internal/db"
+	"github.com/amrrdev/trawl/services/shared/apierror"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestAdminBulkDeactivateUsers(t *testing.T) {
+	const (
+		adminID = "0b7c3f0e-8f1a-4c55-9a10-6b2f4d9e7a01"
+		userA   = "1c8d4e1f-9a2b-4d66-8b21-7c3e5fae8b12"
+		userB   = "2d9e5f20-ab3c-4e77-9c32-8d4f6a0f9c23"
+	)
+
+	tests := []struct {
+		name    string
+		userIDs []string
+		wantErr error
+	}{
+		{"deactivates and signs out", []string{userA, userB}, nil},
+		{"no users", nil, apierror.ErrInvalidInput},
+		{"includes admin", []string{userA, adminID}, apierror.ErrInvalidInput},
+		{"malformed id", []string{userA, "not-a-uuid"}, apierror.ErrInvalidInput},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := &fakeUserRepo{}
+			refresh := &fakeRefreshRepo{}
+			s := NewAuthService(users, refresh, nil, NewHashingService(), nil, nil, nil, 0, 0)
+
+			err := s.AdminBulkDeactivateUsers(context.Background(), adminID, tt.userIDs)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				if len(users.deactivated) != 0 || len(refresh.revokedUsers) != 0 {
+					t.Errorf("rejected request deactivated %v and revoked %v", users.deactivated, refresh.revokedUsers)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i, userID := range tt.userIDs {
+				id := mustUserID(userID)
+				if users.deactivated[i] != id {
+					t.Errorf("deactivated %v, want %v", users.deactivated, tt.userIDs)
+				}
+				if refresh.revokedUsers[i] != id {
+					t.Errorf("refresh tokens revoked for %v, want %v", refresh.revokedUsers, tt.userIDs)
+				}
+			}
+		})
+	}
+}
+
+func TestListUsersByDateRange(t *testing.T) {
+	day := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	users := &fakeUserRepo{}
+	for i := range 5 {
+		users.users = append(users.users, db.GetUsersByDateRangeRow{
+			Email:     fmt.Sprintf("user%d@example.com", 4-i),
+			CreatedAt: pgtype.Timestamp{Time: day.AddDate(0, 0, 4-i), Valid: true},
+		})
+	}
+	s := NewAuthService(users, nil, nil, NewHashingService(), nil, nil, nil, 0, 0)
+
+	tests := []struct {
+		name       string
+		from, to   time.Time
+		limit      int
+		offset     int
+		wantEmails []string
+		wantTotal  int64
+		wantErr    error
+	}{
+		{"whole range", day, day.AddDate(0, 0, 4), 0, 0,
+			[]string{"user4@example.com", "user3@example.com", "user2@example.com", "user1@example.com", "user0@example.com"}, 5, nil},
+		{"page within range", day.AddDate(0, 0, 1), day.AddDate(0, 0, 3), 2, 1,
+			[]string{"user2@example.com", "user1@example.com"}, 3, nil},
+		{"offset past the end", day, day.AddDate(0, 0, 4), 2, 10, nil, 5, nil},
+		{"from after to", day.AddDate(0, 0, 1), day, 0, 0, nil, 0, apierror.ErrInvalidInput},
+		{"limit too large", day, day, MaxUserListLimit + 1, 0, nil, 0, apierror.ErrInvalidInput},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.ListUsersByDateRange(context.Background(), tt.from, tt.to, tt.limit, tt.offset)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Total != tt.wantTotal {
+				t.Errorf("Total = %d, want %d", got.Total, tt.wantTotal)
+			}
+			var emails []string
+			for _, user := range got.Users {
+				emails = append(emails, user.Email)
+			}
+			if !slices.Equal(emails, tt.wantEmails) {
+				t.Errorf("users = %v, want %v", emails, tt.wantEmails)
+			}
+		})
+	}
+}
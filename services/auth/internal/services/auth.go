@@ -4,73 +4,134 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/amrrdev/trawl/services/auth/internal/db"
 	"github.com/amrrdev/trawl/services/auth/internal/repository"
+	"github.com/amrrdev/trawl/services/shared/apierror"
 	"github.com/amrrdev/trawl/services/shared/jwt"
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
 type AuthService struct {
-	repo           repository.UserRepository
-	hashingService *HashingService
-	jwtService     *jwt.Service
+	repo            repository.UserRepository
+	refreshRepo     repository.RefreshTokenRepository
+	resetRepo       repository.PasswordResetTokenRepository
+	hashingService  *HashingService
+	jwtService      *jwt.Service
+	resetSender     PasswordResetSender
+	loginLimiter    *LoginLimiter
+	refreshTokenTTL time.Duration
+	// passwordResetTTL is how long a password reset token stays usable.
+	passwordResetTTL time.Duration
 }
 
 type RegisterResponse struct {
-	UserID      string `json:"user_id"`
-	Email       string `json:"email"`
-	Name        string `json:"name"`
-	AccessToken string `json:"access_token"`
+	UserID       string `json:"user_id"`
+	Email        string `json:"email"`
+	Name         string `json:"name"`
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
 }
 
 type LoginResponse struct {
-	UserID      string `json:"user_id"`
-	Email       string `json:"email"`
-	Name        string `json:"name"`
-	AccessToken string `json:"access_token"`
+	UserID       string `json:"user_id"`
+	Email        string `json:"email"`
+	Name         string `json:"name"`
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
 }
 
-func NewAuthService(repo repository.UserRepository, hashingService *HashingService, jwtService *jwt.Service) *AuthService {
+func NewAuthService(
+	repo repository.UserRepository,
+	refreshRepo repository.RefreshTokenRepository,
+	resetRepo repository.PasswordResetTokenRepository,
+	hashingService *HashingService,
+	jwtService *jwt.Service,
+	resetSender PasswordResetSender,
+	loginLimiter *LoginLimiter,
+	refreshTokenTTL time.Duration,
+	passwordResetTTL time.Duration,
+) *AuthService {
 	return &AuthService{
-		repo:           repo,
-		hashingService: hashingService,
-		jwtService:     jwtService,
+		repo:             repo,
+		refreshRepo:      refreshRepo,
+		resetRepo:        resetRepo,
+		hashingService:   hashingService,
+		jwtService:       jwtService,
+		resetSender:      resetSender,
+		loginLimiter:     loginLimiter,
+		refreshTokenTTL:  refreshTokenTTL,
+		passwordResetTTL: passwordResetTTL,
 	}
 }
 
-func (s *AuthService) Login(ctx context.Context, email, password string) (*LoginResponse, error) {
-	email = strings.ToLower(strings.TrimSpace(email))
+// JWKS returns the public keys that verify access tokens, for services that
+// validate tokens without the signing key.
+func (s *AuthService) JWKS() jwt.JWKS {
+	return s.jwtService.JWKS()
+}
 
-	user, err := s.repo.GetUserByEmail(ctx, email)
+// Login authenticates email/password. Failed attempts are counted per email
+// and per email+clientIP; once locked out, Login returns a *LoginLockedError.
+func (s *AuthService) Login(ctx context.Context, email, password, clientIP string) (*LoginResponse, error) {
+	user, err := s.authenticate(ctx, email, password, clientIP)
 	if err != nil {
-		return nil, fmt.Errorf("invalid credentials")
+		return nil, err
 	}
-
 	if !user.IsActive.Bool {
-		return nil, fmt.Errorf("account is deactivated")
+		return nil, apierror.Forbidden("account is deactivated")
+	}
+	return s.startSession(ctx, user)
+}
+
+// authenticate checks email/password, counting failures towards the login
+// lockout. It doesn't look at whether the account is active.
+func (s *AuthService) authenticate(ctx context.Context, email, password, clientIP string) (db.User, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+
+	if err := s.loginLimiter.Check(email, clientIP); err != nil {
+		return db.User{}, err
+	}
+
+	user, err := s.repo.GetUserByEmailAnyStatus(ctx, email)
+	if err != nil {
+		s.loginLimiter.RecordFailure(email, clientIP)
+		return db.User{}, apierror.Unauthorized("invalid credentials")
 	}
 
 	isValid := s.hashingService.ComparePassword(user.Password, password)
 	if !isValid {
-		return nil, fmt.Errorf("invalid credentials")
+		s.loginLimiter.RecordFailure(email, clientIP)
+		return db.User{}, apierror.Unauthorized("invalid credentials")
 	}
+	s.loginLimiter.RecordSuccess(email, clientIP)
+	return user, nil
+}
 
-	accessToken, err := s.jwtService.GenerateAccessToken(user.UserID.String(), email)
+// startSession issues the access and refresh tokens of a fresh login.
+func (s *AuthService) startSession(ctx context.Context, user db.User) (*LoginResponse, error) {
+	accessToken, err := s.jwtService.GenerateAccessToken(user.UserID.String(), user.Email, user.Role)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate access token: %w", err)
 	}
 
+	refreshToken, err := s.issueRefreshToken(ctx, user.UserID, pgtype.UUID{})
+	if err != nil {
+		return nil, err
+	}
+
 	name := ""
 	if user.Name.Valid {
 		name = user.Name.String
 	}
 
 	return &LoginResponse{
-		UserID:      user.UserID.String(),
-		Email:       user.Email,
-		Name:        name,
-		AccessToken: accessToken,
+		UserID:       user.UserID.String(),
+		Email:        user.Email,
+		Name:         name,
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
 	}, nil
 }
 
@@ -83,7 +144,7 @@ func (s *AuthService) Register(ctx context.Context, name, email, password string
 		return nil, fmt.Errorf("failed to check user existence: %w", err)
 	}
 	if isExists {
-		return nil, fmt.Errorf("user already exists")
+		return nil, apierror.Conflict("user already exists")
 	}
 
 	hashedPassword, err := s.hashingService.HashPassword(password)
@@ -100,20 +161,26 @@ func (s *AuthService) Register(ctx context.Context, name, email, password string
 		return nil, fmt.Errorf("failed to create user: %w", err)
 	}
 
-	accessToken, err := s.jwtService.GenerateAccessToken(newUser.UserID.String(), newUser.Email)
+	accessToken, err := s.jwtService.GenerateAccessToken(newUser.UserID.String(), newUser.Email, newUser.Role)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate access token: %w", err)
 	}
 
+	refreshToken, err := s.issueRefreshToken(ctx, newUser.UserID, pgtype.UUID{})
+	if err != nil {
+		return nil, err
+	}
+
 	userName := ""
 	if newUser.Name.Valid {
 		userName = newUser.Name.String
 	}
 
 	return &RegisterResponse{
-		UserID:      newUser.UserID.String(),
-		Email:       newUser.Email,
-		Name:        userName,
-		AccessToken: accessToken,
+		UserID:       newUser.UserID.String(),
+		Email:        newUser.Email,
+		Name:         userName,
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
 	}, nil
 }
@@ -0,0 +1,92 @@
+package services
+
+import (
+	"context"
+
+	"github.com/amrrdev/trawl/services/auth/internal/db"
+	"github.com/amrrdev/trawl/services/auth/internal/repository"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// The fakes embed their interface, so calling a method a test didn't expect
+// panics.
+
+type fakeUserRepo struct {
+	repository.UserRepository
+	passwords   map[pgtype.UUID]string
+	deactivated []pgtype.UUID
+	// users are listed by the date range queries, newest first.
+	users []db.GetUsersByDateRangeRow
+}
+
+func (r *fakeUserRepo) UpdateUserPassword(ctx context.Context, arg db.UpdateUserPasswordParams) error {
+	if r.passwords == nil {
+		r.passwords = make(map[pgtype.UUID]string)
+	}
+	r.passwords[arg.UserID] = arg.Password
+	return nil
+}
+
+func (r *fakeUserRepo) DeactivateUser(ctx context.Context, userID pgtype.UUID) error {
+	r.deactivated = append(r.deactivated, userID)
+	return nil
+}
+
+func (r *fakeUserRepo) BulkDeactivateUsers(ctx context.Context, userIDs []pgtype.UUID) error {
+	r.deactivated = append(r.deactivated, userIDs...)
+	return nil
+}
+
+func (r *fakeUserRepo) GetUsersByDateRange(ctx context.Context, arg db.GetUsersByDateRangeParams) ([]db.GetUsersByDateRangeRow, error) {
+	rows := r.usersBetween(arg.CreatedAt, arg.CreatedAt_2)
+	start := min(int(arg.Offset), len(rows))
+	return rows[start:min(start+int(arg.Limit), len(rows))], nil
+}
+
+func (r *fakeUserRepo) CountUsersByDateRange(ctx context.Context, arg db.CountUsersByDateRangeParams) (int64, error) {
+	return int64(len(r.usersBetween(arg.CreatedAt, arg.CreatedAt_2))), nil
+}
+
+func (r *fakeUserRepo) usersBetween(from, to pgtype.Timestamp) []db.GetUsersByDateRangeRow {
+	var rows []db.GetUsersByDateRangeRow
+	for _, user := range r.users {
+		if !user.CreatedAt.Time.Before(from.Time) && !user.CreatedAt.Time.After(to.Time) {
+			rows = append(rows, user)
+		}
+	}
+	return rows
+}
+
+type fakeRefreshRepo struct {
+	repository.RefreshTokenRepository
+	revokedUsers []pgtype.UUID
+}
+
+func (r *fakeRefreshRepo) RevokeUserRefreshTokens(ctx context.Context, userID pgtype.UUID) error {
+	r.revokedUsers = append(r.revokedUsers, userID)
+	return nil
+}
+
+// fakeResetRepo holds reset tokens by hash.
+type fakeResetRepo struct {
+	repository.PasswordResetTokenRepository
+	tokens map[string]pgtype.UUID
+}
+
+func (r *fakeResetRepo) ConsumePasswordResetToken(ctx context.Context, tokenHash string) (pgtype.UUID, error) {
+	userID, ok := r.tokens[tokenHash]
+	if !ok {
+		return pgtype.UUID{}, pgx.ErrNoRows
+	}
+	delete(r.tokens, tokenHash)
+	return userID, nil
+}
+
+func mustUserID(s string) pgtype.UUID {
+	id, err := parseUserID(s)
+	if err != nil {
+		panic(err)
+	}
+	return id
+}
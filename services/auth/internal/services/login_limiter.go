@@ -0,0 +1,151 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// maxLockout caps the doubling lockout duration.
+	maxLockout = 24 * time.Hour
+	// emailFailureFactor scales maxFailures for the per-email count, which
+	// catches guessing spread across many IPs. It is higher than the per-pair
+	// threshold so one client can't lock the owner out as easily.
+	emailFailureFactor = 4
+)
+
+// LoginLockedError is returned by Login while an email+IP pair, or the email
+// as a whole, is locked out.
+type LoginLockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LoginLockedError) Error() string {
+	return fmt.Sprintf("too many failed login attempts, retry after %s", e.RetryAfter.Round(time.Second))
+}
+
+type loginAttempts struct {
+	failures    int
+	lockedUntil time.Time
+	lastFailure time.Time
+}
+
+// LoginLimiter counts consecutive failed logins in memory, per email+IP and
+// per email. After maxFailures for a pair, or emailFailureFactor times that
+// for an email from any IPs, it locks the pair or the email for lockout,
+// doubling the lockout for every further failure. A successful login clears
+// both counts. State is per process.
+type LoginLimiter struct {
+	mu          sync.Mutex
+	attempts    map[string]*loginAttempts
+	maxFailures int
+	lockout     time.Duration
+	lastSweep   time.Time
+}
+
+// NewLoginLimiter returns a LoginLimiter. maxFailures <= 0 disables it.
+func NewLoginLimiter(maxFailures int, lockout time.Duration) *LoginLimiter {
+	return &LoginLimiter{
+		attempts:    make(map[string]*loginAttempts),
+		maxFailures: maxFailures,
+		lockout:     lockout,
+	}
+}
+
+// Check returns a LoginLockedError if the pair or the email is currently
+// locked out.
+func (l *LoginLimiter) Check(email, ip string) error {
+	if l == nil || l.maxFailures <= 0 {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var wait time.Duration
+	for _, key := range []string{loginKey(email, ip), emailKey(email)} {
+		if a, ok := l.attempts[key]; ok {
+			wait = max(wait, time.Until(a.lockedUntil))
+		}
+	}
+	if wait > 0 {
+		return &LoginLockedError{RetryAfter: wait}
+	}
+	return nil
+}
+
+// RecordFailure counts a failed login and locks the pair or the email once
+// its threshold is reached.
+func (l *LoginLimiter) RecordFailure(email, ip string) {
+	if l == nil || l.maxFailures <= 0 {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.sweep(now)
+
+	l.fail(loginKey(email, ip), l.maxFailures, now)
+	l.fail(emailKey(email), l.maxFailures*emailFailureFactor, now)
+}
+
+// fail counts a failure against key, locking it once it has maxFailures.
+func (l *LoginLimiter) fail(key string, maxFailures int, now time.Time) {
+	a, ok := l.attempts[key]
+	if !ok {
+		a = &loginAttempts{}
+		l.attempts[key] = a
+	}
+	a.failures++
+	a.lastFailure = now
+
+	if a.failures >= maxFailures {
+		lockout := l.lockout
+		for i := maxFailures; i < a.failures && lockout < maxLockout; i++ {
+			lockout *= 2
+		}
+		if lockout > maxLockout {
+			lockout = maxLockout
+		}
+		a.lockedUntil = now.Add(lockout)
+	}
+}
+
+// RecordSuccess clears the failure counts of the pair and the email.
+func (l *LoginLimiter) RecordSuccess(email, ip string) {
+	if l == nil || l.maxFailures <= 0 {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.attempts, loginKey(email, ip))
+	delete(l.attempts, emailKey(email))
+}
+
+// sweep forgets keys that are no longer locked and have not failed for
+// maxLockout. It runs at most once a minute.
+func (l *LoginLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < time.Minute {
+		return
+	}
+	l.lastSweep = now
+
+	for key, a := range l.attempts {
+		if now.After(a.lockedUntil) && now.Sub(a.lastFailure) > maxLockout {
+			delete(l.attempts, key)
+		}
+	}
+}
+
+func loginKey(email, ip string) string {
+	return emailKey(email) + "|" + ip
+}
+
+func emailKey(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
@@ -0,0 +1,102 @@
+package services
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestLoginLimiter(t *testing.T) {
+	const maxFailures = 3
+
+	tests := []struct {
+		name string
+		// failures are the IPs that fail to log in to user@example.com, in
+		// order.
+		failures   []string
+		succeedIP  string
+		checkEmail string
+		checkIP    string
+		wantLocked bool
+	}{
+		{
+			name:       "below threshold",
+			failures:   repeat("10.0.0.1", maxFailures-1),
+			checkEmail: "user@example.com",
+			checkIP:    "10.0.0.1",
+		},
+		{
+			name:       "pair locked",
+			failures:   repeat("10.0.0.1", maxFailures),
+			checkEmail: "user@example.com",
+			checkIP:    "10.0.0.1",
+			wantLocked: true,
+		},
+		{
+			name:       "pair lock spares other IPs",
+			failures:   repeat("10.0.0.1", maxFailures),
+			checkEmail: "user@example.com",
+			checkIP:    "10.0.0.2",
+		},
+		{
+			name:       "pair lock ignores email case",
+			failures:   repeat("10.0.0.1", maxFailures),
+			checkEmail: " User@Example.com",
+			checkIP:    "10.0.0.1",
+			wantLocked: true,
+		},
+		{
+			name:       "email locked across IPs",
+			failures:   distinctIPs(maxFailures * emailFailureFactor),
+			checkEmail: "user@example.com",
+			checkIP:    "10.1.0.1",
+			wantLocked: true,
+		},
+		{
+			name:       "email lock spares other emails",
+			failures:   distinctIPs(maxFailures * emailFailureFactor),
+			checkEmail: "other@example.com",
+			checkIP:    "10.1.0.1",
+		},
+		{
+			name:       "success clears email count",
+			failures:   distinctIPs(maxFailures*emailFailureFactor - 1),
+			succeedIP:  "10.0.0.1",
+			checkEmail: "user@example.com",
+			checkIP:    "10.1.0.1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := NewLoginLimiter(maxFailures, time.Minute)
+			for _, ip := range tt.failures {
+				l.RecordFailure("user@example.com", ip)
+			}
+			if tt.succeedIP != "" {
+				l.RecordSuccess("user@example.com", tt.succeedIP)
+				l.RecordFailure("user@example.com", "10.2.0.1")
+			}
+
+			err := l.Check(tt.checkEmail, tt.checkIP)
+			if locked := err != nil; locked != tt.wantLocked {
+				t.Errorf("Check() = %v, want locked %v", err, tt.wantLocked)
+			}
+		})
+	}
+}
+
+func repeat(ip string, n int) []string {
+	ips := make([]string, n)
+	for i := range ips {
+		ips[i] = ip
+	}
+	return ips
+}
+
+func distinctIPs(n int) []string {
+	ips := make([]string, n)
+	for i := range ips {
+		ips[i] = fmt.Sprintf("192.0.2.%d", i+1)
+	}
+	return ips
+}
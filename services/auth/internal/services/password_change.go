@@ -0,0 +1,67 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/amrrdev/trawl/services/auth/internal/db"
+	"github.com/amrrdev/trawl/services/shared/apierror"
+	"github.com/amrrdev/trawl/services/shared/jwt"
+	"github.com/jackc/pgx/v5"
+)
+
+// ChangePassword replaces the password of the user described by claims once
+// currentPassword checks out, then signs them out everywhere: every refresh
+// token is revoked, and the password update moves the user's
+// tokens_valid_after cutoff, which rejects every access token issued before
+// it. The access token making the request is also revoked by jti, since the
+// cutoff spares tokens issued in its second. Wrong current passwords count
+// towards the login lockout like failed logins, keyed by clientIP.
+func (s *AuthService) ChangePassword(ctx context.Context, claims *jwt.Claims, currentPassword, newPassword, clientIP string) error {
+	id, err := parseUserID(claims.UserID)
+	if err != nil {
+		return err
+	}
+
+	user, err := s.repo.GetUserByID(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return apierror.NotFound("user not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	if err := s.loginLimiter.Check(user.Email, clientIP); err != nil {
+		return err
+	}
+	if !s.hashingService.ComparePassword(user.Password, currentPassword) {
+		s.loginLimiter.RecordFailure(user.Email, clientIP)
+		return apierror.Unauthorized("current password is incorrect")
+	}
+	s.loginLimiter.RecordSuccess(user.Email, clientIP)
+
+	hashedPassword, err := s.hashingService.HashPassword(newPassword)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	err = s.repo.UpdateUserPassword(ctx, db.UpdateUserPasswordParams{
+		UserID:   id,
+		Password: hashedPassword,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update password: %w", err)
+	}
+
+	if err := s.refreshRepo.RevokeUserRefreshTokens(ctx, id); err != nil {
+		return fmt.Errorf("failed to revoke refresh tokens: %w", err)
+	}
+	if err := s.jwtService.Revoke(ctx, claims); err != nil {
+		return fmt.Errorf("failed to revoke access token: %w", err)
+	}
+
+	slog.InfoContext(ctx, "Password changed", "user_id", claims.UserID)
+	return nil
+}
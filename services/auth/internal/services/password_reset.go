@@ -0,0 +1,157 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/smtp"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/amrrdev/trawl/services/auth/internal/db"
+	"github.com/amrrdev/trawl/services/shared/apierror"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// PasswordResetSender delivers a password reset token to the account owner.
+type PasswordResetSender interface {
+	SendPasswordReset(ctx context.Context, email, token string) error
+}
+
+// LogPasswordResetSender stands in for a mailer in development: it logs that
+// a reset was requested, but never the token, so logs can't be used to take
+// over accounts. The config refuses it in production.
+type LogPasswordResetSender struct{}
+
+func (LogPasswordResetSender) SendPasswordReset(ctx context.Context, email, token string) error {
+	slog.InfoContext(ctx, "Password reset requested; no mailer configured, token not delivered", "email", email)
+	return nil
+}
+
+// SMTPPasswordResetSender mails the reset link to the account owner. The
+// token is appended to ResetURL as the token query parameter.
+type SMTPPasswordResetSender struct {
+	// Addr is the SMTP server's host:port.
+	Addr     string
+	From     string
+	Username string
+	Password string
+	ResetURL string
+}
+
+func (s SMTPPasswordResetSender) SendPasswordReset(ctx context.Context, email, token string) error {
+	link, err := url.Parse(s.ResetURL)
+	if err != nil {
+		return fmt.Errorf("invalid reset URL: %w", err)
+	}
+	query := link.Query()
+	query.Set("token", token)
+	link.RawQuery = query.Encode()
+
+	var auth smtp.Auth
+	if s.Username != "" {
+		host, _, _ := net.SplitHostPort(s.Addr)
+		auth = smtp.PlainAuth("", s.Username, s.Password, host)
+	}
+	msg := "From: " + s.From + "\r\n" +
+		"To: " + email + "\r\n" +
+		"Subject: Reset your password\r\n" +
+		"\r\n" +
+		"Use this link to choose a new password:\r\n\r\n" + link.String() + "\r\n\r\n" +
+		"If you didn't ask to reset your password, ignore this email.\r\n"
+	if err := smtp.SendMail(s.Addr, auth, s.From, []string{email}, []byte(msg)); err != nil {
+		return fmt.Errorf("failed to send reset email: %w", err)
+	}
+	return nil
+}
+
+// ForgotPassword issues a single-use reset token for email and hands it to the
+// PasswordResetSender. Unknown and deactivated accounts are silently ignored
+// so callers can't probe which emails are registered.
+func (s *AuthService) ForgotPassword(ctx context.Context, email string) error {
+	email = strings.ToLower(strings.TrimSpace(email))
+
+	user, err := s.repo.GetUserByEmail(ctx, email)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to look up user: %w", err)
+	}
+	if !user.IsActive.Bool {
+		return nil
+	}
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return fmt.Errorf("failed to generate reset token: %w", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(raw)
+
+	now := time.Now().UTC()
+	if err := s.resetRepo.DeleteExpiredPasswordResetTokens(ctx, pgtype.Timestamp{Time: now, Valid: true}); err != nil {
+		slog.WarnContext(ctx, "Failed to prune expired password reset tokens", "error", err)
+	}
+
+	// Only the most recent token is usable.
+	if err := s.resetRepo.InvalidateUserPasswordResetTokens(ctx, user.UserID); err != nil {
+		return fmt.Errorf("failed to invalidate reset tokens: %w", err)
+	}
+
+	err = s.resetRepo.CreatePasswordResetToken(ctx, db.CreatePasswordResetTokenParams{
+		UserID:    user.UserID,
+		TokenHash: hashToken(token),
+		ExpiresAt: pgtype.Timestamp{Time: now.Add(s.passwordResetTTL), Valid: true},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store reset token: %w", err)
+	}
+
+	if err := s.resetSender.SendPasswordReset(ctx, user.Email, token); err != nil {
+		slog.ErrorContext(ctx, "Failed to send password reset", "user_id", user.UserID.String(), "error", err)
+	}
+	return nil
+}
+
+// ResetPassword sets a new password for the owner of token and signs them out
+// everywhere, as ChangePassword does. The token is consumed even
+// if the password update then fails.
+func (s *AuthService) ResetPassword(ctx context.Context, token, newPassword string) error {
+	token = strings.TrimSpace(token)
+	if token == "" {
+		return apierror.InvalidInput("invalid or expired reset token")
+	}
+
+	userID, err := s.resetRepo.ConsumePasswordResetToken(ctx, hashToken(token))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return apierror.InvalidInput("invalid or expired reset token")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to consume reset token: %w", err)
+	}
+
+	hashedPassword, err := s.hashingService.HashPassword(newPassword)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	err = s.repo.UpdateUserPassword(ctx, db.UpdateUserPasswordParams{
+		UserID:   userID,
+		Password: hashedPassword,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update password: %w", err)
+	}
+	if err := s.refreshRepo.RevokeUserRefreshTokens(ctx, userID); err != nil {
+		return fmt.Errorf("failed to revoke refresh tokens: %w", err)
+	}
+
+	slog.InfoContext(ctx, "Password reset", "user_id", userID.String())
+	return nil
+}
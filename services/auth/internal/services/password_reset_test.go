@@ -0,0 +1,68 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/amrrdev/trawl/services/shared/apierror"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestResetPassword(t *testing.T) {
+	userID := mustUserID("7d0f5a2e-5d8c-4a53-9a3e-1f2b3c4d5e6f")
+
+	tests := []struct {
+		name        string
+		token       string
+		wantErr     bool
+		wantRevoked []pgtype.UUID
+	}{
+		{"valid token", "reset-token", false, []pgtype.UUID{userID}},
+		{"unknown token", "other-token", true, nil},
+		{"empty token", "  ", true, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := &fakeUserRepo{}
+			refresh := &fakeRefreshRepo{}
+			resets := &fakeResetRepo{tokens: map[string]pgtype.UUID{hashToken("reset-token"): userID}}
+			s := NewAuthService(users, refresh, resets, NewHashingService(), nil, LogPasswordResetSender{}, nil, 0, 0)
+
+			err := s.ResetPassword(context.Background(), tt.token, "new-password")
+			if tt.wantErr {
+				if !errors.Is(err, apierror.ErrInvalidInput) {
+					t.Fatalf("err = %v, want an invalid input error", err)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			if len(refresh.revokedUsers) != len(tt.wantRevoked) {
+				t.Fatalf("refresh tokens revoked for %v, want %v", refresh.revokedUsers, tt.wantRevoked)
+			}
+			for i := range tt.wantRevoked {
+				if refresh.revokedUsers[i] != tt.wantRevoked[i] {
+					t.Errorf("refresh tokens revoked for %v, want %v", refresh.revokedUsers, tt.wantRevoked)
+				}
+			}
+		})
+	}
+}
+
+func TestLogPasswordResetSenderOmitsToken(t *testing.T) {
+	var buf bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, nil)))
+	defer slog.SetDefault(previous)
+
+	const token = "secret-reset-token"
+	if err := (LogPasswordResetSender{}).SendPasswordReset(context.Background(), "user@example.com", token); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), token) {
+		t.Errorf("log contains the reset token: %s", buf.String())
+	}
+}
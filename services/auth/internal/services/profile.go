@@ -0,0 +1,142 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/amrrdev/trawl/services/auth/internal/db"
+	"github.com/amrrdev/trawl/services/shared/apierror"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type ProfileResponse struct {
+	UserID    string    `json:"user_id"`
+	Email     string    `json:"email"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// UpdateProfileRequest lists the profile fields to change; nil fields are
+// left as they are.
+type UpdateProfileRequest struct {
+	Name  *string
+	Email *string
+}
+
+func (s *AuthService) GetProfile(ctx context.Context, userID string) (*ProfileResponse, error) {
+	id, err := parseUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.repo.GetUserByID(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, apierror.NotFound("user not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	return profileFromUser(user), nil
+}
+
+func (s *AuthService) UpdateProfile(ctx context.Context, userID string, req UpdateProfileRequest) (*ProfileResponse, error) {
+	id, err := parseUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.repo.GetUserByID(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, apierror.NotFound("user not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	if req.Email != nil {
+		email := strings.ToLower(strings.TrimSpace(*req.Email))
+		if email == "" {
+			return nil, apierror.InvalidInput("email is required")
+		}
+
+		if email != user.Email {
+			isExists, err := s.repo.CheckUserExists(ctx, email)
+			if err != nil {
+				return nil, fmt.Errorf("failed to check user existence: %w", err)
+			}
+			if isExists {
+				return nil, apierror.Conflict("email already in use")
+			}
+
+			updated, err := s.repo.UpdateUserEmail(ctx, db.UpdateUserEmailParams{
+				UserID: id,
+				Email:  email,
+			})
+			if err != nil {
+				return nil, profileUpdateError(err)
+			}
+			user.Email = updated.Email
+		}
+	}
+
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if len(name) < 2 {
+			return nil, apierror.InvalidInput("name is invalid: must be at least 2 characters")
+		}
+
+		updated, err := s.repo.UpdateUserProfile(ctx, db.UpdateUserProfileParams{
+			UserID: id,
+			Name:   pgtype.Text{String: name, Valid: true},
+		})
+		if err != nil {
+			return nil, profileUpdateError(err)
+		}
+		user.Name = updated.Name
+	}
+
+	return profileFromUser(user), nil
+}
+
+func profileFromUser(user db.User) *ProfileResponse {
+	name := ""
+	if user.Name.Valid {
+		name = user.Name.String
+	}
+
+	return &ProfileResponse{
+		UserID:    user.UserID.String(),
+		Email:     user.Email,
+		Name:      name,
+		CreatedAt: user.CreatedAt.Time,
+	}
+}
+
+// profileUpdateError maps the errors of a profile update: the user may have
+// been deleted since the lookup, or another account may have claimed the
+// email in between.
+func profileUpdateError(err error) error {
+	if errors.Is(err, pgx.ErrNoRows) {
+		return apierror.NotFound("user not found")
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return apierror.Conflict("email already in use")
+	}
+	return fmt.Errorf("failed to update profile: %w", err)
+}
+
+func parseUserID(userID string) (pgtype.UUID, error) {
+	var id pgtype.UUID
+	if err := id.Scan(userID); err != nil {
+		return id, apierror.InvalidInput("invalid user id")
+	}
+	return id, nil
+}
@@ -0,0 +1,167 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/amrrdev/trawl/services/auth/internal/db"
+	"github.com/amrrdev/trawl/services/shared/apierror"
+	"github.com/amrrdev/trawl/services/shared/jwt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type RefreshResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+// Refresh exchanges a refresh token for a new access token and a new refresh
+// token; the presented one is revoked (rotation). Presenting a token that was
+// already rotated means it leaked or was replayed, so every token descended
+// from the same login is revoked and the user has to log in again.
+func (s *AuthService) Refresh(ctx context.Context, refreshToken string) (*RefreshResponse, error) {
+	stored, err := s.lookupRefreshToken(ctx, refreshToken)
+	if err != nil {
+		return nil, err
+	}
+
+	if stored.RevokedAt.Valid {
+		s.revokeFamily(ctx, stored.FamilyID)
+		return nil, apierror.Unauthorized("invalid refresh token: reuse detected")
+	}
+	if !stored.ExpiresAt.Time.After(time.Now().UTC()) {
+		return nil, apierror.Unauthorized("invalid refresh token: expired")
+	}
+
+	// Only one of two concurrent refreshes with the same token wins; the
+	// other is treated as a replay.
+	revoked, err := s.refreshRepo.RevokeRefreshToken(ctx, stored.TokenID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to revoke refresh token: %w", err)
+	}
+	if revoked == 0 {
+		s.revokeFamily(ctx, stored.FamilyID)
+		return nil, apierror.Unauthorized("invalid refresh token: reuse detected")
+	}
+
+	user, err := s.repo.GetUserByID(ctx, stored.UserID)
+	if err != nil {
+		return nil, apierror.Unauthorized("invalid refresh token")
+	}
+
+	accessToken, err := s.jwtService.GenerateAccessToken(user.UserID.String(), user.Email, user.Role)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate access token: %w", err)
+	}
+
+	newRefreshToken, err := s.issueRefreshToken(ctx, user.UserID, stored.FamilyID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RefreshResponse{
+		AccessToken:  accessToken,
+		RefreshToken: newRefreshToken,
+	}, nil
+}
+
+// Logout revokes the access token described by claims and, when a refresh
+// token is given, that token and every token rotated from the same login.
+func (s *AuthService) Logout(ctx context.Context, claims *jwt.Claims, refreshToken string) error {
+	if err := s.jwtService.Revoke(ctx, claims); err != nil {
+		return fmt.Errorf("failed to revoke access token: %w", err)
+	}
+
+	if strings.TrimSpace(refreshToken) == "" {
+		return nil
+	}
+
+	stored, err := s.lookupRefreshToken(ctx, refreshToken)
+	if err != nil {
+		return err
+	}
+	if stored.UserID.String() != claims.UserID {
+		return apierror.Unauthorized("invalid refresh token")
+	}
+
+	if err := s.refreshRepo.RevokeRefreshTokenFamily(ctx, stored.FamilyID); err != nil {
+		return fmt.Errorf("failed to revoke refresh tokens: %w", err)
+	}
+	return nil
+}
+
+func (s *AuthService) lookupRefreshToken(ctx context.Context, refreshToken string) (db.RefreshToken, error) {
+	refreshToken = strings.TrimSpace(refreshToken)
+	if refreshToken == "" {
+		return db.RefreshToken{}, apierror.InvalidInput("refresh token is required")
+	}
+
+	stored, err := s.refreshRepo.GetRefreshTokenByHash(ctx, hashToken(refreshToken))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return db.RefreshToken{}, apierror.Unauthorized("invalid refresh token")
+	}
+	if err != nil {
+		return db.RefreshToken{}, fmt.Errorf("failed to load refresh token: %w", err)
+	}
+	return stored, nil
+}
+
+func (s *AuthService) revokeFamily(ctx context.Context, familyID pgtype.UUID) {
+	if err := s.refreshRepo.RevokeRefreshTokenFamily(ctx, familyID); err != nil {
+		slog.ErrorContext(ctx, "Failed to revoke refresh token family", "family_id", familyID.String(), "error", err)
+	}
+}
+
+// issueRefreshToken creates a refresh token for userID. Only its SHA-256 hash
+// is stored. A zero familyID starts a new family (a fresh login).
+func (s *AuthService) issueRefreshToken(ctx context.Context, userID, familyID pgtype.UUID) (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(raw)
+
+	if !familyID.Valid {
+		var err error
+		if familyID, err = newUUID(); err != nil {
+			return "", fmt.Errorf("failed to generate refresh token: %w", err)
+		}
+	}
+
+	_, err := s.refreshRepo.CreateRefreshToken(ctx, db.CreateRefreshTokenParams{
+		UserID:    userID,
+		TokenHash: hashToken(token),
+		FamilyID:  familyID,
+		ExpiresAt: pgtype.Timestamp{Time: time.Now().UTC().Add(s.refreshTokenTTL), Valid: true},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to store refresh token: %w", err)
+	}
+	return token, nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// newUUID returns a random (version 4) UUID.
+func newUUID() (pgtype.UUID, error) {
+	var id pgtype.UUID
+	if _, err := rand.Read(id.Bytes[:]); err != nil {
+		return id, err
+	}
+	id.Bytes[6] = (id.Bytes[6] & 0x0f) | 0x40
+	id.Bytes[8] = (id.Bytes[8] & 0x3f) | 0x80
+	id.Valid = true
+	return id, nil
+}
@@ -0,0 +1,32 @@
+-- ============================================
+-- PASSWORD RESET TOKENS
+-- ============================================
+
+-- name: CreatePasswordResetToken :exec
+INSERT INTO password_reset_tokens (
+    user_id,
+    token_hash,
+    expires_at
+) VALUES (
+    $1, $2, $3
+);
+
+-- name: ConsumePasswordResetToken :one
+UPDATE password_reset_tokens
+SET
+    used_at = CURRENT_TIMESTAMP
+WHERE token_hash = $1
+  AND used_at IS NULL
+  AND expires_at > CURRENT_TIMESTAMP
+RETURNING user_id;
+
+-- name: InvalidateUserPasswordResetTokens :exec
+UPDATE password_reset_tokens
+SET
+    used_at = CURRENT_TIMESTAMP
+WHERE user_id = $1
+  AND used_at IS NULL;
+
+-- name: DeleteExpiredPasswordResetTokens :exec
+DELETE FROM password_reset_tokens
+WHERE expires_at < $1;
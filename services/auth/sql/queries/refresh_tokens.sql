@@ -0,0 +1,55 @@
+-- ============================================
+-- REFRESH TOKENS
+-- ============================================
+
+-- name: CreateRefreshToken :one
+INSERT INTO refresh_tokens (
+    user_id,
+    token_hash,
+    family_id,
+    expires_at
+) VALUES (
+    $1, $2, $3, $4
+)
+RETURNING
+    token_id,
+    user_id,
+    token_hash,
+    family_id,
+    expires_at,
+    created_at,
+    revoked_at;
+
+-- name: GetRefreshTokenByHash :one
+SELECT
+    token_id,
+    user_id,
+    token_hash,
+    family_id,
+    expires_at,
+    created_at,
+    revoked_at
+FROM refresh_tokens
+WHERE token_hash = $1
+LIMIT 1;
+
+-- name: RevokeRefreshToken :execrows
+UPDATE refresh_tokens
+SET
+    revoked_at = CURRENT_TIMESTAMP
+WHERE token_id = $1
+  AND revoked_at IS NULL;
+
+-- name: RevokeRefreshTokenFamily :exec
+UPDATE refresh_tokens
+SET
+    revoked_at = CURRENT_TIMESTAMP
+WHERE family_id = $1
+  AND revoked_at IS NULL;
+
+-- name: RevokeUserRefreshTokens :exec
+UPDATE refresh_tokens
+SET
+    revoked_at = CURRENT_TIMESTAMP
+WHERE user_id = $1
+  AND revoked_at IS NULL;
@@ -10,12 +10,29 @@ SELECT
     name,
     created_at,
     updated_at,
-    is_active
+    is_active,
+    role,
+    tokens_valid_after
 FROM users
 WHERE email = $1
   AND is_active = true
 LIMIT 1;
 
+-- name: GetUserByEmailAnyStatus :one
+SELECT
+    user_id,
+    email,
+    password,
+    name,
+    created_at,
+    updated_at,
+    is_active,
+    role,
+    tokens_valid_after
+FROM users
+WHERE email = $1
+LIMIT 1;
+
 -- name: GetUserByID :one
 SELECT
     user_id,
@@ -24,7 +41,9 @@ SELECT
     name,
     created_at,
     updated_at,
-    is_active
+    is_active,
+    role,
+    tokens_valid_after
 FROM users
 WHERE user_id = $1
   AND is_active = true
@@ -62,7 +81,8 @@ RETURNING
     email,
     name,
     created_at,
-    is_active;
+    is_active,
+    role;
 
 -- ============================================
 -- USER PROFILE UPDATES
@@ -97,7 +117,8 @@ RETURNING
 UPDATE users
 SET
     password = $2,
-    updated_at = CURRENT_TIMESTAMP
+    updated_at = CURRENT_TIMESTAMP,
+    tokens_valid_after = NOW()
 WHERE user_id = $1
   AND is_active = true;
 
@@ -109,7 +130,8 @@ WHERE user_id = $1
 UPDATE users
 SET
     is_active = false,
-    updated_at = CURRENT_TIMESTAMP
+    updated_at = CURRENT_TIMESTAMP,
+    tokens_valid_after = NOW()
 WHERE user_id = $1;
 
 -- name: ReactivateUser :exec
@@ -127,6 +149,13 @@ WHERE user_id = $1;
 DELETE FROM users
 WHERE user_id = $1;
 
+-- name: UpdateUserRole :execrows
+UPDATE users
+SET
+    role = $2,
+    updated_at = CURRENT_TIMESTAMP
+WHERE user_id = $1;
+
 -- ============================================
 -- LISTING & PAGINATION
 -- ============================================
@@ -137,14 +166,15 @@ SELECT
     email,
     name,
     created_at,
-    is_active
+    is_active,
+    role
 FROM users
 WHERE
     (sqlc.narg('is_active')::BOOLEAN IS NULL OR is_active = sqlc.narg('is_active'))
     AND (
-        sqlc.narg('search') IS NULL OR
-        email ILIKE '%' || sqlc.narg('search') || '%' OR
-        name ILIKE '%' || sqlc.narg('search') || '%'
+        sqlc.narg('search')::TEXT IS NULL OR
+        email ILIKE '%' || sqlc.narg('search')::TEXT || '%' OR
+        name ILIKE '%' || sqlc.narg('search')::TEXT || '%'
     )
 ORDER BY created_at DESC
 LIMIT $1 OFFSET $2;
@@ -155,9 +185,9 @@ FROM users
 WHERE
     (sqlc.narg('is_active')::BOOLEAN IS NULL OR is_active = sqlc.narg('is_active'))
     AND (
-        sqlc.narg('search') IS NULL OR
-        email ILIKE '%' || sqlc.narg('search') || '%' OR
-        name ILIKE '%' || sqlc.narg('search') || '%'
+        sqlc.narg('search')::TEXT IS NULL OR
+        email ILIKE '%' || sqlc.narg('search')::TEXT || '%' OR
+        name ILIKE '%' || sqlc.narg('search')::TEXT || '%'
     );
 
 -- name: ListActiveUsers :many
@@ -197,7 +227,13 @@ SELECT
     is_active
 FROM users
 WHERE created_at BETWEEN $1 AND $2
-ORDER BY created_at DESC;
+ORDER BY created_at DESC
+LIMIT $3 OFFSET $4;
+
+-- name: CountUsersByDateRange :one
+SELECT COUNT(*)
+FROM users
+WHERE created_at BETWEEN $1 AND $2;
 
 -- ============================================
 -- BATCH OPERATIONS
@@ -207,7 +243,8 @@ ORDER BY created_at DESC;
 UPDATE users
 SET
     is_active = false,
-    updated_at = CURRENT_TIMESTAMP
+    updated_at = CURRENT_TIMESTAMP,
+    tokens_valid_after = NOW()
 WHERE user_id = ANY($1::UUID[]);
 
 -- ============================================
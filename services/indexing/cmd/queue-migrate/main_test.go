@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// fakeSource hands out its bodies in order and records how each delivery was
+// settled.
+type fakeSource struct {
+	bodies  []string
+	acked   []uint64
+	requeue []uint64
+}
+
+func (s *fakeSource) Get(queueName string, autoAck bool) (amqp.Delivery, bool, error) {
+	if len(s.bodies) == 0 {
+		return amqp.Delivery{}, false, nil
+	}
+	body := s.bodies[0]
+	s.bodies = s.bodies[1:]
+	tag := uint64(len(s.acked) + len(s.requeue) + 1)
+	return amqp.Delivery{
+		Acknowledger: s,
+		DeliveryTag:  tag,
+		Headers:      amqp.Table{"x-retry-count": int32(1)},
+		Body:         []byte(body),
+	}, true, nil
+}
+
+func (s *fakeSource) Ack(tag uint64, multiple bool) error {
+	s.acked = append(s.acked, tag)
+	return nil
+}
+
+func (s *fakeSource) Nack(tag uint64, multiple, requeue bool) error {
+	if requeue {
+		s.requeue = append(s.requeue, tag)
+	}
+	return nil
+}
+
+func (s *fakeSource) Reject(tag uint64, requeue bool) error {
+	return s.Nack(tag, false, requeue)
+}
+
+// fakeDestination confirms publishes until failAfter messages were accepted;
+// a negative failAfter never fails.
+type fakeDestination struct {
+	failAfter int
+	queues    []string
+	bodies    []string
+}
+
+func (d *fakeDestination) PublishConfirmed(ctx context.Context, queueName string, msg amqp.Publishing) error {
+	if d.failAfter >= 0 && len(d.bodies) >= d.failAfter {
+		return errors.New("nacked")
+	}
+	if msg.Headers["x-retry-count"] != int32(1) {
+		return errors.New("headers not copied")
+	}
+	d.queues = append(d.queues, queueName)
+	d.bodies = append(d.bodies, string(msg.Body))
+	return nil
+}
+
+func TestMigrate(t *testing.T) {
+	tests := []struct {
+		name        string
+		bodies      []string
+		limit       int
+		failAfter   int
+		wantMoved   int
+		wantErr     bool
+		wantAcked   []uint64
+		wantRequeue []uint64
+	}{
+		{"drains the queue", []string{"a", "b", "c"}, 0, -1, 3, false, []uint64{1, 2, 3}, nil},
+		{"stops at the limit", []string{"a", "b", "c"}, 2, -1, 2, false, []uint64{1, 2}, nil},
+		{"empty queue", nil, 0, -1, 0, false, nil, nil},
+		{"unconfirmed message is requeued", []string{"a", "b", "c"}, 0, 1, 1, true, []uint64{1}, []uint64{2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := &fakeSource{bodies: slices.Clone(tt.bodies)}
+			dst := &fakeDestination{failAfter: tt.failAfter}
+
+			moved, err := migrate(context.Background(), src, dst, "old", "new", tt.limit)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %v", err, tt.wantErr)
+			}
+			if moved != tt.wantMoved {
+				t.Errorf("moved = %d, want %d", moved, tt.wantMoved)
+			}
+			if !slices.Equal(src.acked, tt.wantAcked) {
+				t.Errorf("acked = %v, want %v", src.acked, tt.wantAcked)
+			}
+			if !slices.Equal(src.requeue, tt.wantRequeue) {
+				t.Errorf("requeued = %v, want %v", src.requeue, tt.wantRequeue)
+			}
+			if want := tt.bodies[:moved]; !slices.Equal(dst.bodies, want) {
+				t.Errorf("published = %v, want %v", dst.bodies, want)
+			}
+			for _, q := range dst.queues {
+				if q != "new" {
+					t.Errorf("published to %q, want %q", q, "new")
+				}
+			}
+		})
+	}
+}
+
+func TestMigrateStopsWhenCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	src := &fakeSource{bodies: []string{"a"}}
+	moved, err := migrate(ctx, src, &fakeDestination{failAfter: -1}, "q", "q", 0)
+	if !errors.Is(err, context.Canceled) || moved != 0 {
+		t.Errorf("migrate = %d, %v, want 0, context.Canceled", moved, err)
+	}
+	if len(src.bodies) != 1 {
+		t.Error("message taken from the source after cancellation")
+	}
+}
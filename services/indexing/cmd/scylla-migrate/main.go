@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/amrrdev/trawl/services/shared/config"
+	"github.com/amrrdev/trawl/services/shared/scylladb"
+	"github.com/lpernett/godotenv"
+)
+
+// scylla-migrate applies or rolls back the ScyllaDB schema migrations
+// embedded in the shared scylladb package.
+func main() {
+	if err := godotenv.Load("../../.env"); err != nil {
+		log.Println("Warning: .env file not found, using defaults")
+	}
+
+	var (
+		direction = flag.String("direction", "up", "Migration direction: up or down")
+		steps     = flag.Int("steps", 0, "Number of steps to rollback (only for down)")
+	)
+	flag.Parse()
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	scyllaConfig := scylladb.DefaultConfig()
+	if err := config.Bind(&scyllaConfig); err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+	// The keyspace may not exist yet; the migrations create it and name it
+	// in every statement.
+	scyllaConfig.Keyspace = ""
+
+	db, err := scylladb.Connect(ctx, scyllaConfig)
+	if err != nil {
+		log.Fatalf("Failed to connect to ScyllaDB cluster: %v", err)
+	}
+	defer db.Close()
+
+	switch *direction {
+	case "up":
+		log.Println("Running migrations...")
+		if err := db.MigrateUp(); err != nil {
+			log.Fatalf("Migration failed: %v", err)
+		}
+		log.Println("✅ Migrations completed successfully")
+	case "down":
+		log.Printf("Rolling back %d migrations...\n", *steps)
+		if err := db.MigrateDown(*steps); err != nil {
+			log.Fatalf("Rollback failed: %v", err)
+		}
+		log.Println("✅ Rollback completed successfully")
+	default:
+		log.Fatalf("Unknown direction %q: use up or down", *direction)
+	}
+}
@@ -0,0 +1,81 @@
+package docindex
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/amrrdev/trawl/services/shared/scylladb"
+	"github.com/gocql/gocql"
+)
+
+type bigram struct {
+	pair  string
+	field string
+}
+
+func documentBigrams(ctx context.Context, session *gocql.Session, docUUID gocql.UUID) ([]bigram, error) {
+	iter := session.Query(`SELECT word_pair, field FROM doc_bigrams WHERE doc_id = ?`, docUUID).
+		WithContext(ctx).Iter()
+
+	var bigrams []bigram
+	var b bigram
+	for iter.Scan(&b.pair, &b.field) {
+		bigrams = append(bigrams, b)
+	}
+	if err := iter.Close(); err != nil {
+		return nil, fmt.Errorf("failed to read document bigrams: %w", err)
+	}
+	return bigrams, nil
+}
+
+// removeBigrams deletes docUUID's bigram_index and doc_bigrams rows. Documents
+// indexed without bigrams have none.
+func removeBigrams(ctx context.Context, session *gocql.Session, docUUID gocql.UUID) error {
+	bigrams, err := documentBigrams(ctx, session, docUUID)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < len(bigrams); i += batchSize {
+		batch := session.NewBatch(gocql.UnloggedBatch)
+		for _, b := range bigrams[i:min(i+batchSize, len(bigrams))] {
+			batch.Query(`DELETE FROM bigram_index WHERE word_pair = ? AND doc_id = ? AND field = ?`, b.pair, docUUID, b.field)
+		}
+		if err := session.ExecuteBatch(batch.WithContext(ctx)); err != nil {
+			return fmt.Errorf("failed to delete bigrams: %w", err)
+		}
+	}
+
+	if err := session.Query(`DELETE FROM doc_bigrams WHERE doc_id = ?`, docUUID).
+		WithContext(ctx).Exec(); err != nil {
+		return fmt.Errorf("failed to delete document bigram list: %w", err)
+	}
+	return nil
+}
+
+// moveBigrams hands the body bigrams of from over to successor, like
+// movePostings, and drops the rest: duplicates are indexed without a title.
+func moveBigrams(ctx context.Context, session *gocql.Session, from, successor gocql.UUID) error {
+	bigrams, err := documentBigrams(ctx, session, from)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < len(bigrams); i += batchSize {
+		batch := session.NewBatch(gocql.LoggedBatch)
+		for _, b := range bigrams[i:min(i+batchSize, len(bigrams))] {
+			if b.field == scylladb.FieldBody {
+				batch.Query(`INSERT INTO bigram_index (word_pair, doc_id, field) VALUES (?, ?, ?)`, b.pair, successor, b.field)
+				batch.Query(`INSERT INTO doc_bigrams (doc_id, word_pair, field) VALUES (?, ?, ?)`, successor, b.pair, b.field)
+			}
+			batch.Query(`DELETE FROM bigram_index WHERE word_pair = ? AND doc_id = ? AND field = ?`, b.pair, from, b.field)
+		}
+		if err := session.ExecuteBatch(batch.WithContext(ctx)); err != nil {
+			return fmt.Errorf("failed to move bigrams: %w", err)
+		}
+	}
+
+	if err := session.Query(`DELETE FROM doc_bigrams WHERE doc_id = ?`, from).
+		WithContext(ctx).Exec(); err != nil {
+		return fmt.Errorf("failed to delete document bigram list: %w", err)
+	}
+	return nil
+}
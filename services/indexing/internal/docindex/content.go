@@ -0,0 +1,240 @@
+package docindex
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/amrrdev/trawl/services/shared/scylladb"
+	"github.com/gocql/gocql"
+)
+
+// Documents with identical content share one set of postings. The document
+// that indexed the content first owns them (content_hashes); every document
+// with that content, the owner included, is listed in content_refs, and the
+// others point at the owner through documents.duplicate_of.
+
+// Claim lists docUUID under contentHash and returns the document whose
+// postings serve that content: docUUID itself, unless another document
+// claimed the hash first.
+func Claim(ctx context.Context, session *gocql.Session, docUUID gocql.UUID, contentHash string) (gocql.UUID, error) {
+	// The reference goes first so that an owner being deleted meanwhile can
+	// hand its postings to this document.
+	if err := session.Query(`INSERT INTO content_refs (content_hash, doc_id) VALUES (?, ?)`, contentHash, docUUID).
+		WithContext(ctx).Exec(); err != nil {
+		return gocql.UUID{}, fmt.Errorf("failed to record content reference: %w", err)
+	}
+
+	existing := make(map[string]interface{})
+	applied, err := session.Query(`INSERT INTO content_hashes (content_hash, doc_id) VALUES (?, ?) IF NOT EXISTS`,
+		contentHash, docUUID).WithContext(ctx).MapScanCAS(existing)
+	if err != nil {
+		return gocql.UUID{}, fmt.Errorf("failed to claim content hash: %w", err)
+	}
+	if applied {
+		return docUUID, nil
+	}
+	owner, ok := existing["doc_id"].(gocql.UUID)
+	if !ok {
+		return gocql.UUID{}, fmt.Errorf("content hash %s has no owner", contentHash)
+	}
+	return owner, nil
+}
+
+// Unclaim undoes Claim for a document whose indexing failed: it drops
+// docUUID's reference and, if docUUID owns contentHash, the ownership, so the
+// content isn't served by a document that never got its postings. The delete
+// is conditional, so an owner that took over meanwhile keeps the hash.
+func Unclaim(ctx context.Context, session *gocql.Session, docUUID gocql.UUID, contentHash string) error {
+	if err := session.Query(`DELETE FROM content_refs WHERE content_hash = ? AND doc_id = ?`, contentHash, docUUID).
+		WithContext(ctx).Exec(); err != nil {
+		return fmt.Errorf("failed to release content reference: %w", err)
+	}
+	existing := make(map[string]interface{})
+	if _, err := session.Query(`DELETE FROM content_hashes WHERE content_hash = ? IF doc_id = ?`, contentHash, docUUID).
+		WithContext(ctx).MapScanCAS(existing); err != nil {
+		return fmt.Errorf("failed to release content hash: %w", err)
+	}
+	return nil
+}
+
+// releaseContent drops docUUID's content reference. It reports whether the
+// postings under docUUID are its own to remove: documents indexed before
+// content hashing, and owners nobody else references. If other documents
+// still share the content, the first of them takes the postings over.
+func releaseContent(ctx context.Context, session *gocql.Session, docUUID gocql.UUID, title Title) (bool, error) {
+	var contentHash string
+	var duplicateOf *gocql.UUID
+	err := session.Query(`SELECT content_hash, duplicate_of FROM documents WHERE doc_id = ?`, docUUID).
+		WithContext(ctx).Scan(&contentHash, &duplicateOf)
+	if err == gocql.ErrNotFound {
+		return true, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to load content hash: %w", err)
+	}
+	if contentHash == "" {
+		return true, nil
+	}
+
+	if err := session.Query(`DELETE FROM content_refs WHERE content_hash = ? AND doc_id = ?`, contentHash, docUUID).
+		WithContext(ctx).Exec(); err != nil {
+		return false, fmt.Errorf("failed to release content reference: %w", err)
+	}
+	if duplicateOf != nil {
+		return false, nil
+	}
+
+	var successor gocql.UUID
+	err = session.Query(`SELECT doc_id FROM content_refs WHERE content_hash = ? LIMIT 1`, contentHash).
+		WithContext(ctx).Scan(&successor)
+	if err == gocql.ErrNotFound {
+		existing := make(map[string]interface{})
+		if _, err := session.Query(`DELETE FROM content_hashes WHERE content_hash = ? IF doc_id = ?`, contentHash, docUUID).
+			WithContext(ctx).MapScanCAS(existing); err != nil {
+			return false, fmt.Errorf("failed to release content hash: %w", err)
+		}
+		return true, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to load content references: %w", err)
+	}
+
+	if err := handOver(ctx, session, docUUID, successor, contentHash, title); err != nil {
+		return false, err
+	}
+	return false, nil
+}
+
+// handOver moves the postings, bigrams, word list, stored text and counted
+// marker of from to successor and makes successor the owner of contentHash.
+// Duplicates are indexed without title postings, so from's title postings are
+// dropped and its title length taken out of collection_stats.
+func handOver(ctx context.Context, session *gocql.Session, from, successor gocql.UUID, contentHash string, title Title) error {
+	words, err := documentWords(ctx, session, from)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < len(words); i += batchSize {
+		end := min(i+batchSize, len(words))
+		if err := movePostings(ctx, session, from, successor, words[i:end]); err != nil {
+			return err
+		}
+	}
+
+	if err := moveBigrams(ctx, session, from, successor); err != nil {
+		return err
+	}
+
+	var content string
+	err = session.Query(`SELECT content FROM document_text WHERE doc_id = ?`, from).
+		WithContext(ctx).Scan(&content)
+	if err != nil && err != gocql.ErrNotFound {
+		return fmt.Errorf("failed to load document text: %w", err)
+	}
+	if err == nil {
+		if err := session.Query(`INSERT INTO document_text (doc_id, content) VALUES (?, ?)`, successor, content).
+			WithContext(ctx).Exec(); err != nil {
+			return fmt.Errorf("failed to copy document text: %w", err)
+		}
+	}
+
+	if len(title.Terms) > 0 {
+		titles := session.NewBatch(gocql.LoggedBatch)
+		for _, term := range title.Terms {
+			titles.Query(`DELETE FROM title_index WHERE word = ? AND doc_id = ?`, term, from)
+		}
+		if err := session.ExecuteBatch(titles.WithContext(ctx)); err != nil {
+			return fmt.Errorf("failed to delete title postings: %w", err)
+		}
+	}
+
+	existing := make(map[string]interface{})
+	counted, err := session.Query(`DELETE FROM word_stats_applied WHERE doc_id = ? IF EXISTS`, from).
+		WithContext(ctx).MapScanCAS(existing)
+	if err != nil {
+		return fmt.Errorf("failed to release word stats marker: %w", err)
+	}
+	if err := deleteCountedWords(ctx, session, from); err != nil {
+		return err
+	}
+	if counted {
+		if err := session.Query(`INSERT INTO word_stats_applied (doc_id, applied_at) VALUES (?, ?)`, successor, time.Now()).
+			WithContext(ctx).Exec(); err != nil {
+			return fmt.Errorf("failed to move word stats marker: %w", err)
+		}
+		if err := session.Query(`
+			UPDATE collection_stats
+			SET total_title_tokens = total_title_tokens - ?
+			WHERE name = ?
+		`, title.Length, scylladb.CollectionStatsRow).WithContext(ctx).Exec(); err != nil {
+			return fmt.Errorf("failed to update collection stats: %w", err)
+		}
+	}
+
+	if err := session.Query(`UPDATE content_hashes SET doc_id = ? WHERE content_hash = ?`, successor, contentHash).
+		WithContext(ctx).Exec(); err != nil {
+		return fmt.Errorf("failed to move content hash: %w", err)
+	}
+	if err := session.Query(`UPDATE documents SET duplicate_of = null WHERE doc_id = ?`, successor).
+		WithContext(ctx).Exec(); err != nil {
+		return fmt.Errorf("failed to promote document %s: %w", successor, err)
+	}
+
+	iter := session.Query(`SELECT doc_id FROM content_refs WHERE content_hash = ?`, contentHash).
+		WithContext(ctx).Iter()
+	var ref gocql.UUID
+	for iter.Scan(&ref) {
+		if ref == successor {
+			continue
+		}
+		if err := session.Query(`UPDATE documents SET duplicate_of = ? WHERE doc_id = ?`, successor, ref).
+			WithContext(ctx).Exec(); err != nil {
+			iter.Close()
+			return fmt.Errorf("failed to repoint duplicate %s: %w", ref, err)
+		}
+	}
+	if err := iter.Close(); err != nil {
+		return fmt.Errorf("failed to load content references: %w", err)
+	}
+
+	if err := session.Query(`DELETE FROM doc_words WHERE doc_id = ?`, from).
+		WithContext(ctx).Exec(); err != nil {
+		return fmt.Errorf("failed to delete document word list: %w", err)
+	}
+	return nil
+}
+
+// movePostings rewrites the inverted_index and doc_words rows of words from
+// one document to another. The new rows are written in the same batch that
+// deletes the old ones, so the postings are never missing.
+func movePostings(ctx context.Context, session *gocql.Session, from, to gocql.UUID, words []word) error {
+	terms := make([]string, len(words))
+	for i, w := range words {
+		terms[i] = w.word
+	}
+	iter := session.Query(`SELECT word, term_frequency, positions FROM inverted_index WHERE word IN ? AND doc_id = ?`, terms, from).
+		WithContext(ctx).Iter()
+
+	batch := session.NewBatch(gocql.LoggedBatch)
+	var w string
+	var frequency int
+	var positions []int
+	for iter.Scan(&w, &frequency, &positions) {
+		batch.Query(`INSERT INTO inverted_index (word, doc_id, term_frequency, positions) VALUES (?, ?, ?, ?)`,
+			w, to, frequency, positions)
+		batch.Query(`INSERT INTO doc_words (doc_id, word, term_frequency) VALUES (?, ?, ?)`, to, w, frequency)
+		batch.Query(`DELETE FROM inverted_index WHERE word = ? AND doc_id = ?`, w, from)
+	}
+	if err := iter.Close(); err != nil {
+		return fmt.Errorf("failed to read postings: %w", err)
+	}
+	if batch.Size() == 0 {
+		return nil
+	}
+
+	if err := session.ExecuteBatch(batch.WithContext(ctx)); err != nil {
+		return fmt.Errorf("failed to move postings: %w", err)
+	}
+	return nil
+}
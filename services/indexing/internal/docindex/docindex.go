@@ -0,0 +1,200 @@
+// Package docindex removes what indexing a document added: its postings and
+// its share of the corpus statistics. Deletion and re-indexing both use it.
+// It also tracks documents with identical content, which share postings.
+package docindex
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/amrrdev/trawl/services/shared/scylladb"
+	"github.com/gocql/gocql"
+)
+
+// batchSize caps the words per deletion batch.
+const batchSize = 100
+
+// CollectionStatsWord stands for a document's share of collection_stats in
+// word_stats_applied_words; no indexed word is empty.
+const CollectionStatsWord = ""
+
+// Title is what the documents row records about a document's title postings.
+type Title struct {
+	Length int
+	Terms  []string
+}
+
+// LoadTitle reads docUUID's title postings from its documents row. A missing
+// row yields an empty Title.
+func LoadTitle(ctx context.Context, session *gocql.Session, docUUID gocql.UUID) (Title, error) {
+	var title Title
+	err := session.Query(`SELECT title_length, title_terms FROM documents WHERE doc_id = ?`, docUUID).
+		WithContext(ctx).Scan(&title.Length, &title.Terms)
+	if err != nil && err != gocql.ErrNotFound {
+		return Title{}, fmt.Errorf("failed to load document title: %w", err)
+	}
+	return title, nil
+}
+
+type word struct {
+	word      string
+	frequency int
+}
+
+// Remove deletes docUUID's inverted_index, title_index, bigram and doc_words
+// rows and, if the document was counted, subtracts it from word_stats and
+// collection_stats. The counted marker is released first, with a lightweight
+// transaction, so a document is never subtracted twice; a failure part-way
+// leaves the counts high rather than short. A document whose job failed
+// while counting it has no marker, and only the words
+// word_stats_applied_words lists are subtracted. The documents row and stored
+// text are left to the caller. It returns how many distinct words were
+// removed.
+//
+// A duplicate has no postings of its own, and postings other documents still
+// share are handed to one of them instead; neither removes anything.
+func Remove(ctx context.Context, session *gocql.Session, docUUID gocql.UUID, title Title) (int, error) {
+	owned, err := releaseContent(ctx, session, docUUID, title)
+	if err != nil {
+		return 0, err
+	}
+	if !owned {
+		return 0, nil
+	}
+
+	words, err := documentWords(ctx, session, docUUID)
+	if err != nil {
+		return 0, err
+	}
+
+	existing := make(map[string]interface{})
+	counted, err := session.Query(`DELETE FROM word_stats_applied WHERE doc_id = ? IF EXISTS`, docUUID).
+		WithContext(ctx).MapScanCAS(existing)
+	if err != nil {
+		return 0, fmt.Errorf("failed to release word stats marker: %w", err)
+	}
+	var partial map[string]bool
+	if !counted {
+		if partial, err = CountedWords(ctx, session, docUUID); err != nil {
+			return 0, err
+		}
+	}
+	isCounted := func(word string) bool { return counted || partial[word] }
+
+	for i := 0; i < len(words); i += batchSize {
+		end := min(i+batchSize, len(words))
+		if err := deletePostings(ctx, session, docUUID, words[i:end], isCounted); err != nil {
+			return 0, err
+		}
+	}
+	if err := removeBigrams(ctx, session, docUUID); err != nil {
+		return 0, err
+	}
+	if len(title.Terms) > 0 {
+		titles := session.NewBatch(gocql.LoggedBatch)
+		for _, term := range title.Terms {
+			titles.Query(`DELETE FROM title_index WHERE word = ? AND doc_id = ?`, term, docUUID)
+		}
+		if err := session.ExecuteBatch(titles.WithContext(ctx)); err != nil {
+			return 0, fmt.Errorf("failed to delete title postings: %w", err)
+		}
+	}
+	if isCounted(CollectionStatsWord) {
+		totalTokens := 0
+		for _, w := range words {
+			totalTokens += w.frequency
+		}
+		if err := session.Query(`
+			UPDATE collection_stats
+			SET total_documents = total_documents - 1,
+			    total_tokens = total_tokens - ?,
+			    total_title_tokens = total_title_tokens - ?
+			WHERE name = ?
+		`, totalTokens, title.Length, scylladb.CollectionStatsRow).WithContext(ctx).Exec(); err != nil {
+			return 0, fmt.Errorf("failed to update collection stats: %w", err)
+		}
+	}
+
+	if err := deleteCountedWords(ctx, session, docUUID); err != nil {
+		return 0, err
+	}
+
+	if err := session.Query(`DELETE FROM doc_words WHERE doc_id = ?`, docUUID).
+		WithContext(ctx).Exec(); err != nil {
+		return 0, fmt.Errorf("failed to delete document word list: %w", err)
+	}
+	return len(words), nil
+}
+
+// deleteCountedWords drops docUUID's word_stats_applied_words rows, which a
+// later version of the document must not inherit.
+func deleteCountedWords(ctx context.Context, session *gocql.Session, docUUID gocql.UUID) error {
+	if err := session.Query(`DELETE FROM word_stats_applied_words WHERE doc_id = ?`, docUUID).
+		WithContext(ctx).Exec(); err != nil {
+		return fmt.Errorf("failed to delete counted words: %w", err)
+	}
+	return nil
+}
+
+// CountedWords returns the words of docUUID that word_stats_applied_words
+// lists as counted, CollectionStatsWord among them if collection_stats was.
+func CountedWords(ctx context.Context, session *gocql.Session, docUUID gocql.UUID) (map[string]bool, error) {
+	iter := session.Query(`SELECT word FROM word_stats_applied_words WHERE doc_id = ?`, docUUID).
+		WithContext(ctx).Iter()
+	counted := make(map[string]bool)
+	var w string
+	for iter.Scan(&w) {
+		counted[w] = true
+	}
+	if err := iter.Close(); err != nil {
+		return nil, fmt.Errorf("failed to read counted words: %w", err)
+	}
+	return counted, nil
+}
+
+func documentWords(ctx context.Context, session *gocql.Session, docUUID gocql.UUID) ([]word, error) {
+	iter := session.Query(`SELECT word, term_frequency FROM doc_words WHERE doc_id = ?`, docUUID).
+		WithContext(ctx).Iter()
+
+	var words []word
+	var w string
+	var frequency int
+	for iter.Scan(&w, &frequency) {
+		words = append(words, word{word: w, frequency: frequency})
+	}
+	if err := iter.Close(); err != nil {
+		return nil, fmt.Errorf("failed to read document words: %w", err)
+	}
+	return words, nil
+}
+
+// deletePostings removes the inverted_index rows of words for one document
+// and decrements the word_stats counters of those counted reports. Counter
+// updates cannot share a batch with regular mutations, hence the two batches.
+func deletePostings(ctx context.Context, session *gocql.Session, docUUID gocql.UUID, words []word, counted func(word string) bool) error {
+	postings := session.NewBatch(gocql.LoggedBatch)
+	stats := session.NewBatch(gocql.CounterBatch)
+	for _, w := range words {
+		postings.Query(`DELETE FROM inverted_index WHERE word = ? AND doc_id = ?`, w.word, docUUID)
+		if !counted(w.word) {
+			continue
+		}
+		stats.Query(`
+            UPDATE word_stats
+            SET doc_count = doc_count - 1,
+                total_occurrences = total_occurrences - ?
+            WHERE word = ?
+        `, w.frequency, w.word)
+	}
+
+	if err := session.ExecuteBatch(postings.WithContext(ctx)); err != nil {
+		return fmt.Errorf("failed to delete postings: %w", err)
+	}
+	if stats.Size() == 0 {
+		return nil
+	}
+	if err := session.ExecuteBatch(stats.WithContext(ctx)); err != nil {
+		return fmt.Errorf("failed to update word stats: %w", err)
+	}
+	return nil
+}
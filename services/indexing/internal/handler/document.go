@@ -1,13 +1,16 @@
 package handler
 
 import (
-	"log"
+	"log/slog"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/amrrdev/trawl/services/indexing/internal/service"
 	"github.com/amrrdev/trawl/services/indexing/internal/types"
+	"github.com/amrrdev/trawl/services/shared/apierror"
 	"github.com/amrrdev/trawl/services/shared/middleware"
+	"github.com/amrrdev/trawl/services/shared/storage"
 	"github.com/gin-gonic/gin"
 )
 
@@ -26,26 +29,13 @@ func (h *DocumentHandler) GetUploadUrl(c *gin.Context) {
 	filename := c.Param("filename")
 
 	if strings.TrimSpace(filename) == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "filename is required",
-		})
+		apierror.Write(c, http.StatusBadRequest, "filename is required")
 		return
 	}
 
 	resp, err := h.documentService.GetUploadUrl(c, userID, filename)
 	if err != nil {
-		statusCode := http.StatusInternalServerError
-		message := "Failed to generate upload URL"
-
-		errMsg := err.Error()
-		if strings.Contains(errMsg, "required") {
-			statusCode = http.StatusBadRequest
-			message = err.Error()
-		}
-
-		c.JSON(statusCode, gin.H{
-			"error": message,
-		})
+		apierror.Respond(c, err, "Failed to generate upload URL")
 		return
 	}
 
@@ -57,29 +47,13 @@ func (h *DocumentHandler) GetDownloadUrl(c *gin.Context) {
 	filename := c.Param("filename")
 
 	if strings.TrimSpace(filename) == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "filename is required",
-		})
+		apierror.Write(c, http.StatusBadRequest, "filename is required")
 		return
 	}
 
 	resp, err := h.documentService.GetDownloadUrl(c, userID, filename)
 	if err != nil {
-		statusCode := http.StatusInternalServerError
-		message := "Failed to generate download URL"
-
-		errMsg := err.Error()
-		if strings.Contains(errMsg, "required") {
-			statusCode = http.StatusBadRequest
-			message = err.Error()
-		} else if strings.Contains(errMsg, "not found") {
-			statusCode = http.StatusNotFound
-			message = "File not found"
-		}
-
-		c.JSON(statusCode, gin.H{
-			"error": message,
-		})
+		apierror.Respond(c, err, "Failed to generate download URL")
 		return
 	}
 
@@ -89,20 +63,46 @@ func (h *DocumentHandler) GetDownloadUrl(c *gin.Context) {
 func (h *DocumentHandler) ListFiles(c *gin.Context) {
 	userID := middleware.GetUserID(c)
 
-	resp, err := h.documentService.ListFiles(c, userID)
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "0"))
 	if err != nil {
-		statusCode := http.StatusInternalServerError
-		message := "Failed to list files"
-
-		errMsg := err.Error()
-		if strings.Contains(errMsg, "required") {
-			statusCode = http.StatusBadRequest
-			message = err.Error()
-		}
-
-		c.JSON(statusCode, gin.H{
-			"error": message,
-		})
+		apierror.Write(c, http.StatusBadRequest, "limit must be an integer")
+		return
+	}
+	opts := storage.ListOptions{
+		Limit:  limit,
+		Cursor: c.Query("cursor"),
+		Sort:   c.Query("sort"),
+	}
+
+	resp, err := h.documentService.ListFiles(c, userID, opts)
+	if err != nil {
+		apierror.Respond(c, err, "Failed to list files")
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *DocumentHandler) DeleteDocument(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+	docID := c.Param("docID")
+
+	resp, err := h.documentService.DeleteDocument(c.Request.Context(), userID, docID)
+	if err != nil {
+		apierror.Respond(c, err, "Failed to delete document")
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *DocumentHandler) GetDocumentStatus(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+	docID := c.Param("docID")
+
+	resp, err := h.documentService.DocumentStatus(c.Request.Context(), userID, docID)
+	if err != nil {
+		apierror.Respond(c, err, "Failed to get document status")
 		return
 	}
 
@@ -113,17 +113,16 @@ func (h *DocumentHandler) HandleWebhook(c *gin.Context) {
 	var event types.MinIOEvent
 
 	if err := c.ShouldBindJSON(&event); err != nil {
-		body, _ := c.GetRawData()
-		log.Printf("❌ Failed to parse webhook: %v\nRaw body: %s", err, string(body))
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		slog.WarnContext(c.Request.Context(), "Failed to parse webhook", "error", err)
+		apierror.Write(c, http.StatusBadRequest, "invalid payload")
 		return
 	}
 
-	log.Printf("✅ Webhook received successfully")
+	slog.DebugContext(c.Request.Context(), "Webhook received", "records", len(event.Records))
 
 	if err := h.documentService.HandlerWebhook(c.Request.Context(), &event); err != nil {
-		log.Printf("❌ Failed to handle webhook: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to process webhook"})
+		slog.ErrorContext(c.Request.Context(), "Failed to handle webhook", "error", err)
+		apierror.Write(c, http.StatusInternalServerError, "failed to process webhook")
 		return
 	}
 
@@ -0,0 +1,93 @@
+// Package jobstatus records where each document's indexing job is, so users
+// can follow an upload through the pipeline.
+package jobstatus
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/gocql/gocql"
+)
+
+const (
+	StateQueued     = "queued"
+	StateProcessing = "processing"
+	// StateRetrying means the last attempt failed and the job is waiting for
+	// its next one.
+	StateRetrying = "retrying"
+	StateIndexed  = "indexed"
+	// StateDuplicate means the document's content was already indexed under
+	// another doc_id, which now serves it; see Status.DuplicateOf.
+	StateDuplicate = "duplicate"
+	// StateFailed means the job exhausted its retries, or failed in a way
+	// retrying can't fix, and went to the DLQ.
+	StateFailed = "failed"
+)
+
+// writeTimeout bounds a status write so a slow cluster never holds up
+// indexing.
+const writeTimeout = 2 * time.Second
+
+type Status struct {
+	DocID       string    `json:"doc_id"`
+	JobID       string    `json:"job_id"`
+	UserID      string    `json:"-"`
+	State       string    `json:"state"`
+	Error       string    `json:"error,omitempty"`
+	DuplicateOf string    `json:"duplicate_of,omitempty"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type Store struct {
+	session *gocql.Session
+}
+
+func NewStore(session *gocql.Session) *Store {
+	return &Store{session: session}
+}
+
+// Set records the state of docID's job. It is best-effort: failures are
+// logged and never returned.
+func (s *Store) Set(ctx context.Context, docID, jobID, userID, state, errMsg string) {
+	s.set(ctx, docID, jobID, userID, state, errMsg, "")
+}
+
+// SetDuplicate records that docID's job found its content already indexed as
+// duplicateOf. Like Set, it is best-effort.
+func (s *Store) SetDuplicate(ctx context.Context, docID, jobID, userID, duplicateOf string) {
+	s.set(ctx, docID, jobID, userID, StateDuplicate, "", duplicateOf)
+}
+
+func (s *Store) set(ctx context.Context, docID, jobID, userID, state, errMsg, duplicateOf string) {
+	docUUID, err := gocql.ParseUUID(docID)
+	if err != nil {
+		slog.WarnContext(ctx, "Not recording job status for invalid doc_id", "doc_id", docID)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), writeTimeout)
+	defer cancel()
+
+	err = s.session.Query(`
+		INSERT INTO job_status (doc_id, job_id, user_id, state, error, duplicate_of, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, docUUID, jobID, userID, state, errMsg, duplicateOf, time.Now()).WithContext(ctx).Exec()
+	if err != nil {
+		slog.WarnContext(ctx, "Failed to record job status", "doc_id", docID, "state", state, "error", err)
+	}
+}
+
+// Get returns the latest status of docID's job, or gocql.ErrNotFound.
+func (s *Store) Get(ctx context.Context, docID gocql.UUID) (*Status, error) {
+	var st Status
+	err := s.session.Query(`
+		SELECT job_id, user_id, state, error, duplicate_of, updated_at
+		FROM job_status WHERE doc_id = ?
+	`, docID).WithContext(ctx).Scan(&st.JobID, &st.UserID, &st.State, &st.Error, &st.DuplicateOf, &st.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	st.DocID = docID.String()
+	return &st, nil
+}
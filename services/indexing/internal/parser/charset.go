@@ -0,0 +1,121 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+const (
+	encodingUTF8        = "utf-8"
+	encodingUTF16LE     = "utf-16le"
+	encodingUTF16BE     = "utf-16be"
+	encodingWindows1252 = "windows-1252"
+)
+
+var (
+	bomUTF8    = []byte{0xEF, 0xBB, 0xBF}
+	bomUTF16LE = []byte{0xFF, 0xFE}
+	bomUTF16BE = []byte{0xFE, 0xFF}
+)
+
+// windows1252 maps bytes 0x80-0x9F to their code points; the rest of the
+// code page matches Latin-1 (and so Unicode) byte for byte. Undefined bytes map
+// to U+FFFD.
+var windows1252 = [32]rune{
+	'€', '�', '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', '�', 'Ž', '�',
+	'�', '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', '�', 'ž', 'Ÿ',
+}
+
+// decodeText detects the character encoding of data and returns it transcoded
+// to UTF-8 along with the encoding name. A byte order mark wins; otherwise
+// valid UTF-8 is taken as is, text with many NUL bytes in alternating
+// positions is treated as BOM-less UTF-16, and anything else as Windows-1252
+// (a superset of Latin-1).
+func decodeText(data []byte) (string, string) {
+	switch {
+	case bytes.HasPrefix(data, bomUTF8):
+		return string(data[len(bomUTF8):]), encodingUTF8
+	case bytes.HasPrefix(data, bomUTF16LE):
+		return decodeUTF16(data[len(bomUTF16LE):], binary.LittleEndian), encodingUTF16LE
+	case bytes.HasPrefix(data, bomUTF16BE):
+		return decodeUTF16(data[len(bomUTF16BE):], binary.BigEndian), encodingUTF16BE
+	}
+
+	if enc := sniffUTF16(data); enc == encodingUTF16LE {
+		return decodeUTF16(data, binary.LittleEndian), enc
+	} else if enc == encodingUTF16BE {
+		return decodeUTF16(data, binary.BigEndian), enc
+	}
+
+	if utf8.Valid(data) {
+		return string(data), encodingUTF8
+	}
+	return decodeWindows1252(data), encodingWindows1252
+}
+
+// sniffUTF16 guesses BOM-less UTF-16 from the share of NUL bytes at even or
+// odd offsets, which is high for mostly-ASCII text in either byte order.
+func sniffUTF16(data []byte) string {
+	n := min(len(data), 4096) &^ 1
+	if n < 4 {
+		return ""
+	}
+
+	var evenZeros, oddZeros int
+	for i := 0; i < n; i += 2 {
+		if data[i] == 0 {
+			evenZeros++
+		}
+		if data[i+1] == 0 {
+			oddZeros++
+		}
+	}
+
+	pairs := n / 2
+	switch {
+	case oddZeros*10 >= pairs*4 && evenZeros*10 < pairs:
+		return encodingUTF16LE
+	case evenZeros*10 >= pairs*4 && oddZeros*10 < pairs:
+		return encodingUTF16BE
+	}
+	return ""
+}
+
+func decodeUTF16(data []byte, order binary.ByteOrder) string {
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		units[i] = order.Uint16(data[2*i:])
+	}
+	return string(utf16.Decode(units))
+}
+
+func decodeWindows1252(data []byte) string {
+	var b strings.Builder
+	b.Grow(len(data))
+	for _, c := range data {
+		if c >= 0x80 && c <= 0x9F {
+			b.WriteRune(windows1252[c-0x80])
+			continue
+		}
+		b.WriteRune(rune(c))
+	}
+	return b.String()
+}
+
+// trimPartialRune drops an incomplete UTF-8 sequence left at the end of data
+// by cutting it at an arbitrary byte, which would otherwise make decodeText
+// take valid UTF-8 for Windows-1252.
+func trimPartialRune(data []byte) []byte {
+	for i := len(data) - 1; i >= 0 && i >= len(data)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(data[i]) {
+			if !utf8.FullRune(data[i:]) {
+				return data[:i]
+			}
+			break
+		}
+	}
+	return data
+}
@@ -0,0 +1,50 @@
+package parser
+
+import (
+	"testing"
+	"unicode/utf16"
+)
+
+func utf16Bytes(s string, bigEndian bool) []byte {
+	var b []byte
+	for _, u := range utf16.Encode([]rune(s)) {
+		if bigEndian {
+			b = append(b, byte(u>>8), byte(u))
+		} else {
+			b = append(b, byte(u), byte(u>>8))
+		}
+	}
+	return b
+}
+
+func TestDecodeText(t *testing.T) {
+	const text = "Café report, naïve résumé"
+
+	tests := []struct {
+		name     string
+		data     []byte
+		want     string
+		encoding string
+	}{
+		{"plain UTF-8", []byte(text), text, encodingUTF8},
+		{"UTF-8 BOM stripped", append([]byte{0xEF, 0xBB, 0xBF}, text...), text, encodingUTF8},
+		{"UTF-16LE BOM", append([]byte{0xFF, 0xFE}, utf16Bytes(text, false)...), text, encodingUTF16LE},
+		{"UTF-16BE BOM", append([]byte{0xFE, 0xFF}, utf16Bytes(text, true)...), text, encodingUTF16BE},
+		{"UTF-16LE without BOM", utf16Bytes(text, false), text, encodingUTF16LE},
+		{"UTF-16BE without BOM", utf16Bytes(text, true), text, encodingUTF16BE},
+		{"Latin-1", []byte("Caf\xe9 na\xefve"), "Café naïve", encodingWindows1252},
+		{"Windows-1252 punctuation", []byte("\x93quoted\x94 \x80 5"), "“quoted” € 5", encodingWindows1252},
+		{"empty", nil, "", encodingUTF8},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, encoding := decodeText(tt.data)
+			if got != tt.want {
+				t.Errorf("text = %q, want %q", got, tt.want)
+			}
+			if encoding != tt.encoding {
+				t.Errorf("encoding = %q, want %q", encoding, tt.encoding)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package parser
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// CSVParser indexes the cell values of a CSV file. The first row is treated
+// as the header: its names are recorded in Metadata["columns"] and are not
+// indexed as content.
+type CSVParser struct{}
+
+func NewCSVParser() *CSVParser {
+	return &CSVParser{}
+}
+
+func (p *CSVParser) Parse(ctx context.Context, reader io.Reader) (*ParsedDocument, error) {
+	r := csv.NewReader(reader)
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+	r.ReuseRecord = true
+
+	header, err := r.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("no text content found in CSV")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV format: %w", err)
+	}
+	columns := make([]string, 0, len(header))
+	for _, name := range header {
+		columns = append(columns, strings.TrimSpace(name))
+	}
+
+	var textBuilder strings.Builder
+	rows := 0
+	for {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid CSV format: %w", err)
+		}
+		rows++
+
+		cells := make([]string, 0, len(record))
+		for _, cell := range record {
+			// Quoted cells may span lines; keep each row on one line.
+			if cell = strings.Join(strings.Fields(cell), " "); cell != "" {
+				cells = append(cells, cell)
+			}
+		}
+		textBuilder.WriteString(strings.Join(cells, " "))
+		textBuilder.WriteString("\n")
+	}
+
+	content := strings.TrimSpace(textBuilder.String())
+	if content == "" {
+		return nil, fmt.Errorf("no text content found in CSV")
+	}
+
+	return &ParsedDocument{
+		Content: content,
+		Metadata: map[string]string{
+			"fileType": "text/csv",
+			"columns":  strings.Join(columns, ","),
+			"rows":     strconv.Itoa(rows),
+		},
+	}, nil
+}
+
+func (p *CSVParser) parsesText() {}
+
+func (p *CSVParser) SupportedTypes() []string {
+	return []string{"text/csv", ".csv"}
+}
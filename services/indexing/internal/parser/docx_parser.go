@@ -10,6 +10,8 @@ import (
 	"github.com/nguyenthenguyen/docx"
 )
 
+const docxMIME = "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
+
 type DOCXParser struct{}
 
 func NewDOCXParser() *DOCXParser {
@@ -46,14 +48,11 @@ func (p *DOCXParser) Parse(ctx context.Context, reader io.Reader) (*ParsedDocume
 	return &ParsedDocument{
 		Content: extractedText,
 		Metadata: map[string]string{
-			"fileType": "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+			"fileType": docxMIME,
 		},
 	}, nil
 }
 
 func (p *DOCXParser) SupportedTypes() []string {
-	return []string{
-		"application/vnd.openxmlformats-officedocument.wordprocessingml.document",
-		".docx",
-	}
+	return []string{docxMIME, ".docx"}
 }
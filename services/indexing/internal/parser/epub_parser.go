@@ -0,0 +1,216 @@
+package parser
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+)
+
+const epubMIME = "application/epub+zip"
+
+// epubBlockElements end a line of extracted text, so that words in adjacent
+// blocks are not run together.
+var epubBlockElements = map[string]bool{
+	"p": true, "div": true, "br": true, "li": true, "tr": true, "td": true, "th": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"section": true, "blockquote": true, "pre": true, "dt": true, "dd": true,
+}
+
+// epubSkippedElements hold no reading text.
+var epubSkippedElements = map[string]bool{"head": true, "script": true, "style": true}
+
+// EPUBParser extracts the text of an EPUB ebook in reading order, as given by
+// the spine of its OPF package document, along with the title, author and
+// language from the package metadata. EPUBs are zip archives, so the registry
+// size cap applies to the compressed file.
+type EPUBParser struct{}
+
+func NewEPUBParser() *EPUBParser {
+	return &EPUBParser{}
+}
+
+type epubContainer struct {
+	Rootfiles []struct {
+		FullPath string `xml:"full-path,attr"`
+	} `xml:"rootfiles>rootfile"`
+}
+
+type epubPackage struct {
+	Metadata struct {
+		Titles    []string `xml:"title"`
+		Creators  []string `xml:"creator"`
+		Languages []string `xml:"language"`
+	} `xml:"metadata"`
+	Manifest []struct {
+		ID        string `xml:"id,attr"`
+		Href      string `xml:"href,attr"`
+		MediaType string `xml:"media-type,attr"`
+	} `xml:"manifest>item"`
+	Spine []struct {
+		IDRef string `xml:"idref,attr"`
+	} `xml:"spine>itemref"`
+}
+
+func (p *EPUBParser) Parse(ctx context.Context, reader io.Reader) (*ParsedDocument, error) {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read epub: %w", err)
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("not an epub archive: %w", err)
+	}
+	files := make(map[string]*zip.File, len(archive.File))
+	for _, f := range archive.File {
+		files[f.Name] = f
+	}
+
+	var container epubContainer
+	if err := unmarshalZipXML(files["META-INF/container.xml"], &container); err != nil {
+		return nil, fmt.Errorf("not an epub archive: container: %w", err)
+	}
+	if len(container.Rootfiles) == 0 {
+		return nil, fmt.Errorf("not an epub archive: no package document")
+	}
+	opfPath := container.Rootfiles[0].FullPath
+
+	var pkg epubPackage
+	if err := unmarshalZipXML(files[opfPath], &pkg); err != nil {
+		return nil, fmt.Errorf("failed to read epub package %s: %w", opfPath, err)
+	}
+
+	// Manifest hrefs are URLs relative to the package document.
+	hrefs := make(map[string]string, len(pkg.Manifest))
+	for _, item := range pkg.Manifest {
+		if item.MediaType != "application/xhtml+xml" && item.MediaType != "text/html" {
+			continue
+		}
+		href, err := url.PathUnescape(item.Href)
+		if err != nil {
+			href = item.Href
+		}
+		hrefs[item.ID] = path.Join(path.Dir(opfPath), href)
+	}
+
+	var textBuilder strings.Builder
+	chapters := 0
+	for _, itemRef := range pkg.Spine {
+		name, ok := hrefs[itemRef.IDRef]
+		if !ok {
+			continue
+		}
+		f := files[name]
+		if f == nil {
+			continue
+		}
+		if err := extractXHTMLText(f, &textBuilder); err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		textBuilder.WriteString("\n")
+		chapters++
+	}
+
+	content := strings.TrimSpace(textBuilder.String())
+	if content == "" {
+		return nil, fmt.Errorf("no text content found in EPUB")
+	}
+
+	metadata := map[string]string{
+		"chapters": strconv.Itoa(chapters),
+		"fileType": epubMIME,
+	}
+	if title := firstNonEmpty(pkg.Metadata.Titles); title != "" {
+		metadata["title"] = title
+	}
+	if author := firstNonEmpty(pkg.Metadata.Creators); author != "" {
+		metadata["author"] = author
+	}
+	if language := firstNonEmpty(pkg.Metadata.Languages); language != "" {
+		metadata["language"] = language
+	}
+
+	return &ParsedDocument{
+		Content:  content,
+		Metadata: metadata,
+	}, nil
+}
+
+func (p *EPUBParser) SupportedTypes() []string {
+	return []string{epubMIME, ".epub"}
+}
+
+func unmarshalZipXML(f *zip.File, v any) error {
+	if f == nil {
+		return fmt.Errorf("file missing")
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return xml.NewDecoder(rc).Decode(v)
+}
+
+// extractXHTMLText writes the text of a content document's body. The decoder
+// is lenient, as content documents are often HTML more than XHTML.
+func extractXHTMLText(f *zip.File, builder *strings.Builder) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	dec := xml.NewDecoder(rc)
+	dec.Strict = false
+	dec.AutoClose = xml.HTMLAutoClose
+	dec.Entity = xml.HTMLEntity
+
+	skipDepth := 0
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			name := strings.ToLower(t.Name.Local)
+			if skipDepth > 0 || epubSkippedElements[name] {
+				skipDepth++
+			}
+		case xml.EndElement:
+			name := strings.ToLower(t.Name.Local)
+			if skipDepth > 0 {
+				skipDepth--
+				continue
+			}
+			if epubBlockElements[name] {
+				builder.WriteString("\n")
+			}
+		case xml.CharData:
+			if skipDepth == 0 {
+				builder.Write(t)
+			}
+		}
+	}
+}
+
+func firstNonEmpty(values []string) string {
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,55 @@
+package parser
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// ImageParser indexes PNG and JPEG images by recognizing their text with OCR.
+// It is only registered when OCR is enabled.
+type ImageParser struct {
+	ocr *OCR
+}
+
+func NewImageParser(ocr *OCR) *ImageParser {
+	return &ImageParser{ocr: ocr}
+}
+
+func (p *ImageParser) Parse(ctx context.Context, reader io.Reader) (*ParsedDocument, error) {
+	if p.ocr == nil {
+		return nil, fmt.Errorf("OCR is disabled, cannot parse images")
+	}
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image: %w", err)
+	}
+	fileType := http.DetectContentType(data)
+	if fileType != "image/png" && fileType != "image/jpeg" {
+		return nil, fmt.Errorf("not a PNG or JPEG image: detected %s", fileType)
+	}
+
+	text, err := p.ocr.Image(ctx, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to OCR image: %w", err)
+	}
+	content := strings.TrimSpace(text)
+	if content == "" {
+		return nil, fmt.Errorf("no text recognized in image")
+	}
+
+	return &ParsedDocument{
+		Content: content,
+		Metadata: map[string]string{
+			"fileType": fileType,
+			"ocr":      "true",
+		},
+	}, nil
+}
+
+func (p *ImageParser) SupportedTypes() []string {
+	return []string{"image/png", "image/jpeg", ".png", ".jpg", ".jpeg"}
+}
@@ -5,28 +5,69 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
+	"strconv"
 	"strings"
 )
 
-type JSONParser struct{}
+// Modes JSONParser can render a document in.
+const (
+	// JSONModeValues indexes string values only.
+	JSONModeValues = "values"
+	// JSONModeKeys indexes object keys along with string values, so field
+	// names are searchable.
+	JSONModeKeys = "keys"
+	// JSONModeFlatten writes one "path: value" line per scalar, the path
+	// being the dot-joined keys leading to it.
+	JSONModeFlatten = "flatten"
+)
+
+// JSONParser extracts the text of JSON documents. Any top-level value is
+// accepted, as are several in a row, which covers newline-delimited JSON.
+type JSONParser struct {
+	mode string
+}
 
 func NewJSONParser() *JSONParser {
-	return &JSONParser{}
+	return NewJSONParserWithMode(JSONModeKeys)
 }
 
-func (p *JSONParser) Parse(ctx context.Context, reader io.Reader) (*ParsedDocument, error) {
-	data, err := io.ReadAll(reader)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read JSON file: %w", err)
+// NewJSONParserWithMode returns a parser rendering documents in one of the
+// JSONMode modes. Unknown modes keep the default.
+func NewJSONParserWithMode(mode string) *JSONParser {
+	switch mode {
+	case JSONModeValues, JSONModeKeys, JSONModeFlatten:
+	default:
+		slog.Warn("Invalid JSON parser mode, using default", "mode", mode, "default", JSONModeKeys)
+		mode = JSONModeKeys
 	}
+	return &JSONParser{mode: mode}
+}
 
-	var jsonData map[string]interface{}
-	if err := json.Unmarshal(data, &jsonData); err != nil {
-		return nil, fmt.Errorf("invalid JSON format: %w", err)
-	}
+func (p *JSONParser) Parse(ctx context.Context, reader io.Reader) (*ParsedDocument, error) {
+	// The document is streamed token by token rather than decoded into
+	// maps, which for large files take many times the size of the file.
+	dec := json.NewDecoder(reader)
+	dec.UseNumber()
 
 	var textBuilder strings.Builder
-	extractText(jsonData, &textBuilder)
+	records := 0
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid JSON format: %w", err)
+		}
+		if err := p.extractText(dec, tok, "", &textBuilder); err != nil {
+			return nil, fmt.Errorf("invalid JSON format: %w", err)
+		}
+		records++
+	}
+	if records == 0 {
+		return nil, fmt.Errorf("invalid JSON format: empty document")
+	}
 
 	content := strings.TrimSpace(textBuilder.String())
 	if content == "" {
@@ -37,26 +78,79 @@ func (p *JSONParser) Parse(ctx context.Context, reader io.Reader) (*ParsedDocume
 		Content: content,
 		Metadata: map[string]string{
 			"fileType": "application/json",
+			"records":  strconv.Itoa(records),
 		},
 	}, nil
 }
 
+func (p *JSONParser) parsesText() {}
+
 func (p *JSONParser) SupportedTypes() []string {
-	return []string{"application/json", ".json"}
+	return []string{"application/json", "application/x-ndjson", ".json", ".ndjson", ".jsonl"}
 }
 
-func extractText(data interface{}, builder *strings.Builder) {
-	switch v := data.(type) {
+// extractText writes the value starting with tok, reading the rest of it
+// from dec when tok opens an object or array. path is the dot-joined keys
+// leading to the value.
+func (p *JSONParser) extractText(dec *json.Decoder, tok json.Token, path string, builder *strings.Builder) error {
+	switch v := tok.(type) {
+	case json.Delim:
+		for dec.More() {
+			childPath := path
+			if v == '{' {
+				keyTok, err := dec.Token()
+				if err != nil {
+					return err
+				}
+				key, _ := keyTok.(string)
+				if p.mode == JSONModeKeys {
+					builder.WriteString(key)
+					builder.WriteString(" ")
+				}
+				childPath = joinJSONPath(path, key)
+			}
+			child, err := dec.Token()
+			if err != nil {
+				return err
+			}
+			if err := p.extractText(dec, child, childPath, builder); err != nil {
+				return err
+			}
+		}
+		// The closing delimiter.
+		_, err := dec.Token()
+		return err
 	case string:
-		builder.WriteString(v)
-		builder.WriteString(" ")
-	case map[string]interface{}:
-		for _, value := range v {
-			extractText(value, builder)
+		p.writeScalar(path, v, builder)
+	case json.Number:
+		if p.mode == JSONModeFlatten {
+			p.writeScalar(path, v.String(), builder)
 		}
-	case []interface{}:
-		for _, item := range v {
-			extractText(item, builder)
+	case bool:
+		if p.mode == JSONModeFlatten {
+			p.writeScalar(path, strconv.FormatBool(v), builder)
 		}
 	}
+	return nil
+}
+
+func (p *JSONParser) writeScalar(path, value string, builder *strings.Builder) {
+	if p.mode != JSONModeFlatten {
+		builder.WriteString(value)
+		builder.WriteString(" ")
+		return
+	}
+	if path != "" {
+		builder.WriteString(path)
+		builder.WriteString(": ")
+	}
+	builder.WriteString(value)
+	builder.WriteString("\n")
+}
+
+func joinJSONPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
 }
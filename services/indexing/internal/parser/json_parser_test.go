@@ -0,0 +1,51 @@
+package parser
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestJSONParser(t *testing.T) {
+	const doc = `{"title": "Report", "meta": {"tags": ["go", "search"], "pages": 3, "draft": false}}`
+
+	tests := []struct {
+		name        string
+		mode        string
+		data        string
+		wantContent string
+		wantRecords string
+		wantErr     bool
+	}{
+		{"values", JSONModeValues, doc, "Report go search", "1", false},
+		{"keys", JSONModeKeys, doc, "title Report meta tags go search pages draft", "1", false},
+		{"flatten", JSONModeFlatten, doc, "title: Report\nmeta.tags: go\nmeta.tags: search\nmeta.pages: 3\nmeta.draft: false", "1", false},
+		{"unknown mode keeps keys", "yaml", `{"a": "b"}`, "a b", "1", false},
+		{"array root", JSONModeValues, `[{"name": "alpha"}, {"name": "beta"}]`, "alpha beta", "1", false},
+		{"NDJSON", JSONModeValues, "{\"msg\": \"first\"}\n{\"msg\": \"second\"}\n", "first second", "2", false},
+		{"bare string", JSONModeValues, `"just text"`, "just text", "1", false},
+		{"empty document", JSONModeValues, "  ", "", "", true},
+		{"malformed", JSONModeValues, `{"a": `, "", "", true},
+		{"no text", JSONModeValues, `{"n": 1}`, "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := NewJSONParserWithMode(tt.mode).Parse(context.Background(), strings.NewReader(tt.data))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parsed %q, want an error", doc.Content)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if doc.Content != tt.wantContent {
+				t.Errorf("content = %q, want %q", doc.Content, tt.wantContent)
+			}
+			if doc.Metadata["records"] != tt.wantRecords {
+				t.Errorf("records = %q, want %q", doc.Metadata["records"], tt.wantRecords)
+			}
+		})
+	}
+}
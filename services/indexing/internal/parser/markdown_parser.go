@@ -0,0 +1,148 @@
+package parser
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+var (
+	mdHeading      = regexp.MustCompile(`^\s{0,3}(#{1,6})\s+(.*?)\s*#*\s*$`)
+	mdSetextH1     = regexp.MustCompile(`^\s{0,3}=+\s*$`)
+	mdSetextH2     = regexp.MustCompile(`^\s{0,3}-+\s*$`)
+	mdRule         = regexp.MustCompile(`^\s{0,3}([-*_])(\s*([-*_]))+\s*$`)
+	mdFence        = regexp.MustCompile("^\\s{0,3}(```|~~~)")
+	mdLinkDef      = regexp.MustCompile(`^\s{0,3}\[[^\]]+\]:\s*\S+.*$`)
+	mdBlockquote   = regexp.MustCompile(`^\s*(>\s?)+`)
+	mdListMarker   = regexp.MustCompile(`^\s*([-*+]|\d+[.)])\s+(\[[ xX]\]\s+)?`)
+	mdImage        = regexp.MustCompile(`!\[([^\]]*)\]\([^)]*\)`)
+	mdLink         = regexp.MustCompile(`\[([^\]]*)\](\([^)]*\)|\[[^\]]*\])`)
+	mdAutolink     = regexp.MustCompile(`<(https?://|mailto:)[^>]+>`)
+	mdHTMLTag      = regexp.MustCompile(`</?[a-zA-Z][^>]*>`)
+	mdInlineCode   = regexp.MustCompile("`+([^`]*)`+")
+	mdTablePipe    = regexp.MustCompile(`\|`)
+	mdTableDivider = regexp.MustCompile(`^\s*\|?\s*:?-+:?\s*(\|\s*:?-+:?\s*)*\|?\s*$`)
+
+	// Underscore emphasis must not start or end inside a word, so that
+	// snake_case identifiers survive.
+	mdEmphasis = []*regexp.Regexp{
+		regexp.MustCompile(`\*{3}(\S(?:.*?\S)?)\*{3}`),
+		regexp.MustCompile(`\*{2}(\S(?:.*?\S)?)\*{2}`),
+		regexp.MustCompile(`\*(\S(?:.*?\S)?)\*`),
+		regexp.MustCompile(`~~(\S(?:.*?\S)?)~~`),
+		regexp.MustCompile(`(^|\W)_{1,3}(\S(?:.*?\S)?)_{1,3}(\W|$)`),
+	}
+)
+
+// MarkdownParser strips Markdown syntax so only the prose is indexed. Fenced
+// code blocks are dropped entirely: identifiers and punctuation in code would
+// otherwise dominate the term statistics of technical documents.
+type MarkdownParser struct{}
+
+func NewMarkdownParser() *MarkdownParser {
+	return &MarkdownParser{}
+}
+
+func (p *MarkdownParser) Parse(ctx context.Context, reader io.Reader) (*ParsedDocument, error) {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read markdown file: %w", err)
+	}
+
+	var lines []string
+	title := ""
+	inFence := false
+	fence := ""
+
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimRight(line, "\r")
+
+		if m := mdFence.FindStringSubmatch(line); m != nil {
+			if !inFence {
+				inFence, fence = true, m[1]
+			} else if m[1] == fence {
+				inFence = false
+			}
+			continue
+		}
+		if inFence {
+			continue
+		}
+
+		// Setext headings underline the previous line.
+		if len(lines) > 0 && lines[len(lines)-1] != "" {
+			if mdSetextH1.MatchString(line) {
+				if title == "" {
+					title = lines[len(lines)-1]
+				}
+				continue
+			}
+			if mdSetextH2.MatchString(line) {
+				continue
+			}
+		}
+
+		if mdRule.MatchString(line) || mdLinkDef.MatchString(line) || mdTableDivider.MatchString(line) {
+			continue
+		}
+
+		if m := mdHeading.FindStringSubmatch(line); m != nil {
+			line = stripInlineMarkdown(m[2])
+			if len(m[1]) == 1 && title == "" {
+				title = line
+			}
+			lines = append(lines, line)
+			continue
+		}
+
+		line = mdBlockquote.ReplaceAllString(line, "")
+		line = mdListMarker.ReplaceAllString(line, "")
+		lines = append(lines, strings.TrimSpace(stripInlineMarkdown(line)))
+	}
+
+	content := strings.TrimSpace(strings.Join(lines, "\n"))
+	if content == "" {
+		return nil, fmt.Errorf("no text content found in markdown")
+	}
+
+	metadata := map[string]string{
+		"fileType": "text/markdown",
+	}
+	if title != "" {
+		metadata["title"] = title
+	}
+
+	return &ParsedDocument{
+		Content:  content,
+		Metadata: metadata,
+	}, nil
+}
+
+func (p *MarkdownParser) acceptsPrefix() {}
+
+func (p *MarkdownParser) parsesText() {}
+
+func (p *MarkdownParser) SupportedTypes() []string {
+	return []string{"text/markdown", ".md", ".markdown"}
+}
+
+// stripInlineMarkdown removes inline markup from a single line, keeping the
+// visible text of links, images and inline code.
+func stripInlineMarkdown(line string) string {
+	line = mdImage.ReplaceAllString(line, "$1")
+	line = mdLink.ReplaceAllString(line, "$1")
+	line = mdAutolink.ReplaceAllString(line, "")
+	line = mdHTMLTag.ReplaceAllString(line, "")
+	line = mdInlineCode.ReplaceAllString(line, "$1")
+	for _, re := range mdEmphasis {
+		if re.NumSubexp() == 3 {
+			line = re.ReplaceAllString(line, "$1$2$3")
+		} else {
+			line = re.ReplaceAllString(line, "$1")
+		}
+	}
+	line = mdTablePipe.ReplaceAllString(line, " ")
+	return strings.TrimSpace(line)
+}
@@ -0,0 +1,113 @@
+package parser
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ocrResolution is the DPI PDF pages are rasterized at; Tesseract is most
+// accurate around 300.
+const ocrResolution = "300"
+
+// OCR recognizes text in images with the tesseract command-line tool. PDF
+// pages are rasterized for it with pdftoppm from poppler-utils. Both run as
+// subprocesses on temporary files, so OCR is slow and disabled by default.
+type OCR struct {
+	tesseract string
+	pdftoppm  string
+	language  string
+}
+
+// NewOCR locates the OCR tools on PATH. language is a Tesseract language
+// code such as "eng", or several joined with "+". Without pdftoppm, images
+// are still recognized but PDFs are not.
+func NewOCR(language string) (*OCR, error) {
+	tesseract, err := exec.LookPath("tesseract")
+	if err != nil {
+		return nil, fmt.Errorf("tesseract not found: %w", err)
+	}
+	pdftoppm, _ := exec.LookPath("pdftoppm")
+	if language == "" {
+		language = "eng"
+	}
+	return &OCR{tesseract: tesseract, pdftoppm: pdftoppm, language: language}, nil
+}
+
+// Image returns the text recognized in an encoded image.
+func (o *OCR) Image(ctx context.Context, data []byte) (string, error) {
+	dir, err := os.MkdirTemp("", "ocr-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temp dir: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	image := filepath.Join(dir, "image")
+	if err := os.WriteFile(image, data, 0o600); err != nil {
+		return "", fmt.Errorf("failed to write temp file: %w", err)
+	}
+	return o.recognize(ctx, image)
+}
+
+// PDF rasterizes every page of a PDF, opened with password if it is
+// encrypted, and returns the text recognized on them in page order.
+func (o *OCR) PDF(ctx context.Context, data []byte, password string) (string, error) {
+	if o.pdftoppm == "" {
+		return "", fmt.Errorf("pdftoppm not found, cannot OCR PDFs")
+	}
+
+	dir, err := os.MkdirTemp("", "ocr-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temp dir: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	input := filepath.Join(dir, "input.pdf")
+	if err := os.WriteFile(input, data, 0o600); err != nil {
+		return "", fmt.Errorf("failed to write temp file: %w", err)
+	}
+	args := []string{"-r", ocrResolution, "-png"}
+	if password != "" {
+		args = append(args, "-upw", password)
+	}
+	args = append(args, input, filepath.Join(dir, "page"))
+	cmd := exec.CommandContext(ctx, o.pdftoppm, args...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return "", fmt.Errorf("pdftoppm failed: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+
+	// pdftoppm zero-pads page numbers to the same width, so the names sort
+	// in page order.
+	pages, err := filepath.Glob(filepath.Join(dir, "page-*.png"))
+	if err != nil {
+		return "", err
+	}
+	sort.Strings(pages)
+
+	var textBuilder strings.Builder
+	for _, page := range pages {
+		text, err := o.recognize(ctx, page)
+		if err != nil {
+			return "", err
+		}
+		textBuilder.WriteString(text)
+		textBuilder.WriteString("\n")
+	}
+	return textBuilder.String(), nil
+}
+
+func (o *OCR) recognize(ctx context.Context, image string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, o.tesseract, image, "stdout", "-l", o.language)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("tesseract failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
@@ -14,3 +14,15 @@ type ParsedDocument struct {
 	Content  string
 	Metadata map[string]string
 }
+
+// prefixParser is implemented by parsers whose formats stay readable when cut
+// short, so that the start of an oversized file can still be indexed.
+type prefixParser interface {
+	acceptsPrefix()
+}
+
+// textParser is implemented by parsers of text formats. They accept nearly
+// any bytes, so the registry doesn't hand them binary content.
+type textParser interface {
+	parsesText()
+}
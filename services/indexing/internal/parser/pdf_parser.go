@@ -1,20 +1,47 @@
 package parser
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/rsc/pdf"
 )
 
-type PDFParser struct{}
+// ErrEncryptedPDF is returned for an encrypted PDF that neither an empty
+// password nor the one supplied with the upload unlocks, or whose encryption
+// scheme isn't supported. Parsing it again can't succeed.
+var ErrEncryptedPDF = errors.New("encrypted PDF")
+
+type passwordKey struct{}
+
+// WithPassword returns a context carrying the password to try on encrypted
+// PDFs parsed under it.
+func WithPassword(ctx context.Context, password string) context.Context {
+	return context.WithValue(ctx, passwordKey{}, password)
+}
+
+// PDFParser extracts the text layer of a PDF. With OCR, scanned PDFs that
+// have none are rasterized and recognized instead.
+type PDFParser struct {
+	ocr *OCR
+}
 
 func NewPDFParser() *PDFParser {
 	return &PDFParser{}
 }
 
+// NewPDFParserWithOCR returns a PDFParser falling back to ocr for PDFs
+// without a text layer.
+func NewPDFParserWithOCR(ocr *OCR) *PDFParser {
+	return &PDFParser{ocr: ocr}
+}
+
 func min(a, b int) int {
 	if a < b {
 		return a
@@ -29,12 +56,27 @@ func (p *PDFParser) Parse(ctx context.Context, reader io.Reader) (*ParsedDocumen
 	}
 
 	// Check if it's a valid PDF by examining the header
-	if len(data) < 4 || !strings.HasPrefix(string(data), "%PDF") {
+	if !bytes.HasPrefix(data, []byte("%PDF")) {
 		return nil, fmt.Errorf("not a PDF file: invalid header (got: %q)", string(data[:min(10, len(data))]))
 	}
 
-	// Parse the PDF
-	r, err := pdf.NewReader(strings.NewReader(string(data)), int64(len(data)))
+	// Parse the PDF. An encrypted one is tried with the empty password
+	// first, then with the supplied one, if any.
+	password, _ := ctx.Value(passwordKey{}).(string)
+	tried := false
+	r, err := pdf.NewReaderEncrypted(bytes.NewReader(data), int64(len(data)), func() string {
+		if tried {
+			return ""
+		}
+		tried = true
+		return password
+	})
+	if err == pdf.ErrInvalidPassword {
+		return nil, fmt.Errorf("%w: missing or wrong password", ErrEncryptedPDF)
+	}
+	if err != nil && bytes.Contains(data, []byte("/Encrypt")) {
+		return nil, fmt.Errorf("%w: %v", ErrEncryptedPDF, err)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse pdf: %w", err)
 	}
@@ -56,20 +98,101 @@ func (p *PDFParser) Parse(ctx context.Context, reader io.Reader) (*ParsedDocumen
 		textBuilder.WriteString("\n")
 	}
 
+	metadata := map[string]string{
+		"pages":    fmt.Sprintf("%d", numPages),
+		"fileType": "application/pdf",
+	}
 	extractedText := strings.TrimSpace(textBuilder.String())
+	if extractedText == "" && p.ocr != nil {
+		text, err := p.ocr.PDF(ctx, data, password)
+		if err != nil {
+			return nil, fmt.Errorf("no text layer in PDF and OCR failed: %w", err)
+		}
+		extractedText = strings.TrimSpace(text)
+		metadata["ocr"] = "true"
+	}
 	if extractedText == "" {
 		return nil, fmt.Errorf("no text content found in PDF")
 	}
+	addInfoMetadata(r, metadata)
 
 	return &ParsedDocument{
-		Content: extractedText,
-		Metadata: map[string]string{
-			"pages":    fmt.Sprintf("%d", numPages),
-			"fileType": "application/pdf",
-		},
+		Content:  extractedText,
+		Metadata: metadata,
 	}, nil
 }
 
+// addInfoMetadata copies the title, author, subject and creation date from
+// the PDF's document information dictionary into metadata. Entries that are
+// missing or empty, as is the whole dictionary in many PDFs, are skipped.
+func addInfoMetadata(r *pdf.Reader, metadata map[string]string) {
+	info := r.Trailer().Key("Info")
+	if info.IsNull() {
+		return
+	}
+	for key, entry := range map[string]string{
+		"title":       "Title",
+		"author":      "Author",
+		"description": "Subject",
+	} {
+		if value := strings.TrimSpace(info.Key(entry).Text()); value != "" {
+			metadata[key] = value
+		}
+	}
+	if created, ok := parsePDFDate(info.Key("CreationDate").Text()); ok {
+		metadata["creationDate"] = created.Format(time.RFC3339)
+	}
+}
+
+// parsePDFDate parses a PDF date string, D:YYYYMMDDHHmmSSOHH'mm', where
+// everything after the year is optional and O is Z, + or -.
+func parsePDFDate(s string) (time.Time, bool) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "D:")
+	digits, zone := s, ""
+	if i := strings.IndexAny(s, "Z+-"); i >= 0 {
+		digits, zone = s[:i], s[i:]
+	}
+	if len(digits) < 4 || len(digits) > 14 || len(digits)%2 != 0 {
+		return time.Time{}, false
+	}
+
+	// year, month, day, hour, minute, second
+	fields := []int{0, 1, 1, 0, 0, 0}
+	for f, i := 0, 0; i < len(digits); f++ {
+		width := 2
+		if f == 0 {
+			width = 4
+		}
+		n, err := strconv.Atoi(digits[i : i+width])
+		if err != nil {
+			return time.Time{}, false
+		}
+		fields[f] = n
+		i += width
+	}
+	if fields[1] < 1 || fields[1] > 12 || fields[2] < 1 || fields[2] > 31 {
+		return time.Time{}, false
+	}
+
+	loc := time.UTC
+	if zone != "" && zone[0] != 'Z' {
+		offset := strings.ReplaceAll(zone[1:], "'", "")
+		hours, minutes := 0, 0
+		if len(offset) >= 2 {
+			hours, _ = strconv.Atoi(offset[:2])
+		}
+		if len(offset) >= 4 {
+			minutes, _ = strconv.Atoi(offset[2:4])
+		}
+		seconds := hours*3600 + minutes*60
+		if zone[0] == '-' {
+			seconds = -seconds
+		}
+		loc = time.FixedZone("", seconds)
+	}
+	return time.Date(fields[0], time.Month(fields[1]), fields[2], fields[3], fields[4], fields[5], 0, loc), true
+}
+
 func (p *PDFParser) SupportedTypes() []string {
 	return []string{"application/pdf", ".pdf"}
 }
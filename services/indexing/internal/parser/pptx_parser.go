@@ -0,0 +1,168 @@
+package parser
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	pptxMIME = "application/vnd.openxmlformats-officedocument.presentationml.presentation"
+	// drawingMLNamespace is the namespace of the a: elements holding slide text.
+	drawingMLNamespace = "http://schemas.openxmlformats.org/drawingml/2006/main"
+)
+
+// PPTXParser extracts the text of a PowerPoint presentation: the slides in
+// order, followed by their speaker notes.
+type PPTXParser struct{}
+
+func NewPPTXParser() *PPTXParser {
+	return &PPTXParser{}
+}
+
+func (p *PPTXParser) Parse(ctx context.Context, reader io.Reader) (*ParsedDocument, error) {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pptx: %w", err)
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("not a pptx archive: %w", err)
+	}
+
+	slides := numberedParts(archive, "ppt/slides/slide")
+	if len(slides) == 0 {
+		return nil, fmt.Errorf("not a pptx archive: no slides found")
+	}
+	notes := numberedParts(archive, "ppt/notesSlides/notesSlide")
+
+	var textBuilder strings.Builder
+	for _, part := range append(slides, notes...) {
+		if err := extractSlideText(part, &textBuilder); err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", part.Name, err)
+		}
+	}
+
+	content := strings.TrimSpace(textBuilder.String())
+	if content == "" {
+		return nil, fmt.Errorf("no text content found in PPTX")
+	}
+
+	return &ParsedDocument{
+		Content: content,
+		Metadata: map[string]string{
+			"slides":   strconv.Itoa(len(slides)),
+			"fileType": pptxMIME,
+		},
+	}, nil
+}
+
+func (p *PPTXParser) SupportedTypes() []string {
+	return []string{pptxMIME, ".pptx"}
+}
+
+// numberedParts returns the archive's prefix<N>.xml parts ordered by N, so
+// that slide10 comes after slide9.
+func numberedParts(archive *zip.Reader, prefix string) []*zip.File {
+	type numbered struct {
+		n    int
+		file *zip.File
+	}
+	var parts []numbered
+	for _, f := range archive.File {
+		name, ok := strings.CutPrefix(f.Name, prefix)
+		if !ok {
+			continue
+		}
+		name, ok = strings.CutSuffix(name, ".xml")
+		if !ok {
+			continue
+		}
+		n, err := strconv.Atoi(name)
+		if err != nil {
+			continue
+		}
+		parts = append(parts, numbered{n: n, file: f})
+	}
+	sort.Slice(parts, func(i, j int) bool { return parts[i].n < parts[j].n })
+
+	files := make([]*zip.File, len(parts))
+	for i, part := range parts {
+		files[i] = part.file
+	}
+	return files
+}
+
+// extractSlideText writes the text runs (a:t) of a slide or notes part, one
+// line per paragraph (a:p). Runs split words wherever formatting changes, so
+// they are joined without a separator. Slide number fields are skipped: on
+// notes pages they would add a bare number per slide.
+func extractSlideText(part *zip.File, builder *strings.Builder) error {
+	rc, err := part.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	dec := xml.NewDecoder(rc)
+	inText := false
+	skipDepth := 0
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if t.Name.Space != drawingMLNamespace {
+				continue
+			}
+			switch {
+			case skipDepth > 0:
+				skipDepth++
+			case t.Name.Local == "fld" && fieldType(t) == "slidenum":
+				skipDepth = 1
+			case t.Name.Local == "t":
+				inText = true
+			}
+		case xml.EndElement:
+			if t.Name.Space != drawingMLNamespace {
+				continue
+			}
+			switch {
+			case skipDepth > 0:
+				skipDepth--
+			case t.Name.Local == "t":
+				inText = false
+			case t.Name.Local == "br":
+				builder.WriteString(" ")
+			case t.Name.Local == "p":
+				builder.WriteString("\n")
+			}
+		case xml.CharData:
+			if inText && skipDepth == 0 {
+				builder.Write(t)
+			}
+		}
+	}
+}
+
+func fieldType(start xml.StartElement) string {
+	for _, attr := range start.Attr {
+		if attr.Name.Local == "type" {
+			return attr.Value
+		}
+	}
+	return ""
+}
@@ -3,33 +3,65 @@ package parser
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
+	"net/http"
 	"path/filepath"
 	"strings"
 )
 
+// DefaultMaxFileSize is the largest file a Registry reads by default.
+const DefaultMaxFileSize = 50 << 20
+
+// ErrUnsupportedFile is returned when no registered parser can read a file.
+var ErrUnsupportedFile = errors.New("unsupported file")
+
 type Registry struct {
 	parsers map[string]Parser
+	// types lists the registered types in the order they were first
+	// registered, which is the order the fallback tries their parsers in.
+	types       []string
+	maxFileSize int64
 }
 
 func NewRegistry() *Registry {
+	return NewRegistryWithMaxFileSize(DefaultMaxFileSize)
+}
+
+// NewRegistryWithMaxFileSize returns a registry that reads at most
+// maxFileSize bytes of a file. Larger plain-text files are indexed from their
+// start; other formats are rejected. Values below 1 keep the default.
+func NewRegistryWithMaxFileSize(maxFileSize int64) *Registry {
+	if maxFileSize < 1 {
+		maxFileSize = DefaultMaxFileSize
+	}
 	registry := &Registry{
-		parsers: make(map[string]Parser),
+		parsers:     make(map[string]Parser),
+		maxFileSize: maxFileSize,
 	}
 
 	registry.Register(NewTextParser())
+	registry.Register(NewMarkdownParser())
+	registry.Register(NewCSVParser())
 	registry.Register(NewJSONParser())
 	registry.Register(NewPDFParser())
 	registry.Register(NewDOCXParser())
+	registry.Register(NewPPTXParser())
+	registry.Register(NewEPUBParser())
+	registry.Register(NewXMLParser())
 
 	return registry
 }
 
 func (r *Registry) Register(parser Parser) {
 	for _, contentType := range parser.SupportedTypes() {
-		r.parsers[strings.ToLower(contentType)] = parser
+		contentType = strings.ToLower(contentType)
+		if _, ok := r.parsers[contentType]; !ok {
+			r.types = append(r.types, contentType)
+		}
+		r.parsers[contentType] = parser
 	}
 }
 
@@ -48,57 +80,111 @@ func (r *Registry) GetParser(filePathOrType string) (Parser, error) {
 }
 
 func (r *Registry) ParseFile(ctx context.Context, filePathOrType string, reader io.Reader) (*ParsedDocument, error) {
-	// Read the content first to enable multiple parsing attempts
-	data, err := io.ReadAll(reader)
+	// Read the content first to enable multiple parsing attempts. One byte
+	// past the limit is enough to tell an oversized file.
+	data, err := io.ReadAll(io.LimitReader(reader, r.maxFileSize+1))
 	if err != nil {
 		return nil, fmt.Errorf("failed to read file content: %w", err)
 	}
+	if int64(len(data)) > r.maxFileSize {
+		return r.parseTruncated(ctx, filePathOrType, data[:r.maxFileSize])
+	}
+
+	// Each parser is tried once, even if registered under several types.
+	// Text parsers accept almost any bytes, so they are never tried on binary
+	// content.
+	tried := make(map[Parser]bool)
+	binary := looksBinary(data)
+	plausible := func(parser Parser) bool {
+		_, text := parser.(textParser)
+		return !binary || !text
+	}
 
 	// Try content-based detection first
-	if len(data) >= 4 && bytes.HasPrefix(data, []byte("%PDF")) {
-		contentReader := bytes.NewReader(data)
-		if parser, ok := r.parsers[".pdf"]; ok {
-			result, err := parser.Parse(ctx, contentReader)
+	ext := strings.ToLower(filepath.Ext(filePathOrType))
+	extParser := r.parsers[ext]
+	if sniffed := sniffContentType(data); sniffed != "" {
+		if parser, ok := r.parsers[sniffed]; ok {
+			if extParser != nil && extParser != parser {
+				slog.WarnContext(ctx, "File content does not match its extension", "file", filePathOrType, "extension", ext, "content_type", sniffed)
+			}
+			tried[parser] = true
+			result, err := parser.Parse(ctx, bytes.NewReader(data))
 			if err == nil {
 				return result, nil
 			}
+			// Other parsers would only index the ciphertext.
+			if errors.Is(err, ErrEncryptedPDF) {
+				return nil, err
+			}
+			slog.WarnContext(ctx, "Failed to parse file", "file", filePathOrType, "as", sniffed, "error", err)
 		}
 	}
 
 	// Try based on file extension
-	ext := strings.ToLower(filepath.Ext(filePathOrType))
-	if parser, ok := r.parsers[ext]; ok {
-		contentReader := bytes.NewReader(data)
-		result, err := parser.Parse(ctx, contentReader)
+	if extParser != nil && !tried[extParser] && !plausible(extParser) {
+		tried[extParser] = true
+		slog.WarnContext(ctx, "File has a text extension but binary content", "file", filePathOrType, "extension", ext)
+	}
+	if extParser != nil && !tried[extParser] {
+		tried[extParser] = true
+		result, err := extParser.Parse(ctx, bytes.NewReader(data))
 		if err == nil {
 			return result, nil
 		}
+		if errors.Is(err, ErrEncryptedPDF) {
+			return nil, err
+		}
 		// Log the failure but continue trying other parsers
-		log.Printf("⚠️  Failed to parse %s as %s: %v", filePathOrType, ext, err)
+		slog.WarnContext(ctx, "Failed to parse file", "file", filePathOrType, "as", ext, "error", err)
 	}
 
-	// Try all other parsers as fallback
-	for contentType, parser := range r.parsers {
-		if contentType == ext {
-			continue // Already tried this one
+	// Try the remaining parsers in registration order as fallback
+	for _, contentType := range r.types {
+		parser := r.parsers[contentType]
+		if tried[parser] || !plausible(parser) {
+			continue
 		}
+		tried[parser] = true
 
-		contentReader := bytes.NewReader(data)
-		result, err := parser.Parse(ctx, contentReader)
+		result, err := parser.Parse(ctx, bytes.NewReader(data))
 		if err == nil {
-			log.Printf("⚠️  File %s parsed as %s instead of expected %s", filePathOrType, contentType, ext)
+			slog.WarnContext(ctx, "File parsed as another type than expected", "file", filePathOrType, "as", contentType, "extension", ext)
 			return result, nil
 		}
 	}
 
-	return nil, fmt.Errorf("failed to parse file %s with any available parser", filePathOrType)
+	// A cancelled parse, such as OCR cut short on shutdown, is worth a retry.
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("%w: no parser could read %s (detected %s)",
+		ErrUnsupportedFile, filePathOrType, http.DetectContentType(data))
+}
+
+// parseTruncated indexes the first bytes of a file over the size limit, if
+// its format is one that can be cut short. The result is marked as truncated.
+func (r *Registry) parseTruncated(ctx context.Context, filePathOrType string, data []byte) (*ParsedDocument, error) {
+	ext := strings.ToLower(filepath.Ext(filePathOrType))
+	parser, ok := r.parsers[ext]
+	if _, prefixOK := parser.(prefixParser); !ok || !prefixOK {
+		return nil, fmt.Errorf("file %s exceeds the size limit of %d bytes", filePathOrType, r.maxFileSize)
+	}
+
+	result, err := parser.Parse(ctx, bytes.NewReader(trimPartialRune(data)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse truncated file %s: %w", filePathOrType, err)
+	}
+	result.Metadata["truncated"] = "true"
+	slog.WarnContext(ctx, "File exceeds the size limit, indexed its start only", "file", filePathOrType, "max_file_size", r.maxFileSize)
+	return result, nil
 }
 
 func (r *Registry) SupportedTypes() []string {
 	var types []string
 	seen := make(map[string]bool)
 
-	for contentType := range r.parsers {
+	for _, contentType := range r.types {
 		if !seen[contentType] {
 			types = append(types, contentType)
 			seen[contentType] = true
@@ -0,0 +1,178 @@
+package parser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestParseFileSizeLimit(t *testing.T) {
+	tests := []struct {
+		name          string
+		file          string
+		data          string
+		wantContent   string
+		wantTruncated bool
+		wantErr       bool
+	}{
+		{"text within limit", "notes.txt", "short note", "short note", false, false},
+		{"text at limit", "notes.txt", "0123456789", "0123456789", false, false},
+		{"text over limit indexes its start", "notes.txt", "0123456789 and more", "0123456789", true, false},
+		{"multi-byte rune cut at the limit", "notes.txt", "naïve caé more", "naïve ca", true, false},
+		{"markdown over limit", "readme.md", "# Title\n\nbody text", "Title\n\nb", true, false},
+		{"JSON over limit is rejected", "data.json", `{"text": "too long"}`, "", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := NewRegistryWithMaxFileSize(10)
+			doc, err := registry.ParseFile(context.Background(), tt.file, strings.NewReader(tt.data))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parsed %q, want an error", doc.Content)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if doc.Content != tt.wantContent {
+				t.Errorf("content = %q, want %q", doc.Content, tt.wantContent)
+			}
+			if truncated := doc.Metadata["truncated"] == "true"; truncated != tt.wantTruncated {
+				t.Errorf("truncated = %v, want %v", truncated, tt.wantTruncated)
+			}
+			if tt.wantTruncated && doc.Metadata["encoding"] == encodingWindows1252 {
+				t.Error("cut UTF-8 decoded as Windows-1252")
+			}
+		})
+	}
+}
+
+func TestNewRegistryWithMaxFileSizeDefault(t *testing.T) {
+	for _, n := range []int64{0, -1} {
+		if r := NewRegistryWithMaxFileSize(n); r.maxFileSize != DefaultMaxFileSize {
+			t.Errorf("NewRegistryWithMaxFileSize(%d).maxFileSize = %d, want %d", n, r.maxFileSize, DefaultMaxFileSize)
+		}
+	}
+}
+
+// lockedPDFParser stands in for PDFParser on a PDF it can't unlock.
+type lockedPDFParser struct {
+	password string
+}
+
+func (p *lockedPDFParser) Parse(ctx context.Context, reader io.Reader) (*ParsedDocument, error) {
+	p.password, _ = ctx.Value(passwordKey{}).(string)
+	return nil, fmt.Errorf("%w: missing or wrong password", ErrEncryptedPDF)
+}
+
+func (p *lockedPDFParser) SupportedTypes() []string {
+	return []string{"application/pdf", ".pdf"}
+}
+
+func TestParseFileEncryptedPDF(t *testing.T) {
+	// Text parsers would index the ciphertext, so none may be tried after
+	// the PDF parser reports encryption, whether the PDF was recognized by
+	// its content or only by its extension.
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"sniffed as PDF", "%PDF-1.7\n%\xe2\xe3\xcf\xd3\nencrypted stream"},
+		{"by extension", "encrypted stream"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			locked := &lockedPDFParser{}
+			registry := NewRegistry()
+			registry.Register(locked)
+
+			ctx := WithPassword(context.Background(), "secret")
+			doc, err := registry.ParseFile(ctx, "report.pdf", strings.NewReader(tt.data))
+			if !errors.Is(err, ErrEncryptedPDF) {
+				t.Fatalf("ParseFile = %v, %v, want %v", doc, err, ErrEncryptedPDF)
+			}
+			if locked.password != "secret" {
+				t.Errorf("parser got password %q, want %q", locked.password, "secret")
+			}
+		})
+	}
+}
+
+// namedParser accepts its types and succeeds unless fail is set, recording
+// the order parsers were tried in.
+type namedParser struct {
+	name  string
+	types []string
+	fail  bool
+	tried *[]string
+}
+
+func (p *namedParser) Parse(ctx context.Context, reader io.Reader) (*ParsedDocument, error) {
+	*p.tried = append(*p.tried, p.name)
+	if p.fail {
+		return nil, errors.New("cannot read")
+	}
+	return &ParsedDocument{Content: p.name, Metadata: map[string]string{}}, nil
+}
+
+func (p *namedParser) SupportedTypes() []string {
+	return p.types
+}
+
+func TestParseFileFallbackOrder(t *testing.T) {
+	var tried []string
+	registry := &Registry{parsers: make(map[string]Parser), maxFileSize: DefaultMaxFileSize}
+	registry.Register(&namedParser{name: "first", types: []string{".a"}, fail: true, tried: &tried})
+	registry.Register(&namedParser{name: "second", types: []string{".b", ".c"}, tried: &tried})
+	registry.Register(&namedParser{name: "third", types: []string{".d"}, tried: &tried})
+
+	// Repeated runs would catch an order that depends on map iteration.
+	for range 20 {
+		tried = nil
+		doc, err := registry.ParseFile(context.Background(), "file.unknown", strings.NewReader("content"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if doc.Content != "second" || len(tried) != 2 || tried[0] != "first" {
+			t.Fatalf("parsed by %q after trying %v, want second after first", doc.Content, tried)
+		}
+	}
+}
+
+func TestParseFileBinaryContent(t *testing.T) {
+	binary := "\x00\x01\x02\x03\xfe\xff\x10\x80binary\x00\x00\x07"
+
+	tests := []struct {
+		name        string
+		file        string
+		data        string
+		wantContent string
+		wantErr     error
+	}{
+		{"binary with a text extension", "notes.txt", binary, "", ErrUnsupportedFile},
+		{"binary without an extension", "blob", binary, "", ErrUnsupportedFile},
+		{"UTF-16 text is not binary", "notes.txt", string(utf16Bytes("plain words", false)), "plain words", nil},
+		{"text without an extension", "README", "plain words", "plain words", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := NewRegistry().ParseFile(context.Background(), tt.file, strings.NewReader(tt.data))
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if doc.Content != tt.wantContent {
+				t.Errorf("content = %q, want %q", doc.Content, tt.wantContent)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package parser
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// sniffContentType returns the MIME type of data judged by its content, or ""
+// if the content doesn't point at a specific format. Plain text is left to
+// the file extension, as it can't tell CSV, Markdown and JSON apart.
+func sniffContentType(data []byte) string {
+	contentType, _, _ := strings.Cut(http.DetectContentType(data), ";")
+	switch contentType {
+	case "application/zip":
+		return sniffZip(data)
+	case "text/plain", "application/octet-stream":
+		return ""
+	}
+	return contentType
+}
+
+// looksBinary reports whether data is neither UTF-8 nor UTF-16 text.
+func looksBinary(data []byte) bool {
+	return !strings.HasPrefix(http.DetectContentType(data), "text/") && sniffUTF16(data) == ""
+}
+
+// sniffZip tells the zip-based formats apart by the entries of the archive.
+func sniffZip(data []byte) string {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return ""
+	}
+	for _, f := range archive.File {
+		switch {
+		case f.Name == "mimetype":
+			// EPUB and OpenDocument both start with a mimetype entry.
+			if zipEntryHasPrefix(f, epubMIME) {
+				return epubMIME
+			}
+		case strings.HasPrefix(f.Name, "word/"):
+			return docxMIME
+		case strings.HasPrefix(f.Name, "ppt/"):
+			return pptxMIME
+		}
+	}
+	return ""
+}
+
+func zipEntryHasPrefix(f *zip.File, prefix string) bool {
+	rc, err := f.Open()
+	if err != nil {
+		return false
+	}
+	defer rc.Close()
+	head := make([]byte, len(prefix))
+	if _, err := io.ReadFull(rc, head); err != nil {
+		return false
+	}
+	return string(head) == prefix
+}
@@ -19,7 +19,8 @@ func (p *TextParser) Parse(ctx context.Context, reader io.Reader) (*ParsedDocume
 		return nil, fmt.Errorf("failed to read text file: %w", err)
 	}
 
-	content := strings.TrimSpace(string(data))
+	text, encoding := decodeText(data)
+	content := strings.TrimSpace(text)
 	if content == "" {
 		return nil, fmt.Errorf("no text content found")
 	}
@@ -28,10 +29,15 @@ func (p *TextParser) Parse(ctx context.Context, reader io.Reader) (*ParsedDocume
 		Content: content,
 		Metadata: map[string]string{
 			"fileType": "text/plain",
+			"encoding": encoding,
 		},
 	}, nil
 }
 
+func (p *TextParser) acceptsPrefix() {}
+
+func (p *TextParser) parsesText() {}
+
 func (p *TextParser) SupportedTypes() []string {
-	return []string{"text/plain", ".txt", ".log", ".md", ".csv", ".pdf"}
+	return []string{"text/plain", ".txt", ".log", ".pdf"}
 }
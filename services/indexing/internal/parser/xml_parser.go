@@ -0,0 +1,90 @@
+package parser
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// XMLParser extracts the character data of an XML document, CDATA sections
+// included. Tag names, attributes, comments and processing instructions are
+// markup rather than content and are left out.
+type XMLParser struct{}
+
+func NewXMLParser() *XMLParser {
+	return &XMLParser{}
+}
+
+func (p *XMLParser) Parse(ctx context.Context, reader io.Reader) (*ParsedDocument, error) {
+	dec := xml.NewDecoder(reader)
+	dec.CharsetReader = xmlCharsetReader
+
+	var textBuilder strings.Builder
+	var root xml.Name
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid XML: %w", err)
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if root.Local == "" {
+				root = t.Name
+			}
+			// Element boundaries separate words: <a>x</a><b>y</b> is "x y".
+			textBuilder.WriteString(" ")
+		case xml.EndElement:
+			textBuilder.WriteString(" ")
+		case xml.CharData:
+			textBuilder.Write(t)
+		}
+	}
+	if root.Local == "" {
+		return nil, fmt.Errorf("invalid XML: no root element")
+	}
+
+	content := strings.TrimSpace(textBuilder.String())
+	if content == "" {
+		return nil, fmt.Errorf("no text content found in XML")
+	}
+
+	metadata := map[string]string{
+		"fileType":    "application/xml",
+		"rootElement": root.Local,
+	}
+	if root.Space != "" {
+		metadata["namespace"] = root.Space
+	}
+
+	return &ParsedDocument{
+		Content:  content,
+		Metadata: metadata,
+	}, nil
+}
+
+func (p *XMLParser) parsesText() {}
+
+func (p *XMLParser) SupportedTypes() []string {
+	return []string{"application/xml", "text/xml", ".xml"}
+}
+
+// xmlCharsetReader transcodes documents declaring a Latin-1 family encoding,
+// which encoding/xml rejects on its own. Windows-1252 is a superset of
+// Latin-1 for text.
+func xmlCharsetReader(charset string, input io.Reader) (io.Reader, error) {
+	switch strings.ToLower(charset) {
+	case "iso-8859-1", "latin1", "latin-1", "windows-1252", "cp1252":
+		data, err := io.ReadAll(input)
+		if err != nil {
+			return nil, err
+		}
+		return strings.NewReader(decodeWindows1252(data)), nil
+	}
+	return nil, fmt.Errorf("unsupported encoding %q", charset)
+}
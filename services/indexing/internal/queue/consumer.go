@@ -2,50 +2,68 @@ package queue
 
 import (
 	"fmt"
+	"strconv"
+	"sync"
+	"time"
 
 	"github.com/amrrdev/trawl/services/shared/queue"
 	amqp "github.com/rabbitmq/amqp091-go"
 )
 
+// DefaultPrefetch is the prefetch count a new Consumer starts with.
+const DefaultPrefetch = 10
+
+// depthCacheTTL is how long Depth reuses a queue inspection.
+const depthCacheTTL = 5 * time.Second
+
+// HeaderLastError carries the error of a job's final attempt on messages in
+// the DLQ.
+const HeaderLastError = "x-last-error"
+
 type Consumer struct {
 	client    *queue.RabbitMQ
 	queueName string
 	dlqName   string
+
+	retryMu     sync.Mutex
+	retryQueues map[int]bool
+
+	depthMu sync.Mutex
+	depth   int
+	depthAt time.Time
 }
 
-func NewConsumer(client *queue.RabbitMQ, queueName, dqlName string) (*Consumer, error) {
+func NewConsumer(client *queue.RabbitMQ, queueName, dlqName string) (*Consumer, error) {
 	consumer := &Consumer{
-		client:    client,
-		queueName: queueName,
-		dlqName:   dqlName,
+		client:      client,
+		queueName:   queueName,
+		dlqName:     dlqName,
+		retryQueues: make(map[int]bool),
 	}
 
 	if err := consumer.declareQueue(); err != nil {
-		return nil, fmt.Errorf("failed to declare DLQ: %w", err)
+		return nil, fmt.Errorf("failed to declare queue: %w", err)
 	}
 
 	if err := consumer.declareDLQ(); err != nil {
-		return nil, fmt.Errorf("failed to declare queue: %w", err)
+		return nil, fmt.Errorf("failed to declare DLQ: %w", err)
 	}
 
-	if err := client.Channel.Qos(10, 0, false); err != nil {
-		return nil, fmt.Errorf("failed to set QoS: %w", err)
+	if err := consumer.SetPrefetch(DefaultPrefetch); err != nil {
+		return nil, err
 	}
 
 	return consumer, nil
 }
 
+// SetPrefetch sets how many unacknowledged messages the broker delivers to
+// this process at once. It can be changed while consuming.
+func (c *Consumer) SetPrefetch(n int) error {
+	return c.client.Qos(n)
+}
+
 func (c *Consumer) declareDLQ() error {
-	_, err := c.client.Channel.QueueDeclare(
-		c.dlqName,
-		true,
-		false,
-		false,
-		false,
-		nil,
-	)
-
-	return err
+	return c.client.DeclareQueue(c.dlqName, true, nil)
 }
 
 func (c *Consumer) declareQueue() error {
@@ -54,27 +72,33 @@ func (c *Consumer) declareQueue() error {
 		"x-dead-letter-routing-key": c.dlqName,
 	}
 
-	_, err := c.client.Channel.QueueDeclare(c.queueName,
-		true,
-		false,
-		false,
-		false,
-		args,
-	)
-	return err
+	return c.client.DeclareQueue(c.queueName, true, args)
 }
 
 func (c *Consumer) Consume() (<-chan amqp.Delivery, error) {
-	consumed, err := c.client.Channel.Consume(c.queueName, "indexing-worker", false, false, false, false, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to consume from %s queue: %s", c.queueName, err)
+	return c.client.Consume(c.queueName, "indexing-worker")
+}
+
+// Depth returns the number of messages ready in the indexing queue on the
+// broker. Inspections are reused for depthCacheTTL, so the autoscaler and
+// metrics scrapes don't each hit the broker.
+func (c *Consumer) Depth() (int, error) {
+	c.depthMu.Lock()
+	defer c.depthMu.Unlock()
+	if !c.depthAt.IsZero() && time.Since(c.depthAt) < depthCacheTTL {
+		return c.depth, nil
 	}
 
-	return consumed, nil
+	depth, err := c.client.QueueDepth(c.queueName)
+	if err != nil {
+		return 0, err
+	}
+	c.depth, c.depthAt = depth, time.Now()
+	return c.depth, nil
 }
 
 func (c *Consumer) Publish(data []byte, headers map[string]interface{}) error {
-	err := c.client.Channel.Publish("", c.queueName, false, false, amqp.Publishing{
+	err := c.client.PublishMessage(c.queueName, amqp.Publishing{
 		ContentType:  "application/json",
 		Body:         data,
 		Headers:      headers,
@@ -86,6 +110,64 @@ func (c *Consumer) Publish(data []byte, headers map[string]interface{}) error {
 	return nil
 }
 
+// PublishToDLQ publishes data straight to the dead-letter queue.
+func (c *Consumer) PublishToDLQ(data []byte, headers map[string]interface{}) error {
+	err := c.client.PublishMessage(c.dlqName, amqp.Publishing{
+		ContentType:  "application/json",
+		Body:         data,
+		Headers:      headers,
+		DeliveryMode: amqp.Persistent,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to publish message in DLQ: %s", err)
+	}
+	return nil
+}
+
+// PublishDelayed publishes data back to the main queue after delay. The
+// message waits in a per-attempt retry queue until its TTL expires and is
+// then dead-lettered to the main queue. Keeping one retry queue per attempt
+// keeps delays in a queue similar, since RabbitMQ only expires messages at
+// the head of a queue.
+func (c *Consumer) PublishDelayed(data []byte, headers map[string]interface{}, delay time.Duration, attempt int) error {
+	retryQueue, err := c.declareRetryQueue(attempt)
+	if err != nil {
+		return fmt.Errorf("failed to declare retry queue: %w", err)
+	}
+
+	err = c.client.PublishMessage(retryQueue, amqp.Publishing{
+		ContentType:  "application/json",
+		Body:         data,
+		Headers:      headers,
+		DeliveryMode: amqp.Persistent,
+		Expiration:   strconv.FormatInt(max(delay.Milliseconds(), 0), 10),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to publish message in retry queue: %s", err)
+	}
+	return nil
+}
+
+func (c *Consumer) declareRetryQueue(attempt int) (string, error) {
+	name := queue.RetryQueue(c.queueName, attempt)
+
+	c.retryMu.Lock()
+	defer c.retryMu.Unlock()
+	if c.retryQueues[attempt] {
+		return name, nil
+	}
+
+	args := amqp.Table{
+		"x-dead-letter-exchange":    "",
+		"x-dead-letter-routing-key": c.queueName,
+	}
+	if err := c.client.DeclareQueue(name, true, args); err != nil {
+		return "", err
+	}
+	c.retryQueues[attempt] = true
+	return name, nil
+}
+
 func (c *Consumer) Close() error {
 	return c.client.Close()
 }
@@ -4,50 +4,109 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
+	"time"
 
 	"github.com/amrrdev/trawl/services/indexing/internal/types"
+	"github.com/amrrdev/trawl/services/shared/metrics"
 	"github.com/amrrdev/trawl/services/shared/queue"
+	"github.com/amrrdev/trawl/services/shared/tracing"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// DefaultConfirmTimeout is how long a publish waits for the broker to
+// confirm a job by default.
+const DefaultConfirmTimeout = 5 * time.Second
+
 type Producer struct {
-	client    *queue.RabbitMQ
-	queueName string
+	client         publisher
+	queueName      string
+	confirmTimeout time.Duration
+}
+
+// publisher is the part of the RabbitMQ client a Producer publishes through.
+type publisher interface {
+	PublishConfirmed(ctx context.Context, queueName string, msg amqp.Publishing) error
 }
 
-func NewProducer(client *queue.RabbitMQ, queueName string) (*Producer, error) {
+// NewProducer declares the indexing queue, dead-lettering to dlqName, and
+// puts the client in publisher-confirm mode, so a job only counts as
+// published once the broker has acked it. confirmTimeout bounds the wait for
+// the ack; zero means DefaultConfirmTimeout.
+func NewProducer(client *queue.RabbitMQ, queueName, dlqName string, confirmTimeout time.Duration) (*Producer, error) {
 	dlqArgs := map[string]interface{}{
 		"x-dead-letter-exchange":    "",
-		"x-dead-letter-routing-key": queueName + "_dlq",
+		"x-dead-letter-routing-key": dlqName,
 	}
 
 	if err := client.DeclareQueue(queueName, true, dlqArgs); err != nil {
 		return nil, fmt.Errorf("failed to declare queue: %w", err)
 	}
 
-	if err := client.DeclareQueue(queueName+"_dlq", true, nil); err != nil {
+	if err := client.DeclareQueue(dlqName, true, nil); err != nil {
 		return nil, fmt.Errorf("failed to declare DLQ: %w", err)
 	}
 
-	log.Printf("✓ Queues declared: %s, %s_dlq", queueName, queueName)
+	slog.Info("Queues declared", "queue", queueName, "dlq", dlqName)
+
+	if err := client.EnableConfirms(); err != nil {
+		return nil, err
+	}
+	if confirmTimeout <= 0 {
+		confirmTimeout = DefaultConfirmTimeout
+	}
 
 	return &Producer{
-		client:    client,
-		queueName: queueName,
+		client:         client,
+		queueName:      queueName,
+		confirmTimeout: confirmTimeout,
 	}, nil
 }
 
-func (p *Producer) PublishIndexingJob(ctx context.Context, job *types.IndexingJob) error {
-	fmt.Println("inside pusshing")
+func (p *Producer) PublishIndexingJob(ctx context.Context, job *types.IndexingJob) (err error) {
+	ctx, span := tracing.Start(ctx, "publish indexing job", trace.WithSpanKind(trace.SpanKindProducer))
+	defer func() { tracing.End(span, err) }()
+
 	data, err := json.Marshal(job)
 	if err != nil {
 		return fmt.Errorf("failed to marshal job: %w", err)
 	}
 
-	if err := p.client.Publish(p.queueName, data); err != nil {
+	// The worker picks the trace up from the headers.
+	headers := amqp.Table{}
+	tracing.Inject(ctx, headers)
+
+	start := time.Now()
+	confirmCtx, cancel := context.WithTimeout(ctx, p.confirmTimeout)
+	err = p.client.PublishConfirmed(confirmCtx, p.queueName, amqp.Publishing{
+		ContentType:  "application/json",
+		Body:         data,
+		Headers:      headers,
+		DeliveryMode: amqp.Persistent,
+	})
+	cancel()
+	duration := time.Since(start)
+
+	if err != nil {
+		metrics.JobsPublished.WithLabelValues("failure").Inc()
+		slog.Error("job publish failed",
+			"job_id", job.JobID,
+			"doc_id", job.Payload.DocID,
+			"queue", p.queueName,
+			"error", err,
+		)
 		return fmt.Errorf("failed to publish job: %w", err)
 	}
 
-	log.Printf("✓ Job published: %s (DocID: %s)", job.JobID, job.Payload.DocID)
+	metrics.JobsPublished.WithLabelValues("success").Inc()
+	metrics.PublishDuration.Observe(duration.Seconds())
+	slog.Info("job published",
+		"job_id", job.JobID,
+		"doc_id", job.Payload.DocID,
+		"queue", p.queueName,
+		"bytes", len(data),
+		"duration", duration,
+	)
 	return nil
 }
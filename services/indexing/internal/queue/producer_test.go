@@ -0,0 +1,97 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/amrrdev/trawl/services/indexing/internal/types"
+	"github.com/amrrdev/trawl/services/shared/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// fakePublisher answers every publish with err, or, when hang is set, waits
+// for the confirm deadline like a broker that never confirms.
+type fakePublisher struct {
+	err       error
+	hang      bool
+	published []amqp.Publishing
+}
+
+func (f *fakePublisher) PublishConfirmed(ctx context.Context, queueName string, msg amqp.Publishing) error {
+	if f.hang {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	if f.err != nil {
+		return f.err
+	}
+	f.published = append(f.published, msg)
+	return nil
+}
+
+func counterValue(t *testing.T, c prometheus.Counter) float64 {
+	t.Helper()
+	var m dto.Metric
+	if err := c.Write(&m); err != nil {
+		t.Fatal(err)
+	}
+	return m.GetCounter().GetValue()
+}
+
+func TestPublishIndexingJob(t *testing.T) {
+	nacked := errors.New("broker nacked message for queue indexing_queue")
+
+	tests := []struct {
+		name      string
+		publisher *fakePublisher
+		wantErr   error
+		status    string
+	}{
+		{"confirmed", &fakePublisher{}, nil, "success"},
+		{"nacked", &fakePublisher{err: nacked}, nacked, "failure"},
+		{"confirm timeout", &fakePublisher{hang: true}, context.DeadlineExceeded, "failure"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Producer{client: tt.publisher, queueName: "indexing_queue", confirmTimeout: 10 * time.Millisecond}
+			counter := metrics.JobsPublished.WithLabelValues(tt.status)
+			before := counterValue(t, counter)
+
+			job := &types.IndexingJob{JobID: "job-1", Payload: types.IndexingPayload{DocID: "doc-1"}}
+			err := p.PublishIndexingJob(context.Background(), job)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := counterValue(t, counter) - before; got != 1 {
+				t.Errorf("%s publishes counted = %v, want 1", tt.status, got)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if len(tt.publisher.published) != 1 {
+				t.Fatalf("published %d messages, want 1", len(tt.publisher.published))
+			}
+			msg := tt.publisher.published[0]
+			if msg.DeliveryMode != amqp.Persistent {
+				t.Errorf("DeliveryMode = %d, want persistent", msg.DeliveryMode)
+			}
+			var got types.IndexingJob
+			if err := json.Unmarshal(msg.Body, &got); err != nil {
+				t.Fatal(err)
+			}
+			if got.JobID != job.JobID || got.Payload.DocID != job.Payload.DocID {
+				t.Errorf("published job %+v, want %+v", got, job)
+			}
+		})
+	}
+}
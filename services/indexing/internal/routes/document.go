@@ -6,16 +6,19 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-func RegisterRoutes(router *gin.RouterGroup, documentHandler *handler.DocumentHandler, authMiddleware *middleware.AuthMiddleware) {
+func RegisterRoutes(router *gin.RouterGroup, documentHandler *handler.DocumentHandler, authMiddleware *middleware.AuthMiddleware, webhookAuth gin.HandlerFunc) {
 	document := router.Group("/documents")
 	document.Use(authMiddleware.RequireAuth())
 	{
 		document.POST("/upload-url/:filename", documentHandler.GetUploadUrl)
 		document.POST("/download-url/:filename", documentHandler.GetDownloadUrl)
 		document.GET("", documentHandler.ListFiles)
+		document.DELETE("/:docID", documentHandler.DeleteDocument)
+		document.GET("/:docID/status", documentHandler.GetDocumentStatus)
 	}
 
 	webhooks := router.Group("/webhooks")
+	webhooks.Use(webhookAuth)
 	{
 		webhooks.POST("/document-uploaded", documentHandler.HandleWebhook)
 	}
@@ -3,13 +3,19 @@ package server
 import (
 	"github.com/amrrdev/trawl/services/indexing/internal/handler"
 	"github.com/amrrdev/trawl/services/indexing/internal/routes"
+	"github.com/amrrdev/trawl/services/shared/metrics"
 	"github.com/amrrdev/trawl/services/shared/middleware"
+	"github.com/amrrdev/trawl/services/shared/tracing"
 	"github.com/gin-gonic/gin"
 )
 
-func NewServer(documentHandler *handler.DocumentHandler, authMiddleware *middleware.AuthMiddleware) *gin.Engine {
-	g := gin.Default()
+func NewServer(documentHandler *handler.DocumentHandler, authMiddleware *middleware.AuthMiddleware, webhookAuth gin.HandlerFunc, corsConfig middleware.CORSConfig) *gin.Engine {
+	g := gin.New()
+	g.Use(tracing.Middleware("trawl-indexing"))
+	g.Use(middleware.RequestID(), middleware.Logger(), gin.Recovery())
+	g.Use(middleware.CORS(corsConfig))
+	g.GET("/metrics", gin.WrapH(metrics.Handler()))
 	api := g.Group("/api/v1")
-	routes.RegisterRoutes(api, documentHandler, authMiddleware)
+	routes.RegisterRoutes(api, documentHandler, authMiddleware, webhookAuth)
 	return g
 }
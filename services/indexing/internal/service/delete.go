@@ -0,0 +1,72 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/amrrdev/trawl/services/indexing/internal/docindex"
+	"github.com/amrrdev/trawl/services/shared/apierror"
+	"github.com/gocql/gocql"
+)
+
+type DeleteDocumentResponse struct {
+	DocID        string `json:"doc_id"`
+	WordsRemoved int    `json:"words_removed"`
+}
+
+// DeleteDocument removes an indexed document owned by userID: its documents
+// row, its stored text and job status, its inverted_index and title_index
+// entries and its contribution to word_stats and collection_stats. The
+// documents row goes first so searches stop returning the document right
+// away; searches already holding its doc_id skip it once the lookup fails.
+// Postings shared with documents of identical content stay in the index.
+func (d *Document) DeleteDocument(ctx context.Context, userID, docID string) (*DeleteDocumentResponse, error) {
+	if strings.TrimSpace(userID) == "" {
+		return nil, apierror.InvalidInput("userID is required")
+	}
+	docUUID, err := gocql.ParseUUID(docID)
+	if err != nil {
+		return nil, apierror.InvalidInput("invalid doc_id %q", docID)
+	}
+
+	var filePath string
+	var title docindex.Title
+	err = d.scylladb.Session.Query(`SELECT file_path, title_length, title_terms FROM documents WHERE doc_id = ?`, docUUID).
+		WithContext(ctx).Scan(&filePath, &title.Length, &title.Terms)
+	if err == gocql.ErrNotFound {
+		return nil, ErrDocumentNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load document: %w", err)
+	}
+	if !strings.HasPrefix(filePath, userID+"/") {
+		return nil, ErrDocumentNotFound
+	}
+
+	if err := d.scylladb.Session.Query(`DELETE FROM documents WHERE doc_id = ?`, docUUID).
+		WithContext(ctx).Exec(); err != nil {
+		return nil, fmt.Errorf("failed to delete document: %w", err)
+	}
+
+	removed, err := docindex.Remove(ctx, d.scylladb.Session, docUUID, title)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := d.scylladb.Session.Query(`DELETE FROM document_text WHERE doc_id = ?`, docUUID).
+		WithContext(ctx).Exec(); err != nil {
+		return nil, fmt.Errorf("failed to delete document text: %w", err)
+	}
+	if err := d.scylladb.Session.Query(`DELETE FROM job_status WHERE doc_id = ?`, docUUID).
+		WithContext(ctx).Exec(); err != nil {
+		slog.WarnContext(ctx, "Failed to delete job status", "doc_id", docID, "error", err)
+	}
+
+	slog.InfoContext(ctx, "Deleted document", "doc_id", docID, "words", removed)
+	return &DeleteDocumentResponse{
+		DocID:        docID,
+		WordsRemoved: removed,
+	}, nil
+}
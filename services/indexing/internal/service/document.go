@@ -2,15 +2,24 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/url"
+	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
+	"github.com/amrrdev/trawl/services/indexing/internal/jobstatus"
+	"github.com/amrrdev/trawl/services/indexing/internal/parser"
 	"github.com/amrrdev/trawl/services/indexing/internal/queue"
 	"github.com/amrrdev/trawl/services/indexing/internal/types"
+	"github.com/amrrdev/trawl/services/shared/apierror"
+	"github.com/amrrdev/trawl/services/shared/logging"
+	"github.com/amrrdev/trawl/services/shared/scylladb"
 	"github.com/amrrdev/trawl/services/shared/storage"
+	"github.com/gocql/gocql"
 	"github.com/google/uuid"
 )
 
@@ -18,9 +27,42 @@ const (
 	urlExpiryDuration = 15 * time.Minute
 )
 
+// ErrDocumentNotFound is returned for documents that don't exist or belong
+// to another user.
+var ErrDocumentNotFound = apierror.NotFound("document not found")
+
+// docIDNamespace seeds the name-based doc_ids derived from object keys.
+var docIDNamespace = uuid.MustParse("6f1c2a4e-8d3b-4f5a-9e7c-1b2d3e4f5a6b")
+
 type Document struct {
-	storage  *storage.Storage
-	producer *queue.Producer
+	storage   *storage.Storage
+	producer  *queue.Producer
+	scylladb  *scylladb.ScyllaDB
+	jobStatus *jobstatus.Store
+	// allowedExtensions are the file extensions upload URLs are issued for,
+	// resolved by NewDocument from uploadExtensions and ocr.
+	allowedExtensions map[string]bool
+	uploadExtensions  []string
+	ocr               bool
+}
+
+// DocumentOption tunes a Document.
+type DocumentOption func(*Document)
+
+// WithAllowedExtensions restricts uploads to the given file extensions
+// (".pdf" or "pdf"). Extensions no parser handles are dropped; an empty list
+// keeps the default of every extension the parsers support.
+func WithAllowedExtensions(exts []string) DocumentOption {
+	return func(d *Document) {
+		d.uploadExtensions = exts
+	}
+}
+
+// WithOCR adds image uploads, which only workers with OCR enabled can index.
+func WithOCR(enabled bool) DocumentOption {
+	return func(d *Document) {
+		d.ocr = enabled
+	}
 }
 
 type GetUrlResponse struct {
@@ -29,40 +71,103 @@ type GetUrlResponse struct {
 }
 
 type GetListFileResponse struct {
-	Files []map[string]any `json:"files"`
+	Files      []map[string]any `json:"files"`
+	NextCursor string           `json:"next_cursor,omitempty"`
 }
 
-func NewDocument(storage *storage.Storage, producer *queue.Producer) *Document {
-	return &Document{
-		storage:  storage,
-		producer: producer,
+func NewDocument(storage *storage.Storage, producer *queue.Producer, scylla *scylladb.ScyllaDB, opts ...DocumentOption) *Document {
+	d := &Document{
+		storage:   storage,
+		producer:  producer,
+		scylladb:  scylla,
+		jobStatus: jobstatus.NewStore(scylla.Session),
 	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	d.allowedExtensions = d.resolveExtensions()
+	return d
 }
 
-func (d *Document) ListFiles(ctx context.Context, userID string) (*GetListFileResponse, error) {
+// resolveExtensions returns the configured upload extensions, or every
+// supported one if none are configured.
+func (d *Document) resolveExtensions() map[string]bool {
+	supported := supportedExtensions(d.ocr)
+	allowed := make(map[string]bool)
+	for _, ext := range d.uploadExtensions {
+		ext = strings.ToLower(strings.TrimSpace(ext))
+		if ext == "" {
+			continue
+		}
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		if !supported[ext] {
+			slog.Warn("Ignoring upload extension no parser supports", "extension", ext)
+			continue
+		}
+		allowed[ext] = true
+	}
+	if len(allowed) == 0 {
+		return supported
+	}
+	return allowed
+}
+
+// supportedExtensions returns the file extensions the parser registry
+// handles, and with ocr the image extensions.
+func supportedExtensions(ocr bool) map[string]bool {
+	registry := parser.NewRegistry()
+	if ocr {
+		registry.Register(parser.NewImageParser(nil))
+	}
+	exts := make(map[string]bool)
+	for _, t := range registry.SupportedTypes() {
+		if strings.HasPrefix(t, ".") {
+			exts[t] = true
+		}
+	}
+	return exts
+}
+
+// AllowedExtensions lists the file extensions accepted for upload, sorted.
+func (d *Document) AllowedExtensions() []string {
+	exts := make([]string, 0, len(d.allowedExtensions))
+	for ext := range d.allowedExtensions {
+		exts = append(exts, ext)
+	}
+	sort.Strings(exts)
+	return exts
+}
+
+func (d *Document) ListFiles(ctx context.Context, userID string, opts storage.ListOptions) (*GetListFileResponse, error) {
 	if strings.TrimSpace(userID) == "" {
-		return nil, fmt.Errorf("userID is required")
+		return nil, apierror.InvalidInput("userID is required")
 	}
 
-	files, err := d.storage.ListFiles(ctx, userID)
+	list, err := d.storage.ListFiles(ctx, userID, opts)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list files: %w", err)
 	}
 
 	return &GetListFileResponse{
-		Files: files,
+		Files:      list.Files,
+		NextCursor: list.NextCursor,
 	}, nil
 }
 
 func (d *Document) GetDownloadUrl(ctx context.Context, userID, filename string) (*GetUrlResponse, error) {
 	if strings.TrimSpace(userID) == "" {
-		return nil, fmt.Errorf("userID is required")
+		return nil, apierror.InvalidInput("userID is required")
 	}
 	if strings.TrimSpace(filename) == "" {
-		return nil, fmt.Errorf("filename is required")
+		return nil, apierror.InvalidInput("filename is required")
 	}
 
 	presignedUrl, err := d.storage.GetDownloadUrl(ctx, userID, filename, urlExpiryDuration)
+	if errors.Is(err, storage.ErrObjectNotFound) {
+		return nil, apierror.NotFound("file %q not found", filename)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate download URL: %w", err)
 	}
@@ -75,10 +180,17 @@ func (d *Document) GetDownloadUrl(ctx context.Context, userID, filename string)
 
 func (d *Document) GetUploadUrl(ctx context.Context, userID, filename string) (*GetUrlResponse, error) {
 	if strings.TrimSpace(userID) == "" {
-		return nil, fmt.Errorf("userID is required")
+		return nil, apierror.InvalidInput("userID is required")
 	}
 	if strings.TrimSpace(filename) == "" {
-		return nil, fmt.Errorf("filename is required")
+		return nil, apierror.InvalidInput("filename is required")
+	}
+	// Files the worker can't parse would only fail indexing later. Their
+	// size is capped by the worker, as a presigned PUT can't limit it.
+	ext := strings.ToLower(filepath.Ext(filename))
+	if !d.allowedExtensions[ext] {
+		return nil, apierror.InvalidInput("unsupported file type %q; supported types: %s",
+			ext, strings.Join(d.AllowedExtensions(), ", "))
 	}
 
 	presignedUrl, err := d.storage.GetUploadUrl(ctx, userID, filename, urlExpiryDuration)
@@ -92,58 +204,216 @@ func (d *Document) GetUploadUrl(ctx context.Context, userID, filename string) (*
 	}, nil
 }
 
+// HandlerWebhook acts on each record of a MinIO bucket notification: new
+// objects are queued for indexing and removed ones deleted from the index.
+// Malformed records are skipped and a failed record doesn't stop the rest;
+// the failures are returned together afterwards.
 func (d *Document) HandlerWebhook(ctx context.Context, event *types.MinIOEvent) error {
 	if event == nil {
 		return fmt.Errorf("event is nil")
 	}
 
-	for _, record := range event.Records {
-		if record.EventName == "s3:ObjectCreated:Put" {
-			log.Printf("File uploaded: %s (size: %d bytes)",
-				record.S3.Object.Key,
-				record.S3.Object.Size)
-
-			// Decode URL-encoded object key
-			decodedKey, err := url.QueryUnescape(record.S3.Object.Key)
-			if err != nil {
-				log.Printf("Failed to decode object key: %s", record.S3.Object.Key)
-				continue
+	var queued, deleted, skipped int
+	var errs []error
+	for i := range event.Records {
+		record := &event.Records[i]
+		var acted bool
+		var err error
+		switch {
+		case objectCreatedEvents[record.EventName]:
+			acted, err = d.indexUploadedObject(ctx, record)
+			if acted {
+				queued++
 			}
-
-			// Extract userID from object key (format: "userID/filename")
-			parts := strings.SplitN(decodedKey, "/", 2)
-			if len(parts) != 2 {
-				log.Printf("Invalid object key format: %s", decodedKey)
-				continue
+		case strings.HasPrefix(record.EventName, "s3:ObjectRemoved:"):
+			acted, err = d.deleteRemovedObject(ctx, record)
+			if acted {
+				deleted++
 			}
+		default:
+			slog.DebugContext(ctx, "Ignoring webhook event", "event", record.EventName, "key", record.S3.Object.Key)
+		}
+		if err != nil {
+			errs = append(errs, err)
+		} else if !acted {
+			skipped++
+		}
+	}
 
-			userID := parts[0]
-			fileName := parts[1]
-
-			// Create indexing job
-			job := &types.IndexingJob{
-				JobID:     uuid.New().String(),
-				Type:      "document_indexing",
-				CreatedAt: time.Now(),
-				Payload: types.IndexingPayload{
-					DocID:    uuid.New().String(),
-					UserID:   userID,
-					FilePath: decodedKey, // Use decoded key
-					FileName: fileName,
-					FileSize: record.S3.Object.Size,
-					Metadata: map[string]string{
-						"bucket": record.S3.Bucket.Name,
-					},
-				},
-				RetryCount: 0,
-			}
+	slog.InfoContext(ctx, "Webhook processed",
+		"records", len(event.Records),
+		"queued", queued,
+		"deleted", deleted,
+		"skipped", skipped,
+		"failed", len(errs),
+	)
+	return errors.Join(errs...)
+}
 
-			if err := d.producer.PublishIndexingJob(ctx, job); err != nil {
-				log.Printf("Failed to publish job: %v", err)
-				return fmt.Errorf("failed to publish indexing job: %w", err)
-			}
+// objectCreatedEvents are the creation events that put new content under a
+// key. Large files arrive through multipart uploads.
+var objectCreatedEvents = map[string]bool{
+	"s3:ObjectCreated:Put":                     true,
+	"s3:ObjectCreated:Post":                    true,
+	"s3:ObjectCreated:Copy":                    true,
+	"s3:ObjectCreated:CompleteMultipartUpload": true,
+}
+
+// parseObjectKey decodes the URL-encoded object key of record and splits it
+// into the owner's user ID and the file name ("userID/filename").
+func parseObjectKey(ctx context.Context, record *types.MinIORecord) (key, userID, fileName string, ok bool) {
+	key, err := url.QueryUnescape(record.S3.Object.Key)
+	if err != nil {
+		slog.WarnContext(ctx, "Failed to decode object key", "key", record.S3.Object.Key)
+		return "", "", "", false
+	}
+
+	parts := strings.SplitN(key, "/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		slog.WarnContext(ctx, "Invalid object key format", "key", key)
+		return "", "", "", false
+	}
+	return key, parts[0], parts[1], true
+}
+
+// indexUploadedObject queues an indexing job for the object of a creation
+// record. It reports false for records it skips.
+func (d *Document) indexUploadedObject(ctx context.Context, record *types.MinIORecord) (bool, error) {
+	slog.InfoContext(ctx, "File uploaded", "key", record.S3.Object.Key, "size", record.S3.Object.Size, "event", record.EventName)
+
+	decodedKey, userID, fileName, ok := parseObjectKey(ctx, record)
+	if !ok {
+		return false, nil
+	}
+	etag := strings.Trim(record.S3.Object.ETag, "\"")
+
+	metadata := map[string]string{
+		"bucket": record.S3.Bucket.Name,
+		"etag":   etag,
+	}
+	for key, value := range userMetadataOverrides(record.S3.Object.UserMetadata) {
+		metadata[key] = value
+	}
+
+	// The doc_id is derived from the object key, so uploading a new
+	// version of a file re-indexes the same document instead of adding
+	// a second one; the worker drops the old postings first.
+	docID := documentID(decodedKey)
+	if d.documentExists(ctx, docID) {
+		slog.InfoContext(ctx, "Re-upload, re-indexing document", "key", decodedKey, "doc_id", docID)
+	}
+
+	// Create indexing job
+	job := &types.IndexingJob{
+		JobID:     uuid.New().String(),
+		Type:      "document_indexing",
+		CreatedAt: time.Now(),
+		Payload: types.IndexingPayload{
+			DocID:    docID,
+			UserID:   userID,
+			FilePath: decodedKey, // Use decoded key
+			FileName: fileName,
+			FileSize: record.S3.Object.Size,
+			Metadata: metadata,
+		},
+		RetryCount:     0,
+		IdempotencyKey: types.IdempotencyKey(userID, decodedKey, etag),
+		RequestID:      logging.RequestIDFromContext(ctx),
+	}
+
+	// Recorded before publishing so it can't overwrite a state the
+	// worker has already set.
+	d.jobStatus.Set(ctx, job.Payload.DocID, job.JobID, userID, jobstatus.StateQueued, "")
+
+	if err := d.producer.PublishIndexingJob(ctx, job); err != nil {
+		slog.ErrorContext(ctx, "Failed to publish job", "job_id", job.JobID, "doc_id", docID, "error", err)
+		d.jobStatus.Set(ctx, job.Payload.DocID, job.JobID, userID, jobstatus.StateFailed, "failed to queue indexing job")
+		return false, fmt.Errorf("failed to publish indexing job for %s: %w", decodedKey, err)
+	}
+	return true, nil
+}
+
+// deleteRemovedObject deletes the document indexed from the object of a
+// removal record. It reports false if there was nothing to delete.
+func (d *Document) deleteRemovedObject(ctx context.Context, record *types.MinIORecord) (bool, error) {
+	decodedKey, userID, _, ok := parseObjectKey(ctx, record)
+	if !ok {
+		return false, nil
+	}
+
+	docID := documentID(decodedKey)
+	if _, err := d.DeleteDocument(ctx, userID, docID); err != nil {
+		// Not indexed (yet), or indexed under a random doc_id from before
+		// they were derived from keys.
+		if errors.Is(err, ErrDocumentNotFound) {
+			slog.InfoContext(ctx, "Removed object has no indexed document", "key", decodedKey, "doc_id", docID)
+			return false, nil
 		}
+		return false, fmt.Errorf("failed to delete document for %s: %w", decodedKey, err)
+	}
+	slog.InfoContext(ctx, "File removed, document deleted", "key", decodedKey, "doc_id", docID, "event", record.EventName)
+	return true, nil
+}
+
+// documentID returns the doc_id of the object stored under key. Documents
+// indexed before doc_ids were derived keep their random ids.
+func documentID(key string) string {
+	return uuid.NewSHA1(docIDNamespace, []byte(key)).String()
+}
+
+// documentExists reports whether docID is already indexed. It is only used
+// for logging, so lookup errors count as "no".
+func (d *Document) documentExists(ctx context.Context, docID string) bool {
+	docUUID, err := gocql.ParseUUID(docID)
+	if err != nil {
+		return false
+	}
+	var existing gocql.UUID
+	err = d.scylladb.Session.Query(`SELECT doc_id FROM documents WHERE doc_id = ?`, docUUID).
+		WithContext(ctx).Scan(&existing)
+	return err == nil
+}
+
+// DocumentStatus returns the indexing status of docID if userID owns it.
+func (d *Document) DocumentStatus(ctx context.Context, userID, docID string) (*jobstatus.Status, error) {
+	if strings.TrimSpace(userID) == "" {
+		return nil, apierror.InvalidInput("userID is required")
+	}
+	docUUID, err := gocql.ParseUUID(docID)
+	if err != nil {
+		return nil, apierror.InvalidInput("invalid doc_id %q", docID)
 	}
 
-	return nil
+	status, err := d.jobStatus.Get(ctx, docUUID)
+	if err == gocql.ErrNotFound {
+		return nil, ErrDocumentNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load job status: %w", err)
+	}
+	if status.UserID != userID {
+		return nil, ErrDocumentNotFound
+	}
+	return status, nil
+}
+
+// userMetadataOverrides picks the user-supplied title/author/description/language out of
+// the object's user metadata. MinIO reports the keys as X-Amz-Meta-<Name> with
+// inconsistent casing, so matching is case-insensitive.
+func userMetadataOverrides(userMetadata map[string]string) map[string]string {
+	overrides := make(map[string]string)
+	for rawKey, value := range userMetadata {
+		key := strings.ToLower(rawKey)
+		key = strings.TrimPrefix(key, "x-amz-meta-")
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		for _, allowed := range types.UserMetadataKeys {
+			if key == allowed {
+				overrides[key] = value
+			}
+		}
+	}
+	return overrides
 }
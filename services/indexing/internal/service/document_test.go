@@ -0,0 +1,125 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/amrrdev/trawl/services/shared/apierror"
+	"github.com/amrrdev/trawl/services/shared/storage"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+func TestUserMetadataOverrides(t *testing.T) {
+	tests := []struct {
+		name     string
+		metadata map[string]string
+		want     map[string]string
+	}{
+		{"none", nil, map[string]string{}},
+		{"any key casing", map[string]string{
+			"X-Amz-Meta-Title":  "Quarterly report",
+			"x-amz-meta-AUTHOR": "Alice",
+			"Description":       "Numbers",
+		}, map[string]string{"title": "Quarterly report", "author": "Alice", "description": "Numbers"}},
+		{"values trimmed, blanks dropped", map[string]string{
+			"X-Amz-Meta-Title":  "  Report  ",
+			"X-Amz-Meta-Author": "   ",
+		}, map[string]string{"title": "Report"}},
+		{"unknown keys ignored", map[string]string{
+			"X-Amz-Meta-Owner":    "mallory",
+			"Content-Type":        "application/pdf",
+			"X-Amz-Meta-Language": "de",
+		}, map[string]string{"language": "de"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := userMetadataOverrides(tt.metadata); !maps.Equal(got, tt.want) {
+				t.Errorf("overrides = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// newTestStorage returns storage backed by a stand-in for MinIO that holds
+// the given object names and denies access to any starting with "private/".
+func newTestStorage(t *testing.T, objects ...string) *storage.Storage {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(r.URL.Path, "/docs/")
+		switch {
+		case strings.HasPrefix(name, "private/"):
+			w.WriteHeader(http.StatusForbidden)
+		case slices.Contains(objects, name):
+			w.Header().Set("Content-Length", "4")
+			w.Header().Set("Last-Modified", "Wed, 01 Jan 2025 00:00:00 GMT")
+			w.Header().Set("ETag", `"etag"`)
+			w.WriteHeader(http.StatusOK)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	endpoint, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := minio.New(endpoint.Host, &minio.Options{
+		Creds:  credentials.NewStaticV4("access", "secret", ""),
+		Region: "us-east-1",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &storage.Storage{Client: client, Bucket: "docs"}
+}
+
+func TestGetDownloadUrl(t *testing.T) {
+	d := &Document{storage: newTestStorage(t, "user-1/report.pdf")}
+
+	tests := []struct {
+		name     string
+		userID   string
+		filename string
+		wantErr  error
+	}{
+		{"existing file", "user-1", "report.pdf", nil},
+		{"missing file", "user-1", "missing.pdf", apierror.ErrNotFound},
+		{"other user's file", "user-2", "report.pdf", apierror.ErrNotFound},
+		{"missing filename", "user-1", " ", apierror.ErrInvalidInput},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := d.GetDownloadUrl(context.Background(), tt.userID, tt.filename)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(resp.PresignedUrl, "/docs/user-1/report.pdf?") {
+				t.Errorf("URL %q does not presign the object", resp.PresignedUrl)
+			}
+		})
+	}
+}
+
+func TestGetDownloadUrlStorageError(t *testing.T) {
+	d := &Document{storage: newTestStorage(t)}
+
+	_, err := d.GetDownloadUrl(context.Background(), "private", "report.pdf")
+	if err == nil || errors.Is(err, apierror.ErrNotFound) {
+		t.Errorf("err = %v, want a storage failure other than not found", err)
+	}
+}
@@ -1,18 +1,22 @@
 package types
 
 type MinIOEvent struct {
-	EventName string `json:"EventName"`
-	Key       string `json:"Key"`
-	Records   []struct {
-		EventName string `json:"eventName"`
-		S3        struct {
-			Bucket struct {
-				Name string `json:"name"`
-			} `json:"bucket"`
-			Object struct {
-				Key  string `json:"key"`
-				Size int64  `json:"size"`
-			} `json:"object"`
-		} `json:"s3"`
-	} `json:"Records"`
+	EventName string        `json:"EventName"`
+	Key       string        `json:"Key"`
+	Records   []MinIORecord `json:"Records"`
+}
+
+type MinIORecord struct {
+	EventName string `json:"eventName"`
+	S3        struct {
+		Bucket struct {
+			Name string `json:"name"`
+		} `json:"bucket"`
+		Object struct {
+			Key          string            `json:"key"`
+			Size         int64             `json:"size"`
+			ETag         string            `json:"eTag"`
+			UserMetadata map[string]string `json:"userMetadata"`
+		} `json:"object"`
+	} `json:"s3"`
 }
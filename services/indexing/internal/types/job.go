@@ -1,13 +1,21 @@
 package types
 
-import "time"
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
 
 type IndexingJob struct {
-	JobID      string          `json:"job_id"`
-	Type       string          `json:"type"`
-	CreatedAt  time.Time       `json:"created_at"`
-	Payload    IndexingPayload `json:"payload"`
-	RetryCount int             `json:"retry_count"`
+	JobID          string          `json:"job_id"`
+	Type           string          `json:"type"`
+	CreatedAt      time.Time       `json:"created_at"`
+	Payload        IndexingPayload `json:"payload"`
+	RetryCount     int             `json:"retry_count"`
+	IdempotencyKey string          `json:"idempotency_key"`
+	// RequestID is the X-Request-ID of the API request that queued the job,
+	// so the worker's log lines can be matched with the API's.
+	RequestID string `json:"request_id,omitempty"`
 }
 
 type IndexingPayload struct {
@@ -18,3 +26,25 @@ type IndexingPayload struct {
 	FileSize int64             `json:"size"`
 	Metadata map[string]string `json:"metadata"`
 }
+
+// Metadata keys a user may set on upload (as X-Amz-Meta-* headers) to override
+// what the parser extracts.
+const (
+	MetadataTitle       = "title"
+	MetadataAuthor      = "author"
+	MetadataDescription = "description"
+	MetadataLanguage    = "language"
+	// MetadataPassword unlocks an encrypted PDF. It overrides nothing and is
+	// only passed to the parser.
+	MetadataPassword = "pdf-password"
+)
+
+// UserMetadataKeys lists the metadata keys taken from the upload.
+var UserMetadataKeys = []string{MetadataTitle, MetadataAuthor, MetadataDescription, MetadataLanguage, MetadataPassword}
+
+// IdempotencyKey derives a deterministic key for a logical upload so that the
+// same object delivered twice (e.g. a double-fired webhook) maps to one job.
+func IdempotencyKey(userID, filePath, etag string) string {
+	sum := sha256.Sum256([]byte(userID + "\x00" + filePath + "\x00" + etag))
+	return hex.EncodeToString(sum[:])
+}
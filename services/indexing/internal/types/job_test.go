@@ -0,0 +1,30 @@
+package types
+
+import "testing"
+
+func TestIdempotencyKey(t *testing.T) {
+	base := IdempotencyKey("user-1", "user-1/report.pdf", "etag-1")
+
+	tests := []struct {
+		name                   string
+		userID, filePath, etag string
+		wantSame               bool
+	}{
+		{"same upload delivered twice", "user-1", "user-1/report.pdf", "etag-1", true},
+		{"other user", "user-2", "user-1/report.pdf", "etag-1", false},
+		{"other object", "user-1", "user-1/notes.pdf", "etag-1", false},
+		{"object overwritten", "user-1", "user-1/report.pdf", "etag-2", false},
+		{"fields shifted across the separator", "user-1user-1/", "report.pdf", "etag-1", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key := IdempotencyKey(tt.userID, tt.filePath, tt.etag)
+			if len(key) != 64 {
+				t.Errorf("key %q is not a hex SHA-256", key)
+			}
+			if (key == base) != tt.wantSame {
+				t.Errorf("key equal to the original upload's = %v, want %v", key == base, tt.wantSame)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package worker
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+)
+
+// wordWith returns a word occurring positions times.
+func wordWith(name string, positions int) *WordData {
+	word := &WordData{Word: name, Frequency: positions}
+	for i := range positions {
+		word.Positions = append(word.Positions, i)
+	}
+	return word
+}
+
+func TestEstimatePostingSize(t *testing.T) {
+	tests := []struct {
+		name string
+		word *WordData
+		want int
+	}{
+		{"no positions", wordWith("ab", 0), 2 * (2 + 52)},
+		{"positions", wordWith("abc", 3), 2*(3+52) + 3*4},
+		{"longer word", wordWith("abcdef", 1), 2*(6+52) + 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := estimatePostingSize(tt.word); got != tt.want {
+				t.Errorf("estimatePostingSize = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitWordBatches(t *testing.T) {
+	small := func(name string) *WordData { return wordWith(name, 1) }
+	// Each small word is estimated at 2*(1+52)+4 = 110 bytes.
+	tests := []struct {
+		name     string
+		words    []*WordData
+		maxWords int
+		maxBytes int
+		want     [][]string
+	}{
+		{"no words", nil, 10, 1000, nil},
+		{"within limits", []*WordData{small("a"), small("b")}, 10, 1000, [][]string{{"a", "b"}}},
+		{"word limit", []*WordData{small("a"), small("b"), small("c"), small("d"), small("e")}, 2, 1000,
+			[][]string{{"a", "b"}, {"c", "d"}, {"e"}}},
+		{"byte limit", []*WordData{small("a"), small("b"), small("c")}, 10, 250,
+			[][]string{{"a", "b"}, {"c"}}},
+		{"exact byte limit", []*WordData{small("a"), small("b")}, 10, 220, [][]string{{"a", "b"}}},
+		{"oversized word alone", []*WordData{small("a"), wordWith("z", 1000), small("b")}, 10, 500,
+			[][]string{{"a"}, {"z"}, {"b"}}},
+		{"oversized first word", []*WordData{wordWith("z", 1000), small("a")}, 10, 500,
+			[][]string{{"z"}, {"a"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got [][]string
+			for _, batch := range splitWordBatches(tt.words, tt.maxWords, tt.maxBytes) {
+				var names []string
+				for _, word := range batch {
+					names = append(names, word.Word)
+				}
+				got = append(got, names)
+			}
+			if !slices.EqualFunc(got, tt.want, slices.Equal) {
+				t.Errorf("batches = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func BenchmarkSplitWordBatches(b *testing.B) {
+	// A long document: many rare words and a few very frequent ones.
+	words := make([]*WordData, 20000)
+	for i := range words {
+		words[i] = wordWith(fmt.Sprintf("word%d", i), 1+1000/(i+1))
+	}
+	for b.Loop() {
+		splitWordBatches(words, defaultBatchSize, maxBatchBytes)
+	}
+}
@@ -2,131 +2,420 @@ package worker
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net/url"
+	"path"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/amrrdev/trawl/services/indexing/internal/docindex"
+	"github.com/amrrdev/trawl/services/indexing/internal/jobstatus"
 	"github.com/amrrdev/trawl/services/indexing/internal/parser"
 	"github.com/amrrdev/trawl/services/indexing/internal/queue"
-	"github.com/amrrdev/trawl/services/indexing/internal/scylladb"
-	"github.com/amrrdev/trawl/services/indexing/internal/tokenizer"
 	"github.com/amrrdev/trawl/services/indexing/internal/types"
+	"github.com/amrrdev/trawl/services/shared/metrics"
+	"github.com/amrrdev/trawl/services/shared/scylladb"
 	"github.com/amrrdev/trawl/services/shared/storage"
+	"github.com/amrrdev/trawl/services/shared/tokenizer"
+	"github.com/amrrdev/trawl/services/shared/tracing"
 	"github.com/gocql/gocql"
 	"github.com/minio/minio-go/v7"
 	amqp "github.com/rabbitmq/amqp091-go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// jobQueue is the queue jobs are consumed from and requeued to; a
+// *queue.Consumer in production.
+type jobQueue interface {
+	Consume() (<-chan amqp.Delivery, error)
+	SetPrefetch(n int) error
+	Depth() (int, error)
+	PublishDelayed(data []byte, headers map[string]interface{}, delay time.Duration, attempt int) error
+	PublishToDLQ(data []byte, headers map[string]interface{}) error
+}
+
 type IndexingWorker struct {
-	consumer       *queue.Consumer
+	consumer       jobQueue
 	minioStorage   *storage.Storage
 	tokenizer      *tokenizer.Tokenizer
 	scylladb       *scylladb.ScyllaDB
 	parserRegistry *parser.Registry
 	concurrency    int
+	minWorkers     int
+	maxWorkers     int
+	prefetchFactor int
+	jobTimeout     time.Duration
 	batchSize      int
+	batchWorkers   int
 	maxRetries     int
+	dedupTTL       time.Duration
+	inflight       *inflightLimiter
+	parses         *parseLimiter
+	jobStatus      *jobstatus.Store
+	stats          statsStore
+	bigrams        bool
+	// process runs one job; it is processJob outside of tests.
+	process func(ctx context.Context, workerID int, job *types.IndexingJob) error
+}
+
+type Config struct {
+	// MaxInFlight caps jobs being processed. When reached, the worker stops
+	// pulling messages until work drains. Zero disables it.
+	MaxInFlight int
+	// StopWords are dropped during tokenization. They must match the search
+	// service's list. Nil disables stop-word filtering.
+	StopWords map[string]bool
+	// MaxFileSize caps how many bytes of a file are read for parsing; see
+	// parser.NewRegistryWithMaxFileSize.
+	MaxFileSize int64
+	// JSONMode is how JSON documents are rendered for indexing; see the
+	// parser.JSONMode constants.
+	JSONMode string
+	// OCR recognizes the text of images and of PDFs without a text layer.
+	// It needs tesseract (and pdftoppm for PDFs) installed.
+	OCR bool
+	// OCRLanguage is the Tesseract language code OCR uses.
+	OCRLanguage string
+	// Bigrams also records the pairs of adjacent words of each document in
+	// bigram_index, for the search service to answer two-word phrases
+	// from. It grows the index by about one row per body word.
+	Bigrams bool
+}
+
+func DefaultConfig() *Config {
+	stopWords, _ := tokenizer.StopWordsFor("en")
+	return &Config{
+		MaxInFlight: 20,
+		StopWords:   stopWords,
+		MaxFileSize: parser.DefaultMaxFileSize,
+		JSONMode:    parser.JSONModeKeys,
+		OCRLanguage: "eng",
+	}
+}
+
+const (
+	defaultConcurrency    = 5
+	defaultPrefetchFactor = 2
+	defaultBatchSize      = 50
+	defaultBatchWorkers   = 8
+	defaultMaxRetries     = 3
+	defaultJobTimeout     = 2 * time.Minute
+)
+
+// Option tunes an IndexingWorker.
+type Option func(*IndexingWorker)
+
+// WithConcurrency sets how many jobs are processed in parallel. Values below
+// 1 keep the default.
+func WithConcurrency(n int) Option {
+	return func(w *IndexingWorker) {
+		if n < 1 {
+			slog.Warn("Invalid worker concurrency, using default", "value", n, "default", defaultConcurrency)
+			return
+		}
+		w.concurrency = n
+	}
+}
+
+// WithPrefetchFactor sets how many messages the broker may deliver ahead per
+// worker, see prefetchFor. Values below 1 keep the default.
+func WithPrefetchFactor(n int) Option {
+	return func(w *IndexingWorker) {
+		if n < 1 {
+			slog.Warn("Invalid worker prefetch factor, using default", "value", n, "default", defaultPrefetchFactor)
+			return
+		}
+		w.prefetchFactor = n
+	}
+}
+
+// WithBatchSize sets the most words whose postings are written per batch;
+// batches are also capped by size, see maxBatchBytes. Values below 1 keep the
+// default.
+func WithBatchSize(n int) Option {
+	return func(w *IndexingWorker) {
+		if n < 1 {
+			slog.Warn("Invalid worker batch size, using default", "value", n, "default", defaultBatchSize)
+			return
+		}
+		w.batchSize = n
+	}
+}
+
+// WithBatchConcurrency sets how many batches one job writes at once. Values
+// below 1 keep the default.
+func WithBatchConcurrency(n int) Option {
+	return func(w *IndexingWorker) {
+		if n < 1 {
+			slog.Warn("Invalid worker batch concurrency, using default", "value", n, "default", defaultBatchWorkers)
+			return
+		}
+		w.batchWorkers = n
+	}
+}
+
+// WithMaxRetries sets how often a failed job is requeued before it goes to
+// the DLQ. Zero disables retries; negative values keep the default.
+func WithMaxRetries(n int) Option {
+	return func(w *IndexingWorker) {
+		if n < 0 {
+			slog.Warn("Invalid worker max retries, using default", "value", n, "default", defaultMaxRetries)
+			return
+		}
+		w.maxRetries = n
+	}
+}
+
+// WithJobTimeout sets how long one job may run. A job past it is abandoned and
+// retried like any failed job. Non-positive values keep the default.
+func WithJobTimeout(d time.Duration) Option {
+	return func(w *IndexingWorker) {
+		if d <= 0 {
+			slog.Warn("Invalid worker job timeout, using default", "value", d, "default", defaultJobTimeout)
+			return
+		}
+		w.jobTimeout = d
+	}
 }
 
 func NewIndexingWorker(
 	consumer *queue.Consumer,
 	minioStorage *storage.Storage,
 	scylla *scylladb.ScyllaDB,
+	cfg *Config,
+	opts ...Option,
 ) *IndexingWorker {
-	return &IndexingWorker{
+	if cfg == nil {
+		cfg = DefaultConfig()
+	}
+	parserRegistry := parser.NewRegistryWithMaxFileSize(cfg.MaxFileSize)
+	if cfg.JSONMode != "" {
+		parserRegistry.Register(parser.NewJSONParserWithMode(cfg.JSONMode))
+	}
+	if cfg.OCR {
+		if ocr, err := parser.NewOCR(cfg.OCRLanguage); err != nil {
+			slog.Warn("OCR disabled", "error", err)
+		} else {
+			parserRegistry.Register(parser.NewPDFParserWithOCR(ocr))
+			parserRegistry.Register(parser.NewImageParser(ocr))
+		}
+	}
+	w := &IndexingWorker{
 		consumer:       consumer,
 		scylladb:       scylla,
 		minioStorage:   minioStorage,
-		tokenizer:      tokenizer.NewTokenizer(),
-		parserRegistry: parser.NewRegistry(),
-		concurrency:    5,
-		batchSize:      50,
-		maxRetries:     3,
+		tokenizer:      tokenizer.NewTokenizerWithStopWords(cfg.StopWords),
+		parserRegistry: parserRegistry,
+		concurrency:    defaultConcurrency,
+		prefetchFactor: defaultPrefetchFactor,
+		jobTimeout:     defaultJobTimeout,
+		batchSize:      defaultBatchSize,
+		batchWorkers:   defaultBatchWorkers,
+		maxRetries:     defaultMaxRetries,
+		dedupTTL:       24 * time.Hour,
+		inflight:       newInflightLimiter(cfg.MaxInFlight),
+		jobStatus:      jobstatus.NewStore(scylla.Session),
+		stats:          scyllaStats{session: scylla.Session},
+		bigrams:        cfg.Bigrams,
+	}
+	w.process = w.processJob
+	for _, opt := range opts {
+		opt(w)
 	}
+	w.parses = newParseLimiter(max(w.concurrency, w.maxWorkers) + abandonedParseSlots)
+	return w
 }
 
-func (w *IndexingWorker) Start(ctx context.Context) error {
-	log.Printf("Starting indexing worker with %d concurrent workers", w.concurrency)
+// InFlight reports how many jobs are currently being processed.
+func (w *IndexingWorker) InFlight() int {
+	return w.inflight.inFlight()
+}
 
+func (w *IndexingWorker) Start(ctx context.Context) error {
 	messages, err := w.consumer.Consume()
 	if err != nil {
 		return fmt.Errorf("failed to start consuming: %w", err)
 	}
 
-	var wg sync.WaitGroup
-	for i := 0; i < w.concurrency; i++ {
-		wg.Add(1)
-		go func(workerID int) {
-			defer wg.Done()
-			w.worker(ctx, workerID, messages)
-		}(i)
+	size, capacity := w.concurrency, w.concurrency
+	if w.maxWorkers > 0 {
+		size, capacity = w.minWorkers, w.maxWorkers
+		slog.Info("Starting indexing worker", "min_concurrency", w.minWorkers, "max_concurrency", w.maxWorkers)
+	} else {
+		slog.Info("Starting indexing worker", "concurrency", w.concurrency)
+	}
+	metrics.WorkerConcurrency.Set(float64(size))
+	if err := w.consumer.SetPrefetch(w.prefetchFor(size)); err != nil {
+		return fmt.Errorf("failed to set prefetch: %w", err)
+	}
+
+	pool := newWorkerPool(capacity, func(workerID int, retire <-chan struct{}) {
+		w.worker(ctx, workerID, messages, retire)
+	})
+	pool.resize(size)
+	if w.maxWorkers > 0 {
+		go w.autoscale(ctx, pool)
 	}
 
 	<-ctx.Done()
-	log.Println("Shutting down workers...")
+	slog.Info("Shutting down workers")
 
-	wg.Wait()
+	pool.wait()
 
 	return ctx.Err()
 }
 
-func (w *IndexingWorker) worker(ctx context.Context, workerID int, messages <-chan amqp.Delivery) {
-	log.Printf("Worker %d started", workerID)
+// prefetchFor returns the prefetch count for the given number of workers.
+// Prefetching more messages than there are workers keeps the next ones
+// buffered, so a worker finishing a job doesn't wait a round trip to the
+// broker. But prefetched messages stay unacknowledged and can't go to
+// another worker process, and the in-flight limit (Config.MaxInFlight) may
+// keep workers from taking them, so the count scales with the workers rather
+// than being fixed.
+func (w *IndexingWorker) prefetchFor(workers int) int {
+	return workers * w.prefetchFactor
+}
+
+// worker processes messages until ctx is done, the channel closes or it
+// takes a token from retire.
+func (w *IndexingWorker) worker(ctx context.Context, workerID int, messages <-chan amqp.Delivery, retire <-chan struct{}) {
+	slog.Debug("Worker started", "worker_id", workerID)
 
 	for {
+		// Wait for capacity before pulling the next message; this is what
+		// applies backpressure when work piles up.
+		if err := w.inflight.acquire(ctx); err != nil {
+			slog.Debug("Worker stopped", "worker_id", workerID, "reason", "context cancelled")
+			return
+		}
+
 		select {
 		case msg, ok := <-messages:
 			if !ok {
-				log.Printf("Worker %d stopped (channel closed)", workerID)
+				w.inflight.release()
+				slog.Warn("Worker stopped", "worker_id", workerID, "reason", "channel closed")
 				return
 			}
 
-			var job types.IndexingJob
-			if err := json.Unmarshal(msg.Body, &job); err != nil {
-				log.Printf("Worker %d: Failed to parse job: %v", workerID, err)
-				msg.Nack(false, false)
-				continue
-			}
-
-			if err := w.processJob(ctx, workerID, &job); err != nil {
-				log.Printf("Worker %d: Failed to process job %s: %v", workerID, job.JobID, err)
-
-				retryCount := w.getRetryCount(msg)
-				if retryCount < w.maxRetries {
-					retryCount++
-					log.Printf("Worker %d: Retrying job %s (attempt %d/%d)",
-						workerID, job.JobID, retryCount, w.maxRetries)
-					if msg.Headers == nil {
-						msg.Headers = make(map[string]interface{})
-					}
-					msg.Headers["x-retry-count"] = int32(retryCount)
-					if pubErr := w.consumer.Publish(msg.Body, msg.Headers); pubErr != nil {
-						log.Printf("Worker %d: Failed to republish job %s: %v", workerID, job.JobID, pubErr)
-						msg.Nack(false, false)
-					} else {
-						msg.Ack(false)
-					}
-				} else {
-					log.Printf("Worker %d: Job %s failed after %d retries, sending to DLQ",
-						workerID, job.JobID, w.maxRetries)
-					msg.Nack(false, false)
-				}
-				continue
-			}
+			w.handleMessage(ctx, workerID, msg)
+			w.inflight.release()
 
-			if err := msg.Ack(false); err != nil {
-				log.Printf("Worker %d: Failed to ack message: %v", workerID, err)
-			}
+		case <-retire:
+			w.inflight.release()
+			slog.Debug("Worker stopped", "worker_id", workerID, "reason", "scaled down")
+			return
 
 		case <-ctx.Done():
-			log.Printf("Worker %d stopped (context cancelled)", workerID)
+			w.inflight.release()
+			slog.Debug("Worker stopped", "worker_id", workerID, "reason", "context cancelled")
+			return
+		}
+	}
+}
+
+func (w *IndexingWorker) handleMessage(ctx context.Context, workerID int, msg amqp.Delivery) {
+	var job types.IndexingJob
+	if err := json.Unmarshal(msg.Body, &job); err != nil {
+		slog.Error("Failed to parse job", "worker_id", workerID, "error", err)
+		msg.Nack(false, false)
+		return
+	}
+	logger := jobLogger(workerID, &job)
+
+	// A retry that arrives early (e.g. redelivered out of its retry queue)
+	// goes back to wait for the rest of its backoff.
+	if wait := time.Until(nextAttemptAt(msg)); wait > 0 {
+		if err := w.consumer.PublishDelayed(msg.Body, msg.Headers, wait, w.getRetryCount(msg)); err != nil {
+			logger.Error("Failed to defer job", "error", err)
+			msg.Nack(false, true)
 			return
 		}
+		msg.Ack(false)
+		return
+	}
+
+	// The job continues the trace of the request that published it.
+	jobCtx, cancel := context.WithTimeout(tracing.Extract(ctx, msg.Headers), w.jobTimeout)
+	err := w.process(jobCtx, workerID, &job)
+	if err != nil && errors.Is(jobCtx.Err(), context.DeadlineExceeded) {
+		err = fmt.Errorf("job timed out after %s: %w", w.jobTimeout, err)
+	}
+	cancel()
+	if err != nil {
+		logger.Error("Failed to process job", "error", err)
+
+		retryCount := w.getRetryCount(msg)
+		permanent := isPermanent(err)
+		if retryCount < w.maxRetries && !permanent {
+			retryCount++
+			delay := retryDelay(retryCount)
+			logger.Info("Retrying job", "delay", delay.Round(time.Millisecond),
+				"attempt", retryCount, "max_retries", w.maxRetries)
+			if msg.Headers == nil {
+				msg.Headers = make(map[string]interface{})
+			}
+			msg.Headers["x-retry-count"] = int32(retryCount)
+			msg.Headers["x-next-attempt-at"] = time.Now().Add(delay).UnixMilli()
+			w.setJobStatus(ctx, &job, jobstatus.StateRetrying, err)
+			if pubErr := w.consumer.PublishDelayed(msg.Body, msg.Headers, delay, retryCount); pubErr != nil {
+				logger.Error("Failed to republish job", "error", pubErr)
+				msg.Nack(false, false)
+			} else {
+				msg.Ack(false)
+			}
+		} else {
+			if permanent {
+				logger.Warn("Job cannot succeed on retry, sending to DLQ")
+			} else {
+				logger.Warn("Job failed after all retries, sending to DLQ", "retries", w.maxRetries)
+			}
+			// Publish to the DLQ directly so the failure reason travels with
+			// the message; dead-lettering via Nack can't add headers.
+			if msg.Headers == nil {
+				msg.Headers = make(map[string]interface{})
+			}
+			msg.Headers[queue.HeaderLastError] = err.Error()
+			w.setJobStatus(ctx, &job, jobstatus.StateFailed, err)
+			if pubErr := w.consumer.PublishToDLQ(msg.Body, msg.Headers); pubErr != nil {
+				logger.Error("Failed to publish job to DLQ", "error", pubErr)
+				msg.Nack(false, false)
+			} else {
+				msg.Ack(false)
+			}
+		}
+		return
+	}
+
+	if err := msg.Ack(false); err != nil {
+		logger.Error("Failed to ack message", "error", err)
 	}
 }
 
+// jobLogger returns a logger tagging lines with job, and with the ID of the
+// request that queued it so they can be matched with the API's logs.
+func jobLogger(workerID int, job *types.IndexingJob) *slog.Logger {
+	logger := slog.With("worker_id", workerID, "job_id", job.JobID, "doc_id", job.Payload.DocID)
+	if job.RequestID != "" {
+		logger = logger.With("request_id", job.RequestID)
+	}
+	return logger
+}
+
+// isPermanent reports whether a job error would recur on every attempt, so
+// the job goes to the DLQ without being retried.
+func isPermanent(err error) bool {
+	return errors.Is(err, parser.ErrEncryptedPDF) || errors.Is(err, parser.ErrUnsupportedFile)
+}
+
 func (w *IndexingWorker) getRetryCount(msg amqp.Delivery) int {
 	if msg.Headers == nil {
 		return 0
@@ -139,60 +428,404 @@ func (w *IndexingWorker) getRetryCount(msg amqp.Delivery) int {
 	return 0
 }
 
-func (w *IndexingWorker) processJob(ctx context.Context, workerID int, job *types.IndexingJob) error {
+const (
+	retryBaseDelay = time.Second
+	retryMaxDelay  = 5 * time.Minute
+)
+
+// retryDelay is the backoff before the given retry attempt: 2^attempt
+// seconds capped at retryMaxDelay, with the upper half jittered so retries of
+// jobs that failed together spread out.
+func retryDelay(attempt int) time.Duration {
+	delay := retryMaxDelay
+	if attempt < 20 {
+		delay = min(retryBaseDelay<<attempt, retryMaxDelay)
+	}
+	half := delay / 2
+	return half + time.Duration(rand.Int64N(int64(half)+1))
+}
+
+// nextAttemptAt returns when a retried message becomes due, or the zero time
+// for messages that are due immediately.
+func nextAttemptAt(msg amqp.Delivery) time.Time {
+	if msg.Headers == nil {
+		return time.Time{}
+	}
+	if ms, ok := msg.Headers["x-next-attempt-at"].(int64); ok {
+		return time.UnixMilli(ms)
+	}
+	return time.Time{}
+}
+
+func (w *IndexingWorker) processJob(ctx context.Context, workerID int, job *types.IndexingJob) (err error) {
 	startTime := time.Now()
-	log.Printf("Worker %d: Processing job %s (doc: %s)", workerID, job.JobID, job.Payload.DocID)
+	logger := jobLogger(workerID, job)
+	logger.Info("Processing job")
+	ctx, span := tracing.Start(ctx, "process job",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			attribute.String("job.id", job.JobID),
+			attribute.String("doc.id", job.Payload.DocID),
+		))
+	defer func() { tracing.End(span, err) }()
 
-	parsedDoc, err := w.downloadAndParse(ctx, job.Payload.FilePath)
+	claimed, err := w.claimJob(ctx, job)
+	if err != nil {
+		return fmt.Errorf("failed to claim job: %w", err)
+	}
+	if !claimed {
+		logger.Info("Skipping duplicate job", "idempotency_key", job.IdempotencyKey)
+		return nil
+	}
+	w.setJobStatus(ctx, job, jobstatus.StateProcessing, nil)
+	defer func() {
+		status := "success"
+		if err != nil {
+			status = "failure"
+			w.releaseJob(job)
+		} else {
+			w.completeJob(ctx, job)
+		}
+		metrics.JobDuration.WithLabelValues(status).Observe(time.Since(startTime).Seconds())
+	}()
+
+	parseCtx := parser.WithPassword(ctx, job.Payload.Metadata[types.MetadataPassword])
+	parsedDoc, err := w.downloadAndParse(parseCtx, job.Payload.FilePath)
 	if err != nil {
 		return fmt.Errorf("failed to parse document: %w", err)
 	}
 
+	_, tokenizeSpan := tracing.Start(ctx, "tokenize")
 	tokens := w.tokenizer.Tokenize(parsedDoc.Content)
-	log.Printf("Worker %d: Extracted %d tokens from document %s", workerID, len(tokens), job.Payload.DocID)
+	tokenizeSpan.SetAttributes(attribute.Int("tokens", len(tokens)))
+	tokenizeSpan.End()
+	logger.Debug("Extracted tokens", "tokens", len(tokens))
 
 	if len(tokens) == 0 {
 		return fmt.Errorf("no tokens extracted from document")
 	}
+	metrics.TokensPerDocument.Observe(float64(len(tokens)))
+
+	// A re-uploaded file keeps its doc_id, so whatever the previous version
+	// indexed has to go before the new postings are written.
+	if err := w.removePreviousVersion(ctx, logger, job.Payload.DocID); err != nil {
+		return fmt.Errorf("failed to remove previous version: %w", err)
+	}
+
+	title := resolveMetadata(types.MetadataTitle, job, parsedDoc, displayTitle(job.Payload.FileName))
+	contentHash := hashContent(parsedDoc.Content)
+	docUUID, err := gocql.ParseUUID(job.Payload.DocID)
+	if err != nil {
+		return fmt.Errorf("invalid doc_id: %w", err)
+	}
+	owner, err := docindex.Claim(ctx, w.scylladb.Session, docUUID, contentHash)
+	if err != nil {
+		return fmt.Errorf("failed to claim content: %w", err)
+	}
+	// Nothing fails the job once the metadata is stored, so a failure here
+	// leaves the content claimed by a document search won't find. Release it;
+	// a retry claims it again.
+	defer func() {
+		if err != nil {
+			w.releaseContent(job, docUUID, contentHash)
+		}
+	}()
+	if owner != docUUID {
+		// Identical content is already indexed: link to it rather than
+		// index it again and count it twice in the stats.
+		if err := w.storeDocumentMetadata(ctx, job, parsedDoc, title, nil, len(tokens), contentHash, &owner); err != nil {
+			return fmt.Errorf("failed to store document metadata: %w", err)
+		}
+		w.jobStatus.SetDuplicate(ctx, job.Payload.DocID, job.JobID, job.Payload.UserID, owner.String())
+		logger.Info("Document duplicates indexed content, linked without re-indexing", "duplicate_of", owner.String())
+		return nil
+	}
 
-	if err := w.buildInvertedIndex(ctx, job.Payload.DocID, tokens); err != nil {
+	indexCtx, indexSpan := tracing.Start(ctx, "index")
+	if err := w.buildInvertedIndex(indexCtx, job.Payload.DocID, tokens); err != nil {
+		tracing.End(indexSpan, err)
 		return fmt.Errorf("failed to build inverted index: %w", err)
 	}
 
-	if err := w.storeDocumentMetadata(ctx, job, parsedDoc, len(tokens)); err != nil {
+	titleTokens := w.tokenizer.Tokenize(title)
+	if err := w.buildTitleIndex(indexCtx, job.Payload.DocID, titleTokens); err != nil {
+		tracing.End(indexSpan, err)
+		return fmt.Errorf("failed to build title index: %w", err)
+	}
+	if w.bigrams {
+		if err := w.buildBigramIndex(indexCtx, docUUID, tokens, titleTokens); err != nil {
+			tracing.End(indexSpan, err)
+			return fmt.Errorf("failed to build bigram index: %w", err)
+		}
+	}
+	indexSpan.End()
+
+	// Stats are part of the job: if they can't be written the job fails and
+	// is retried, and shutdown cancels them along with the rest of the job.
+	// word_stats is a counter table, so it can't join the inverted_index
+	// batches; updateWordStats guards against counting a document twice.
+	statsCtx, statsSpan := tracing.Start(ctx, "stats")
+	err = w.updateWordStats(statsCtx, job.Payload.DocID, tokens, len(titleTokens))
+	tracing.End(statsSpan, err)
+	if err != nil {
+		return fmt.Errorf("failed to update word stats: %w", err)
+	}
+
+	if err := w.storeDocumentMetadata(ctx, job, parsedDoc, title, titleTokens, len(tokens), contentHash, nil); err != nil {
 		return fmt.Errorf("failed to store document metadata: %w", err)
 	}
 
-	go func() {
-		statsCtx := context.Background()
-		if err := w.updateWordStats(statsCtx, tokens); err != nil {
-			log.Printf("Worker %d: Failed to update word stats (non-critical): %v", workerID, err)
-		} else {
-			log.Printf("Worker %d: Updated word statistics", workerID)
-		}
-	}()
+	if err := w.storeDocumentText(ctx, job.Payload.DocID, parsedDoc.Content); err != nil {
+		logger.Warn("Failed to store document text", "error", err)
+	}
 
-	duration := time.Since(startTime)
-	log.Printf("Worker %d: Successfully indexed document %s in %v", workerID, job.Payload.DocID, duration)
+	if err := w.indexTermPrefixes(ctx, tokens); err != nil {
+		logger.Warn("Failed to index term prefixes", "error", err)
+	}
+
+	metrics.DocumentsIndexed.Inc()
+	w.setJobStatus(ctx, job, jobstatus.StateIndexed, nil)
+
+	logger.Info("Indexed document", "tokens", len(tokens), "duration", time.Since(startTime))
 	return nil
 }
 
-func (w *IndexingWorker) downloadAndParse(ctx context.Context, filePath string) (*parser.ParsedDocument, error) {
+// hashContent identifies a document's extracted text, so the same content
+// uploaded under another name or format is recognised.
+func hashContent(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// removePreviousVersion drops the postings and stats of any earlier version
+// of docID. A first upload has none and costs a read of doc_words.
+func (w *IndexingWorker) removePreviousVersion(ctx context.Context, logger *slog.Logger, docID string) error {
+	docUUID, err := gocql.ParseUUID(docID)
+	if err != nil {
+		return fmt.Errorf("invalid doc_id: %w", err)
+	}
+
+	title, err := docindex.LoadTitle(ctx, w.scylladb.Session, docUUID)
+	if err != nil {
+		return err
+	}
+	removed, err := docindex.Remove(ctx, w.scylladb.Session, docUUID, title)
+	if err != nil {
+		return err
+	}
+	if removed > 0 {
+		logger.Info("Removed previous version of document", "words", removed)
+	}
+	return nil
+}
+
+// setJobStatus records the job's state; it never fails the job.
+func (w *IndexingWorker) setJobStatus(ctx context.Context, job *types.IndexingJob, state string, jobErr error) {
+	errMsg := ""
+	if jobErr != nil {
+		errMsg = jobErr.Error()
+	}
+	w.jobStatus.Set(ctx, job.Payload.DocID, job.JobID, job.Payload.UserID, state, errMsg)
+}
+
+// claimJob records the job's idempotency key so duplicate deliveries of the same
+// upload are skipped. It reports false when a different job already holds the key.
+func (w *IndexingWorker) claimJob(ctx context.Context, job *types.IndexingJob) (bool, error) {
+	if job.IdempotencyKey == "" {
+		return true, nil
+	}
+
+	query := `
+        INSERT INTO processed_jobs (idempotency_key, job_id, claimed_at)
+        VALUES (?, ?, ?)
+        IF NOT EXISTS
+        USING TTL ?
+    `
+	existing := make(map[string]interface{})
+	applied, err := w.scylladb.Session.Query(query,
+		job.IdempotencyKey,
+		job.JobID,
+		time.Now(),
+		int(w.dedupTTL.Seconds()),
+	).WithContext(ctx).MapScanCAS(existing)
+	if err != nil {
+		return false, err
+	}
+	if applied {
+		return true, nil
+	}
+	return ownsClaim(existing, job.JobID), nil
+}
+
+// ownsClaim reports whether jobID may run under an existing processed_jobs
+// row. A retry of the same job republishes the same body, so it still owns
+// the key until it succeeds; after that a redelivery is a duplicate too.
+func ownsClaim(existing map[string]interface{}, jobID string) bool {
+	ownerID, _ := existing["job_id"].(string)
+	done, _ := existing["done"].(bool)
+	return ownerID == jobID && !done
+}
+
+// completeJob marks the job's claim done so redeliveries of it are skipped.
+// The job has already succeeded, so a failure is only logged: at worst a
+// redelivery indexes the document again.
+func (w *IndexingWorker) completeJob(ctx context.Context, job *types.IndexingJob) {
+	if job.IdempotencyKey == "" {
+		return
+	}
+
+	query := `
+        INSERT INTO processed_jobs (idempotency_key, job_id, claimed_at, done)
+        VALUES (?, ?, ?, true)
+        USING TTL ?
+    `
+	if err := w.scylladb.Session.Query(query,
+		job.IdempotencyKey,
+		job.JobID,
+		time.Now(),
+		int(w.dedupTTL.Seconds()),
+	).WithContext(ctx).Exec(); err != nil {
+		slog.ErrorContext(ctx, "Failed to mark job done", "job_id", job.JobID, "error", err)
+	}
+}
+
+// releaseJob drops the claim on a failed job so a retry or re-delivery can run.
+func (w *IndexingWorker) releaseJob(job *types.IndexingJob) {
+	if job.IdempotencyKey == "" {
+		return
+	}
+
+	query := `DELETE FROM processed_jobs WHERE idempotency_key = ?`
+	if err := w.scylladb.Session.Query(query, job.IdempotencyKey).Exec(); err != nil {
+		slog.Error("Failed to release job claim", "job_id", job.JobID, "error", err)
+	}
+}
+
+// releaseContent undoes the content claim of a failed job. Like releaseJob it
+// runs after the job's context may have expired, so it uses its own.
+func (w *IndexingWorker) releaseContent(job *types.IndexingJob, docUUID gocql.UUID, contentHash string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := docindex.Unclaim(ctx, w.scylladb.Session, docUUID, contentHash); err != nil {
+		slog.Error("Failed to release content claim", "job_id", job.JobID, "doc_id", job.Payload.DocID, "error", err)
+	}
+}
+
+func (w *IndexingWorker) downloadAndParse(ctx context.Context, filePath string) (_ *parser.ParsedDocument, err error) {
+	parseCtx, parseSpan := tracing.Start(ctx, "parse")
+	defer func() { tracing.End(parseSpan, err) }()
+	// The object streams in as the parser reads it, so the download span
+	// overlaps the parse span and ends once the body has been read.
+	_, downloadSpan := tracing.Start(ctx, "download")
+	defer downloadSpan.End()
+
 	reader, err := w.minioStorage.Client.GetObject(ctx, w.minioStorage.Bucket, filePath, minio.GetObjectOptions{})
 	if err != nil {
+		tracing.End(downloadSpan, err)
 		return nil, fmt.Errorf("failed to download file: %w", err)
 	}
 	defer reader.Close()
 
-	parsedDoc, err := w.parserRegistry.ParseFile(ctx, filePath, reader)
+	// A parser stuck on a pathological file is left behind when ctx is done
+	// rather than holding up the worker; w.parses bounds how many pile up.
+	return w.parses.run(ctx, func() (*parser.ParsedDocument, error) {
+		doc, err := w.parserRegistry.ParseFile(parseCtx, filePath, &spanReader{Reader: reader, span: downloadSpan})
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse file: %w", err)
+		}
+		return doc, nil
+	})
+}
+
+// spanReader ends span once its reader is drained.
+type spanReader struct {
+	io.Reader
+	span trace.Span
+}
+
+func (r *spanReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	if err == io.EOF {
+		r.span.End()
+	} else if err != nil {
+		tracing.End(r.span, err)
+	}
+	return n, err
+}
+
+func (w *IndexingWorker) buildInvertedIndex(ctx context.Context, docID string, tokens []tokenizer.Token) error {
+	return w.insertWordsBatched(ctx, docID, groupTokens(tokens))
+}
+
+// buildTitleIndex writes the postings of the title's words to title_index.
+// Titles are short, so a single batch holds them.
+func (w *IndexingWorker) buildTitleIndex(ctx context.Context, docID string, tokens []tokenizer.Token) error {
+	if len(tokens) == 0 {
+		return nil
+	}
+	docUUID, err := gocql.ParseUUID(docID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse file: %w", err)
+		return fmt.Errorf("invalid doc_id UUID: %w", err)
 	}
 
-	return parsedDoc, nil
+	batch := w.scylladb.Session.NewBatch(gocql.UnloggedBatch)
+	for _, word := range groupTokens(tokens) {
+		batch.Query(`INSERT INTO title_index (word, doc_id, term_frequency, positions) VALUES (?, ?, ?, ?)`,
+			word.Word, docUUID, word.Frequency, word.Positions)
+	}
+	if err := w.scylladb.Session.ExecuteBatch(batch.WithContext(ctx)); err != nil {
+		return fmt.Errorf("batch insert failed: %w", err)
+	}
+	return nil
 }
 
-func (w *IndexingWorker) buildInvertedIndex(ctx context.Context, docID string, tokens []tokenizer.Token) error {
+// buildBigramIndex records the pairs of adjacent words in the body and title
+// in bigram_index, and in doc_bigrams so they can be removed again.
+func (w *IndexingWorker) buildBigramIndex(ctx context.Context, docUUID gocql.UUID, body, title []tokenizer.Token) error {
+	type fieldPair struct {
+		pair, field string
+	}
+	var pairs []fieldPair
+	for _, p := range wordPairs(body) {
+		pairs = append(pairs, fieldPair{p, scylladb.FieldBody})
+	}
+	for _, p := range wordPairs(title) {
+		pairs = append(pairs, fieldPair{p, scylladb.FieldTitle})
+	}
+
+	for i := 0; i < len(pairs); i += w.batchSize {
+		batch := w.scylladb.Session.NewBatch(gocql.UnloggedBatch)
+		for _, p := range pairs[i:min(i+w.batchSize, len(pairs))] {
+			batch.Query(`INSERT INTO bigram_index (word_pair, doc_id, field) VALUES (?, ?, ?)`, p.pair, docUUID, p.field)
+			batch.Query(`INSERT INTO doc_bigrams (doc_id, word_pair, field) VALUES (?, ?, ?)`, docUUID, p.pair, p.field)
+		}
+		if err := w.scylladb.Session.ExecuteBatch(batch.WithContext(ctx)); err != nil {
+			return fmt.Errorf("batch insert failed: %w", err)
+		}
+	}
+	return nil
+}
+
+// wordPairs returns the distinct pairs of words at adjacent positions, the
+// way a two-word phrase query must find them.
+func wordPairs(tokens []tokenizer.Token) []string {
+	seen := make(map[string]bool)
+	var pairs []string
+	for i := 1; i < len(tokens); i++ {
+		if tokens[i].Position != tokens[i-1].Position+1 {
+			continue
+		}
+		pair := scylladb.WordPair(tokens[i-1].Word, tokens[i].Word)
+		if !seen[pair] {
+			seen[pair] = true
+			pairs = append(pairs, pair)
+		}
+	}
+	return pairs
+}
+
+// groupTokens collects the positions and frequency of each distinct word.
+func groupTokens(tokens []tokenizer.Token) []*WordData {
 	wordMap := make(map[string]*WordData)
 
 	for _, token := range tokens {
@@ -212,26 +845,38 @@ func (w *IndexingWorker) buildInvertedIndex(ctx context.Context, docID string, t
 	for _, data := range wordMap {
 		words = append(words, data)
 	}
-
-	return w.insertWordsBatched(ctx, docID, words)
+	return words
 }
 
+const (
+	// maxBatchBytes caps the estimated payload of one index batch, well
+	// under ScyllaDB's batch size warning threshold (128 KiB by default).
+	maxBatchBytes = 64 << 10
+)
+
+// insertWordsBatched writes the postings of words in unlogged batches of at
+// most w.batchSize words and maxBatchBytes, several at a time. The rows span
+// many partitions, so a logged batch would only add the batchlog's cost: a
+// job failing part-way is retried and rewrites the same rows. At most
+// w.batchWorkers batches are in flight.
 func (w *IndexingWorker) insertWordsBatched(ctx context.Context, docID string, words []*WordData) error {
-	numBatches := (len(words) + w.batchSize - 1) / w.batchSize
-	errChan := make(chan error, numBatches)
+	batches := splitWordBatches(words, w.batchSize, maxBatchBytes)
+	errChan := make(chan error, len(batches))
+	sem := make(chan struct{}, w.batchWorkers)
 	var wg sync.WaitGroup
 
-	for i := 0; i < len(words); i += w.batchSize {
-		end := i + w.batchSize
-		if end > len(words) {
-			end = len(words)
+	for _, batch := range batches {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+			return ctx.Err()
 		}
 
-		batch := words[i:end]
 		wg.Add(1)
-
 		go func(batchWords []*WordData) {
 			defer wg.Done()
+			defer func() { <-sem }()
 			if err := w.insertBatch(ctx, docID, batchWords); err != nil {
 				errChan <- err
 			}
@@ -250,8 +895,39 @@ func (w *IndexingWorker) insertWordsBatched(ctx context.Context, docID string, w
 	return nil
 }
 
+// splitWordBatches groups words into batches of at most maxWords words whose
+// estimated size stays within maxBytes. A word too large on its own still
+// gets a batch.
+func splitWordBatches(words []*WordData, maxWords, maxBytes int) [][]*WordData {
+	var (
+		batches [][]*WordData
+		start   int
+		size    int
+	)
+	for i, word := range words {
+		wordSize := estimatePostingSize(word)
+		if i > start && (i-start >= maxWords || size+wordSize > maxBytes) {
+			batches = append(batches, words[start:i])
+			start, size = i, 0
+		}
+		size += wordSize
+	}
+	if start < len(words) {
+		batches = append(batches, words[start:])
+	}
+	return batches
+}
+
+// estimatePostingSize approximates the bytes a word adds to an index batch:
+// its inverted_index and doc_words rows, each with the word, a 16-byte
+// doc_id, an int and some per-statement overhead, plus 4 bytes per position.
+func estimatePostingSize(word *WordData) int {
+	const rowOverhead = 16 + 4 + 32
+	return 2*(len(word.Word)+rowOverhead) + 4*len(word.Positions)
+}
+
 func (w *IndexingWorker) insertBatch(ctx context.Context, docID string, words []*WordData) error {
-	batch := w.scylladb.Session.NewBatch(gocql.LoggedBatch)
+	batch := w.scylladb.Session.NewBatch(gocql.UnloggedBatch)
 
 	docUUID, err := gocql.ParseUUID(docID)
 	if err != nil {
@@ -264,6 +940,10 @@ func (w *IndexingWorker) insertBatch(ctx context.Context, docID string, words []
             VALUES (?, ?, ?, ?)
         `
 		batch.Query(query, word.Word, docUUID, word.Frequency, word.Positions)
+		// doc_words lets a document's postings be found again for deletion,
+		// since inverted_index is partitioned by word.
+		batch.Query(`INSERT INTO doc_words (doc_id, word, term_frequency) VALUES (?, ?, ?)`,
+			docUUID, word.Word, word.Frequency)
 	}
 
 	if err := w.scylladb.Session.ExecuteBatch(batch.WithContext(ctx)); err != nil {
@@ -277,101 +957,154 @@ func (w *IndexingWorker) storeDocumentMetadata(
 	ctx context.Context,
 	job *types.IndexingJob,
 	parsedDoc *parser.ParsedDocument,
+	title string,
+	titleTokens []tokenizer.Token,
 	wordCount int,
+	contentHash string,
+	duplicateOf *gocql.UUID,
 ) error {
-	docUUID, err := gocql.ParseUUID(job.Payload.DocID)
+	values, err := documentRow(job, parsedDoc, title, titleTokens, wordCount, contentHash, duplicateOf, time.Now())
 	if err != nil {
-		return fmt.Errorf("invalid doc_id UUID: %w", err)
+		return err
 	}
 
-	title := parsedDoc.Metadata["title"]
-	if title == "" {
-		title = job.Payload.FileName
-	}
+	query := fmt.Sprintf(`INSERT INTO documents (%s) VALUES (?%s)`,
+		strings.Join(documentColumns, ", "), strings.Repeat(", ?", len(documentColumns)-1))
+	return w.scylladb.Session.Query(query, values...).WithContext(ctx).Exec()
+}
 
-	author := parsedDoc.Metadata["author"]
-	if author == "" {
-		author = "unknown"
+// documentColumns are the documents columns written by storeDocumentMetadata,
+// in the order documentRow returns their values.
+var documentColumns = []string{
+	"doc_id", "user_id", "title", "author", "description", "file_type", "language",
+	"file_path", "file_name", "file_size", "doc_length", "title_length", "title_terms",
+	"content_hash", "duplicate_of", "created_at",
+}
+
+// documentRow returns the documents row for an indexed job, one value per
+// entry of documentColumns.
+func documentRow(
+	job *types.IndexingJob,
+	parsedDoc *parser.ParsedDocument,
+	title string,
+	titleTokens []tokenizer.Token,
+	wordCount int,
+	contentHash string,
+	duplicateOf *gocql.UUID,
+	createdAt time.Time,
+) ([]any, error) {
+	docUUID, err := gocql.ParseUUID(job.Payload.DocID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid doc_id UUID: %w", err)
 	}
 
-	query := `
-        INSERT INTO documents (doc_id, title, author, file_path, created_at)
-        VALUES (?, ?, ?, ?, ?)
-    `
+	titleTerms := make([]string, 0, len(titleTokens))
+	for _, t := range titleTokens {
+		titleTerms = append(titleTerms, t.Word)
+	}
+	author := resolveMetadata(types.MetadataAuthor, job, parsedDoc, "unknown")
+	description := resolveMetadata(types.MetadataDescription, job, parsedDoc, "")
+	language := strings.ToLower(strings.TrimSpace(resolveMetadata(types.MetadataLanguage, job, parsedDoc, "")))
 
-	return w.scylladb.Session.Query(query,
+	return []any{
 		docUUID,
+		job.Payload.UserID,
 		title,
 		author,
+		description,
+		parsedDoc.Metadata["fileType"],
+		language,
 		job.Payload.FilePath,
-		time.Now(),
-	).WithContext(ctx).Exec()
+		job.Payload.FileName,
+		job.Payload.FileSize,
+		wordCount,
+		len(titleTokens),
+		titleTerms,
+		contentHash,
+		duplicateOf,
+		createdAt,
+	}, nil
 }
 
-func (w *IndexingWorker) updateWordStats(ctx context.Context, tokens []tokenizer.Token) error {
-	uniqueWords := make(map[string]int)
+// indexTermPrefixes lists each distinct word of the document in
+// term_prefixes under its prefixes, so the search service can suggest
+// completions. Rows are idempotent; words already listed are simply
+// rewritten.
+func (w *IndexingWorker) indexTermPrefixes(ctx context.Context, tokens []tokenizer.Token) error {
+	seen := make(map[string]bool)
+	batch := w.scylladb.Session.NewBatch(gocql.UnloggedBatch)
 	for _, token := range tokens {
-		uniqueWords[token.Word]++
+		if seen[token.Word] {
+			continue
+		}
+		seen[token.Word] = true
+
+		runes := []rune(token.Word)
+		for n := scylladb.TermPrefixMinLen; n <= min(len(runes), scylladb.TermPrefixMaxLen); n++ {
+			batch.Query(`INSERT INTO term_prefixes (prefix, word) VALUES (?, ?)`, string(runes[:n]), token.Word)
+		}
+		if batch.Size() >= w.batchSize {
+			if err := w.scylladb.Session.ExecuteBatch(batch.WithContext(ctx)); err != nil {
+				return fmt.Errorf("batch insert failed: %w", err)
+			}
+			batch = w.scylladb.Session.NewBatch(gocql.UnloggedBatch)
+		}
+	}
+	if batch.Size() == 0 {
+		return nil
 	}
+	if err := w.scylladb.Session.ExecuteBatch(batch.WithContext(ctx)); err != nil {
+		return fmt.Errorf("batch insert failed: %w", err)
+	}
+	return nil
+}
 
-	const batchSize = 100
-	var wg sync.WaitGroup
-	errChan := make(chan error, (len(uniqueWords)+batchSize-1)/batchSize)
+// maxStoredTextBytes caps the extracted text kept for snippet generation.
+const maxStoredTextBytes = 1 << 20
 
-	wordList := make([]string, 0, len(uniqueWords))
-	freqList := make([]int, 0, len(uniqueWords))
-	for word, freq := range uniqueWords {
-		wordList = append(wordList, word)
-		freqList = append(freqList, freq)
+// storeDocumentText keeps the extracted text so the search service can build
+// snippets without downloading and re-parsing the original file.
+func (w *IndexingWorker) storeDocumentText(ctx context.Context, docID, content string) error {
+	docUUID, err := gocql.ParseUUID(docID)
+	if err != nil {
+		return fmt.Errorf("invalid doc_id UUID: %w", err)
 	}
 
-	for i := 0; i < len(wordList); i += batchSize {
-		end := i + batchSize
-		if end > len(wordList) {
-			end = len(wordList)
-		}
-		batchWords := wordList[i:end]
-		batchFreqs := freqList[i:end]
-
-		wg.Add(1)
-		go func(words []string, freqs []int) {
-			defer wg.Done()
-			select {
-			case <-ctx.Done():
-				return
-			default:
-			}
-			if err := w.updateWordStatsBatch(ctx, words, freqs); err != nil {
-				errChan <- err
-			}
-		}(batchWords, batchFreqs)
+	if len(content) > maxStoredTextBytes {
+		content = strings.ToValidUTF8(content[:maxStoredTextBytes], "")
 	}
 
-	wg.Wait()
-	close(errChan)
+	return w.scylladb.Session.Query(`INSERT INTO document_text (doc_id, content) VALUES (?, ?)`,
+		docUUID, content).WithContext(ctx).Exec()
+}
 
-	for err := range errChan {
-		if err != nil {
-			return err
-		}
+// resolveMetadata picks a document metadata value with the precedence:
+// user-supplied value on the job > value extracted by the parser > fallback.
+func resolveMetadata(key string, job *types.IndexingJob, parsedDoc *parser.ParsedDocument, fallback string) string {
+	if value := job.Payload.Metadata[key]; value != "" {
+		return value
 	}
-
-	return nil
+	if value := parsedDoc.Metadata[key]; value != "" {
+		return value
+	}
+	return fallback
 }
 
-func (w *IndexingWorker) updateWordStatsBatch(ctx context.Context, words []string, freqs []int) error {
-	for i, word := range words {
-		query := `
-            UPDATE word_stats
-            SET doc_count = doc_count + 1,
-                total_occurrences = total_occurrences + ?
-            WHERE word = ?
-        `
-		if err := w.scylladb.Session.Query(query, freqs[i], word).WithContext(ctx).Exec(); err != nil {
-			return fmt.Errorf("failed to update stats for word %q: %w", word, err)
-		}
+// displayTitle turns a file name into a human-friendly title: any leftover
+// URL-encoding is decoded and the directory and extension are dropped. Case is
+// preserved; only the inverted index works on lowercased tokens.
+func displayTitle(fileName string) string {
+	name := fileName
+	if decoded, err := url.PathUnescape(name); err == nil {
+		name = decoded
 	}
-	return nil
+	name = path.Base(name)
+	name = strings.TrimSuffix(name, path.Ext(name))
+	name = strings.TrimSpace(name)
+	if name == "" || name == "." || name == "/" {
+		return fileName
+	}
+	return name
 }
 
 type WordData struct {
@@ -0,0 +1,203 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/amrrdev/trawl/services/indexing/internal/jobstatus"
+	"github.com/amrrdev/trawl/services/indexing/internal/parser"
+	"github.com/amrrdev/trawl/services/indexing/internal/types"
+	"github.com/gocql/gocql"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+func TestIsPermanent(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"encrypted PDF", fmt.Errorf("failed to parse document: %w", parser.ErrEncryptedPDF), true},
+		{"unsupported file", fmt.Errorf("failed to parse document: %w", parser.ErrUnsupportedFile), true},
+		{"cancelled", context.Canceled, false},
+		{"transient", errors.New("connection reset"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isPermanent(tt.err); got != tt.want {
+				t.Errorf("isPermanent(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrefetchFor(t *testing.T) {
+	tests := []struct {
+		name    string
+		factor  int
+		workers int
+		want    int
+	}{
+		{"default factor", 0, 4, 4 * defaultPrefetchFactor},
+		{"configured factor", 3, 4, 12},
+		{"scaled down", 3, 1, 3},
+		{"invalid factor keeps default", -2, 5, 5 * defaultPrefetchFactor},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := &IndexingWorker{prefetchFactor: defaultPrefetchFactor}
+			if tt.factor != 0 {
+				WithPrefetchFactor(tt.factor)(w)
+			}
+			if got := w.prefetchFor(tt.workers); got != tt.want {
+				t.Errorf("prefetchFor(%d) = %d, want %d", tt.workers, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOwnsClaim(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing map[string]interface{}
+		want     bool
+	}{
+		{"retry of the claiming job", map[string]interface{}{"job_id": "job-1"}, true},
+		{"retry, done unset", map[string]interface{}{"job_id": "job-1", "done": false}, true},
+		{"redelivery after success", map[string]interface{}{"job_id": "job-1", "done": true}, false},
+		{"second webhook for the upload", map[string]interface{}{"job_id": "job-2"}, false},
+		{"second webhook after success", map[string]interface{}{"job_id": "job-2", "done": true}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ownsClaim(tt.existing, "job-1"); got != tt.want {
+				t.Errorf("ownsClaim(%v) = %v, want %v", tt.existing, got, tt.want)
+			}
+		})
+	}
+}
+
+// fakeQueue is a jobQueue delivering what is sent on messages and recording
+// the jobs requeued.
+type fakeQueue struct {
+	messages chan amqp.Delivery
+
+	mu      sync.Mutex
+	retried int
+}
+
+func (q *fakeQueue) Consume() (<-chan amqp.Delivery, error) { return q.messages, nil }
+func (q *fakeQueue) SetPrefetch(n int) error                { return nil }
+func (q *fakeQueue) Depth() (int, error)                    { return len(q.messages), nil }
+
+func (q *fakeQueue) PublishDelayed(data []byte, headers map[string]interface{}, delay time.Duration, attempt int) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.retried++
+	return nil
+}
+
+func (q *fakeQueue) PublishToDLQ(data []byte, headers map[string]interface{}) error { return nil }
+
+// slowStats is a statsStore whose word counter writes signal started and then
+// run write.
+type slowStats struct {
+	*memoryStats
+	started chan struct{}
+	write   func(ctx context.Context) error
+}
+
+func (s *slowStats) addWordStats(ctx context.Context, word string, occurrences int) error {
+	close(s.started)
+	if err := s.write(ctx); err != nil {
+		return err
+	}
+	return s.memoryStats.addWordStats(ctx, word, occurrences)
+}
+
+func TestStartWaitsForWordStats(t *testing.T) {
+	tests := []struct {
+		name  string
+		write func(ctx context.Context) error
+	}{
+		{"write finishes", func(ctx context.Context) error {
+			time.Sleep(100 * time.Millisecond)
+			return nil
+		}},
+		{"write cancelled", func(ctx context.Context) error {
+			<-ctx.Done()
+			time.Sleep(50 * time.Millisecond)
+			return ctx.Err()
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stats := &slowStats{memoryStats: newMemoryStats(), started: make(chan struct{}), write: tt.write}
+			q := &fakeQueue{messages: make(chan amqp.Delivery, 1)}
+			result := make(chan error, 1)
+			w := &IndexingWorker{
+				consumer:       q,
+				concurrency:    1,
+				prefetchFactor: defaultPrefetchFactor,
+				jobTimeout:     time.Minute,
+				batchWorkers:   1,
+				maxRetries:     defaultMaxRetries,
+				inflight:       newInflightLimiter(0),
+				jobStatus:      jobstatus.NewStore(nil),
+				stats:          stats,
+			}
+			// Stands in for processJob at its word stats step.
+			w.process = func(ctx context.Context, workerID int, job *types.IndexingJob) error {
+				err := w.updateWordStats(ctx, gocql.MustRandomUUID().String(), tokensOf("alpha"), 0)
+				result <- err
+				return err
+			}
+
+			// The doc_id is not a UUID, so status writes are skipped rather
+			// than sent to a cluster.
+			body, err := json.Marshal(&types.IndexingJob{JobID: "job-1", Payload: types.IndexingPayload{DocID: "doc-1"}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			q.messages <- amqp.Delivery{Body: body}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			stopped := make(chan error, 1)
+			go func() { stopped <- w.Start(ctx) }()
+
+			select {
+			case <-stats.started:
+			case <-time.After(5 * time.Second):
+				t.Fatal("job never reached its word stats write")
+			}
+			cancel()
+
+			select {
+			case err := <-stopped:
+				if !errors.Is(err, context.Canceled) {
+					t.Errorf("Start = %v, want context.Canceled", err)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("Start did not return after cancellation")
+			}
+
+			select {
+			case err := <-result:
+				if err != nil && !errors.Is(err, context.Canceled) {
+					t.Errorf("word stats = %v, want success or context.Canceled", err)
+				}
+			default:
+				t.Fatal("Start returned while the word stats write was in flight")
+			}
+			// The interrupted job is requeued rather than lost.
+			if q.retried != 1 {
+				t.Errorf("job requeued %d times, want 1", q.retried)
+			}
+		})
+	}
+}
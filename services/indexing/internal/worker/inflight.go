@@ -0,0 +1,57 @@
+package worker
+
+import (
+	"context"
+	"sync"
+)
+
+// inflightLimiter counts in-flight jobs and lets the consume loop wait until
+// the count drops below max.
+type inflightLimiter struct {
+	mu      sync.Mutex
+	count   int
+	max     int
+	drained chan struct{}
+}
+
+func newInflightLimiter(max int) *inflightLimiter {
+	return &inflightLimiter{
+		max:     max,
+		drained: make(chan struct{}),
+	}
+}
+
+// acquire blocks until there is capacity for another unit of work, then
+// claims it. A non-positive max disables the limit.
+func (l *inflightLimiter) acquire(ctx context.Context) error {
+	for {
+		l.mu.Lock()
+		if l.max <= 0 || l.count < l.max {
+			l.count++
+			l.mu.Unlock()
+			return nil
+		}
+		drained := l.drained
+		l.mu.Unlock()
+
+		select {
+		case <-drained:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (l *inflightLimiter) release() {
+	l.mu.Lock()
+	l.count--
+	close(l.drained)
+	l.drained = make(chan struct{})
+	l.mu.Unlock()
+}
+
+func (l *inflightLimiter) inFlight() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.count
+}
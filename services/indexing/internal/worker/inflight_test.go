@@ -0,0 +1,69 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestInflightLimiter(t *testing.T) {
+	tests := []struct {
+		name     string
+		max      int
+		acquired int
+		wantWait bool
+	}{
+		{"below the limit", 2, 1, false},
+		{"at the limit", 2, 2, true},
+		{"unlimited", 0, 100, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newInflightLimiter(tt.max)
+			for range tt.acquired {
+				if err := l.acquire(context.Background()); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if got := l.inFlight(); got != tt.acquired {
+				t.Errorf("inFlight = %d, want %d", got, tt.acquired)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+			defer cancel()
+			err := l.acquire(ctx)
+			if waited := errors.Is(err, context.DeadlineExceeded); waited != tt.wantWait {
+				t.Errorf("acquire err = %v, want waiting = %v", err, tt.wantWait)
+			}
+		})
+	}
+}
+
+func TestInflightLimiterReleaseWakesWaiter(t *testing.T) {
+	l := newInflightLimiter(1)
+	if err := l.acquire(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	acquired := make(chan error, 1)
+	go func() { acquired <- l.acquire(context.Background()) }()
+	select {
+	case err := <-acquired:
+		t.Fatalf("acquired past the limit: %v", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	l.release()
+	select {
+	case err := <-acquired:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("waiter not woken by release")
+	}
+	if got := l.inFlight(); got != 1 {
+		t.Errorf("inFlight = %d, want 1", got)
+	}
+}
@@ -0,0 +1,139 @@
+package worker
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/amrrdev/trawl/services/indexing/internal/parser"
+	"github.com/amrrdev/trawl/services/indexing/internal/types"
+	"github.com/amrrdev/trawl/services/shared/tokenizer"
+	"github.com/gocql/gocql"
+)
+
+func TestResolveMetadata(t *testing.T) {
+	tests := []struct {
+		name     string
+		uploaded map[string]string
+		parsed   map[string]string
+		want     string
+	}{
+		{"uploaded value wins", map[string]string{"title": "Uploaded"}, map[string]string{"title": "Parsed"}, "Uploaded"},
+		{"parsed value", nil, map[string]string{"title": "Parsed"}, "Parsed"},
+		{"empty values fall through", map[string]string{"title": ""}, map[string]string{"title": ""}, "fallback"},
+		{"fallback", nil, nil, "fallback"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job := &types.IndexingJob{Payload: types.IndexingPayload{Metadata: tt.uploaded}}
+			doc := &parser.ParsedDocument{Metadata: tt.parsed}
+			if got := resolveMetadata(types.MetadataTitle, job, doc, "fallback"); got != tt.want {
+				t.Errorf("resolveMetadata = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDisplayTitle(t *testing.T) {
+	tests := []struct {
+		fileName string
+		want     string
+	}{
+		{"Quarterly Report.pdf", "Quarterly Report"},
+		{"Quarterly%20Report.pdf", "Quarterly Report"},
+		{"reports/2024/Annual-Summary.docx", "Annual-Summary"},
+		{"archive.tar.gz", "archive.tar"},
+		{"README", "README"},
+		{"  spaced  .txt", "spaced"},
+		{"bad%zzescape.pdf", "bad%zzescape"},
+		{".pdf", ".pdf"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fileName, func(t *testing.T) {
+			if got := displayTitle(tt.fileName); got != tt.want {
+				t.Errorf("displayTitle(%q) = %q, want %q", tt.fileName, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDocumentRow(t *testing.T) {
+	created := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	owner := gocql.TimeUUID()
+	docID := gocql.TimeUUID()
+	queued := &types.IndexingJob{
+		JobID: "job-1",
+		Payload: types.IndexingPayload{
+			DocID:    docID.String(),
+			UserID:   "user-1",
+			FilePath: "user-1/reports/Q1 Report.pdf",
+			FileName: "reports/Q1 Report.pdf",
+			FileSize: 48213,
+			Metadata: map[string]string{"author": "Alice", "language": " EN "},
+		},
+	}
+	// Jobs reach the worker as JSON, so round-trip the payload the way the
+	// queue does.
+	body, err := json.Marshal(queued)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var job types.IndexingJob
+	if err := json.Unmarshal(body, &job); err != nil {
+		t.Fatal(err)
+	}
+	doc := &parser.ParsedDocument{Metadata: map[string]string{"fileType": "pdf", "description": "Quarterly numbers"}}
+	titleTokens := []tokenizer.Token{{Word: "q1"}, {Word: "report"}}
+
+	values, err := documentRow(&job, doc, "Q1 Report", titleTokens, 120, "hash", &owner, created)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(values) != len(documentColumns) {
+		t.Fatalf("%d values for %d columns", len(values), len(documentColumns))
+	}
+	row := make(map[string]any, len(values))
+	for i, column := range documentColumns {
+		row[column] = values[i]
+	}
+
+	tests := []struct {
+		column string
+		want   any
+	}{
+		{"doc_id", docID},
+		{"user_id", "user-1"},
+		{"title", "Q1 Report"},
+		{"author", "Alice"},
+		{"description", "Quarterly numbers"},
+		{"file_type", "pdf"},
+		{"language", "en"},
+		{"file_path", "user-1/reports/Q1 Report.pdf"},
+		{"file_name", "reports/Q1 Report.pdf"},
+		{"file_size", int64(48213)},
+		{"doc_length", 120},
+		{"title_length", 2},
+		{"content_hash", "hash"},
+		{"duplicate_of", &owner},
+		{"created_at", created},
+	}
+	for _, tt := range tests {
+		t.Run(tt.column, func(t *testing.T) {
+			if got := row[tt.column]; got != tt.want {
+				t.Errorf("%s = %v, want %v", tt.column, got, tt.want)
+			}
+		})
+	}
+	if got := row["title_terms"].([]string); !slices.Equal(got, []string{"q1", "report"}) {
+		t.Errorf("title_terms = %v, want [q1 report]", got)
+	}
+}
+
+func TestDocumentRowInvalidDocID(t *testing.T) {
+	job := &types.IndexingJob{Payload: types.IndexingPayload{DocID: "not-a-uuid"}}
+	if _, err := documentRow(job, &parser.ParsedDocument{}, "", nil, 0, "", nil, time.Time{}); err == nil {
+		t.Error("documentRow accepted an invalid doc_id")
+	}
+}
@@ -0,0 +1,53 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/amrrdev/trawl/services/indexing/internal/parser"
+)
+
+// abandonedParseSlots is how many parsers may keep running after their job
+// gave up on them, on top of one per worker.
+const abandonedParseSlots = 4
+
+// parseLimiter bounds the parser goroutines running at once, including the
+// ones left behind by a timed-out job: most parsers don't watch ctx, so one
+// stuck on a pathological file runs until it returns. Once every slot is
+// taken, new parses wait for one instead of piling up more goroutines.
+type parseLimiter struct {
+	slots chan struct{}
+}
+
+func newParseLimiter(n int) *parseLimiter {
+	return &parseLimiter{slots: make(chan struct{}, n)}
+}
+
+// run calls parse in its own goroutine and waits for it until ctx is done.
+// The goroutine keeps its slot until parse returns, even after run has given
+// up on it.
+func (l *parseLimiter) run(ctx context.Context, parse func() (*parser.ParsedDocument, error)) (*parser.ParsedDocument, error) {
+	select {
+	case l.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, fmt.Errorf("no parser free: %w", ctx.Err())
+	}
+
+	type parseResult struct {
+		doc *parser.ParsedDocument
+		err error
+	}
+	done := make(chan parseResult, 1)
+	go func() {
+		defer func() { <-l.slots }()
+		doc, err := parse()
+		done <- parseResult{doc, err}
+	}()
+
+	select {
+	case result := <-done:
+		return result.doc, result.err
+	case <-ctx.Done():
+		return nil, fmt.Errorf("parse abandoned: %w", ctx.Err())
+	}
+}
@@ -0,0 +1,68 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/amrrdev/trawl/services/indexing/internal/parser"
+)
+
+func TestParseLimiter(t *testing.T) {
+	parseFailed := errors.New("corrupt file")
+	stuck := make(chan struct{})
+	defer close(stuck)
+
+	limiter := newParseLimiter(1)
+	// The first parse never returns in time, so it is abandoned and keeps
+	// the only slot; the next ones can't start until it finishes.
+	tests := []struct {
+		name       string
+		parse      func() (*parser.ParsedDocument, error)
+		wantErr    error
+		wantCalled bool
+	}{
+		{"abandoned", func() (*parser.ParsedDocument, error) { <-stuck; return nil, nil }, context.DeadlineExceeded, true},
+		{"waits for a slot", func() (*parser.ParsedDocument, error) { return &parser.ParsedDocument{}, nil }, context.DeadlineExceeded, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+			defer cancel()
+			var called atomic.Bool
+			_, err := limiter.run(ctx, func() (*parser.ParsedDocument, error) {
+				called.Store(true)
+				return tt.parse()
+			})
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("err = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantCalled && !called.Load() {
+				t.Error("parse was not started")
+			}
+			if !tt.wantCalled && called.Load() {
+				t.Error("parse started without a free slot")
+			}
+		})
+	}
+
+	t.Run("slot freed once the abandoned parse returns", func(t *testing.T) {
+		stuck <- struct{}{}
+		for _, want := range []error{nil, parseFailed} {
+			doc, err := limiter.run(context.Background(), func() (*parser.ParsedDocument, error) {
+				if want != nil {
+					return nil, want
+				}
+				return &parser.ParsedDocument{}, nil
+			})
+			if !errors.Is(err, want) {
+				t.Fatalf("err = %v, want %v", err, want)
+			}
+			if want == nil && doc == nil {
+				t.Error("doc = nil, want the parsed document")
+			}
+		}
+	})
+}
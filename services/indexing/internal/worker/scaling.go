@@ -0,0 +1,147 @@
+package worker
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/amrrdev/trawl/services/shared/metrics"
+)
+
+const (
+	// scaleInterval is how often the queue depth is checked when auto
+	// scaling.
+	scaleInterval = 10 * time.Second
+	// scaleBacklogPerWorker is how many waiting messages justify one worker.
+	scaleBacklogPerWorker = 5
+)
+
+// WithAutoScaling makes the worker adjust its concurrency to the indexing
+// queue's depth, between minWorkers and maxWorkers, instead of running the
+// fixed number set by WithConcurrency. A maxWorkers below 1 leaves auto
+// scaling off; minWorkers is raised to at least 1.
+func WithAutoScaling(minWorkers, maxWorkers int) Option {
+	return func(w *IndexingWorker) {
+		if maxWorkers < 1 {
+			return
+		}
+		minWorkers = max(minWorkers, 1)
+		if minWorkers > maxWorkers {
+			slog.Warn("Invalid worker scaling bounds, auto scaling disabled", "min", minWorkers, "max", maxWorkers)
+			return
+		}
+		w.minWorkers, w.maxWorkers = minWorkers, maxWorkers
+	}
+}
+
+// scaleTarget returns how many workers to run for depth ready messages. It
+// scales up straight to what the backlog needs but down one worker at a
+// time, so a briefly empty queue doesn't retire the whole pool.
+func scaleTarget(depth, current, minWorkers, maxWorkers int) int {
+	desired := (depth + scaleBacklogPerWorker - 1) / scaleBacklogPerWorker
+
+	target := current
+	switch {
+	case desired > current:
+		target = desired
+	case desired < current:
+		target = current - 1
+	}
+	return max(minWorkers, min(target, maxWorkers))
+}
+
+// autoscale resizes pool to the queue depth every scaleInterval until ctx is
+// done.
+func (w *IndexingWorker) autoscale(ctx context.Context, pool *workerPool) {
+	ticker := time.NewTicker(scaleInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		depth, err := w.consumer.Depth()
+		if err != nil {
+			slog.Warn("Failed to read queue depth, keeping worker count", "error", err)
+			continue
+		}
+
+		current := pool.len()
+		target := scaleTarget(depth, current, w.minWorkers, w.maxWorkers)
+		if target == current {
+			continue
+		}
+		slog.Info("Scaling indexing workers", "from", current, "to", target, "queue_depth", depth)
+		pool.resize(target)
+		metrics.WorkerConcurrency.Set(float64(target))
+		if err := w.consumer.SetPrefetch(w.prefetchFor(target)); err != nil {
+			slog.Warn("Failed to update prefetch", "workers", target, "error", err)
+		}
+	}
+}
+
+// workerPool runs worker goroutines and adds or retires them on demand.
+type workerPool struct {
+	run func(workerID int, retire <-chan struct{})
+
+	// retire holds one token per worker still to be retired; each worker
+	// takes at most one between jobs and exits.
+	retire chan struct{}
+	wg     sync.WaitGroup
+
+	mu     sync.Mutex
+	size   int
+	nextID int
+}
+
+// newWorkerPool returns an empty pool of at most max workers, each running
+// run until it returns.
+func newWorkerPool(max int, run func(workerID int, retire <-chan struct{})) *workerPool {
+	return &workerPool{
+		run:    run,
+		retire: make(chan struct{}, max),
+	}
+}
+
+// len returns how many workers the pool is running, not counting those
+// already asked to retire.
+func (p *workerPool) len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.size
+}
+
+// resize starts or retires workers until size are running. Retired workers
+// finish their current job first.
+func (p *workerPool) resize(size int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for ; p.size < size; p.size++ {
+		select {
+		case <-p.retire:
+			// Take back a retirement no worker has acted on yet.
+			continue
+		default:
+		}
+
+		p.wg.Add(1)
+		go func(workerID int) {
+			defer p.wg.Done()
+			p.run(workerID, p.retire)
+		}(p.nextID)
+		p.nextID++
+	}
+	for ; p.size > size; p.size-- {
+		p.retire <- struct{}{}
+	}
+}
+
+// wait blocks until every worker has returned.
+func (p *workerPool) wait() {
+	p.wg.Wait()
+}
@@ -0,0 +1,76 @@
+package worker
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestScaleTarget(t *testing.T) {
+	tests := []struct {
+		name                       string
+		depth, current, minW, maxW int
+		want                       int
+	}{
+		{"backlog scales straight up", 23, 1, 1, 10, 5},
+		{"capped at max", 500, 2, 1, 10, 10},
+		{"steady", 10, 2, 1, 10, 2},
+		{"empty queue scales down one at a time", 0, 6, 1, 10, 5},
+		{"never below min", 0, 2, 2, 10, 2},
+		{"min raised from below", 0, 1, 3, 10, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := scaleTarget(tt.depth, tt.current, tt.minW, tt.maxW); got != tt.want {
+				t.Errorf("scaleTarget(%d, %d, %d, %d) = %d, want %d",
+					tt.depth, tt.current, tt.minW, tt.maxW, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithAutoScaling(t *testing.T) {
+	tests := []struct {
+		name             string
+		minW, maxW       int
+		wantMin, wantMax int
+	}{
+		{"bounds", 2, 8, 2, 8},
+		{"min raised to one", 0, 4, 1, 4},
+		{"no max leaves scaling off", 2, 0, 0, 0},
+		{"inverted bounds leave scaling off", 5, 3, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := &IndexingWorker{}
+			WithAutoScaling(tt.minW, tt.maxW)(w)
+			if w.minWorkers != tt.wantMin || w.maxWorkers != tt.wantMax {
+				t.Errorf("bounds = [%d, %d], want [%d, %d]", w.minWorkers, w.maxWorkers, tt.wantMin, tt.wantMax)
+			}
+		})
+	}
+}
+
+func TestWorkerPoolResize(t *testing.T) {
+	var running atomic.Int32
+	pool := newWorkerPool(8, func(workerID int, retire <-chan struct{}) {
+		running.Add(1)
+		defer running.Add(-1)
+		<-retire
+	})
+
+	for _, size := range []int{3, 8, 2, 5, 0} {
+		pool.resize(size)
+		if got := pool.len(); got != size {
+			t.Fatalf("len = %d after resize(%d)", got, size)
+		}
+		deadline := time.Now().Add(time.Second)
+		for int(running.Load()) != size {
+			if time.Now().After(deadline) {
+				t.Fatalf("%d workers running after resize(%d)", running.Load(), size)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+	pool.wait()
+}
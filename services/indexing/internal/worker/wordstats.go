@@ -0,0 +1,200 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/amrrdev/trawl/services/indexing/internal/docindex"
+	"github.com/amrrdev/trawl/services/shared/scylladb"
+	"github.com/amrrdev/trawl/services/shared/tokenizer"
+	"github.com/gocql/gocql"
+)
+
+// statsStore holds the corpus statistics updateWordStats writes and the
+// markers that keep it from counting a document twice.
+type statsStore interface {
+	// counted reports whether docUUID is fully counted.
+	counted(ctx context.Context, docUUID gocql.UUID) (bool, error)
+	// countedWords returns the words of docUUID counted so far, with
+	// docindex.CollectionStatsWord for its collection_stats share.
+	countedWords(ctx context.Context, docUUID gocql.UUID) (map[string]bool, error)
+	addCollectionStats(ctx context.Context, tokens, titleTokens int) error
+	addWordStats(ctx context.Context, word string, occurrences int) error
+	markWordCounted(ctx context.Context, docUUID gocql.UUID, word string) error
+	// markCounted marks docUUID fully counted and forgets its counted
+	// words.
+	markCounted(ctx context.Context, docUUID gocql.UUID) error
+}
+
+// updateWordStats adds the document's words to word_stats, and its length to
+// the global collection_stats row, once per doc_id. Counters can't be written
+// idempotently, so each one is recorded in word_stats_applied_words as soon
+// as it has moved, and the document is marked counted in word_stats_applied
+// only once all of them have. A retried or redelivered job skips what was
+// counted already, so a failure part-way neither loses nor doubles counts.
+// Only a failure between a counter write and its record counts that word
+// twice.
+func (w *IndexingWorker) updateWordStats(ctx context.Context, docID string, tokens []tokenizer.Token, titleTokens int) error {
+	docUUID, err := gocql.ParseUUID(docID)
+	if err != nil {
+		return fmt.Errorf("invalid doc_id %q: %w", docID, err)
+	}
+
+	counted, err := w.stats.counted(ctx, docUUID)
+	if err != nil {
+		return fmt.Errorf("failed to check word stats for %s: %w", docID, err)
+	}
+	if counted {
+		slog.Debug("Word stats already counted, skipping", "doc_id", docID)
+		return nil
+	}
+	done, err := w.stats.countedWords(ctx, docUUID)
+	if err != nil {
+		return fmt.Errorf("failed to load counted words for %s: %w", docID, err)
+	}
+
+	if !done[docindex.CollectionStatsWord] {
+		if err := w.stats.addCollectionStats(ctx, len(tokens), titleTokens); err != nil {
+			return fmt.Errorf("failed to update collection stats: %w", err)
+		}
+		if err := w.stats.markWordCounted(ctx, docUUID, docindex.CollectionStatsWord); err != nil {
+			return fmt.Errorf("failed to record collection stats for %s: %w", docID, err)
+		}
+	}
+
+	uniqueWords := make(map[string]int)
+	for _, token := range tokens {
+		if !done[token.Word] {
+			uniqueWords[token.Word]++
+		}
+	}
+
+	const batchSize = 100
+	sem := make(chan struct{}, w.batchWorkers)
+	var wg sync.WaitGroup
+	errChan := make(chan error, (len(uniqueWords)+batchSize-1)/batchSize)
+
+	wordList := make([]string, 0, len(uniqueWords))
+	freqList := make([]int, 0, len(uniqueWords))
+	for word, freq := range uniqueWords {
+		wordList = append(wordList, word)
+		freqList = append(freqList, freq)
+	}
+
+	for i := 0; i < len(wordList); i += batchSize {
+		end := min(i+batchSize, len(wordList))
+		batchWords := wordList[i:end]
+		batchFreqs := freqList[i:end]
+
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+			return ctx.Err()
+		}
+
+		wg.Add(1)
+		go func(words []string, freqs []int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			select {
+			case <-ctx.Done():
+				return
+			default:
+			}
+			if err := w.updateWordStatsBatch(ctx, docUUID, words, freqs); err != nil {
+				errChan <- err
+			}
+		}(batchWords, batchFreqs)
+	}
+
+	wg.Wait()
+	close(errChan)
+
+	for err := range errChan {
+		if err != nil {
+			return err
+		}
+	}
+	// Batches skipped because ctx was done report no error of their own.
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if err := w.stats.markCounted(ctx, docUUID); err != nil {
+		return fmt.Errorf("failed to mark word stats for %s: %w", docID, err)
+	}
+	return nil
+}
+
+func (w *IndexingWorker) updateWordStatsBatch(ctx context.Context, docUUID gocql.UUID, words []string, freqs []int) error {
+	for i, word := range words {
+		if err := w.stats.addWordStats(ctx, word, freqs[i]); err != nil {
+			return fmt.Errorf("failed to update stats for word %q: %w", word, err)
+		}
+		if err := w.stats.markWordCounted(ctx, docUUID, word); err != nil {
+			return fmt.Errorf("failed to record stats for word %q: %w", word, err)
+		}
+	}
+	return nil
+}
+
+// scyllaStats is the statsStore on the word_stats, collection_stats,
+// word_stats_applied and word_stats_applied_words tables.
+type scyllaStats struct {
+	session *gocql.Session
+}
+
+func (s scyllaStats) counted(ctx context.Context, docUUID gocql.UUID) (bool, error) {
+	var appliedAt time.Time
+	err := s.session.Query(`SELECT applied_at FROM word_stats_applied WHERE doc_id = ?`, docUUID).
+		WithContext(ctx).Scan(&appliedAt)
+	if err == gocql.ErrNotFound {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (s scyllaStats) countedWords(ctx context.Context, docUUID gocql.UUID) (map[string]bool, error) {
+	return docindex.CountedWords(ctx, s.session, docUUID)
+}
+
+func (s scyllaStats) addCollectionStats(ctx context.Context, tokens, titleTokens int) error {
+	return s.session.Query(`
+		UPDATE collection_stats
+		SET total_documents = total_documents + 1,
+		    total_tokens = total_tokens + ?,
+		    total_title_tokens = total_title_tokens + ?
+		WHERE name = ?
+	`, tokens, titleTokens, scylladb.CollectionStatsRow).WithContext(ctx).Exec()
+}
+
+func (s scyllaStats) addWordStats(ctx context.Context, word string, occurrences int) error {
+	return s.session.Query(`
+		UPDATE word_stats
+		SET doc_count = doc_count + 1,
+		    total_occurrences = total_occurrences + ?
+		WHERE word = ?
+	`, occurrences, word).WithContext(ctx).Exec()
+}
+
+func (s scyllaStats) markWordCounted(ctx context.Context, docUUID gocql.UUID, word string) error {
+	return s.session.Query(`INSERT INTO word_stats_applied_words (doc_id, word) VALUES (?, ?)`, docUUID, word).
+		WithContext(ctx).Exec()
+}
+
+func (s scyllaStats) markCounted(ctx context.Context, docUUID gocql.UUID) error {
+	if err := s.session.Query(`INSERT INTO word_stats_applied (doc_id, applied_at) VALUES (?, ?)`, docUUID, time.Now()).
+		WithContext(ctx).Exec(); err != nil {
+		return err
+	}
+	// Left behind, the rows are only dropped when the document is removed.
+	if err := s.session.Query(`DELETE FROM word_stats_applied_words WHERE doc_id = ?`, docUUID).
+		WithContext(ctx).Exec(); err != nil {
+		slog.WarnContext(ctx, "Failed to drop counted words", "doc_id", docUUID.String(), "error", err)
+	}
+	return nil
+}
@@ -0,0 +1,249 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/amrrdev/trawl/services/shared/tokenizer"
+	"github.com/gocql/gocql"
+)
+
+var errInjected = errors.New("injected failure")
+
+// memoryStats is an in-memory statsStore. fail, when set, is consulted
+// before every write and fails it by returning an error.
+type memoryStats struct {
+	mu          sync.Mutex
+	documents   int
+	tokens      int
+	docCounts   map[string]int
+	occurrences map[string]int
+	words       map[gocql.UUID]map[string]bool
+	done        map[gocql.UUID]bool
+	fail        func(op, word string) error
+}
+
+func newMemoryStats() *memoryStats {
+	return &memoryStats{
+		docCounts:   make(map[string]int),
+		occurrences: make(map[string]int),
+		words:       make(map[gocql.UUID]map[string]bool),
+		done:        make(map[gocql.UUID]bool),
+	}
+}
+
+func (m *memoryStats) check(op, word string) error {
+	if m.fail == nil {
+		return nil
+	}
+	return m.fail(op, word)
+}
+
+func (m *memoryStats) counted(ctx context.Context, docUUID gocql.UUID) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.done[docUUID], nil
+}
+
+func (m *memoryStats) countedWords(ctx context.Context, docUUID gocql.UUID) (map[string]bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	words := make(map[string]bool, len(m.words[docUUID]))
+	for w := range m.words[docUUID] {
+		words[w] = true
+	}
+	return words, nil
+}
+
+func (m *memoryStats) addCollectionStats(ctx context.Context, tokens, titleTokens int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check("collection", ""); err != nil {
+		return err
+	}
+	m.documents++
+	m.tokens += tokens
+	return nil
+}
+
+func (m *memoryStats) addWordStats(ctx context.Context, word string, occurrences int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check("word", word); err != nil {
+		return err
+	}
+	m.docCounts[word]++
+	m.occurrences[word] += occurrences
+	return nil
+}
+
+func (m *memoryStats) markWordCounted(ctx context.Context, docUUID gocql.UUID, word string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check("mark word", word); err != nil {
+		return err
+	}
+	if m.words[docUUID] == nil {
+		m.words[docUUID] = make(map[string]bool)
+	}
+	m.words[docUUID][word] = true
+	return nil
+}
+
+func (m *memoryStats) markCounted(ctx context.Context, docUUID gocql.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.check("mark", ""); err != nil {
+		return err
+	}
+	m.done[docUUID] = true
+	delete(m.words, docUUID)
+	return nil
+}
+
+// failOnce fails the first write matching op and word.
+func failOnce(op, word string) func(string, string) error {
+	failed := false
+	return func(gotOp, gotWord string) error {
+		if failed || gotOp != op || gotWord != word {
+			return nil
+		}
+		failed = true
+		return errInjected
+	}
+}
+
+func tokensOf(words ...string) []tokenizer.Token {
+	tokens := make([]tokenizer.Token, len(words))
+	for i, w := range words {
+		tokens[i] = tokenizer.Token{Word: w, Position: i}
+	}
+	return tokens
+}
+
+func TestUpdateWordStatsCountsOnce(t *testing.T) {
+	docID := gocql.MustRandomUUID()
+	tokens := tokensOf("alpha", "beta", "alpha", "gamma", "delta")
+	wantOccurrences := map[string]int{"alpha": 2, "beta": 1, "gamma": 1, "delta": 1}
+
+	tests := []struct {
+		name string
+		// fail injects a failure into the first delivery; the job is then
+		// delivered again.
+		fail func(op, word string) error
+	}{
+		{"no failure", nil},
+		{"collection stats fail", failOnce("collection", "")},
+		{"word counter fails", failOnce("word", "gamma")},
+		{"last word counter fails", failOnce("word", "delta")},
+		{"final marker fails", failOnce("mark", "")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stats := newMemoryStats()
+			stats.fail = tt.fail
+			w := &IndexingWorker{stats: stats, batchWorkers: 1}
+
+			err := w.updateWordStats(context.Background(), docID.String(), tokens, 0)
+			if tt.fail != nil {
+				if !errors.Is(err, errInjected) {
+					t.Fatalf("first delivery: err = %v, want the injected failure", err)
+				}
+				if stats.done[docID] {
+					t.Fatal("document marked counted after a failure")
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+
+			// Redelivery, and a further duplicate delivery, must not count
+			// anything again.
+			for range 2 {
+				if err := w.updateWordStats(context.Background(), docID.String(), tokens, 0); err != nil {
+					t.Fatalf("redelivery: %v", err)
+				}
+			}
+
+			if !stats.done[docID] {
+				t.Error("document not marked counted")
+			}
+			if stats.documents != 1 || stats.tokens != len(tokens) {
+				t.Errorf("collection stats = %d documents, %d tokens; want 1, %d", stats.documents, stats.tokens, len(tokens))
+			}
+			for word, want := range wantOccurrences {
+				if stats.docCounts[word] != 1 || stats.occurrences[word] != want {
+					t.Errorf("%s: doc_count %d, occurrences %d; want 1, %d",
+						word, stats.docCounts[word], stats.occurrences[word], want)
+				}
+			}
+		})
+	}
+}
+
+// concurrencyStats records the most addWordStats calls in flight at once.
+type concurrencyStats struct {
+	*memoryStats
+	inFlight, peak atomic.Int32
+}
+
+func (c *concurrencyStats) addWordStats(ctx context.Context, word string, occurrences int) error {
+	n := c.inFlight.Add(1)
+	defer c.inFlight.Add(-1)
+	for {
+		peak := c.peak.Load()
+		if n <= peak || c.peak.CompareAndSwap(peak, n) {
+			break
+		}
+	}
+	time.Sleep(10 * time.Microsecond)
+	return c.memoryStats.addWordStats(ctx, word, occurrences)
+}
+
+func TestUpdateWordStatsBatchConcurrency(t *testing.T) {
+	words := make([]string, 1000)
+	for i := range words {
+		words[i] = fmt.Sprintf("word%d", i)
+	}
+	tokens := tokensOf(words...)
+
+	for _, workers := range []int{1, 3} {
+		t.Run(fmt.Sprintf("%d workers", workers), func(t *testing.T) {
+			stats := &concurrencyStats{memoryStats: newMemoryStats()}
+			w := &IndexingWorker{stats: stats, batchWorkers: workers}
+
+			if err := w.updateWordStats(context.Background(), gocql.MustRandomUUID().String(), tokens, 0); err != nil {
+				t.Fatal(err)
+			}
+			if peak := int(stats.peak.Load()); peak > workers {
+				t.Errorf("%d batches in flight, want at most %d", peak, workers)
+			}
+			if len(stats.docCounts) != len(words) {
+				t.Errorf("counted %d words, want %d", len(stats.docCounts), len(words))
+			}
+		})
+	}
+}
+
+func TestWithBatchConcurrency(t *testing.T) {
+	tests := []struct {
+		n    int
+		want int
+	}{
+		{4, 4},
+		{1, 1},
+		{0, defaultBatchWorkers},
+		{-3, defaultBatchWorkers},
+	}
+	for _, tt := range tests {
+		w := &IndexingWorker{batchWorkers: defaultBatchWorkers}
+		WithBatchConcurrency(tt.n)(w)
+		if w.batchWorkers != tt.want {
+			t.Errorf("WithBatchConcurrency(%d): batchWorkers = %d, want %d", tt.n, w.batchWorkers, tt.want)
+		}
+	}
+}
@@ -1,42 +1,304 @@
 package handler
 
 import (
+	"fmt"
+	"log/slog"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/amrrdev/trawl/services/search/internal/service"
+	"github.com/amrrdev/trawl/services/shared/apierror"
+	"github.com/amrrdev/trawl/services/shared/middleware"
 	"github.com/gin-gonic/gin"
 )
 
+// DefaultMaxQueryBytes bounds the raw query length accepted by Search.
+const DefaultMaxQueryBytes = 1024
+
 type SearchHandler struct {
 	searchService *service.Search
+	maxQueryBytes int
 }
 
-func NewSearchHandler(searchService *service.Search) *SearchHandler {
+func NewSearchHandler(searchService *service.Search, maxQueryBytes int) *SearchHandler {
+	if maxQueryBytes <= 0 {
+		maxQueryBytes = DefaultMaxQueryBytes
+	}
 	return &SearchHandler{
 		searchService: searchService,
+		maxQueryBytes: maxQueryBytes,
 	}
 }
 
+// SearchRequest is the JSON body of a POST search, or the query string of a
+// GET one ("?q=...&page=2"), where lists may also be comma-separated.
 type SearchRequest struct {
-	Query string `json:"query" binding:"required"`
+	Query       string   `json:"query" form:"q" binding:"required"`
+	Operator    string   `json:"operator" form:"operator"`
+	Page        int      `json:"page" form:"page"`
+	PageSize    int      `json:"page_size" form:"page_size"`
+	K1          *float64 `json:"k1" form:"k1"`
+	B           *float64 `json:"b" form:"b"`
+	Fields      []string `json:"fields" form:"fields"`
+	Sort        []string `json:"sort" form:"sort"`
+	Languages   []string `json:"languages" form:"languages"`
+	MatchFields []string `json:"match_fields" form:"match_fields"`
+	Fuzzy       bool     `json:"fuzzy" form:"fuzzy"`
+	Author      string   `json:"author" form:"author"`
+	// Scope is "all" (default) or "mine", for the caller's documents only.
+	Scope string `json:"scope" form:"scope"`
+	// MinScore drops results with a lower BM25 score; MinScoreRatio drops
+	// those scoring below that fraction (0-1) of the top result.
+	MinScore      float64 `json:"min_score" form:"min_score"`
+	MinScoreRatio float64 `json:"min_score_ratio" form:"min_score_ratio"`
+	// From and To bound the documents' indexing time. Both are RFC 3339
+	// timestamps, e.g. "2024-01-31T00:00:00Z".
+	From *time.Time `json:"from" form:"from"`
+	To   *time.Time `json:"to" form:"to"`
+
+	// includeURLs comes from the include_urls query parameter.
+	includeURLs bool
+	// explain comes from the explain query parameter.
+	explain bool
+	// highlights comes from the highlights query parameter.
+	highlights bool
 }
 
-type SearchResponse struct {
-	Results []service.SearchResult `json:"results"`
+func (r *SearchRequest) options(c *gin.Context) service.SearchOptions {
+	return service.SearchOptions{
+		UserID:           middleware.GetUserID(c),
+		Operator:         r.Operator,
+		Page:             r.Page,
+		PageSize:         r.PageSize,
+		K1:               r.K1,
+		B:                r.B,
+		Fields:           r.Fields,
+		Sort:             r.Sort,
+		Languages:        r.Languages,
+		MatchFields:      r.MatchFields,
+		Fuzzy:            r.Fuzzy,
+		Author:           r.Author,
+		Scope:            r.Scope,
+		From:             r.From,
+		To:               r.To,
+		MinScore:         r.MinScore,
+		MinScoreRatio:    r.MinScoreRatio,
+		OmitDownloadURLs: !r.includeURLs,
+		Explain:          r.explain,
+		Highlights:       r.highlights,
+	}
 }
 
-func (h *SearchHandler) Search(c *gin.Context) {
+// bindSearchRequest parses and validates the request body, or the query
+// string of a GET, writing a 400 and returning false when it is unusable.
+func (h *SearchHandler) bindSearchRequest(c *gin.Context) (*SearchRequest, bool) {
 	var req SearchRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if c.Request.Method == http.MethodGet {
+		if err := c.ShouldBindQuery(&req); err != nil {
+			apierror.Write(c, http.StatusBadRequest, err.Error())
+			return nil, false
+		}
+		req.Fields = splitList(req.Fields)
+		req.Sort = splitList(req.Sort)
+		req.Languages = splitList(req.Languages)
+		req.MatchFields = splitList(req.MatchFields)
+	} else if err := c.ShouldBindJSON(&req); err != nil {
+		apierror.Write(c, http.StatusBadRequest, err.Error())
+		return nil, false
+	}
+
+	if len(req.Query) > h.maxQueryBytes {
+		apierror.Write(c, http.StatusBadRequest, fmt.Sprintf("query exceeds maximum length of %d bytes", h.maxQueryBytes))
+		return nil, false
+	}
+
+	includeURLs, err := strconv.ParseBool(c.DefaultQuery("include_urls", "true"))
+	if err != nil {
+		apierror.Write(c, http.StatusBadRequest, "include_urls must be a boolean")
+		return nil, false
+	}
+	req.includeURLs = includeURLs
+
+	explain, err := strconv.ParseBool(c.DefaultQuery("explain", "false"))
+	if err != nil {
+		apierror.Write(c, http.StatusBadRequest, "explain must be a boolean")
+		return nil, false
+	}
+	req.explain = explain
+
+	highlights, err := strconv.ParseBool(c.DefaultQuery("highlights", "false"))
+	if err != nil {
+		apierror.Write(c, http.StatusBadRequest, "highlights must be a boolean")
+		return nil, false
+	}
+	req.highlights = highlights
+
+	return &req, true
+}
+
+// splitList splits comma-separated query parameter values, so
+// "fields=title,score" works like "fields=title&fields=score".
+func splitList(values []string) []string {
+	var list []string
+	for _, value := range values {
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+	}
+	return list
+}
+
+func (h *SearchHandler) Search(c *gin.Context) {
+	req, ok := h.bindSearchRequest(c)
+	if !ok {
+		return
+	}
+
+	resp, err := h.searchService.Search(c.Request.Context(), req.Query, req.options(c))
+	if err != nil {
+		apierror.Respond(c, err, "Search failed")
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// SearchStream runs a search and streams it back as Server-Sent Events: a
+// "partial" event with the provisional ranking each time a shard answers,
+// then a single "result" event with the complete response (or "error").
+func (h *SearchHandler) SearchStream(c *gin.Context) {
+	req, ok := h.bindSearchRequest(c)
+	if !ok {
+		return
+	}
+
+	streaming := false
+	startStream := func() {
+		if streaming {
+			return
+		}
+		streaming = true
+		c.Header("Content-Type", "text/event-stream")
+		c.Header("Cache-Control", "no-cache")
+		c.Header("Connection", "keep-alive")
+		c.Status(http.StatusOK)
+	}
+
+	// The request context is cancelled when the client disconnects, which
+	// stops the shard fan-out.
+	resp, err := h.searchService.SearchStream(c.Request.Context(), req.Query, req.options(c),
+		func(partial []service.SearchResult) {
+			startStream()
+			c.SSEvent("partial", gin.H{"results": partial})
+			c.Writer.Flush()
+		})
+	if err != nil {
+		if !streaming {
+			apierror.Respond(c, err, "Search failed")
+			return
+		}
+		status, body := apierror.FromError(err, "Search failed")
+		if status == http.StatusInternalServerError {
+			slog.ErrorContext(c.Request.Context(), "Search failed", "error", err)
+		}
+		c.SSEvent("error", body)
+		c.Writer.Flush()
+		return
+	}
+
+	startStream()
+	c.SSEvent("result", resp)
+	c.Writer.Flush()
+}
+
+func (h *SearchHandler) TermPostings(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+	word := c.Param("word")
+
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil {
+		apierror.Write(c, http.StatusBadRequest, "offset must be an integer")
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(service.DefaultPostingsLimit)))
+	if err != nil {
+		apierror.Write(c, http.StatusBadRequest, "limit must be an integer")
+		return
+	}
+	withPositions, err := strconv.ParseBool(c.DefaultQuery("positions", "true"))
+	if err != nil {
+		apierror.Write(c, http.StatusBadRequest, "positions must be a boolean")
+		return
+	}
+
+	resp, err := h.searchService.TermPostings(c.Request.Context(), userID, word, offset, limit, withPositions)
+	if err != nil {
+		apierror.Respond(c, err, "Failed to get postings")
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// Similar returns the documents most like the one in the docID path
+// parameter, at most "limit" of them.
+func (h *SearchHandler) Similar(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(service.DefaultSimilarLimit)))
+	if err != nil {
+		apierror.Write(c, http.StatusBadRequest, "limit must be an integer")
+		return
+	}
+
+	resp, err := h.searchService.Similar(c.Request.Context(), middleware.GetUserID(c), c.Param("docID"), limit)
+	if err != nil {
+		apierror.Respond(c, err, "Failed to find similar documents")
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// Suggest returns completions for the "prefix" query parameter.
+func (h *SearchHandler) Suggest(c *gin.Context) {
+	resp, err := h.searchService.Suggest(c.Request.Context(), c.Query("prefix"))
+	if err != nil {
+		apierror.Respond(c, err, "Failed to get suggestions")
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *SearchHandler) GetPreferences(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+
+	prefs, err := h.searchService.GetPreferences(c.Request.Context(), userID)
+	if err != nil {
+		apierror.Respond(c, err, "Failed to get preferences")
+		return
+	}
+
+	c.JSON(http.StatusOK, prefs)
+}
+
+func (h *SearchHandler) UpdatePreferences(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+
+	var prefs service.Preferences
+	if err := c.ShouldBindJSON(&prefs); err != nil {
+		apierror.Write(c, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	results, err := h.searchService.Search(c.Request.Context(), req.Query)
+	saved, err := h.searchService.SetPreferences(c.Request.Context(), userID, &prefs)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		apierror.Respond(c, err, "Failed to update preferences")
 		return
 	}
 
-	c.JSON(http.StatusOK, SearchResponse{Results: results})
+	c.JSON(http.StatusOK, saved)
 }
@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// bind runs bindSearchRequest on req and returns the response status, 200
+// when the request was accepted.
+func bind(h *SearchHandler, req *http.Request) (*SearchRequest, int) {
+	rec := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(rec)
+	c.Request = req
+	parsed, ok := h.bindSearchRequest(c)
+	if !ok {
+		return nil, rec.Code
+	}
+	return parsed, http.StatusOK
+}
+
+func TestBindSearchRequestQueryLength(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := NewSearchHandler(nil, 10)
+
+	get := func(q string) *http.Request {
+		return httptest.NewRequest(http.MethodGet, "/search?q="+url.QueryEscape(q), nil)
+	}
+	post := func(body string) *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/search", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		return req
+	}
+
+	tests := []struct {
+		name string
+		req  *http.Request
+		want int
+	}{
+		{"GET within limit", get("go search"), http.StatusOK},
+		{"GET at limit", get("0123456789"), http.StatusOK},
+		{"GET over limit", get("0123456789a"), http.StatusBadRequest},
+		{"limit counts bytes", get("ééééé1"), http.StatusBadRequest},
+		{"POST within limit", post(`{"query":"go"}`), http.StatusOK},
+		{"POST over limit", post(`{"query":"a much longer query"}`), http.StatusBadRequest},
+		{"missing query", get(""), http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, got := bind(h, tt.req); got != tt.want {
+				t.Errorf("status = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewSearchHandlerDefaultMaxQueryBytes(t *testing.T) {
+	for _, n := range []int{0, -1} {
+		if h := NewSearchHandler(nil, n); h.maxQueryBytes != DefaultMaxQueryBytes {
+			t.Errorf("NewSearchHandler(%d).maxQueryBytes = %d, want %d", n, h.maxQueryBytes, DefaultMaxQueryBytes)
+		}
+	}
+}
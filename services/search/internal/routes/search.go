@@ -10,6 +10,14 @@ func RegisterRoutes(router *gin.RouterGroup, searchHandler *handler.SearchHandle
 	search := router.Group("/search")
 	search.Use(authMiddleware.RequireAuth())
 	{
+		search.GET("", searchHandler.Search)
 		search.POST("", searchHandler.Search)
+		search.POST("/stream", searchHandler.SearchStream)
+		search.GET("/term/:word", searchHandler.TermPostings)
+		search.GET("/preferences", searchHandler.GetPreferences)
+		search.PUT("/preferences", searchHandler.UpdatePreferences)
 	}
+
+	router.GET("/suggest", authMiddleware.RequireAuth(), searchHandler.Suggest)
+	router.GET("/documents/:docID/similar", authMiddleware.RequireAuth(), searchHandler.Similar)
 }
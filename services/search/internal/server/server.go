@@ -3,12 +3,18 @@ package server
 import (
 	"github.com/amrrdev/trawl/services/search/internal/handler"
 	"github.com/amrrdev/trawl/services/search/internal/routes"
+	"github.com/amrrdev/trawl/services/shared/metrics"
 	"github.com/amrrdev/trawl/services/shared/middleware"
+	"github.com/amrrdev/trawl/services/shared/tracing"
 	"github.com/gin-gonic/gin"
 )
 
-func NewServer(searchHandler *handler.SearchHandler, authMiddleware *middleware.AuthMiddleware) *gin.Engine {
-	g := gin.Default()
+func NewServer(searchHandler *handler.SearchHandler, authMiddleware *middleware.AuthMiddleware, corsConfig middleware.CORSConfig) *gin.Engine {
+	g := gin.New()
+	g.Use(tracing.Middleware("trawl-search"))
+	g.Use(middleware.RequestID(), middleware.Logger(), gin.Recovery())
+	g.Use(middleware.CORS(corsConfig))
+	g.GET("/metrics", gin.WrapH(metrics.Handler()))
 	api := g.Group("/api/v1")
 	routes.RegisterRoutes(api, searchHandler, authMiddleware)
 	return g
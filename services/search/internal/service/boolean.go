@@ -0,0 +1,114 @@
+package service
+
+import (
+	"strings"
+
+	"github.com/amrrdev/trawl/services/shared/tokenizer"
+)
+
+// Boolean operators. They must be written in upper case: lower-case "and",
+// "or" and "not" are stopwords and searched for (or dropped) as usual.
+const (
+	boolAnd = "AND"
+	boolOr  = "OR"
+	boolNot = "NOT"
+)
+
+// parseBoolean reads the unquoted part of a query. AND binds tighter than OR,
+// and terms with no operator between them are OR-ed, so `a b AND c` means
+// a OR (b AND c). The resulting groups are the disjuncts: a document matches
+// when it contains every term of at least one group. NOT excludes documents
+// containing the word that follows it.
+func (q *parsedQuery) parseBoolean(tk *tokenizer.Tokenizer, text string, addTerm func(string)) {
+	connector := ""
+	negate := false
+	var group []string
+	for _, field := range strings.Fields(text) {
+		switch field {
+		case boolAnd, boolOr:
+			connector = field
+			continue
+		case boolNot:
+			negate = true
+			continue
+		}
+
+		toks := tk.Tokenize(field)
+		if negate {
+			for _, t := range toks {
+				q.excluded = append(q.excluded, t.Word)
+			}
+			negate = false
+			continue
+		}
+		for _, t := range toks {
+			addTerm(t.Word)
+			if connector != boolAnd && len(group) > 0 {
+				q.groups = append(q.groups, group)
+				group = nil
+			}
+			group = append(group, t.Word)
+			connector = ""
+		}
+	}
+	if len(group) > 0 {
+		q.groups = append(q.groups, group)
+	}
+}
+
+// conjunctive reports whether the query has an AND group. Without one every
+// group is a single term, and any candidate document matches one of them.
+func (q parsedQuery) conjunctive() bool {
+	for _, g := range q.groups {
+		if len(g) > 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesGroups reports whether a document containing docTerms satisfies the
+// query's boolean groups.
+func (q parsedQuery) matchesGroups(docTerms map[string]bool) bool {
+	if len(q.groups) == 0 {
+		return true
+	}
+	for _, g := range q.groups {
+		all := true
+		for _, term := range g {
+			if !docTerms[term] {
+				all = false
+				break
+			}
+		}
+		if all {
+			return true
+		}
+	}
+	return false
+}
+
+// dropTerms removes terms from the query's groups, e.g. after pruning. A
+// group left empty is dropped rather than matching every document.
+func (q *parsedQuery) dropTerms(terms []string) {
+	if len(terms) == 0 {
+		return
+	}
+	drop := make(map[string]bool, len(terms))
+	for _, t := range terms {
+		drop[t] = true
+	}
+	groups := q.groups[:0]
+	for _, g := range q.groups {
+		kept := g[:0]
+		for _, term := range g {
+			if !drop[term] {
+				kept = append(kept, term)
+			}
+		}
+		if len(kept) > 0 {
+			groups = append(groups, kept)
+		}
+	}
+	q.groups = groups
+}
@@ -0,0 +1,76 @@
+package service
+
+import "sort"
+
+const (
+	FacetAuthor   = "author"
+	FacetFileType = "file_type"
+)
+
+// facetCounter accumulates per-field value counts over a candidate set.
+type facetCounter struct {
+	fields []string
+	counts map[string]map[string]int
+}
+
+func newFacetCounter(fields []string) *facetCounter {
+	counts := make(map[string]map[string]int, len(fields))
+	for _, f := range fields {
+		counts[f] = make(map[string]int)
+	}
+	return &facetCounter{fields: fields, counts: counts}
+}
+
+func (f *facetCounter) enabled() bool {
+	return len(f.fields) > 0
+}
+
+func (f *facetCounter) add(doc *documentResult) {
+	for _, field := range f.fields {
+		var value string
+		switch field {
+		case FacetAuthor:
+			value = doc.Author
+		case FacetFileType:
+			value = doc.FileType
+		}
+		if value == "" {
+			value = "unknown"
+		}
+		f.counts[field][value]++
+	}
+}
+
+// result returns the counts, keeping only the maxValues most frequent values
+// per field. A non-positive maxValues keeps everything.
+func (f *facetCounter) result(maxValues int) map[string]map[string]int {
+	if !f.enabled() {
+		return nil
+	}
+
+	out := make(map[string]map[string]int, len(f.counts))
+	for field, values := range f.counts {
+		if maxValues <= 0 || len(values) <= maxValues {
+			out[field] = values
+			continue
+		}
+
+		keys := make([]string, 0, len(values))
+		for k := range values {
+			keys = append(keys, k)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			if values[keys[i]] != values[keys[j]] {
+				return values[keys[i]] > values[keys[j]]
+			}
+			return keys[i] < keys[j]
+		})
+
+		capped := make(map[string]int, maxValues)
+		for _, k := range keys[:maxValues] {
+			capped[k] = values[k]
+		}
+		out[field] = capped
+	}
+	return out
+}
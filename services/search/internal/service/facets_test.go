@@ -0,0 +1,57 @@
+package service
+
+import (
+	"maps"
+	"testing"
+)
+
+func TestFacetCounter(t *testing.T) {
+	docs := []*documentResult{
+		{Author: "Alice", FileType: "pdf"},
+		{Author: "Alice", FileType: "docx"},
+		{Author: "Bob", FileType: "pdf"},
+		{Author: "Carol", FileType: "pdf"},
+		{FileType: "txt"},
+	}
+
+	tests := []struct {
+		name      string
+		fields    []string
+		maxValues int
+		want      map[string]map[string]int
+	}{
+		{"disabled", nil, 0, nil},
+		{"every value", []string{FacetAuthor, FacetFileType}, 0, map[string]map[string]int{
+			FacetAuthor:   {"Alice": 2, "Bob": 1, "Carol": 1, "unknown": 1},
+			FacetFileType: {"pdf": 3, "docx": 1, "txt": 1},
+		}},
+		{"most frequent values, ties by name", []string{FacetAuthor, FacetFileType}, 2, map[string]map[string]int{
+			FacetAuthor:   {"Alice": 2, "Bob": 1},
+			FacetFileType: {"pdf": 3, "docx": 1},
+		}},
+		{"cap above the value count", []string{FacetFileType}, 5, map[string]map[string]int{
+			FacetFileType: {"pdf": 3, "docx": 1, "txt": 1},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			counter := newFacetCounter(tt.fields)
+			if counter.enabled() != (len(tt.fields) > 0) {
+				t.Errorf("enabled = %v with fields %v", counter.enabled(), tt.fields)
+			}
+			if counter.enabled() {
+				for _, doc := range docs {
+					counter.add(doc)
+				}
+			}
+
+			got := counter.result(tt.maxValues)
+			if !maps.EqualFunc(got, tt.want, maps.Equal) {
+				t.Errorf("facets = %v, want %v", got, tt.want)
+			}
+			if tt.want == nil && got != nil {
+				t.Errorf("facets = %v, want nil", got)
+			}
+		})
+	}
+}
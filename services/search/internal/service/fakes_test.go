@@ -0,0 +1,123 @@
+package service
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"sort"
+	"sync"
+
+	"github.com/amrrdev/trawl/services/shared/tokenizer"
+)
+
+// memoryIndex is an in-memory ScyllaClient over the body text of a few
+// documents, tokenized the way the indexer does. It has no title postings.
+type memoryIndex struct {
+	postings map[string][]DocScore
+	docs     int
+	tokens   int
+
+	mu sync.Mutex
+	// positionFetches records withPositions for every GetPostings call.
+	positionFetches []bool
+}
+
+func newMemoryIndex(docs map[string]string) *memoryIndex {
+	tk := tokenizer.NewTokenizer()
+	idx := &memoryIndex{postings: make(map[string][]DocScore)}
+	for _, docID := range slices.Sorted(maps.Keys(docs)) {
+		tokens := tk.Tokenize(docs[docID])
+		idx.docs++
+		idx.tokens += len(tokens)
+
+		positions := make(map[string][]int)
+		for _, token := range tokens {
+			positions[token.Word] = append(positions[token.Word], token.Position)
+		}
+		for word, pos := range positions {
+			idx.postings[word] = append(idx.postings[word], DocScore{
+				DocID:     docID,
+				Term:      word,
+				TF:        len(pos),
+				DocLen:    len(tokens),
+				Positions: pos,
+			})
+		}
+	}
+	for word, postings := range idx.postings {
+		for i := range postings {
+			postings[i].DocFreq = len(postings)
+		}
+		idx.postings[word] = postings
+	}
+	return idx
+}
+
+func (m *memoryIndex) GetPostings(ctx context.Context, field string, shard int, terms []string, topN int, withPositions bool) (PostingsResponse, error) {
+	m.mu.Lock()
+	m.positionFetches = append(m.positionFetches, withPositions)
+	m.mu.Unlock()
+
+	resp := PostingsResponse{ShardID: shard, Field: field}
+	if field != MatchFieldBody {
+		return resp, nil
+	}
+	for _, term := range terms {
+		for _, posting := range m.postings[term] {
+			if !withPositions {
+				posting.Positions = nil
+			}
+			resp.Results = append(resp.Results, posting)
+		}
+		resp.DocCount += len(m.postings[term])
+	}
+	sort.SliceStable(resp.Results, func(i, j int) bool { return resp.Results[i].TF > resp.Results[j].TF })
+	if len(resp.Results) > topN {
+		resp.Results = resp.Results[:topN]
+	}
+	return resp, nil
+}
+
+func (m *memoryIndex) GetDocFreqs(ctx context.Context, terms []string) (map[string]int, error) {
+	freqs := make(map[string]int, len(terms))
+	for _, term := range terms {
+		freqs[term] = len(m.postings[term])
+	}
+	return freqs, nil
+}
+
+func (m *memoryIndex) GetCorpusSize(ctx context.Context) (int, error) {
+	return m.docs, nil
+}
+
+func (m *memoryIndex) GetCollectionStats(ctx context.Context) (CollectionStats, error) {
+	return CollectionStats{Documents: m.docs, Tokens: m.tokens}, nil
+}
+
+func (m *memoryIndex) GetVocabulary(ctx context.Context) (map[string]int, error) {
+	vocab := make(map[string]int, len(m.postings))
+	for word, postings := range m.postings {
+		vocab[word] = len(postings)
+	}
+	return vocab, nil
+}
+
+func (m *memoryIndex) DocsContaining(ctx context.Context, field string, terms []string, docIDs []string) (map[string]bool, error) {
+	found := make(map[string]bool)
+	if field != MatchFieldBody {
+		return found, nil
+	}
+	for _, term := range terms {
+		for _, posting := range m.postings[term] {
+			if slices.Contains(docIDs, posting.DocID) {
+				found[posting.DocID] = true
+			}
+		}
+	}
+	return found, nil
+}
+
+// DocsWithWordPair finds nothing: the index has no bigrams.
+func (m *memoryIndex) DocsWithWordPair(ctx context.Context, fields []string, pair string, docIDs []string) (map[string]bool, error) {
+	return map[string]bool{}, nil
+}
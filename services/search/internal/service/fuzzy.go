@@ -0,0 +1,198 @@
+package service
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// vocabularyTTL is how long the word_stats vocabulary is cached before a
+// fuzzy query reloads it.
+const vocabularyTTL = 10 * time.Minute
+
+// fuzzyExpansion is a vocabulary word standing in for a query term.
+type fuzzyExpansion struct {
+	term   string
+	weight float64
+}
+
+// vocabularyCache holds the indexed words (word -> document count) used to
+// find fuzzy expansions. The zero value is ready to use.
+type vocabularyCache struct {
+	mu       sync.Mutex
+	words    map[string]int
+	loadedAt time.Time
+}
+
+func (v *vocabularyCache) get(ctx context.Context, client ScyllaClient) (map[string]int, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.words != nil && time.Since(v.loadedAt) < vocabularyTTL {
+		return v.words, nil
+	}
+	words, err := client.GetVocabulary(ctx)
+	if err != nil {
+		return nil, err
+	}
+	v.words = words
+	v.loadedAt = time.Now()
+	return words, nil
+}
+
+// maxEditDistance is the edit distance allowed for a term: none for very
+// short terms, where almost every word is one edit away, then 1, then 2.
+func maxEditDistance(term string) int {
+	switch n := utf8.RuneCountInString(term); {
+	case n < 3:
+		return 0
+	case n < 6:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// expandFuzzy adds, for every non-phrase query term, up to FuzzyMaxExpansions
+// vocabulary words within maxEditDistance of it. The closest and most common
+// words are preferred. Each expansion is scored as its own term, weighted down
+// by FuzzyPenalty per edit, but counts as a match of the term it stands for.
+func (s *Searcher) expandFuzzy(ctx context.Context, q *parsedQuery) error {
+	if s.FuzzyMaxExpansions <= 0 {
+		return nil
+	}
+
+	inPhrase := q.phraseTerms()
+	inQuery := make(map[string]bool, len(q.terms))
+	for _, t := range q.terms {
+		inQuery[t] = true
+	}
+
+	var vocab map[string]int
+	for _, term := range q.terms {
+		maxDist := maxEditDistance(term)
+		if inPhrase[term] || maxDist == 0 {
+			continue
+		}
+		if vocab == nil {
+			var err error
+			if vocab, err = s.vocab.get(ctx, s.Client); err != nil {
+				return err
+			}
+		}
+
+		type candidate struct {
+			word     string
+			dist     int
+			docCount int
+		}
+		var candidates []candidate
+		termLen := utf8.RuneCountInString(term)
+		for word, docCount := range vocab {
+			if inQuery[word] || q.expansions[word].term != "" {
+				continue
+			}
+			if diff := utf8.RuneCountInString(word) - termLen; diff > maxDist || -diff > maxDist {
+				continue
+			}
+			if d := editDistance(term, word, maxDist); d <= maxDist {
+				candidates = append(candidates, candidate{word: word, dist: d, docCount: docCount})
+			}
+		}
+		sort.Slice(candidates, func(i, j int) bool {
+			if candidates[i].dist != candidates[j].dist {
+				return candidates[i].dist < candidates[j].dist
+			}
+			if candidates[i].docCount != candidates[j].docCount {
+				return candidates[i].docCount > candidates[j].docCount
+			}
+			return candidates[i].word < candidates[j].word
+		})
+		if len(candidates) > s.FuzzyMaxExpansions {
+			candidates = candidates[:s.FuzzyMaxExpansions]
+		}
+
+		for _, c := range candidates {
+			weight := 1 - s.FuzzyPenalty*float64(c.dist)
+			if weight <= 0 {
+				continue
+			}
+			if q.expansions == nil {
+				q.expansions = make(map[string]fuzzyExpansion)
+			}
+			q.expansions[c.word] = fuzzyExpansion{term: term, weight: weight}
+		}
+	}
+	return nil
+}
+
+// fetchTerms returns the terms whose postings a query needs: its own terms
+// followed by their fuzzy expansions.
+func (q parsedQuery) fetchTerms() []string {
+	if len(q.expansions) == 0 {
+		return q.terms
+	}
+	terms := append([]string(nil), q.terms...)
+	for word := range q.expansions {
+		terms = append(terms, word)
+	}
+	return terms
+}
+
+// origin returns the query term that postings of term count for, and the
+// weight of their score.
+func (q parsedQuery) origin(term string) (string, float64) {
+	if e, ok := q.expansions[term]; ok {
+		return e.term, e.weight
+	}
+	return term, 1
+}
+
+// expandedTerms lists the fuzzy expansions of each query term.
+func (q parsedQuery) expandedTerms() map[string][]string {
+	if len(q.expansions) == 0 {
+		return nil
+	}
+	out := make(map[string][]string)
+	for word, e := range q.expansions {
+		out[e.term] = append(out[e.term], word)
+	}
+	for _, words := range out {
+		sort.Strings(words)
+	}
+	return out
+}
+
+// editDistance returns the edit distance between a and b in runes, counting
+// insertions, deletions, substitutions and swaps of adjacent runes (the most
+// common typo), or limit+1 once it is known to exceed limit.
+func editDistance(a, b string, limit int) int {
+	ra, rb := []rune(a), []rune(b)
+	prevPrev := make([]int, len(rb)+1)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		rowMin := cur[0]
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				cur[j] = min(cur[j], prevPrev[j-2]+1)
+			}
+			rowMin = min(rowMin, cur[j])
+		}
+		if rowMin > limit {
+			return limit + 1
+		}
+		prevPrev, prev, cur = prev, cur, prevPrev
+	}
+	return prev[len(rb)]
+}
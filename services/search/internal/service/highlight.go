@@ -0,0 +1,82 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+
+	"github.com/gocql/gocql"
+)
+
+// MaxHighlightPositions caps the term positions returned per result, so
+// frequent terms in long documents don't bloat the response.
+const MaxHighlightPositions = 100
+
+// Highlight lists where a query term occurs in one field of a result.
+// Positions are token positions as stored by the indexer: the first indexed
+// word of the field is 0, and stopwords and single characters aren't counted.
+type Highlight struct {
+	Field     string `json:"field"`
+	Term      string `json:"term"`
+	Positions []int  `json:"positions"`
+}
+
+// highlights returns where the terms occur in docID's fields, read from the
+// stored postings. Only the first MaxHighlightPositions positions are kept,
+// fields in the order given. Like snippet, it returns nil rather than fail the
+// search when the postings can't be read.
+func (s *Search) highlights(ctx context.Context, docID string, fields []string, terms map[string]bool) []Highlight {
+	id, err := gocql.ParseUUID(docID)
+	if err != nil || len(terms) == 0 {
+		return nil
+	}
+	words := make([]string, 0, len(terms))
+	for t := range terms {
+		words = append(words, t)
+	}
+
+	type occurrence struct {
+		term     string
+		position int
+	}
+	var highlights []Highlight
+	remaining := MaxHighlightPositions
+	for _, field := range fields {
+		if remaining == 0 {
+			break
+		}
+		iter := s.scylladb.Session.Query(`SELECT word, positions FROM `+postingsTable(field)+` WHERE word IN ? AND doc_id = ?`, words, id).
+			WithContext(ctx).Iter()
+		var occurrences []occurrence
+		var word string
+		var positions []int
+		for iter.Scan(&word, &positions) {
+			for _, p := range positions {
+				occurrences = append(occurrences, occurrence{word, p})
+			}
+			positions = nil
+		}
+		if err := iter.Close(); err != nil {
+			slog.WarnContext(ctx, "Failed to load term positions", "doc_id", docID, "field", field, "error", err)
+			return nil
+		}
+
+		sort.Slice(occurrences, func(i, j int) bool {
+			return occurrences[i].position < occurrences[j].position
+		})
+		occurrences = occurrences[:min(remaining, len(occurrences))]
+		remaining -= len(occurrences)
+
+		byTerm := make(map[string]int)
+		for _, o := range occurrences {
+			i, ok := byTerm[o.term]
+			if !ok {
+				i = len(highlights)
+				byTerm[o.term] = i
+				highlights = append(highlights, Highlight{Field: field, Term: o.term})
+			}
+			highlights[i].Positions = append(highlights[i].Positions, o.position)
+		}
+	}
+	return highlights
+}
@@ -0,0 +1,187 @@
+package service
+
+import (
+	"context"
+	"regexp"
+
+	"github.com/amrrdev/trawl/services/shared/scylladb"
+	"github.com/amrrdev/trawl/services/shared/tokenizer"
+)
+
+var quotedSegment = regexp.MustCompile(`"([^"]*)"`)
+
+// parsedQuery is a query split into its distinct terms and its quoted
+// phrases. Phrase terms are included in terms so they are scored like any
+// other term.
+type parsedQuery struct {
+	terms   []string
+	phrases [][]string
+	// groups is the boolean structure of the unquoted terms (see
+	// parseBoolean); excluded holds the terms negated with NOT.
+	groups   [][]string
+	excluded []string
+	// expansions maps fuzzy expansion words to the term they stand for.
+	expansions map[string]fuzzyExpansion
+	// pairDocs holds, for each phrase answered from bigram_index, the
+	// candidate documents containing it; it is nil for the other phrases.
+	// pairChecked holds the candidates already looked up.
+	pairDocs    []map[string]bool
+	pairChecked map[string]bool
+}
+
+// parseQuery tokenizes query, treating every double-quoted segment as a
+// phrase whose terms must appear at consecutive positions. Quoted segments
+// that reduce to a single term (e.g. a stopword plus one word) still make that
+// term required. An unmatched quote is ignored. The rest of the query may use
+// the AND, OR and NOT operators.
+func parseQuery(tk *tokenizer.Tokenizer, query string) parsedQuery {
+	var q parsedQuery
+	seen := make(map[string]bool)
+	addTerm := func(term string) {
+		if seen[term] {
+			return
+		}
+		seen[term] = true
+		q.terms = append(q.terms, term)
+	}
+
+	for _, m := range quotedSegment.FindAllStringSubmatch(query, -1) {
+		toks := tk.Tokenize(m[1])
+		if len(toks) == 0 {
+			continue
+		}
+		phrase := make([]string, 0, len(toks))
+		for _, t := range toks {
+			phrase = append(phrase, t.Word)
+			addTerm(t.Word)
+		}
+		q.phrases = append(q.phrases, phrase)
+	}
+
+	// The phrase placeholder keeps a NOT in front of a phrase from applying
+	// to the word after it.
+	q.parseBoolean(tk, quotedSegment.ReplaceAllString(query, ` "" `), addTerm)
+	return q
+}
+
+// phraseTerms returns the set of terms that belong to a phrase.
+func (q parsedQuery) phraseTerms() map[string]bool {
+	set := make(map[string]bool)
+	for _, phrase := range q.phrases {
+		for _, term := range phrase {
+			set[term] = true
+		}
+	}
+	return set
+}
+
+// containsPhrase reports whether positions (term -> positions in one
+// document) hold phrase as a run of consecutive positions.
+func containsPhrase(positions map[string][]int, phrase []string) bool {
+	sets := make([]map[int]bool, len(phrase))
+	for i, term := range phrase {
+		termPositions, ok := positions[term]
+		if !ok {
+			return false
+		}
+		sets[i] = make(map[int]bool, len(termPositions))
+		for _, p := range termPositions {
+			sets[i][p] = true
+		}
+	}
+
+	for start := range sets[0] {
+		matched := true
+		for i := 1; i < len(phrase); i++ {
+			if !sets[i][start+i] {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+// usePairIndex makes the two-word phrases of q answered from bigram_index.
+func (q *parsedQuery) usePairIndex() {
+	q.pairDocs = make([]map[string]bool, len(q.phrases))
+	q.pairChecked = make(map[string]bool)
+	for i, phrase := range q.phrases {
+		if len(phrase) == 2 {
+			q.pairDocs[i] = make(map[string]bool)
+		}
+	}
+}
+
+// needsPositions reports whether a phrase of q has to be matched on term
+// positions.
+func (q parsedQuery) needsPositions() bool {
+	for i := range q.phrases {
+		if q.pairDocs == nil || q.pairDocs[i] == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// markPhrasePairs looks up which documents of resp contain each two-word
+// phrase answered from bigram_index in one of fields, recording them in
+// q.pairDocs.
+func (s *Searcher) markPhrasePairs(ctx context.Context, q parsedQuery, fields []string, resp PostingsResponse) error {
+	if q.pairDocs == nil {
+		return nil
+	}
+	var ids []string
+	for _, d := range resp.Results {
+		if !q.pairChecked[d.DocID] {
+			q.pairChecked[d.DocID] = true
+			ids = append(ids, d.DocID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	for i, phrase := range q.phrases {
+		if q.pairDocs[i] == nil {
+			continue
+		}
+		found, err := s.Client.DocsWithWordPair(ctx, fields, scylladb.WordPair(phrase[0], phrase[1]), ids)
+		if err != nil {
+			return err
+		}
+		for id := range found {
+			q.pairDocs[i][id] = true
+		}
+	}
+	return nil
+}
+
+// matchesPhrases reports whether document id contains every phrase within a
+// single field, looking up phrases answered from bigram_index in pairDocs and
+// the others in byField, which maps field -> term -> positions for the
+// document.
+func (q parsedQuery) matchesPhrases(id string, byField map[string]map[string][]int) bool {
+	for i, phrase := range q.phrases {
+		if q.pairDocs != nil && q.pairDocs[i] != nil {
+			if !q.pairDocs[i][id] {
+				return false
+			}
+			continue
+		}
+
+		found := false
+		for _, positions := range byField {
+			if containsPhrase(positions, phrase) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,94 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/amrrdev/trawl/services/shared/apierror"
+)
+
+const (
+	DefaultPostingsLimit = 20
+	MaxPostingsLimit     = 100
+)
+
+type TermPosting struct {
+	DocID         string `json:"doc_id"`
+	Title         string `json:"title"`
+	TermFrequency int    `json:"term_frequency"`
+	Positions     []int  `json:"positions,omitempty"`
+}
+
+type TermPostingsResponse struct {
+	Term     string        `json:"term"`
+	Postings []TermPosting `json:"postings"`
+	Total    int           `json:"total"`
+	Offset   int           `json:"offset"`
+	Limit    int           `json:"limit"`
+}
+
+// TermPostings returns the raw, unranked postings for a single term that
+// belong to userID. The word goes through the indexing tokenizer so that it is
+// stemmed the same way it was stored. Positions are only read and returned
+// when withPositions is set.
+func (s *Search) TermPostings(ctx context.Context, userID, word string, offset, limit int, withPositions bool) (*TermPostingsResponse, error) {
+	if strings.TrimSpace(userID) == "" {
+		return nil, apierror.InvalidInput("userID is required")
+	}
+	if offset < 0 {
+		return nil, apierror.InvalidInput("invalid offset %d", offset)
+	}
+	if limit <= 0 {
+		limit = DefaultPostingsLimit
+	}
+	if limit > MaxPostingsLimit {
+		limit = MaxPostingsLimit
+	}
+
+	tokens := s.tokenizer.Tokenize(word)
+	if len(tokens) != 1 {
+		return nil, apierror.InvalidInput("invalid term %q: must be a single indexable word", word)
+	}
+	term := tokens[0].Word
+
+	postings, err := s.queryInvertedIndex(ctx, term, withPositions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read postings: %w", err)
+	}
+
+	owned := make([]TermPosting, 0, len(postings))
+	for _, p := range postings {
+		doc, err := s.getDocument(ctx, p.DocID)
+		if err != nil {
+			slog.WarnContext(ctx, "Failed to get document", "doc_id", p.DocID, "error", err)
+			continue
+		}
+		if doc.Owner != userID {
+			continue
+		}
+		owned = append(owned, TermPosting{
+			DocID:         p.DocID.String(),
+			Title:         doc.Title,
+			TermFrequency: p.Frequency,
+			Positions:     p.Positions,
+		})
+	}
+
+	return &TermPostingsResponse{
+		Term:     term,
+		Postings: postingsPage(owned, offset, limit),
+		Total:    len(owned),
+		Offset:   offset,
+		Limit:    limit,
+	}, nil
+}
+
+// postingsPage returns the limit postings from offset on, never nil.
+func postingsPage(postings []TermPosting, offset, limit int) []TermPosting {
+	if offset >= len(postings) {
+		return []TermPosting{}
+	}
+	return postings[offset:min(offset+limit, len(postings))]
+}
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/amrrdev/trawl/services/shared/apierror"
+	"github.com/amrrdev/trawl/services/shared/tokenizer"
+)
+
+func TestTermPostingsValidation(t *testing.T) {
+	// Invalid requests are refused before any lookup, so no session is
+	// needed.
+	s := &Search{tokenizer: tokenizer.NewTokenizer()}
+
+	tests := []struct {
+		name   string
+		userID string
+		word   string
+		offset int
+	}{
+		{"no user", " ", "search", 0},
+		{"negative offset", "user-1", "search", -1},
+		{"several words", "user-1", "full text", 0},
+		{"stop word", "user-1", "the", 0},
+		{"no word", "user-1", "", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.TermPostings(context.Background(), tt.userID, tt.word, tt.offset, 0, false)
+			if !errors.Is(err, apierror.ErrInvalidInput) {
+				t.Errorf("err = %v, want %v", err, apierror.ErrInvalidInput)
+			}
+		})
+	}
+}
+
+func TestPostingsPage(t *testing.T) {
+	postings := make([]TermPosting, 5)
+	for i := range postings {
+		postings[i].DocID = fmt.Sprintf("doc-%d", i)
+	}
+
+	tests := []struct {
+		name          string
+		offset, limit int
+		want          []string
+	}{
+		{"first page", 0, 2, []string{"doc-0", "doc-1"}},
+		{"last partial page", 4, 2, []string{"doc-4"}},
+		{"offset at the end", 5, 2, []string{}},
+		{"offset past the end", 9, 2, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page := postingsPage(postings, tt.offset, tt.limit)
+			if page == nil {
+				t.Fatal("page is nil, want an empty slice")
+			}
+			got := []string{}
+			for _, p := range page {
+				got = append(got, p.DocID)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("page = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/amrrdev/trawl/services/shared/apierror"
+	"github.com/gocql/gocql"
+)
+
+// Preferences are a user's saved search defaults. Zero values mean "not set",
+// in which case the system default applies.
+type Preferences struct {
+	DefaultOperator string `json:"default_operator,omitempty"`
+	// ResultLimit is the default page size.
+	ResultLimit     int   `json:"result_limit,omitempty"`
+	IncludeSnippets *bool `json:"include_snippets,omitempty"`
+}
+
+func (p *Preferences) validate() error {
+	p.DefaultOperator = strings.ToLower(strings.TrimSpace(p.DefaultOperator))
+	switch p.DefaultOperator {
+	case "", OperatorOr, OperatorAnd:
+	default:
+		return apierror.InvalidInput("invalid default_operator %q", p.DefaultOperator)
+	}
+	if p.ResultLimit < 0 || p.ResultLimit > MaxPageSize {
+		return apierror.InvalidInput("invalid result_limit %d: must be between 1 and %d", p.ResultLimit, MaxPageSize)
+	}
+	return nil
+}
+
+func (s *Search) GetPreferences(ctx context.Context, userID string) (*Preferences, error) {
+	if strings.TrimSpace(userID) == "" {
+		return nil, apierror.InvalidInput("userID is required")
+	}
+
+	query := `SELECT default_operator, result_limit, include_snippets FROM user_preferences WHERE user_id = ?`
+	var operator string
+	var limit int
+	var snippets *bool
+
+	err := s.scylladb.Session.Query(query, userID).WithContext(ctx).Scan(&operator, &limit, &snippets)
+	if err == gocql.ErrNotFound {
+		return &Preferences{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load preferences: %w", err)
+	}
+
+	return &Preferences{
+		DefaultOperator: operator,
+		ResultLimit:     limit,
+		IncludeSnippets: snippets,
+	}, nil
+}
+
+func (s *Search) SetPreferences(ctx context.Context, userID string, prefs *Preferences) (*Preferences, error) {
+	if strings.TrimSpace(userID) == "" {
+		return nil, apierror.InvalidInput("userID is required")
+	}
+	if err := prefs.validate(); err != nil {
+		return nil, err
+	}
+
+	query := `
+		INSERT INTO user_preferences (user_id, default_operator, result_limit, include_snippets, updated_at)
+		VALUES (?, ?, ?, ?, ?)
+	`
+	err := s.scylladb.Session.Query(query,
+		userID,
+		prefs.DefaultOperator,
+		prefs.ResultLimit,
+		prefs.IncludeSnippets,
+		time.Now(),
+	).WithContext(ctx).Exec()
+	if err != nil {
+		return nil, fmt.Errorf("failed to save preferences: %w", err)
+	}
+
+	return prefs, nil
+}
+
+// applyPreferences fills options the request left unset from the user's saved
+// preferences. Precedence: request value > user preference > system default
+// (applied later when the option is still unset).
+func (s *Search) applyPreferences(ctx context.Context, opts *SearchOptions) error {
+	if opts.UserID == "" {
+		return nil
+	}
+
+	prefs, err := s.GetPreferences(ctx, opts.UserID)
+	if err != nil {
+		return err
+	}
+	prefs.applyTo(opts)
+	return nil
+}
+
+// applyTo fills the options left unset in opts from p.
+func (p *Preferences) applyTo(opts *SearchOptions) {
+	if opts.Operator == "" {
+		opts.Operator = p.DefaultOperator
+	}
+	if opts.PageSize == 0 {
+		opts.PageSize = p.ResultLimit
+	}
+	if len(opts.Fields) == 0 && p.IncludeSnippets != nil && !*p.IncludeSnippets {
+		for _, f := range allFields {
+			if f != FieldSnippet {
+				opts.Fields = append(opts.Fields, f)
+			}
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package service
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/amrrdev/trawl/services/shared/apierror"
+)
+
+func TestPreferencesValidate(t *testing.T) {
+	tests := []struct {
+		name         string
+		prefs        Preferences
+		wantOperator string
+		wantErr      error
+	}{
+		{"unset", Preferences{}, "", nil},
+		{"operator normalized", Preferences{DefaultOperator: " AND "}, OperatorAnd, nil},
+		{"unknown operator", Preferences{DefaultOperator: "xor"}, "", apierror.ErrInvalidInput},
+		{"limit at maximum", Preferences{ResultLimit: MaxPageSize}, "", nil},
+		{"limit above maximum", Preferences{ResultLimit: MaxPageSize + 1}, "", apierror.ErrInvalidInput},
+		{"negative limit", Preferences{ResultLimit: -1}, "", apierror.ErrInvalidInput},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.prefs.validate()
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.prefs.DefaultOperator != tt.wantOperator {
+				t.Errorf("operator = %q, want %q", tt.prefs.DefaultOperator, tt.wantOperator)
+			}
+		})
+	}
+}
+
+func TestPreferencesApplyTo(t *testing.T) {
+	off, on := false, true
+	withoutSnippet := []string{FieldDocID, FieldTitle, FieldAuthor, FieldScore, FieldDownloadURL}
+
+	tests := []struct {
+		name  string
+		prefs Preferences
+		opts  SearchOptions
+		want  SearchOptions
+	}{
+		{"nothing saved", Preferences{}, SearchOptions{}, SearchOptions{}},
+		{
+			"fills unset options",
+			Preferences{DefaultOperator: OperatorAnd, ResultLimit: 50, IncludeSnippets: &off},
+			SearchOptions{},
+			SearchOptions{Operator: OperatorAnd, PageSize: 50, Fields: withoutSnippet},
+		},
+		{
+			"request wins",
+			Preferences{DefaultOperator: OperatorAnd, ResultLimit: 50, IncludeSnippets: &off},
+			SearchOptions{Operator: OperatorOr, PageSize: 5, Fields: []string{FieldSnippet}},
+			SearchOptions{Operator: OperatorOr, PageSize: 5, Fields: []string{FieldSnippet}},
+		},
+		{"snippets on keeps every field", Preferences{IncludeSnippets: &on}, SearchOptions{}, SearchOptions{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := tt.opts
+			tt.prefs.applyTo(&opts)
+			if opts.Operator != tt.want.Operator || opts.PageSize != tt.want.PageSize || !slices.Equal(opts.Fields, tt.want.Fields) {
+				t.Errorf("options = {%q %d %v}, want {%q %d %v}",
+					opts.Operator, opts.PageSize, opts.Fields, tt.want.Operator, tt.want.PageSize, tt.want.Fields)
+			}
+		})
+	}
+}
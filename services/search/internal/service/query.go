@@ -8,11 +8,52 @@ import (
 	"sync"
 	"time"
 
-	"github.com/amrrdev/trawl/services/search/internal/tokenizer"
+	"github.com/amrrdev/trawl/services/shared/tokenizer"
 )
 
 type ScyllaClient interface {
-	GetPostings(ctx context.Context, shard int, terms []string, topN int) (PostingsResponse, error)
+	GetPostings(ctx context.Context, field string, shard int, terms []string, topN int, withPositions bool) (PostingsResponse, error)
+	GetDocFreqs(ctx context.Context, terms []string) (map[string]int, error)
+	GetCorpusSize(ctx context.Context) (int, error)
+	GetCollectionStats(ctx context.Context) (CollectionStats, error)
+	// GetVocabulary returns every indexed word with its document count.
+	GetVocabulary(ctx context.Context) (map[string]int, error)
+	// DocsContaining returns which of docIDs contain at least one of terms
+	// in field.
+	DocsContaining(ctx context.Context, field string, terms []string, docIDs []string) (map[string]bool, error)
+	// DocsWithWordPair returns which of docIDs have the words of pair (see
+	// scylladb.WordPair) at adjacent positions in one of fields.
+	DocsWithWordPair(ctx context.Context, fields []string, pair string, docIDs []string) (map[string]bool, error)
+}
+
+// Indexed fields a query can match. The body is the document's extracted
+// text; the title has its own postings so it can be weighted separately.
+const (
+	MatchFieldBody  = "body"
+	MatchFieldTitle = "title"
+)
+
+// CollectionStats are the corpus totals maintained by the indexer.
+type CollectionStats struct {
+	Documents   int
+	Tokens      int
+	TitleTokens int
+}
+
+// averageLengths returns the corpus average length of each field, leaving out
+// fields without stats.
+func (c CollectionStats) averageLengths() map[string]float64 {
+	avg := make(map[string]float64, 2)
+	if c.Documents <= 0 {
+		return avg
+	}
+	if c.Tokens > 0 {
+		avg[MatchFieldBody] = float64(c.Tokens) / float64(c.Documents)
+	}
+	if c.TitleTokens > 0 {
+		avg[MatchFieldTitle] = float64(c.TitleTokens) / float64(c.Documents)
+	}
+	return avg
 }
 
 type Posting struct {
@@ -22,32 +63,154 @@ type Posting struct {
 }
 
 type PostingsResponse struct {
-	ShardID  int
-	Results  []DocScore
+	ShardID int
+	Field   string
+	Results []DocScore
+	// DocCount sums the document frequencies of the shard's terms. It only
+	// stands in for the corpus size when there are no collection stats.
 	DocCount int
 }
 
 type DocScore struct {
 	DocID   string
+	Term    string
 	Score   float64
 	TF      int
 	DocLen  int
 	DocFreq int
+	// Positions is only populated when the postings were fetched with
+	// positions.
+	Positions []int
+	// MatchedTerms counts the distinct query terms found in the document
+	// once postings are merged.
+	MatchedTerms int
+	// Explanation breaks Score down when the query asked for it.
+	Explanation *Explanation
+}
+
+// Explanation breaks a document's BM25 score down by matched term, with the
+// corpus size and parameters it was computed with. The terms' scores add up
+// to the document's.
+type Explanation struct {
+	// TotalDocs is the corpus size IDF was computed with: the indexed
+	// document count, or an estimate before there are collection stats.
+	TotalDocs int               `json:"total_docs"`
+	K1        float64           `json:"k1"`
+	B         float64           `json:"b"`
+	Delta     float64           `json:"delta,omitempty"`
+	Terms     []TermExplanation `json:"terms"`
+}
+
+// TermExplanation is one term's part of a document's score: Score is
+// Weight * IDF * (saturated TFNorm + delta).
+type TermExplanation struct {
+	Term string `json:"term"`
+	// QueryTerm is the query term a fuzzy expansion came from, when it
+	// differs from Term.
+	QueryTerm string `json:"query_term,omitempty"`
+	// TF is the raw term frequency summed over the searched fields, before
+	// TFCap; TFNorm is the length-normalized, field-weighted frequency
+	// that is scored.
+	TF      int     `json:"tf"`
+	TFNorm  float64 `json:"tf_norm"`
+	DocFreq int     `json:"df"`
+	IDF     float64 `json:"idf"`
+	// Weight scales the term's score down for fuzzy expansions.
+	Weight float64 `json:"weight"`
+	Score  float64 `json:"score"`
+}
+
+const (
+	OperatorOr  = "or"
+	OperatorAnd = "and"
+)
+
+// QueryOptions controls a single Searcher query.
+type QueryOptions struct {
+	TopK int
+	// Operator combines query terms: OperatorOr (default) ranks documents
+	// matching any term, OperatorAnd keeps only documents matching all.
+	Operator string
+	// WithPositions fetches term positions along with the postings. Only
+	// phrase and proximity matching need them.
+	WithPositions bool
+	// K1 and B override the Searcher's BM25 parameters for this query when
+	// set.
+	K1 *float64
+	B  *float64
+	// MatchFields lists the indexed fields searched, MatchFieldBody first
+	// when present. Empty means the body only.
+	MatchFields []string
+	// Fuzzy also matches vocabulary words a few edits away from each query
+	// term (see expandFuzzy).
+	Fuzzy bool
+	// Explain attaches an Explanation of its score to each document.
+	Explain bool
+}
+
+func (o QueryOptions) matchFields() []string {
+	if len(o.MatchFields) == 0 {
+		return []string{MatchFieldBody}
+	}
+	return o.MatchFields
 }
 
 type Searcher struct {
 	Client     ScyllaClient
 	ShardCount int
+	Tokenizer  *tokenizer.Tokenizer
 	K1         float64
 	B          float64
+	// Delta is the BM25+ lower bound added to every matching term's
+	// normalized TF. Zero keeps standard BM25.
+	Delta float64
+	// TFCap clamps raw term frequency before scoring. Zero disables it.
+	TFCap int
+	// MinDocFreq drops query terms found in fewer documents (e.g. typos
+	// that made it into the index). Zero disables it.
+	MinDocFreq int
+	// MaxDocFreqRatio drops query terms found in more than this fraction of
+	// the corpus (boilerplate). Zero disables it.
+	MaxDocFreqRatio float64
+	// TitleWeight and BodyWeight scale each field's normalized term
+	// frequency before saturation (BM25F). They only apply when a query
+	// searches more than one field.
+	TitleWeight float64
+	BodyWeight  float64
+	// FuzzyMaxExpansions caps the words each query term expands to in a fuzzy
+	// query; zero disables fuzzy matching. FuzzyPenalty is the score weight
+	// lost per edit.
+	FuzzyMaxExpansions int
+	FuzzyPenalty       float64
+	// BigramIndex answers two-word phrases from bigram_index rather than
+	// term positions. It needs every document indexed with bigrams.
+	BigramIndex bool
+
+	vocab vocabularyCache
+}
+
+// QueryResult is the outcome of a Searcher query.
+type QueryResult struct {
+	Docs []DocScore
+	// Total counts the documents that matched before the top-K cut. Postings
+	// are truncated per shard, so it is a lower bound.
+	Total        int
+	SkippedTerms []string
+	// ExpandedTerms lists the fuzzy expansions of each query term.
+	ExpandedTerms map[string][]string
 }
 
 func NewSearcher(client ScyllaClient, shards int) *Searcher {
 	return &Searcher{
-		Client:     client,
-		ShardCount: shards,
-		K1:         1.2,
-		B:          0.75,
+		Client:             client,
+		ShardCount:         shards,
+		Tokenizer:          tokenizer.NewTokenizer(),
+		K1:                 1.2,
+		B:                  0.75,
+		TitleWeight:        3,
+		BodyWeight:         1,
+		FuzzyMaxExpansions: 3,
+		FuzzyPenalty:       0.3,
 	}
 }
 
@@ -60,76 +223,361 @@ func (s *Searcher) routeTerms(terms []string) map[int][]string {
 	return m
 }
 
-func (s *Searcher) Search(ctx context.Context, query string, topK int) ([]DocScore, error) {
+func (s *Searcher) Search(ctx context.Context, query string, opts QueryOptions) (*QueryResult, error) {
+	return s.SearchWithProgress(ctx, query, opts, nil)
+}
+
+// SearchWithProgress behaves like Search, additionally calling onPartial with
+// the ranking merged from the shards that have answered so far, once per shard
+// response. onPartial runs on the caller's goroutine.
+//
+// Double-quoted segments of query are phrases: only documents containing
+// every phrase at consecutive positions match, while the remaining terms are
+// scored as usual. The remaining terms may be combined with AND, OR and NOT
+// (see parseBoolean).
+func (s *Searcher) SearchWithProgress(ctx context.Context, query string, opts QueryOptions, onPartial func([]DocScore)) (*QueryResult, error) {
 	// use the project's tokenizer to normalize, lowercase and stem terms
-	tk := tokenizer.NewTokenizer()
-	toks := tk.Tokenize(query)
-	var terms []string
-	for _, t := range toks {
-		terms = append(terms, t.Word)
+	return s.run(ctx, parseQuery(s.Tokenizer, query), nil, opts, onPartial)
+}
+
+// SearchTerms ranks the documents matching any of terms, which are taken as
+// indexed words rather than tokenized, leaving out the documents in exclude.
+func (s *Searcher) SearchTerms(ctx context.Context, terms []string, exclude []string, opts QueryOptions) (*QueryResult, error) {
+	return s.run(ctx, parsedQuery{terms: terms}, exclude, opts, nil)
+}
+
+// run executes q, never returning the documents in exclude.
+func (s *Searcher) run(ctx context.Context, q parsedQuery, exclude []string, opts QueryOptions, onPartial func([]DocScore)) (*QueryResult, error) {
+	// Phrase terms are never pruned: dropping one would make its phrase
+	// impossible to match.
+	inPhrase := q.phraseTerms()
+	var prunable, required []string
+	for _, t := range q.terms {
+		if inPhrase[t] {
+			required = append(required, t)
+		} else {
+			prunable = append(prunable, t)
+		}
+	}
+	kept, skipped, err := s.pruneTerms(ctx, prunable)
+	if err != nil {
+		return nil, fmt.Errorf("doc frequency lookup error: %w", err)
+	}
+	q.terms = append(required, kept...)
+	q.dropTerms(skipped)
+	if len(q.phrases) > 0 {
+		if s.BigramIndex {
+			q.usePairIndex()
+		}
+		opts.WithPositions = opts.WithPositions || q.needsPositions()
 	}
-	termToShards := s.routeTerms(terms)
+	if opts.Fuzzy {
+		if err := s.expandFuzzy(ctx, &q); err != nil {
+			return nil, fmt.Errorf("fuzzy expansion error: %w", err)
+		}
+	}
+
+	stats, err := s.Client.GetCollectionStats(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("collection stats lookup error: %w", err)
+	}
+	fields := opts.matchFields()
+
+	termToShards := s.routeTerms(q.fetchTerms())
 	type shardResult struct {
 		resp PostingsResponse
 		err  error
 	}
-	resultsCh := make(chan shardResult, len(termToShards))
+	resultsCh := make(chan shardResult, len(termToShards)*len(fields))
 	var wg sync.WaitGroup
 	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
 	defer cancel()
 	for shard, termsForShard := range termToShards {
-		wg.Add(1)
-		go func(sh int, ts []string) {
-			defer wg.Done()
-			resp, err := s.Client.GetPostings(ctx, sh, ts, topK*2)
-			if err != nil {
-				resultsCh <- shardResult{err: err}
-				return
-			}
-			resultsCh <- shardResult{resp: resp}
-		}(shard, termsForShard)
+		for _, field := range fields {
+			wg.Add(1)
+			go func(f string, sh int, ts []string) {
+				defer wg.Done()
+				resp, err := s.Client.GetPostings(ctx, f, sh, ts, opts.TopK*2, opts.WithPositions)
+				if err != nil {
+					resultsCh <- shardResult{err: err}
+					return
+				}
+				resultsCh <- shardResult{resp: resp}
+			}(field, shard, termsForShard)
+		}
 	}
 	go func() {
 		wg.Wait()
 		close(resultsCh)
 	}()
 	var shardResponses []PostingsResponse
+	excluded := make(map[string]bool)
+	for _, id := range exclude {
+		excluded[id] = true
+	}
 	for r := range resultsCh {
 		if r.err != nil {
 			return nil, fmt.Errorf("shard fetch error: %w", r.err)
 		}
+		if err := s.markExcluded(ctx, q, fields, r.resp, excluded); err != nil {
+			return nil, fmt.Errorf("excluded term lookup error: %w", err)
+		}
+		if err := s.markPhrasePairs(ctx, q, fields, r.resp); err != nil {
+			return nil, fmt.Errorf("bigram lookup error: %w", err)
+		}
 		shardResponses = append(shardResponses, r.resp)
+		if onPartial != nil {
+			partial, _ := s.mergeShardCandidates(shardResponses, opts, q, stats, excluded)
+			onPartial(partial)
+		}
+	}
+	merged, total := s.mergeShardCandidates(shardResponses, opts, q, stats, excluded)
+	return &QueryResult{Docs: merged, Total: total, SkippedTerms: skipped, ExpandedTerms: q.expandedTerms()}, nil
+}
+
+// pruneTerms removes query terms whose document frequency falls outside the
+// configured MinDocFreq / MaxDocFreqRatio bounds.
+func (s *Searcher) pruneTerms(ctx context.Context, terms []string) (kept, skipped []string, err error) {
+	if len(terms) == 0 || (s.MinDocFreq <= 0 && s.MaxDocFreqRatio <= 0) {
+		return terms, nil, nil
+	}
+
+	docFreqs, err := s.Client.GetDocFreqs(ctx, terms)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	maxDocFreq := math.MaxInt
+	if s.MaxDocFreqRatio > 0 {
+		corpusSize, err := s.Client.GetCorpusSize(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		if corpusSize > 0 {
+			maxDocFreq = int(s.MaxDocFreqRatio * float64(corpusSize))
+		}
 	}
-	merged := mergeShardCandidates(shardResponses, topK)
-	return merged, nil
+
+	for _, t := range terms {
+		df := docFreqs[t]
+		if df < s.MinDocFreq || df > maxDocFreq {
+			skipped = append(skipped, t)
+			continue
+		}
+		kept = append(kept, t)
+	}
+	return kept, skipped, nil
 }
 
-func mergeShardCandidates(shardResponses []PostingsResponse, topK int) []DocScore {
-	var all []DocScore
-	totalDocs := 0
-	totalDocLen := 0
-	docCount := 0
+// markExcluded adds the documents of resp that contain one of the query's NOT
+// terms in any of fields to excluded. Postings are truncated per shard, so the
+// NOT terms' own postings can't tell; the candidates are looked up instead.
+func (s *Searcher) markExcluded(ctx context.Context, q parsedQuery, fields []string, resp PostingsResponse, excluded map[string]bool) error {
+	if len(q.excluded) == 0 {
+		return nil
+	}
+	var ids []string
+	seen := make(map[string]bool)
+	for _, d := range resp.Results {
+		if seen[d.DocID] || excluded[d.DocID] {
+			continue
+		}
+		seen[d.DocID] = true
+		ids = append(ids, d.DocID)
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	for _, field := range fields {
+		found, err := s.Client.DocsContaining(ctx, field, q.excluded, ids)
+		if err != nil {
+			return err
+		}
+		for id := range found {
+			excluded[id] = true
+		}
+	}
+	return nil
+}
+
+// mergeShardCandidates scores every document and returns the topK. A term's
+// normalized frequencies in each field are weighted and summed before BM25
+// saturation (BM25F), and the per-term scores of a document are summed, fuzzy
+// expansions weighted down by their distance. With
+// OperatorAnd, documents that did not match all query terms are dropped;
+// documents missing any of the query's phrases, satisfying none of its AND
+// groups or listed in excluded are always dropped. It also returns how many
+// documents matched in total.
+//
+// IDF's corpus size and each field's average length come from stats. Without
+// collection stats yet, the corpus size falls back to the sum of the terms'
+// document frequencies, and a field's average length to the average over the
+// candidates.
+func (s *Searcher) mergeShardCandidates(shardResponses []PostingsResponse, opts QueryOptions, q parsedQuery, stats CollectionStats, excluded map[string]bool) ([]DocScore, int) {
+	fields := opts.matchFields()
+	avgLens := stats.averageLengths()
+	docFreqSum := 0
+	lenSum := make(map[string]int, len(fields))
+	lenCount := make(map[string]int, len(fields))
 	for _, sr := range shardResponses {
-		totalDocs += sr.DocCount
+		// Every field's responses carry the same per-term counts.
+		if sr.Field == fields[0] {
+			docFreqSum += sr.DocCount
+		}
 		for _, d := range sr.Results {
-			totalDocLen += d.DocLen
-			docCount++
+			lenSum[sr.Field] += d.DocLen
+			lenCount[sr.Field]++
 		}
 	}
-	avgDocLen := 1.0
-	if docCount > 0 {
-		avgDocLen = float64(totalDocLen) / float64(docCount)
+	totalDocs := stats.Documents
+	if totalDocs <= 0 {
+		totalDocs = docFreqSum
+	}
+	avg := make(map[string]float64, len(fields))
+	for _, f := range fields {
+		switch {
+		case avgLens[f] > 0:
+			avg[f] = avgLens[f]
+		case lenCount[f] > 0:
+			avg[f] = float64(lenSum[f]) / float64(lenCount[f])
+		default:
+			avg[f] = 1.0
+		}
+	}
+	weights := s.fieldWeights(fields)
+
+	k1, b := s.K1, s.B
+	if opts.K1 != nil {
+		k1 = *opts.K1
+	}
+	if opts.B != nil {
+		b = *opts.B
+	}
+
+	// The boolean filter runs before scoring, so excluded documents and
+	// documents satisfying no AND group are never scored.
+	var termsByDoc map[string]map[string]bool
+	if q.conjunctive() {
+		termsByDoc = make(map[string]map[string]bool)
+		for _, sr := range shardResponses {
+			for _, d := range sr.Results {
+				if termsByDoc[d.DocID] == nil {
+					termsByDoc[d.DocID] = make(map[string]bool)
+				}
+				term, _ := q.origin(d.Term)
+				termsByDoc[d.DocID][term] = true
+			}
+		}
+	}
+
+	type termMatch struct {
+		term    string
+		origin  string
+		weight  float64
+		tf      int
+		tfNorm  float64
+		docFreq int
+	}
+	byDoc := make(map[string]*DocScore)
+	matches := make(map[string][]*termMatch)
+	var order []string
+	var positions map[string]map[string]map[string][]int
+	if len(q.phrases) > 0 {
+		positions = make(map[string]map[string]map[string][]int)
 	}
 	for _, sr := range shardResponses {
 		for _, d := range sr.Results {
-			score := bm25Score(d.TF, d.DocLen, avgDocLen, d.DocFreq, totalDocs, 1.2, 0.75)
-			all = append(all, DocScore{DocID: d.DocID, Score: score, TF: d.TF, DocLen: d.DocLen, DocFreq: d.DocFreq})
+			if excluded[d.DocID] {
+				continue
+			}
+			if termsByDoc != nil && !q.matchesGroups(termsByDoc[d.DocID]) {
+				continue
+			}
+			if positions != nil {
+				if positions[d.DocID] == nil {
+					positions[d.DocID] = make(map[string]map[string][]int)
+				}
+				if positions[d.DocID][sr.Field] == nil {
+					positions[d.DocID][sr.Field] = make(map[string][]int)
+				}
+				positions[d.DocID][sr.Field][d.Term] = d.Positions
+			}
+			tf := d.TF
+			if s.TFCap > 0 && tf > s.TFCap {
+				tf = s.TFCap
+			}
+
+			agg, ok := byDoc[d.DocID]
+			if !ok {
+				agg = &DocScore{DocID: d.DocID, DocLen: d.DocLen, DocFreq: d.DocFreq}
+				byDoc[d.DocID] = agg
+				order = append(order, d.DocID)
+			}
+			agg.TF += d.TF
+			if sr.Field == MatchFieldBody {
+				agg.DocLen = d.DocLen
+			}
+
+			var m *termMatch
+			for _, existing := range matches[d.DocID] {
+				if existing.term == d.Term {
+					m = existing
+					break
+				}
+			}
+			if m == nil {
+				origin, weight := q.origin(d.Term)
+				counted := false
+				for _, existing := range matches[d.DocID] {
+					counted = counted || existing.origin == origin
+				}
+				if !counted {
+					agg.MatchedTerms++
+				}
+				m = &termMatch{term: d.Term, origin: origin, weight: weight}
+				matches[d.DocID] = append(matches[d.DocID], m)
+			}
+			m.tf += d.TF
+			m.tfNorm += weights[sr.Field] * normalizedTF(tf, d.DocLen, avg[sr.Field], b)
+			m.docFreq = max(m.docFreq, d.DocFreq)
 		}
 	}
+
 	h := &minHeap{}
 	heap.Init(h)
-	for _, d := range all {
-		if h.Len() < topK {
+	matched := 0
+	for _, id := range order {
+		d := *byDoc[id]
+		if opts.Operator == OperatorAnd && d.MatchedTerms < len(q.terms) {
+			continue
+		}
+		if !q.matchesPhrases(id, positions[id]) {
+			continue
+		}
+		if opts.Explain {
+			d.Explanation = &Explanation{TotalDocs: totalDocs, K1: k1, B: b, Delta: s.Delta}
+		}
+		for _, m := range matches[id] {
+			score := m.weight * bm25Score(m.tfNorm, m.docFreq, totalDocs, k1, s.Delta)
+			d.Score += score
+			if d.Explanation != nil {
+				term := TermExplanation{
+					Term:    m.term,
+					TF:      m.tf,
+					TFNorm:  m.tfNorm,
+					DocFreq: m.docFreq,
+					IDF:     bm25IDF(m.docFreq, totalDocs),
+					Weight:  m.weight,
+					Score:   score,
+				}
+				if m.origin != m.term {
+					term.QueryTerm = m.origin
+				}
+				d.Explanation.Terms = append(d.Explanation.Terms, term)
+			}
+		}
+		matched++
+		if h.Len() < opts.TopK {
 			heap.Push(h, d)
 			continue
 		}
@@ -143,7 +591,21 @@ func mergeShardCandidates(shardResponses []PostingsResponse, topK int) []DocScor
 	for i := n - 1; i >= 0; i-- {
 		out[i] = heap.Pop(h).(DocScore)
 	}
-	return out
+	return out, matched
+}
+
+// fieldWeights returns the BM25F weight of each searched field. A single
+// field is weighted 1 so its scores match plain BM25.
+func (s *Searcher) fieldWeights(fields []string) map[string]float64 {
+	weights := make(map[string]float64, len(fields))
+	for _, f := range fields {
+		weights[f] = 1
+	}
+	if len(fields) > 1 {
+		weights[MatchFieldBody] = s.BodyWeight
+		weights[MatchFieldTitle] = s.TitleWeight
+	}
+	return weights
 }
 
 func hashString(s string) uint64 {
@@ -155,13 +617,26 @@ func hashString(s string) uint64 {
 	return h
 }
 
-func bm25Score(tf int, docLen int, avgDocLen float64, docFreq int, totalDocs int, k1, b float64) float64 {
-	if tf == 0 || docFreq == 0 {
+// normalizedTF is BM25's length-normalized term frequency within one field.
+func normalizedTF(tf int, fieldLen int, avgFieldLen float64, b float64) float64 {
+	return float64(tf) / (1 - b + b*(float64(fieldLen)/avgFieldLen))
+}
+
+// bm25Score computes the BM25 contribution of one term for one document from
+// its normalized term frequency (summed over weighted fields for BM25F).
+// A positive delta turns it into BM25+, which lower-bounds the TF component so
+// long documents are not over-penalized by length normalization.
+func bm25Score(tfNorm float64, docFreq int, totalDocs int, k1, delta float64) float64 {
+	if tfNorm == 0 || docFreq == 0 {
 		return 0
 	}
-	idf := math.Log((float64(totalDocs)-float64(docFreq)+0.5)/(float64(docFreq)+0.5) + 1)
-	tfNorm := float64(tf) * (k1 + 1) / (float64(tf) + k1*(1-b+b*(float64(docLen)/avgDocLen)))
-	return idf * tfNorm
+	return bm25IDF(docFreq, totalDocs) * (tfNorm*(k1+1)/(k1+tfNorm) + delta)
+}
+
+// bm25IDF is the inverse document frequency of a term found in docFreq of
+// totalDocs documents.
+func bm25IDF(docFreq int, totalDocs int) float64 {
+	return math.Log((float64(totalDocs)-float64(docFreq)+0.5)/(float64(docFreq)+0.5) + 1)
 }
 
 type minHeap []DocScore
@@ -0,0 +1,355 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestMergeShardCandidatesCorpusSize(t *testing.T) {
+	// Two terms in one shard: their document frequencies sum to 15, far
+	// below the corpus size.
+	responses := []PostingsResponse{{
+		Field:    MatchFieldBody,
+		DocCount: 15,
+		Results: []DocScore{
+			{DocID: "doc-1", Term: "alpha", TF: 2, DocLen: 10, DocFreq: 5},
+			{DocID: "doc-1", Term: "beta", TF: 1, DocLen: 10, DocFreq: 10},
+		},
+	}}
+
+	tests := []struct {
+		name  string
+		stats CollectionStats
+		wantN int
+	}{
+		{"collection stats", CollectionStats{Documents: 1000, Tokens: 10000}, 1000},
+		{"no collection stats", CollectionStats{}, 15},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewSearcher(nil, 1)
+			q := parseQuery(s.Tokenizer, "alpha beta")
+			docs, _ := s.mergeShardCandidates(responses, QueryOptions{TopK: 10}, q, tt.stats, nil)
+			if len(docs) != 1 {
+				t.Fatalf("got %d documents, want 1", len(docs))
+			}
+
+			avgLen := 10.0
+			if tt.stats.Documents > 0 {
+				avgLen = float64(tt.stats.Tokens) / float64(tt.stats.Documents)
+			}
+			want := bm25Score(normalizedTF(2, 10, avgLen, s.B), 5, tt.wantN, s.K1, 0) +
+				bm25Score(normalizedTF(1, 10, avgLen, s.B), 10, tt.wantN, s.K1, 0)
+			if math.Abs(docs[0].Score-want) > 1e-9 {
+				t.Errorf("score = %v, want %v (N = %d)", docs[0].Score, want, tt.wantN)
+			}
+		})
+	}
+}
+
+func TestMergeShardCandidatesExplanation(t *testing.T) {
+	responses := []PostingsResponse{
+		{
+			Field:    MatchFieldBody,
+			DocCount: 7,
+			Results: []DocScore{
+				{DocID: "doc-1", Term: "alpha", TF: 3, DocLen: 40, DocFreq: 4},
+				{DocID: "doc-1", Term: "beta", TF: 1, DocLen: 40, DocFreq: 3},
+				{DocID: "doc-2", Term: "alpha", TF: 1, DocLen: 12, DocFreq: 4},
+			},
+		},
+		{
+			Field:    MatchFieldTitle,
+			DocCount: 7,
+			Results: []DocScore{
+				{DocID: "doc-1", Term: "alpha", TF: 1, DocLen: 3, DocFreq: 4},
+			},
+		},
+	}
+	stats := CollectionStats{Documents: 500, Tokens: 12500, TitleTokens: 2000}
+
+	tests := []struct {
+		name   string
+		fields []string
+		query  string
+	}{
+		{"body", []string{MatchFieldBody}, "alpha beta"},
+		{"body and title", []string{MatchFieldBody, MatchFieldTitle}, "alpha beta"},
+		{"single term", []string{MatchFieldBody, MatchFieldTitle}, "alpha"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewSearcher(nil, 1)
+			s.Delta = 0.5
+			q := parseQuery(s.Tokenizer, tt.query)
+			opts := QueryOptions{TopK: 10, MatchFields: tt.fields, Explain: true}
+			docs, _ := s.mergeShardCandidates(responses, opts, q, stats, nil)
+			if len(docs) == 0 {
+				t.Fatal("no documents scored")
+			}
+			for _, d := range docs {
+				e := d.Explanation
+				if e == nil {
+					t.Fatalf("%s: no explanation", d.DocID)
+				}
+				if e.TotalDocs != stats.Documents {
+					t.Errorf("%s: TotalDocs = %d, want %d", d.DocID, e.TotalDocs, stats.Documents)
+				}
+				sum := 0.0
+				for _, term := range e.Terms {
+					want := term.Weight * term.IDF * (term.TFNorm*(e.K1+1)/(e.K1+term.TFNorm) + e.Delta)
+					if math.Abs(term.Score-want) > 1e-9 {
+						t.Errorf("%s/%s: term score = %v, want %v from its parts", d.DocID, term.Term, term.Score, want)
+					}
+					if idf := bm25IDF(term.DocFreq, e.TotalDocs); math.Abs(term.IDF-idf) > 1e-9 {
+						t.Errorf("%s/%s: IDF = %v, want %v for N = %d", d.DocID, term.Term, term.IDF, idf, e.TotalDocs)
+					}
+					sum += term.Score
+				}
+				if math.Abs(sum-d.Score) > 1e-9 {
+					t.Errorf("%s: explained terms sum to %v, score is %v", d.DocID, sum, d.Score)
+				}
+			}
+		})
+	}
+}
+
+func TestSearchFetchesPositionsOnlyWhenNeeded(t *testing.T) {
+	docs := map[string]string{
+		"doc-1": "quick brown fox",
+		"doc-2": "quick fox jumps over the brown dog",
+		"doc-3": "slow brown turtle",
+	}
+
+	tests := []struct {
+		name          string
+		query         string
+		withPositions bool
+		wantDocs      []string
+		wantFetch     bool
+	}{
+		{"terms", "quick fox", false, []string{"doc-1", "doc-2"}, false},
+		{"phrase", `"quick fox"`, false, []string{"doc-2"}, true},
+		{"requested", "turtle", true, []string{"doc-3"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idx := newMemoryIndex(docs)
+			s := NewSearcher(idx, 2)
+			result, err := s.Search(context.Background(), tt.query, QueryOptions{TopK: 10, WithPositions: tt.withPositions})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			for _, d := range result.Docs {
+				got = append(got, d.DocID)
+				if !tt.wantFetch && len(d.Positions) > 0 {
+					t.Errorf("%s has positions %v without asking for them", d.DocID, d.Positions)
+				}
+			}
+			slices.Sort(got)
+			if !slices.Equal(got, tt.wantDocs) {
+				t.Errorf("docs = %v, want %v", got, tt.wantDocs)
+			}
+			for _, fetched := range idx.positionFetches {
+				if fetched != tt.wantFetch {
+					t.Errorf("postings fetched with positions = %v, want %v", fetched, tt.wantFetch)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkSearch(b *testing.B) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	vocabulary := make([]string, 500)
+	for i := range vocabulary {
+		vocabulary[i] = fmt.Sprintf("word%c%c", 'a'+i/26%26, 'a'+i%26)
+	}
+	docs := make(map[string]string, 5000)
+	for i := range 5000 {
+		words := make([]string, 200)
+		for j := range words {
+			// Skewed toward the first words, like natural text.
+			words[j] = vocabulary[int(float64(len(vocabulary))*math.Pow(rng.Float64(), 3))]
+		}
+		docs[fmt.Sprintf("doc-%d", i)] = strings.Join(words, " ")
+	}
+	s := NewSearcher(newMemoryIndex(docs), 4)
+
+	for _, bb := range []struct {
+		name  string
+		query string
+	}{
+		{"terms", "wordaa wordab wordbz"},
+		{"phrase", `"wordaa wordab"`},
+	} {
+		b.Run(bb.name, func(b *testing.B) {
+			for b.Loop() {
+				if _, err := s.Search(context.Background(), bb.query, QueryOptions{TopK: 20}); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestBM25Score(t *testing.T) {
+	const k1 = 1.2
+	idf := bm25IDF(10, 1000)
+
+	tests := []struct {
+		name    string
+		tfNorm  float64
+		docFreq int
+		delta   float64
+		want    float64
+	}{
+		{"term absent", 0, 10, 0, 0},
+		{"term absent with delta", 0, 10, 1, 0},
+		{"unknown doc freq", 1, 0, 0, 0},
+		{"bm25", 1, 10, 0, idf * (2.2 / 2.2)},
+		{"bm25+ adds delta", 1, 10, 1, idf * (2.2/2.2 + 1)},
+		{"saturates", 1e9, 10, 0, idf * (k1 + 1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := bm25Score(tt.tfNorm, tt.docFreq, 1000, k1, tt.delta)
+			if math.Abs(got-tt.want) > 1e-6 {
+				t.Errorf("bm25Score = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeShardCandidatesTFCap(t *testing.T) {
+	responses := []PostingsResponse{{
+		Field: MatchFieldBody,
+		Results: []DocScore{
+			{DocID: "spam", Term: "alpha", TF: 50, DocLen: 100, DocFreq: 2},
+			{DocID: "normal", Term: "alpha", TF: 5, DocLen: 100, DocFreq: 2},
+		},
+	}}
+	stats := CollectionStats{Documents: 100, Tokens: 10000}
+
+	tests := []struct {
+		name      string
+		tfCap     int
+		wantEqual bool
+	}{
+		{"uncapped", 0, false},
+		{"capped at the smaller frequency", 5, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewSearcher(nil, 1)
+			s.TFCap = tt.tfCap
+			docs, _ := s.mergeShardCandidates(responses, QueryOptions{TopK: 10}, parseQuery(s.Tokenizer, "alpha"), stats, nil)
+			scores := make(map[string]float64)
+			for _, d := range docs {
+				scores[d.DocID] = d.Score
+			}
+			if equal := scores["spam"] == scores["normal"]; equal != tt.wantEqual {
+				t.Errorf("scores %v: equal = %v, want %v", scores, equal, tt.wantEqual)
+			}
+			if scores["spam"] < scores["normal"] {
+				t.Errorf("scores %v: higher frequency ranks lower", scores)
+			}
+		})
+	}
+}
+
+func TestSearchSkipsTermsOutsideDocFreqBounds(t *testing.T) {
+	idx := newMemoryIndex(map[string]string{
+		"doc-1": "common rare",
+		"doc-2": "common middle",
+		"doc-3": "common middle",
+		"doc-4": "common",
+	})
+
+	tests := []struct {
+		name         string
+		minDocFreq   int
+		maxRatio     float64
+		wantSkipped  []string
+		wantMatching []string
+	}{
+		{"no bounds", 0, 0, nil, []string{"doc-1", "doc-2", "doc-3", "doc-4"}},
+		{"too rare", 2, 0, []string{"rare"}, []string{"doc-1", "doc-2", "doc-3", "doc-4"}},
+		{"too common", 0, 0.75, []string{"common"}, []string{"doc-1", "doc-2", "doc-3"}},
+		{"both", 2, 0.75, []string{"common", "rare"}, []string{"doc-2", "doc-3"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewSearcher(idx, 2)
+			s.MinDocFreq = tt.minDocFreq
+			s.MaxDocFreqRatio = tt.maxRatio
+			result, err := s.Search(context.Background(), "common rare middle", QueryOptions{TopK: 10})
+			if err != nil {
+				t.Fatal(err)
+			}
+			skipped := slices.Sorted(slices.Values(result.SkippedTerms))
+			if !slices.Equal(skipped, tt.wantSkipped) {
+				t.Errorf("skipped %v, want %v", skipped, tt.wantSkipped)
+			}
+			var matching []string
+			for _, d := range result.Docs {
+				matching = append(matching, d.DocID)
+			}
+			slices.Sort(matching)
+			if !slices.Equal(matching, tt.wantMatching) {
+				t.Errorf("matching %v, want %v", matching, tt.wantMatching)
+			}
+		})
+	}
+}
+
+func TestSearchWithProgress(t *testing.T) {
+	idx := newMemoryIndex(map[string]string{
+		"doc-1": "alpha beta gamma",
+		"doc-2": "alpha alpha",
+		"doc-3": "gamma delta",
+		"doc-4": "beta delta epsilon",
+	})
+
+	tests := []struct {
+		name   string
+		query  string
+		shards int
+	}{
+		{"one shard", "alpha gamma", 1},
+		{"several shards", "alpha beta gamma delta epsilon", 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewSearcher(idx, tt.shards)
+			var partials [][]DocScore
+			result, err := s.SearchWithProgress(context.Background(), tt.query, QueryOptions{TopK: 10}, func(partial []DocScore) {
+				partials = append(partials, partial)
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			wantPartials := len(s.routeTerms(parseQuery(s.Tokenizer, tt.query).terms))
+			if len(partials) != wantPartials {
+				t.Fatalf("got %d partial rankings, want one per shard queried (%d)", len(partials), wantPartials)
+			}
+			for i := 1; i < len(partials); i++ {
+				if len(partials[i]) < len(partials[i-1]) {
+					t.Errorf("partial %d ranks %d documents, fewer than the %d before it", i, len(partials[i]), len(partials[i-1]))
+				}
+			}
+			last := partials[len(partials)-1]
+			if !slices.EqualFunc(last, result.Docs, func(a, b DocScore) bool { return a.DocID == b.DocID && a.Score == b.Score }) {
+				t.Errorf("last partial %v differs from the result %v", last, result.Docs)
+			}
+		})
+	}
+}
@@ -4,7 +4,7 @@ import (
 	"context"
 	"sort"
 
-	"github.com/amrrdev/trawl/services/search/internal/scylladb"
+	"github.com/amrrdev/trawl/services/shared/scylladb"
 	"github.com/gocql/gocql"
 )
 
@@ -17,16 +17,28 @@ func NewScyllaClient(db *scylladb.ScyllaDB) *ScyllaClientImpl {
 	return &ScyllaClientImpl{db: db}
 }
 
-func (c *ScyllaClientImpl) GetPostings(ctx context.Context, shard int, terms []string, topN int) (PostingsResponse, error) {
+// postingsTable returns the table holding the postings of field.
+func postingsTable(field string) string {
+	if field == MatchFieldTitle {
+		return "title_index"
+	}
+	return "inverted_index"
+}
+
+// GetPostings reads the postings of terms in field. Position lists are only
+// selected when withPositions is set, since they dominate the row size for
+// frequent terms.
+func (c *ScyllaClientImpl) GetPostings(ctx context.Context, field string, shard int, terms []string, topN int, withPositions bool) (PostingsResponse, error) {
 	var results []DocScore
-	totalDocs := 0
+	docFreqSum := 0
+	table := postingsTable(field)
 
 	for _, term := range terms {
 		// Try to read doc_count from word_stats (counter table). If missing, fallback to counting inverted_index rows.
 		var docCount int
 		if err := c.db.Session.Query(`SELECT doc_count FROM word_stats WHERE word = ?`, term).WithContext(ctx).Scan(&docCount); err != nil {
 			// fallback: count rows for the term
-			iter := c.db.Session.Query(`SELECT doc_id FROM inverted_index WHERE word = ?`, term).WithContext(ctx).Iter()
+			iter := c.db.Session.Query(`SELECT doc_id FROM `+table+` WHERE word = ?`, term).WithContext(ctx).Iter()
 			var id gocql.UUID
 			seen := make(map[string]struct{})
 			for iter.Scan(&id) {
@@ -36,20 +48,32 @@ func (c *ScyllaClientImpl) GetPostings(ctx context.Context, shard int, terms []s
 			docCount = len(seen)
 		}
 
-		totalDocs += docCount
+		docFreqSum += docCount
 
 		// Fetch postings for the term
-		iter := c.db.Session.Query(`SELECT doc_id, term_frequency, positions FROM inverted_index WHERE word = ?`, term).WithContext(ctx).Iter()
+		query := `SELECT doc_id, term_frequency FROM ` + table + ` WHERE word = ?`
+		if withPositions {
+			query = `SELECT doc_id, term_frequency, positions FROM ` + table + ` WHERE word = ?`
+		}
+		iter := c.db.Session.Query(query, term).WithContext(ctx).Iter()
 		var docID gocql.UUID
 		var tf int
 		var positions []int
-		for iter.Scan(&docID, &tf, &positions) {
+		dest := []any{&docID, &tf}
+		if withPositions {
+			dest = append(dest, &positions)
+		}
+		for iter.Scan(dest...) {
 			ds := DocScore{
 				DocID:   docID.String(),
+				Term:    term,
 				TF:      tf,
-				DocLen:  len(positions),
 				DocFreq: docCount,
 			}
+			if withPositions {
+				ds.Positions = positions
+				positions = nil
+			}
 			results = append(results, ds)
 		}
 		if err := iter.Close(); err != nil {
@@ -63,5 +87,178 @@ func (c *ScyllaClientImpl) GetPostings(ctx context.Context, shard int, terms []s
 		results = results[:topN]
 	}
 
-	return PostingsResponse{ShardID: shard, Results: results, DocCount: totalDocs}, nil
+	if err := c.fillDocLengths(ctx, field, results); err != nil {
+		return PostingsResponse{}, err
+	}
+
+	return PostingsResponse{ShardID: shard, Field: field, Results: results, DocCount: docFreqSum}, nil
+}
+
+// docLookupBatchSize caps the doc_ids per IN lookup.
+const docLookupBatchSize = 100
+
+// fillDocLengths sets DocLen to the token count of field in each document.
+// Documents indexed before the length was stored fall back to the term's TF.
+func (c *ScyllaClientImpl) fillDocLengths(ctx context.Context, field string, results []DocScore) error {
+	column := "doc_length"
+	if field == MatchFieldTitle {
+		column = "title_length"
+	}
+
+	ids := make([]gocql.UUID, 0, len(results))
+	seen := make(map[string]bool, len(results))
+	for _, r := range results {
+		if seen[r.DocID] {
+			continue
+		}
+		seen[r.DocID] = true
+		id, err := gocql.ParseUUID(r.DocID)
+		if err != nil {
+			continue
+		}
+		ids = append(ids, id)
+	}
+
+	lengths := make(map[string]int, len(ids))
+	for i := 0; i < len(ids); i += docLookupBatchSize {
+		end := min(i+docLookupBatchSize, len(ids))
+		iter := c.db.Session.Query(`SELECT doc_id, `+column+` FROM documents WHERE doc_id IN ?`, ids[i:end]).
+			WithContext(ctx).Iter()
+		var id gocql.UUID
+		var length int
+		for iter.Scan(&id, &length) {
+			lengths[id.String()] = length
+		}
+		if err := iter.Close(); err != nil {
+			return err
+		}
+	}
+
+	for i := range results {
+		results[i].DocLen = lengths[results[i].DocID]
+		if results[i].DocLen <= 0 {
+			results[i].DocLen = results[i].TF
+		}
+	}
+	return nil
+}
+
+// GetDocFreqs returns the document frequency of each term from word_stats.
+// Terms without a stats row are reported as 0.
+func (c *ScyllaClientImpl) GetDocFreqs(ctx context.Context, terms []string) (map[string]int, error) {
+	freqs := make(map[string]int, len(terms))
+	for _, term := range terms {
+		var docCount int
+		err := c.db.Session.Query(`SELECT doc_count FROM word_stats WHERE word = ?`, term).WithContext(ctx).Scan(&docCount)
+		if err != nil && err != gocql.ErrNotFound {
+			return nil, err
+		}
+		freqs[term] = docCount
+	}
+	return freqs, nil
+}
+
+// GetCorpusSize returns the number of indexed documents.
+func (c *ScyllaClientImpl) GetCorpusSize(ctx context.Context) (int, error) {
+	var count int
+	if err := c.db.Session.Query(`SELECT COUNT(*) FROM documents`).WithContext(ctx).Scan(&count); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// GetVocabulary reads every word in word_stats with its document count.
+// Words no document contains any more are left out.
+func (c *ScyllaClientImpl) GetVocabulary(ctx context.Context) (map[string]int, error) {
+	words := make(map[string]int)
+	iter := c.db.Session.Query(`SELECT word, doc_count FROM word_stats`).WithContext(ctx).Iter()
+	var word string
+	var docCount int
+	for iter.Scan(&word, &docCount) {
+		if docCount > 0 {
+			words[word] = docCount
+		}
+	}
+	if err := iter.Close(); err != nil {
+		return nil, err
+	}
+	return words, nil
+}
+
+// GetCollectionStats returns the corpus totals kept in collection_stats by the
+// indexer. They are all 0 when the row doesn't exist yet.
+func (c *ScyllaClientImpl) GetCollectionStats(ctx context.Context) (CollectionStats, error) {
+	var docs, tokens, titleTokens int64
+	err := c.db.Session.Query(`SELECT total_documents, total_tokens, total_title_tokens FROM collection_stats WHERE name = ?`, scylladb.CollectionStatsRow).
+		WithContext(ctx).Scan(&docs, &tokens, &titleTokens)
+	if err == gocql.ErrNotFound {
+		return CollectionStats{}, nil
+	}
+	if err != nil {
+		return CollectionStats{}, err
+	}
+	return CollectionStats{Documents: int(docs), Tokens: int(tokens), TitleTokens: int(titleTokens)}, nil
+}
+
+// DocsContaining returns the subset of docIDs with a posting in field for at
+// least one of terms.
+func (c *ScyllaClientImpl) DocsContaining(ctx context.Context, field string, terms []string, docIDs []string) (map[string]bool, error) {
+	ids := parseDocIDs(docIDs)
+	found := make(map[string]bool)
+	for _, term := range terms {
+		for i := 0; i < len(ids); i += docLookupBatchSize {
+			end := min(i+docLookupBatchSize, len(ids))
+			iter := c.db.Session.Query(`SELECT doc_id FROM `+postingsTable(field)+` WHERE word = ? AND doc_id IN ?`, term, ids[i:end]).
+				WithContext(ctx).Iter()
+			var id gocql.UUID
+			for iter.Scan(&id) {
+				found[id.String()] = true
+			}
+			if err := iter.Close(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return found, nil
+}
+
+// DocsWithWordPair returns the subset of docIDs whose bigram_index rows list
+// pair in one of fields.
+func (c *ScyllaClientImpl) DocsWithWordPair(ctx context.Context, fields []string, pair string, docIDs []string) (map[string]bool, error) {
+	ids := parseDocIDs(docIDs)
+	wanted := make(map[string]bool, len(fields))
+	for _, f := range fields {
+		wanted[f] = true
+	}
+
+	found := make(map[string]bool)
+	for i := 0; i < len(ids); i += docLookupBatchSize {
+		end := min(i+docLookupBatchSize, len(ids))
+		iter := c.db.Session.Query(`SELECT doc_id, field FROM bigram_index WHERE word_pair = ? AND doc_id IN ?`, pair, ids[i:end]).
+			WithContext(ctx).Iter()
+		var id gocql.UUID
+		var field string
+		for iter.Scan(&id, &field) {
+			if wanted[field] {
+				found[id.String()] = true
+			}
+		}
+		if err := iter.Close(); err != nil {
+			return nil, err
+		}
+	}
+	return found, nil
+}
+
+// parseDocIDs parses docIDs as UUIDs, skipping any that aren't.
+func parseDocIDs(docIDs []string) []gocql.UUID {
+	ids := make([]gocql.UUID, 0, len(docIDs))
+	for _, docID := range docIDs {
+		id, err := gocql.ParseUUID(docID)
+		if err != nil {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	return ids
 }
@@ -2,14 +2,16 @@ package service
 
 import (
 	"context"
-	"log"
-	"sort"
+	"fmt"
+	"log/slog"
 	"strings"
 	"time"
 
-	"github.com/amrrdev/trawl/services/search/internal/scylladb"
-	"github.com/amrrdev/trawl/services/search/internal/tokenizer"
+	"github.com/amrrdev/trawl/services/shared/apierror"
+	"github.com/amrrdev/trawl/services/shared/metrics"
+	"github.com/amrrdev/trawl/services/shared/scylladb"
 	"github.com/amrrdev/trawl/services/shared/storage"
+	"github.com/amrrdev/trawl/services/shared/tokenizer"
 	"github.com/gocql/gocql"
 )
 
@@ -18,87 +20,702 @@ type Search struct {
 	tokenizer *tokenizer.Tokenizer
 	minio     *storage.Storage
 	searcher  *Searcher
+	config    *Config
+}
+
+type SearchResponse struct {
+	Results []SearchResult `json:"results"`
+	// Total estimates how many documents matched, across all pages.
+	Total        int                       `json:"total"`
+	Page         int                       `json:"page"`
+	PageSize     int                       `json:"page_size"`
+	Facets       map[string]map[string]int `json:"facets,omitempty"`
+	SkippedTerms []string                  `json:"skipped_terms,omitempty"`
+	// ExpandedTerms lists, for a fuzzy search, the indexed words each query
+	// term was expanded to.
+	ExpandedTerms map[string][]string `json:"expanded_terms,omitempty"`
 }
 
 type SearchResult struct {
-	DocID       string  `json:"doc_id"`
-	Title       string  `json:"title"`
-	Author      string  `json:"author"`
-	Score       float64 `json:"score"`
-	Snippet     string  `json:"snippet,omitempty"`
-	DownloadURL string  `json:"download_url"`
+	DocID  string `json:"doc_id,omitempty"`
+	Title  string `json:"title,omitempty"`
+	Author string `json:"author,omitempty"`
+	// Score is nil only when FieldScore wasn't requested; a zero score is
+	// still returned.
+	Score       *float64 `json:"score,omitempty"`
+	Snippet     string   `json:"snippet,omitempty"`
+	DownloadURL string   `json:"download_url,omitempty"`
+	// Highlights locates the query terms in the document; only set when
+	// SearchOptions.Highlights is.
+	Highlights []Highlight `json:"highlights,omitempty"`
+	// Debug explains the score; only set when SearchOptions.Explain is.
+	Debug *Explanation `json:"debug,omitempty"`
+}
+
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+	// MaxResultDepth bounds page * page_size, i.e. how many candidates the
+	// searcher has to rank to serve a page.
+	MaxResultDepth = 1000
+	// filterOverfetch multiplies the candidates ranked when metadata filters
+	// apply, since filtering after retrieval drops some of them.
+	filterOverfetch = 3
+)
+
+// SearchOptions carries per-request knobs for Search. Unset options fall back
+// to the user's saved preferences, then to system defaults.
+type SearchOptions struct {
+	// UserID identifies whose saved preferences apply.
+	UserID string
+	// Operator is OperatorOr or OperatorAnd.
+	Operator string
+	// Page is the 1-based result page; zero means the first page.
+	Page int
+	// PageSize is the number of results per page.
+	PageSize int
+	// K1 and B override the configured BM25 parameters for this request.
+	// K1 must be within [0, 3] and B within [0, 1].
+	K1 *float64
+	B  *float64
+	// Fields selects which SearchResult fields are computed and returned.
+	// An empty list selects all of them.
+	Fields []string
+	// OmitDownloadURLs drops download_url from the selected fields, so no URL
+	// is presigned.
+	OmitDownloadURLs bool
+	// Sort lists orderings applied in turn, later keys breaking ties of
+	// earlier ones: relevance (default), newest, oldest, title, author.
+	// BM25 still decides which candidates make the top-K; other orders only
+	// re-sort that set.
+	Sort []string
+	// Languages restricts results to documents whose stored language code is
+	// in the list. Documents without a language are governed by
+	// Config.IncludeUnknownLanguage. Empty means no restriction.
+	Languages []string
+	// MatchFields restricts which indexed fields (MatchFieldBody,
+	// MatchFieldTitle) the query is matched against. Empty means both.
+	MatchFields []string
+	// Fuzzy tolerates typos by also matching indexed words within one or two
+	// edits of each query term.
+	Fuzzy bool
+	// Author keeps only documents by this author (case-insensitive). Empty
+	// means no restriction.
+	Author string
+	// Scope is ScopeAll (default), searching every user's documents, or
+	// ScopeMine, keeping only the documents UserID uploaded. A document
+	// whose content another user indexed first is ranked on that user's
+	// copy, which ScopeMine reports as the caller's own document instead.
+	Scope string
+	// From and To keep only documents indexed within [From, To]. Either may
+	// be nil for an open-ended range.
+	From *time.Time
+	To   *time.Time
+	// Explain adds each result's per-term BM25 breakdown in its Debug
+	// field, for tuning relevance.
+	Explain bool
+	// Highlights adds the positions of the query terms in each result's
+	// searched fields, at most MaxHighlightPositions of them.
+	Highlights bool
+	// MinScore drops results scoring below it. MinScoreRatio drops results
+	// scoring below that fraction of the best result's score, which unlike
+	// MinScore doesn't depend on the corpus. Zero disables either.
+	MinScore      float64
+	MinScoreRatio float64
+}
+
+const (
+	FieldDocID       = "doc_id"
+	FieldTitle       = "title"
+	FieldAuthor      = "author"
+	FieldScore       = "score"
+	FieldSnippet     = "snippet"
+	FieldDownloadURL = "download_url"
+)
+
+var allFields = []string{FieldDocID, FieldTitle, FieldAuthor, FieldScore, FieldSnippet, FieldDownloadURL}
+
+// Search scopes.
+const (
+	ScopeAll  = "all"
+	ScopeMine = "mine"
+)
+
+func resolveQueryOptions(opts SearchOptions) (QueryOptions, error) {
+	operator := strings.ToLower(strings.TrimSpace(opts.Operator))
+	switch operator {
+	case "":
+		operator = OperatorOr
+	case OperatorOr, OperatorAnd:
+	default:
+		return QueryOptions{}, apierror.InvalidInput("invalid operator %q", opts.Operator)
+	}
+
+	if opts.Page < 0 {
+		return QueryOptions{}, apierror.InvalidInput("invalid page %d: must not be negative", opts.Page)
+	}
+	if opts.PageSize < 0 || opts.PageSize > MaxPageSize {
+		return QueryOptions{}, apierror.InvalidInput("invalid page_size %d: must be between 1 and %d", opts.PageSize, MaxPageSize)
+	}
+	depth := opts.Page * opts.PageSize
+	if depth > MaxResultDepth {
+		return QueryOptions{}, apierror.InvalidInput("invalid page %d: results beyond %d are not available", opts.Page, MaxResultDepth)
+	}
+
+	if opts.K1 != nil && (*opts.K1 < 0 || *opts.K1 > 3) {
+		return QueryOptions{}, apierror.InvalidInput("invalid k1 %v: must be between 0 and 3", *opts.K1)
+	}
+	if opts.B != nil && (*opts.B < 0 || *opts.B > 1) {
+		return QueryOptions{}, apierror.InvalidInput("invalid b %v: must be between 0 and 1", *opts.B)
+	}
+	if opts.MinScore < 0 {
+		return QueryOptions{}, apierror.InvalidInput("invalid min_score %v: must not be negative", opts.MinScore)
+	}
+	if opts.MinScoreRatio < 0 || opts.MinScoreRatio > 1 {
+		return QueryOptions{}, apierror.InvalidInput("invalid min_score_ratio %v: must be between 0 and 1", opts.MinScoreRatio)
+	}
+	if opts.From != nil && opts.To != nil && opts.From.After(*opts.To) {
+		return QueryOptions{}, apierror.InvalidInput("invalid date range: from is after to")
+	}
+	switch strings.ToLower(strings.TrimSpace(opts.Scope)) {
+	case "", ScopeAll:
+	case ScopeMine:
+		if strings.TrimSpace(opts.UserID) == "" {
+			return QueryOptions{}, apierror.InvalidInput("scope %q needs an authenticated user", ScopeMine)
+		}
+	default:
+		return QueryOptions{}, apierror.InvalidInput("invalid scope %q", opts.Scope)
+	}
+	if hasMetadataFilters(opts) {
+		depth *= filterOverfetch
+	}
+
+	matchFields, err := resolveMatchFields(opts.MatchFields)
+	if err != nil {
+		return QueryOptions{}, err
+	}
+
+	return QueryOptions{
+		TopK:        depth,
+		Operator:    operator,
+		K1:          opts.K1,
+		B:           opts.B,
+		MatchFields: matchFields,
+		Fuzzy:       opts.Fuzzy,
+		Explain:     opts.Explain,
+	}, nil
+}
+
+// resolveMatchFields validates the indexed fields to search and returns them
+// body first. Empty selects all of them.
+func resolveMatchFields(fields []string) ([]string, error) {
+	if len(fields) == 0 {
+		return []string{MatchFieldBody, MatchFieldTitle}, nil
+	}
+
+	selected := make(map[string]bool, len(fields))
+	for _, f := range fields {
+		f = strings.ToLower(strings.TrimSpace(f))
+		if f != MatchFieldBody && f != MatchFieldTitle {
+			return nil, apierror.InvalidInput("invalid match field %q", f)
+		}
+		selected[f] = true
+	}
+
+	var resolved []string
+	for _, f := range []string{MatchFieldBody, MatchFieldTitle} {
+		if selected[f] {
+			resolved = append(resolved, f)
+		}
+	}
+	return resolved, nil
+}
+
+func resolveFields(fields []string) (map[string]bool, error) {
+	if len(fields) == 0 {
+		fields = allFields
+	}
+
+	valid := make(map[string]bool, len(allFields))
+	for _, f := range allFields {
+		valid[f] = true
+	}
+
+	selected := make(map[string]bool, len(fields))
+	for _, f := range fields {
+		f = strings.ToLower(strings.TrimSpace(f))
+		if !valid[f] {
+			return nil, apierror.InvalidInput("invalid field %q", f)
+		}
+		selected[f] = true
+	}
+	return selected, nil
 }
 
-func NewSearch(scylla *scylladb.ScyllaDB, minio *storage.Storage) *Search {
+// Config holds the ranking parameters used by the BM25 searcher and the
+// facets computed alongside results.
+type Config struct {
+	K1    float64
+	B     float64
+	Delta float64
+	TFCap int
+
+	MinDocFreq      int
+	MaxDocFreqRatio float64
+
+	// TitleWeight and BodyWeight are the BM25F field weights used when a
+	// query searches both the title and the body.
+	TitleWeight float64
+	BodyWeight  float64
+
+	// FuzzyMaxExpansions caps the indexed words each term of a fuzzy query
+	// expands to; zero disables fuzzy search. FuzzyPenalty is the fraction
+	// of score an expansion loses per edit.
+	FuzzyMaxExpansions int
+	FuzzyPenalty       float64
+
+	// BigramIndex answers two-word phrases from the bigram index. Only
+	// enable it once the indexer records bigrams and every document has been
+	// indexed since, or phrases in older documents stop matching.
+	BigramIndex bool
+
+	FacetFields    []string
+	MaxFacetValues int
+
+	// IncludeUnknownLanguage keeps documents with no detected language when a
+	// search filters by language.
+	IncludeUnknownLanguage bool
+
+	// DownloadURLExpiry is how long presigned download URLs stay valid.
+	DownloadURLExpiry time.Duration
+
+	// StopWords are dropped from queries. They must match the list the
+	// indexing worker used. Nil disables stop-word filtering.
+	StopWords map[string]bool
+}
+
+// DefaultConfig returns standard BM25 parameters (no BM25+ delta, no TF cap)
+// with author and file type facets.
+func DefaultConfig() *Config {
+	stopWords, _ := tokenizer.StopWordsFor("en")
+	return &Config{
+		K1:                     1.2,
+		B:                      0.75,
+		TitleWeight:            3,
+		BodyWeight:             1,
+		FuzzyMaxExpansions:     3,
+		FuzzyPenalty:           0.3,
+		DownloadURLExpiry:      24 * time.Hour,
+		FacetFields:            []string{FacetAuthor, FacetFileType},
+		MaxFacetValues:         10,
+		IncludeUnknownLanguage: true,
+		StopWords:              stopWords,
+	}
+}
+
+func NewSearch(scylla *scylladb.ScyllaDB, minio *storage.Storage, cfg *Config) *Search {
 	// create a Scylla client adapter and BM25 searcher (default shard count = 4)
 	client := NewScyllaClient(scylla)
 	searcher := NewSearcher(client, 4)
+	if cfg == nil {
+		cfg = DefaultConfig()
+	}
+	searcher.K1 = cfg.K1
+	searcher.B = cfg.B
+	searcher.Delta = cfg.Delta
+	searcher.TFCap = cfg.TFCap
+	searcher.MinDocFreq = cfg.MinDocFreq
+	searcher.MaxDocFreqRatio = cfg.MaxDocFreqRatio
+	searcher.TitleWeight = cfg.TitleWeight
+	searcher.BodyWeight = cfg.BodyWeight
+	searcher.FuzzyMaxExpansions = cfg.FuzzyMaxExpansions
+	searcher.FuzzyPenalty = cfg.FuzzyPenalty
+	searcher.BigramIndex = cfg.BigramIndex
+	searcher.Tokenizer = tokenizer.NewTokenizerWithStopWords(cfg.StopWords)
 	return &Search{
 		scylladb:  scylla,
-		tokenizer: tokenizer.NewTokenizer(),
+		tokenizer: searcher.Tokenizer,
 		minio:     minio,
 		searcher:  searcher,
+		config:    cfg,
 	}
 }
 
-func (s *Search) Search(ctx context.Context, query string) ([]SearchResult, error) {
+func (s *Search) Search(ctx context.Context, query string, opts SearchOptions) (*SearchResponse, error) {
+	return s.searchQuery(ctx, query, opts, nil)
+}
+
+// SearchStream is Search with incremental feedback: onPartial receives the
+// provisional ranking (doc IDs and scores only) as each shard responds,
+// before metadata is fetched for the final response. Scoped searches send no
+// partial rankings, since they would list other users' documents.
+func (s *Search) SearchStream(ctx context.Context, query string, opts SearchOptions, onPartial func([]SearchResult)) (*SearchResponse, error) {
+	if scopedToUser(opts) {
+		return s.searchQuery(ctx, query, opts, nil)
+	}
+	return s.searchQuery(ctx, query, opts, func(partial []DocScore) {
+		results := make([]SearchResult, 0, len(partial))
+		for _, d := range partial {
+			results = append(results, SearchResult{DocID: d.DocID, Score: &d.Score})
+		}
+		onPartial(results)
+	})
+}
+
+func (s *Search) searchQuery(ctx context.Context, query string, opts SearchOptions, onPartial func([]DocScore)) (*SearchResponse, error) {
 	query = strings.TrimSpace(query)
 	if query == "" {
-		return []SearchResult{}, nil
+		return s.search(ctx, opts, nil, nil)
 	}
+	rank := func(queryOpts QueryOptions) (*QueryResult, error) {
+		slog.DebugContext(ctx, "Search query", "query", query)
+		// Delegate candidate retrieval & scoring to the BM25 Searcher implemented in query.go
+		return s.searcher.SearchWithProgress(ctx, query, queryOpts, onPartial)
+	}
+	return s.search(ctx, opts, rank, s.queryTermSet(query))
+}
+
+// search resolves opts, ranks the candidates with rank and builds the
+// response page. A nil rank means there is nothing to search for. terms are
+// the indexed words highlighted in snippets.
+func (s *Search) search(ctx context.Context, opts SearchOptions, rank func(QueryOptions) (*QueryResult, error), terms map[string]bool) (*SearchResponse, error) {
+	start := time.Now()
+	defer func() {
+		metrics.SearchLatency.Observe(time.Since(start).Seconds())
+	}()
 
-	log.Printf("🔍 Search query (BM25): %q", query)
+	if err := s.applyPreferences(ctx, &opts); err != nil {
+		slog.WarnContext(ctx, "Failed to load preferences, using defaults", "user_id", opts.UserID, "error", err)
+	}
+	if opts.Page == 0 {
+		opts.Page = 1
+	}
+	if opts.PageSize == 0 {
+		opts.PageSize = DefaultPageSize
+	}
 
-	// Delegate candidate retrieval & scoring to the BM25 Searcher implemented in query.go
-	candidates, err := s.searcher.Search(ctx, query, 50)
+	queryOpts, err := resolveQueryOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	fields, err := resolveFields(opts.Fields)
+	if err != nil {
+		return nil, err
+	}
+	if opts.OmitDownloadURLs {
+		delete(fields, FieldDownloadURL)
+	}
+	sortKeys, err := resolveSort(opts.Sort)
+	if err != nil {
+		return nil, err
+	}
+
+	if rank == nil {
+		return &SearchResponse{Results: []SearchResult{}, Page: opts.Page, PageSize: opts.PageSize}, nil
+	}
+	queryResult, err := rank(queryOpts)
 	if err != nil {
 		return nil, err
 	}
 
+	candidates, matched := applyScoreThreshold(queryResult.Docs, queryResult.Total, opts.MinScore, opts.MinScoreRatio)
 	if len(candidates) == 0 {
-		log.Printf("⚠️  No candidates returned from searcher for query: %q", query)
-		return []SearchResult{}, nil
+		slog.DebugContext(ctx, "No candidates for query")
+		metrics.SearchZeroResults.Inc()
+		return &SearchResponse{
+			Results:       []SearchResult{},
+			Page:          opts.Page,
+			PageSize:      opts.PageSize,
+			SkippedTerms:  queryResult.SkippedTerms,
+			ExpandedTerms: queryResult.ExpandedTerms,
+		}, nil
 	}
 
-	var results []SearchResult
+	languages := resolveLanguages(opts.Languages)
+	author := strings.ToLower(strings.TrimSpace(opts.Author))
+	owner := ""
+	if scopedToUser(opts) {
+		owner = opts.UserID
+	}
+	facets := newFacetCounter(s.config.FacetFields)
+	needsMetadata := fields[FieldTitle] || fields[FieldAuthor] || fields[FieldDownloadURL] ||
+		facets.enabled() || sortNeedsMetadata(sortKeys) || hasMetadataFilters(opts)
+
+	valid := make([]DocScore, 0, len(candidates))
+	ids := make([]gocql.UUID, 0, len(candidates))
 	for _, c := range candidates {
 		// convert doc id string to UUID for metadata lookup
 		id, err := gocql.ParseUUID(c.DocID)
 		if err != nil {
-			log.Printf("⚠️  invalid doc id from index: %s", c.DocID)
+			slog.WarnContext(ctx, "Invalid doc_id in index", "doc_id", c.DocID)
 			continue
 		}
-		doc, err := s.getDocument(ctx, id)
-		if err != nil {
-			log.Printf("⚠️  Failed to get document %s: %v", id, err)
+		valid = append(valid, c)
+		ids = append(ids, id)
+	}
+
+	var docs map[gocql.UUID]*documentResult
+	if needsMetadata {
+		if docs, err = s.getDocuments(ctx, ids); err != nil {
+			return nil, fmt.Errorf("failed to load document metadata: %w", err)
+		}
+	}
+	var duplicates map[gocql.UUID]ownDuplicate
+	if owner != "" {
+		if duplicates, err = s.ownDuplicates(ctx, docs, owner); err != nil {
+			return nil, fmt.Errorf("failed to resolve duplicates: %w", err)
+		}
+	}
+
+	// Hits keep the candidates' score order; the lookup result is unordered.
+	hits := make([]searchHit, 0, len(valid))
+	for i, c := range valid {
+		hit := searchHit{candidate: c, docID: c.DocID}
+		if needsMetadata {
+			doc, ok := docs[ids[i]]
+			if !ok {
+				slog.WarnContext(ctx, "No metadata for document", "doc_id", ids[i].String())
+				continue
+			}
+			if dup, ok := duplicates[ids[i]]; ok {
+				hit.docID, doc = dup.id.String(), dup.doc
+			}
+			if !s.matchesLanguage(doc, languages) || !matchesAuthor(doc, author) ||
+				!matchesDateRange(doc, opts.From, opts.To) || !matchesOwner(doc, owner) {
+				continue
+			}
+			facets.add(doc)
+			hit.doc = doc
+		}
+		hits = append(hits, hit)
+	}
+
+	sortHits(hits, sortKeys)
+	// Candidates dropped after retrieval (missing metadata, filters) no
+	// longer count towards the total.
+	total := matched - (len(candidates) - len(hits))
+	offset := (opts.Page - 1) * opts.PageSize
+	if offset < len(hits) {
+		hits = hits[offset:min(offset+opts.PageSize, len(hits))]
+	} else {
+		hits = nil
+	}
+
+	// Snippets and highlights mark the query terms and their expansions.
+	var matchTerms map[string]bool
+	if fields[FieldSnippet] || opts.Highlights {
+		matchTerms = make(map[string]bool, len(terms))
+		for t := range terms {
+			matchTerms[t] = true
+		}
+		for _, words := range queryResult.ExpandedTerms {
+			for _, w := range words {
+				matchTerms[w] = true
+			}
+		}
+	}
+
+	results := make([]SearchResult, 0, len(hits))
+	for _, hit := range hits {
+		result := s.project(ctx, hit, fields, matchTerms)
+		if opts.Highlights {
+			result.Highlights = s.highlights(ctx, hit.candidate.DocID, queryOpts.MatchFields, matchTerms)
+		}
+		results = append(results, result)
+	}
+
+	if total == 0 {
+		metrics.SearchZeroResults.Inc()
+	}
+
+	slog.InfoContext(ctx, "Search completed", "results", len(results), "total", total, "duration", time.Since(start))
+	return &SearchResponse{
+		Results:       results,
+		Total:         total,
+		Page:          opts.Page,
+		PageSize:      opts.PageSize,
+		Facets:        facets.result(s.config.MaxFacetValues),
+		SkippedTerms:  queryResult.SkippedTerms,
+		ExpandedTerms: queryResult.ExpandedTerms,
+	}, nil
+}
+
+// applyScoreThreshold drops the candidates scoring below minScore or below
+// minScoreRatio times the top score, and adjusts the matched total. The
+// candidates come best first, so once one is dropped so would be every match
+// past the top-K, and the total becomes exact.
+func applyScoreThreshold(candidates []DocScore, total int, minScore, minScoreRatio float64) ([]DocScore, int) {
+	if len(candidates) == 0 {
+		return candidates, total
+	}
+	threshold := max(minScore, minScoreRatio*candidates[0].Score)
+	if threshold <= 0 {
+		return candidates, total
+	}
+	for i, c := range candidates {
+		if c.Score < threshold {
+			return candidates[:i], i
+		}
+	}
+	return candidates, total
+}
+
+// resolveLanguages normalizes the requested language codes into a set, or
+// returns nil when no language filter applies.
+func resolveLanguages(languages []string) map[string]bool {
+	var set map[string]bool
+	for _, lang := range languages {
+		lang = strings.ToLower(strings.TrimSpace(lang))
+		if lang == "" {
 			continue
 		}
+		if set == nil {
+			set = make(map[string]bool, len(languages))
+		}
+		set[lang] = true
+	}
+	return set
+}
+
+func (s *Search) matchesLanguage(doc *documentResult, languages map[string]bool) bool {
+	if languages == nil {
+		return true
+	}
+	if doc.Language == "" {
+		return s.config.IncludeUnknownLanguage
+	}
+	return languages[doc.Language]
+}
+
+// hasMetadataFilters reports whether opts filters on document metadata, which
+// happens after retrieval.
+func hasMetadataFilters(opts SearchOptions) bool {
+	return len(resolveLanguages(opts.Languages)) > 0 || strings.TrimSpace(opts.Author) != "" ||
+		opts.From != nil || opts.To != nil || scopedToUser(opts)
+}
+
+// scopedToUser reports whether opts keeps only the caller's documents.
+func scopedToUser(opts SearchOptions) bool {
+	return strings.ToLower(strings.TrimSpace(opts.Scope)) == ScopeMine
+}
+
+// matchesOwner reports whether doc was uploaded by userID. An empty userID
+// matches every document.
+func matchesOwner(doc *documentResult, userID string) bool {
+	return userID == "" || doc.Owner == userID
+}
 
-		downloadURL := ""
-		if doc.FilePath != "" {
-			url, err := s.minio.GetDownloadUrl(ctx, doc.UserID, doc.FileName, 24*time.Hour)
-			if err != nil {
-				log.Printf("⚠️  Failed to generate download URL for %s: %v", doc.FileName, err)
-			} else {
-				downloadURL = url
+// ownDuplicate is a caller's document whose content another user indexed
+// first.
+type ownDuplicate struct {
+	id  gocql.UUID
+	doc *documentResult
+}
+
+// ownDuplicates finds, among docs owned by other users, the ones userID holds
+// a duplicate of, keyed by the document that holds the postings. Duplicates
+// have no postings of their own, so this is how ScopeMine finds them. If
+// userID uploaded the same content more than once, the first copy listed in
+// content_refs is used.
+func (s *Search) ownDuplicates(ctx context.Context, docs map[gocql.UUID]*documentResult, userID string) (map[gocql.UUID]ownDuplicate, error) {
+	refs := make(map[gocql.UUID][]gocql.UUID)
+	var refIDs []gocql.UUID
+	for id, doc := range docs {
+		if doc.Owner == userID || doc.ContentHash == "" {
+			continue
+		}
+		iter := s.scylladb.Session.Query(`SELECT doc_id FROM content_refs WHERE content_hash = ?`, doc.ContentHash).
+			WithContext(ctx).Iter()
+		var ref gocql.UUID
+		for iter.Scan(&ref) {
+			if ref != id {
+				refs[id] = append(refs[id], ref)
+				refIDs = append(refIDs, ref)
 			}
 		}
+		if err := iter.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if len(refIDs) == 0 {
+		return nil, nil
+	}
 
-		results = append(results, SearchResult{
-			DocID:       c.DocID,
-			Title:       doc.Title,
-			Author:      doc.Author,
-			Score:       c.Score,
-			DownloadURL: downloadURL,
-		})
+	refDocs, err := s.getDocuments(ctx, refIDs)
+	if err != nil {
+		return nil, err
 	}
+	return pickOwnDuplicates(refs, refDocs, userID), nil
+}
 
-	sort.Slice(results, func(i, j int) bool { return results[i].Score > results[j].Score })
-	if len(results) > 50 {
-		results = results[:50]
+// pickOwnDuplicates returns, for each document in refs, the first of its
+// references that refDocs shows userID owns.
+func pickOwnDuplicates(refs map[gocql.UUID][]gocql.UUID, refDocs map[gocql.UUID]*documentResult, userID string) map[gocql.UUID]ownDuplicate {
+	duplicates := make(map[gocql.UUID]ownDuplicate)
+	for id, candidates := range refs {
+		for _, ref := range candidates {
+			if doc, ok := refDocs[ref]; ok && doc.Owner == userID {
+				duplicates[id] = ownDuplicate{id: ref, doc: doc}
+				break
+			}
+		}
 	}
-	log.Printf("🔍 Generated %d search results (BM25)", len(results))
-	return results, nil
+	return duplicates
+}
+
+// matchesAuthor reports whether doc is by author, which must be lowercased.
+// An empty author matches every document.
+func matchesAuthor(doc *documentResult, author string) bool {
+	return author == "" || strings.ToLower(strings.TrimSpace(doc.Author)) == author
+}
+
+// matchesDateRange reports whether doc was indexed within [from, to].
+func matchesDateRange(doc *documentResult, from, to *time.Time) bool {
+	if from != nil && doc.CreatedAt.Before(*from) {
+		return false
+	}
+	if to != nil && doc.CreatedAt.After(*to) {
+		return false
+	}
+	return true
+}
+
+// project builds the client-facing result for a hit, computing only the
+// requested fields. Download URLs are presigned and snippets built only when
+// asked for.
+func (s *Search) project(ctx context.Context, hit searchHit, fields map[string]bool, snippetTerms map[string]bool) SearchResult {
+	result := SearchResult{}
+	if fields[FieldDocID] {
+		result.DocID = hit.docID
+	}
+	if fields[FieldScore] {
+		score := hit.candidate.Score
+		result.Score = &score
+	}
+	result.Debug = hit.candidate.Explanation
+	if fields[FieldSnippet] {
+		result.Snippet = s.snippet(ctx, hit.candidate.DocID, snippetTerms)
+	}
+
+	doc := hit.doc
+	if doc == nil {
+		return result
+	}
+	if fields[FieldTitle] {
+		result.Title = doc.Title
+	}
+	if fields[FieldAuthor] {
+		result.Author = doc.Author
+	}
+	if fields[FieldDownloadURL] && doc.FilePath != "" {
+		url, err := s.minio.GetDownloadUrl(ctx, doc.UserID, doc.FileName, s.config.DownloadURLExpiry)
+		if err != nil {
+			slog.WarnContext(ctx, "Failed to generate download URL", "file_name", doc.FileName, "error", err)
+		} else {
+			result.DownloadURL = url
+		}
+	}
+	return result
 }
 
 func (s *Search) tokenExistsInIndex(ctx context.Context, word string) (bool, error) {
@@ -122,21 +739,29 @@ type invertedIndexResult struct {
 	Positions []int
 }
 
-func (s *Search) queryInvertedIndex(ctx context.Context, word string) ([]invertedIndexResult, error) {
-	query := `SELECT doc_id, term_frequency, positions FROM inverted_index WHERE word = ?`
+func (s *Search) queryInvertedIndex(ctx context.Context, word string, withPositions bool) ([]invertedIndexResult, error) {
+	query := `SELECT doc_id, term_frequency FROM inverted_index WHERE word = ?`
+	if withPositions {
+		query = `SELECT doc_id, term_frequency, positions FROM inverted_index WHERE word = ?`
+	}
 	iter := s.scylladb.Session.Query(query, word).WithContext(ctx).Iter()
 
 	var results []invertedIndexResult
 	var docID gocql.UUID
 	var frequency int
 	var positions []int
+	dest := []any{&docID, &frequency}
+	if withPositions {
+		dest = append(dest, &positions)
+	}
 
-	for iter.Scan(&docID, &frequency, &positions) {
+	for iter.Scan(dest...) {
 		results = append(results, invertedIndexResult{
 			DocID:     docID,
 			Frequency: frequency,
 			Positions: positions,
 		})
+		positions = nil
 	}
 
 	if err := iter.Close(); err != nil {
@@ -147,39 +772,89 @@ func (s *Search) queryInvertedIndex(ctx context.Context, word string) ([]inverte
 }
 
 type documentResult struct {
-	Title    string
-	Author   string
-	FilePath string
-	UserID   string
-	FileName string
+	Title     string
+	Author    string
+	FileType  string
+	Language  string
+	FilePath  string
+	UserID    string
+	FileName  string
+	CreatedAt time.Time
+	// Owner is the user who uploaded the document. UserID and FileName only
+	// locate the file in storage.
+	Owner string
+	// ContentHash identifies the document's text; it is empty for documents
+	// indexed before content hashing.
+	ContentHash string
 }
 
 func (s *Search) getDocument(ctx context.Context, docID gocql.UUID) (*documentResult, error) {
-	query := `SELECT title, author, file_path FROM documents WHERE doc_id = ?`
-	var title, author, filePath string
+	query := `SELECT user_id, title, author, file_type, language, file_path, file_name, created_at FROM documents WHERE doc_id = ?`
+	var owner, title, author, fileType, language, filePath, fileName string
+	var createdAt time.Time
 
-	err := s.scylladb.Session.Query(query, docID).WithContext(ctx).Scan(&title, &author, &filePath)
+	err := s.scylladb.Session.Query(query, docID).WithContext(ctx).Scan(&owner, &title, &author, &fileType, &language, &filePath, &fileName, &createdAt)
 	if err != nil {
 		return nil, err
 	}
+	return newDocumentResult(owner, title, author, fileType, language, filePath, fileName, createdAt), nil
+}
+
+// getDocuments loads the metadata of docIDs with one IN query per
+// docLookupBatchSize documents. Documents without a row are absent from the
+// result.
+func (s *Search) getDocuments(ctx context.Context, docIDs []gocql.UUID) (map[gocql.UUID]*documentResult, error) {
+	docs := make(map[gocql.UUID]*documentResult, len(docIDs))
+	for i := 0; i < len(docIDs); i += docLookupBatchSize {
+		end := min(i+docLookupBatchSize, len(docIDs))
+		query := `SELECT doc_id, user_id, title, author, file_type, language, file_path, file_name, created_at, content_hash FROM documents WHERE doc_id IN ?`
+		iter := s.scylladb.Session.Query(query, docIDs[i:end]).WithContext(ctx).Iter()
 
+		var id gocql.UUID
+		var owner, title, author, fileType, language, filePath, fileName, contentHash string
+		var createdAt time.Time
+		for iter.Scan(&id, &owner, &title, &author, &fileType, &language, &filePath, &fileName, &createdAt, &contentHash) {
+			docs[id] = newDocumentResult(owner, title, author, fileType, language, filePath, fileName, createdAt)
+			docs[id].ContentHash = contentHash
+		}
+		if err := iter.Close(); err != nil {
+			return nil, err
+		}
+	}
+	return docs, nil
+}
+
+// newDocumentResult builds a document's metadata from its row. owner and
+// fileName are the user_id and file_name columns; documents indexed before
+// those were added take them from their file_path.
+func newDocumentResult(owner, title, author, fileType, language, filePath, fileName string, createdAt time.Time) *documentResult {
 	// Parse file_path to extract userID and fileName
 	// file_path format: "userID/filename"
 	userID := ""
-	fileName := ""
+	pathName := ""
 	if filePath != "" {
 		parts := strings.Split(filePath, "/")
 		if len(parts) >= 2 {
 			userID = parts[0]
-			fileName = strings.Join(parts[1:], "/") // Handle filenames with slashes
+			pathName = strings.Join(parts[1:], "/") // Handle filenames with slashes
 		}
 	}
+	if owner == "" {
+		owner = userID
+	}
+	if fileName == "" {
+		fileName = pathName
+	}
 
 	return &documentResult{
-		Title:    title,
-		Author:   author,
-		FilePath: filePath,
-		UserID:   userID,
-		FileName: fileName,
-	}, nil
+		Title:     title,
+		Author:    author,
+		FileType:  fileType,
+		Language:  strings.ToLower(language),
+		FilePath:  filePath,
+		Owner:     owner,
+		UserID:    userID,
+		FileName:  fileName,
+		CreatedAt: createdAt,
+	}
 }
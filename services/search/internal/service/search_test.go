@@ -0,0 +1,259 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"maps"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/amrrdev/trawl/services/shared/apierror"
+	"github.com/gocql/gocql"
+)
+
+func TestPickOwnDuplicates(t *testing.T) {
+	owned := gocql.MustRandomUUID()
+	ownedCopy := gocql.MustRandomUUID()
+	otherCopy := gocql.MustRandomUUID()
+	secondCopy := gocql.MustRandomUUID()
+	refDocs := map[gocql.UUID]*documentResult{
+		ownedCopy:  {Owner: "alice"},
+		otherCopy:  {Owner: "bob"},
+		secondCopy: {Owner: "alice"},
+	}
+
+	tests := []struct {
+		name string
+		refs []gocql.UUID
+		want *gocql.UUID
+	}{
+		{"caller holds a copy", []gocql.UUID{otherCopy, ownedCopy}, &ownedCopy},
+		{"first of several copies", []gocql.UUID{ownedCopy, secondCopy}, &ownedCopy},
+		{"only other users' copies", []gocql.UUID{otherCopy}, nil},
+		{"copy without metadata", []gocql.UUID{gocql.MustRandomUUID()}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := pickOwnDuplicates(map[gocql.UUID][]gocql.UUID{owned: tt.refs}, refDocs, "alice")
+			dup, ok := got[owned]
+			if tt.want == nil {
+				if ok {
+					t.Errorf("picked %s, want none", dup.id)
+				}
+				return
+			}
+			if !ok || dup.id != *tt.want {
+				t.Errorf("picked %v (found %v), want %s", dup.id, ok, *tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveFields(t *testing.T) {
+	tests := []struct {
+		name    string
+		fields  []string
+		want    []string
+		wantErr error
+	}{
+		{"defaults to every field", nil, allFields, nil},
+		{"normalizes names", []string{" Title", "SCORE "}, []string{FieldTitle, FieldScore}, nil},
+		{"unknown field", []string{FieldTitle, "body"}, nil, apierror.ErrInvalidInput},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveFields(tt.fields)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if keys := slices.Sorted(maps.Keys(got)); !slices.Equal(keys, slices.Sorted(slices.Values(tt.want))) {
+				t.Errorf("fields = %v, want %v", keys, tt.want)
+			}
+		})
+	}
+}
+
+func TestProjectFields(t *testing.T) {
+	hit := func(score float64) searchHit {
+		return searchHit{
+			candidate: DocScore{DocID: "doc-1", Score: score},
+			doc:       &documentResult{Title: "Report", Author: "Alice"},
+			docID:     "doc-1",
+		}
+	}
+
+	tests := []struct {
+		name   string
+		hit    searchHit
+		fields []string
+		want   map[string]any
+	}{
+		{"selected fields only", hit(1.5), []string{FieldDocID, FieldTitle},
+			map[string]any{"doc_id": "doc-1", "title": "Report"}},
+		{"score", hit(1.5), []string{FieldScore}, map[string]any{"score": 1.5}},
+		{"zero score kept", hit(0), []string{FieldDocID, FieldScore},
+			map[string]any{"doc_id": "doc-1", "score": 0.0}},
+		{"hit without metadata", searchHit{candidate: DocScore{DocID: "doc-2", Score: 2}, docID: "doc-2"},
+			[]string{FieldTitle, FieldAuthor, FieldScore}, map[string]any{"score": 2.0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fields, err := resolveFields(tt.fields)
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, err := json.Marshal((&Search{}).project(context.Background(), tt.hit, fields, nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got map[string]any
+			if err := json.Unmarshal(data, &got); err != nil {
+				t.Fatal(err)
+			}
+			if !maps.Equal(got, tt.want) {
+				t.Errorf("result = %s, want %v", data, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchesLanguage(t *testing.T) {
+	tests := []struct {
+		name           string
+		requested      []string
+		includeUnknown bool
+		docLanguage    string
+		want           bool
+	}{
+		{"no filter", nil, false, "de", true},
+		{"blank codes are no filter", []string{" ", ""}, false, "de", true},
+		{"requested language", []string{"en", "de"}, false, "de", true},
+		{"codes normalized", []string{" EN "}, false, "en", true},
+		{"other language", []string{"en"}, true, "fr", false},
+		{"unknown language kept", []string{"en"}, true, "", true},
+		{"unknown language dropped", []string{"en"}, false, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Search{config: &Config{IncludeUnknownLanguage: tt.includeUnknown}}
+			doc := &documentResult{Language: tt.docLanguage}
+			if got := s.matchesLanguage(doc, resolveLanguages(tt.requested)); got != tt.want {
+				t.Errorf("matchesLanguage = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewDocumentResult(t *testing.T) {
+	created := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name                          string
+		owner, filePath, fileName     string
+		language                      string
+		wantOwner, wantUser, wantName string
+		wantLanguage                  string
+	}{
+		{"columns set", "user-1", "user-1/uploads/report.pdf", "Report.pdf", "EN",
+			"user-1", "user-1", "Report.pdf", "en"},
+		{"older rows fall back to the path", "", "user-2/notes/todo.txt", "", "",
+			"user-2", "user-2", "notes/todo.txt", ""},
+		{"path without a user", "", "orphan.txt", "", "de",
+			"", "", "", "de"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := newDocumentResult(tt.owner, "Title", "Author", "pdf", tt.language, tt.filePath, tt.fileName, created)
+			if doc.Owner != tt.wantOwner || doc.UserID != tt.wantUser || doc.FileName != tt.wantName {
+				t.Errorf("owner, user, name = %q, %q, %q, want %q, %q, %q",
+					doc.Owner, doc.UserID, doc.FileName, tt.wantOwner, tt.wantUser, tt.wantName)
+			}
+			if doc.Language != tt.wantLanguage {
+				t.Errorf("language = %q, want %q", doc.Language, tt.wantLanguage)
+			}
+			if doc.Title != "Title" || doc.Author != "Author" || doc.FilePath != tt.filePath || !doc.CreatedAt.Equal(created) {
+				t.Errorf("row not copied: %+v", doc)
+			}
+		})
+	}
+}
+
+func TestScopeMine(t *testing.T) {
+	tests := []struct {
+		name    string
+		scope   string
+		userID  string
+		wantErr bool
+	}{
+		{"default", "", "", false},
+		{"all", ScopeAll, "", false},
+		{"mine", ScopeMine, "alice", false},
+		{"mine, any case", " Mine ", "alice", false},
+		{"mine without a user", ScopeMine, " ", true},
+		{"unknown scope", "team", "alice", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := SearchOptions{Scope: tt.scope, UserID: tt.userID, Page: 1, PageSize: 10}
+			_, err := resolveQueryOptions(opts)
+			if tt.wantErr {
+				if !errors.Is(err, apierror.ErrInvalidInput) {
+					t.Errorf("err = %v, want %v", err, apierror.ErrInvalidInput)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if scopedToUser(opts) != (tt.scope != "" && tt.scope != ScopeAll) {
+				t.Errorf("scopedToUser = %v for scope %q", scopedToUser(opts), tt.scope)
+			}
+			if !hasMetadataFilters(opts) && scopedToUser(opts) {
+				t.Error("scoped search doesn't load the owners it filters on")
+			}
+		})
+	}
+}
+
+func TestMatchesOwner(t *testing.T) {
+	created := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	docs := map[string]*documentResult{
+		"alice's":          newDocumentResult("alice", "A", "", "", "", "alice/a.pdf", "a.pdf", created),
+		"bob's":            newDocumentResult("bob", "B", "", "", "", "bob/b.pdf", "b.pdf", created),
+		"alice's, legacy":  newDocumentResult("", "C", "", "", "", "alice/c.pdf", "", created),
+		"stored under bob": newDocumentResult("alice", "D", "", "", "", "bob/d.pdf", "d.pdf", created),
+	}
+
+	tests := []struct {
+		name  string
+		owner string
+		want  []string
+	}{
+		{"unscoped", "", []string{"alice's", "alice's, legacy", "bob's", "stored under bob"}},
+		{"alice", "alice", []string{"alice's", "alice's, legacy", "stored under bob"}},
+		{"bob", "bob", []string{"bob's"}},
+		{"nobody", "carol", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for name, doc := range docs {
+				if matchesOwner(doc, tt.owner) {
+					got = append(got, name)
+				}
+			}
+			slices.Sort(got)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("matched %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/amrrdev/trawl/services/shared/apierror"
+	"github.com/gocql/gocql"
+)
+
+const (
+	// DefaultSimilarLimit is the number of similar documents returned when
+	// the request doesn't say.
+	DefaultSimilarLimit = 10
+	// similarSeedTerms caps the document terms the similar-documents query is
+	// built from, which bounds the postings it reads.
+	similarSeedTerms = 20
+)
+
+// Similar returns the documents most like docID: its similarSeedTerms most
+// frequent indexed words are run as an OR query, and the best BM25 matches
+// other than docID itself come back as a single page of at most limit
+// results. A duplicate is matched on the postings of the document it
+// duplicates, which is then left out too.
+func (s *Search) Similar(ctx context.Context, userID, docID string, limit int) (*SearchResponse, error) {
+	id, err := gocql.ParseUUID(docID)
+	if err != nil {
+		return nil, apierror.InvalidInput("invalid doc_id %q", docID)
+	}
+	if limit < 0 || limit > MaxPageSize {
+		return nil, apierror.InvalidInput("invalid limit %d: must be between 1 and %d", limit, MaxPageSize)
+	}
+	if limit == 0 {
+		limit = DefaultSimilarLimit
+	}
+
+	var duplicateOf *gocql.UUID
+	err = s.scylladb.Session.Query(`SELECT duplicate_of FROM documents WHERE doc_id = ?`, id).
+		WithContext(ctx).Scan(&duplicateOf)
+	if err == gocql.ErrNotFound {
+		return nil, apierror.NotFound("document %s not found", docID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load document: %w", err)
+	}
+	source := id
+	if duplicateOf != nil {
+		source = *duplicateOf
+	}
+
+	terms, err := s.topTerms(ctx, source, similarSeedTerms)
+	if err != nil {
+		return nil, err
+	}
+	opts := SearchOptions{UserID: userID, Operator: OperatorOr, PageSize: limit}
+	if len(terms) == 0 {
+		return s.search(ctx, opts, nil, nil)
+	}
+
+	exclude := []string{id.String(), source.String()}
+	termSet := make(map[string]bool, len(terms))
+	for _, t := range terms {
+		termSet[t] = true
+	}
+	rank := func(queryOpts QueryOptions) (*QueryResult, error) {
+		return s.searcher.SearchTerms(ctx, terms, exclude, queryOpts)
+	}
+	return s.search(ctx, opts, rank, termSet)
+}
+
+// topTerms returns the n words occurring most often in docUUID, most frequent
+// first.
+func (s *Search) topTerms(ctx context.Context, docUUID gocql.UUID, n int) ([]string, error) {
+	type termCount struct {
+		word string
+		tf   int
+	}
+	iter := s.scylladb.Session.Query(`SELECT word, term_frequency FROM doc_words WHERE doc_id = ?`, docUUID).
+		WithContext(ctx).Iter()
+	var counts []termCount
+	var c termCount
+	for iter.Scan(&c.word, &c.tf) {
+		counts = append(counts, c)
+	}
+	if err := iter.Close(); err != nil {
+		return nil, fmt.Errorf("failed to read document words: %w", err)
+	}
+
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].tf != counts[j].tf {
+			return counts[i].tf > counts[j].tf
+		}
+		return counts[i].word < counts[j].word
+	})
+	terms := make([]string, 0, min(n, len(counts)))
+	for _, c := range counts[:min(n, len(counts))] {
+		terms = append(terms, c.word)
+	}
+	return terms, nil
+}
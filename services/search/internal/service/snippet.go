@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"html"
+	"log/slog"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/amrrdev/trawl/services/shared/tokenizer"
+	"github.com/gocql/gocql"
+)
+
+// snippetWindow is the approximate snippet length in bytes.
+const snippetWindow = 200
+
+// queryTermSet returns the distinct stemmed terms of query, leaving out the
+// ones negated with NOT.
+func (s *Search) queryTermSet(query string) map[string]bool {
+	terms := make(map[string]bool)
+	for _, t := range parseQuery(s.tokenizer, query).terms {
+		terms[t] = true
+	}
+	return terms
+}
+
+// snippet returns the text around the first query term in the document, with
+// every query term wrapped in <mark> tags. It returns "" when the document
+// text is not available, so a missing text never fails the search.
+func (s *Search) snippet(ctx context.Context, docID string, terms map[string]bool) string {
+	id, err := gocql.ParseUUID(docID)
+	if err != nil {
+		return ""
+	}
+
+	var content string
+	err = s.scylladb.Session.Query(`SELECT content FROM document_text WHERE doc_id = ?`, id).
+		WithContext(ctx).Scan(&content)
+	if err != nil {
+		if err != gocql.ErrNotFound {
+			slog.WarnContext(ctx, "Failed to load document text", "doc_id", docID, "error", err)
+		}
+		return ""
+	}
+
+	return buildSnippet(s.tokenizer, content, terms)
+}
+
+// buildSnippet locates the first token whose stem is a query term (the
+// lowest stored position among the matched terms) and cuts a window of about
+// snippetWindow bytes around it. The text is HTML-escaped before marking.
+func buildSnippet(tk *tokenizer.Tokenizer, content string, terms map[string]bool) string {
+	tokens := tk.Tokenize(content)
+	first := -1
+	for i, t := range tokens {
+		if terms[t.Word] {
+			first = i
+			break
+		}
+	}
+	if first < 0 {
+		return ""
+	}
+
+	hit := tokens[first]
+	start := max(hit.Start-(snippetWindow-(hit.End-hit.Start))/2, 0)
+	end := min(start+snippetWindow, len(content))
+	start = max(end-snippetWindow, 0)
+	for start > 0 && !utf8.RuneStart(content[start]) {
+		start--
+	}
+	for end < len(content) && !utf8.RuneStart(content[end]) {
+		end++
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("… ")
+	}
+	cursor := start
+	for _, t := range tokens[first:] {
+		if t.Start >= end {
+			break
+		}
+		if t.End > end || !terms[t.Word] {
+			continue
+		}
+		b.WriteString(html.EscapeString(content[cursor:t.Start]))
+		b.WriteString("<mark>")
+		b.WriteString(html.EscapeString(content[t.Start:t.End]))
+		b.WriteString("</mark>")
+		cursor = t.End
+	}
+	b.WriteString(html.EscapeString(content[cursor:end]))
+	if end < len(content) {
+		b.WriteString(" …")
+	}
+
+	return strings.Join(strings.Fields(b.String()), " ")
+}
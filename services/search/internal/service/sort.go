@@ -0,0 +1,96 @@
+package service
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/amrrdev/trawl/services/shared/apierror"
+)
+
+const (
+	SortRelevance = "relevance"
+	SortNewest    = "newest"
+	SortOldest    = "oldest"
+	SortTitle     = "title"
+	SortAuthor    = "author"
+)
+
+var validSorts = map[string]bool{
+	SortRelevance: true,
+	SortNewest:    true,
+	SortOldest:    true,
+	SortTitle:     true,
+	SortAuthor:    true,
+}
+
+// searchHit pairs a scored candidate with its document metadata (nil when no
+// metadata was needed for the request).
+type searchHit struct {
+	candidate DocScore
+	doc       *documentResult
+	// docID is the document reported for the hit: the candidate, or with
+	// ScopeMine the caller's duplicate of it. Snippets and highlights still
+	// come from the candidate, which holds the postings and text.
+	docID string
+}
+
+func resolveSort(keys []string) ([]string, error) {
+	if len(keys) == 0 {
+		return []string{SortRelevance}, nil
+	}
+
+	resolved := make([]string, 0, len(keys))
+	for _, k := range keys {
+		k = strings.ToLower(strings.TrimSpace(k))
+		if !validSorts[k] {
+			return nil, apierror.InvalidInput("invalid sort %q", k)
+		}
+		resolved = append(resolved, k)
+	}
+	return resolved, nil
+}
+
+func sortNeedsMetadata(keys []string) bool {
+	for _, k := range keys {
+		if k != SortRelevance {
+			return true
+		}
+	}
+	return false
+}
+
+// sortHits orders hits by each key in turn, falling through to the next key
+// on ties. The sort is stable, so hits that tie on every key keep their
+// relevance order.
+func sortHits(hits []searchHit, keys []string) {
+	sort.SliceStable(hits, func(i, j int) bool {
+		for _, k := range keys {
+			if c := compareHits(hits[i], hits[j], k); c != 0 {
+				return c < 0
+			}
+		}
+		return false
+	})
+}
+
+func compareHits(a, b searchHit, key string) int {
+	switch key {
+	case SortRelevance:
+		switch {
+		case a.candidate.Score > b.candidate.Score:
+			return -1
+		case a.candidate.Score < b.candidate.Score:
+			return 1
+		}
+		return 0
+	case SortNewest:
+		return b.doc.CreatedAt.Compare(a.doc.CreatedAt)
+	case SortOldest:
+		return a.doc.CreatedAt.Compare(b.doc.CreatedAt)
+	case SortTitle:
+		return strings.Compare(strings.ToLower(a.doc.Title), strings.ToLower(b.doc.Title))
+	case SortAuthor:
+		return strings.Compare(strings.ToLower(a.doc.Author), strings.ToLower(b.doc.Author))
+	}
+	return 0
+}
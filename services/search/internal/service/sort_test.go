@@ -0,0 +1,100 @@
+package service
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/amrrdev/trawl/services/shared/apierror"
+)
+
+func TestResolveSort(t *testing.T) {
+	tests := []struct {
+		name    string
+		keys    []string
+		want    []string
+		wantErr error
+	}{
+		{"defaults to relevance", nil, []string{SortRelevance}, nil},
+		{"combined, normalized", []string{" Author", "NEWEST"}, []string{SortAuthor, SortNewest}, nil},
+		{"unknown key", []string{SortTitle, "size"}, nil, apierror.ErrInvalidInput},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveSort(tt.keys)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("keys = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSortHits(t *testing.T) {
+	day := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	hit := func(id string, score float64, author, title string, created time.Time) searchHit {
+		return searchHit{
+			candidate: DocScore{DocID: id, Score: score},
+			doc:       &documentResult{Author: author, Title: title, CreatedAt: created},
+			docID:     id,
+		}
+	}
+	// In relevance order, as the searcher returns them.
+	hits := []searchHit{
+		hit("a", 3, "bob", "Zeta", day),
+		hit("b", 2, "Alice", "alpha", day.AddDate(0, 0, 2)),
+		hit("c", 2, "alice", "Beta", day.AddDate(0, 0, 1)),
+		hit("d", 1, "Bob", "gamma", day.AddDate(0, 0, 2)),
+	}
+
+	tests := []struct {
+		name string
+		keys []string
+		want []string
+	}{
+		{"relevance", []string{SortRelevance}, []string{"a", "b", "c", "d"}},
+		{"newest, ties keep relevance", []string{SortNewest}, []string{"b", "d", "c", "a"}},
+		{"oldest", []string{SortOldest}, []string{"a", "c", "b", "d"}},
+		{"title ignores case", []string{SortTitle}, []string{"b", "c", "d", "a"}},
+		{"author then newest", []string{SortAuthor, SortNewest}, []string{"b", "c", "d", "a"}},
+		{"author then relevance", []string{SortAuthor, SortRelevance}, []string{"b", "c", "a", "d"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sorted := slices.Clone(hits)
+			sortHits(sorted, tt.keys)
+			var got []string
+			for _, h := range sorted {
+				got = append(got, h.docID)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("order = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSortNeedsMetadata(t *testing.T) {
+	tests := []struct {
+		keys []string
+		want bool
+	}{
+		{[]string{SortRelevance}, false},
+		{[]string{SortRelevance, SortTitle}, true},
+		{[]string{SortNewest}, true},
+	}
+	for _, tt := range tests {
+		if got := sortNeedsMetadata(tt.keys); got != tt.want {
+			t.Errorf("sortNeedsMetadata(%v) = %v, want %v", tt.keys, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/amrrdev/trawl/services/shared/apierror"
+	"github.com/amrrdev/trawl/services/shared/scylladb"
+)
+
+const (
+	// SuggestLimit is the number of suggestions returned for a prefix.
+	SuggestLimit = 10
+	// suggestCandidates caps the words read for one prefix before they are
+	// ranked, so short prefixes of large vocabularies stay cheap.
+	suggestCandidates = 500
+)
+
+type Suggestion struct {
+	Term        string `json:"term"`
+	Occurrences int    `json:"occurrences"`
+}
+
+type SuggestResponse struct {
+	Prefix      string       `json:"prefix"`
+	Suggestions []Suggestion `json:"suggestions"`
+}
+
+// Suggest returns the indexed terms starting with prefix, most frequent first.
+// Terms are stored stemmed, so suggestions are stems. The prefix is only
+// lowercased: stemming a partial word would not give a prefix of its stem.
+func (s *Search) Suggest(ctx context.Context, prefix string) (*SuggestResponse, error) {
+	prefix = strings.ToLower(strings.TrimSpace(prefix))
+	if prefix == "" {
+		return nil, apierror.InvalidInput("prefix is required")
+	}
+	runes := []rune(prefix)
+	if len(runes) < scylladb.TermPrefixMinLen {
+		return nil, apierror.InvalidInput("invalid prefix %q: must be at least %d characters", prefix, scylladb.TermPrefixMinLen)
+	}
+
+	// Longer prefixes are looked up by their first TermPrefixMaxLen runes
+	// and filtered here.
+	stored := prefix
+	if len(runes) > scylladb.TermPrefixMaxLen {
+		stored = string(runes[:scylladb.TermPrefixMaxLen])
+	}
+	iter := s.scylladb.Session.Query(`SELECT word FROM term_prefixes WHERE prefix = ? LIMIT ?`, stored, suggestCandidates).
+		WithContext(ctx).Iter()
+	var words []string
+	var word string
+	for iter.Scan(&word) {
+		if strings.HasPrefix(word, prefix) {
+			words = append(words, word)
+		}
+	}
+	if err := iter.Close(); err != nil {
+		return nil, fmt.Errorf("failed to read term prefixes: %w", err)
+	}
+
+	suggestions := make([]Suggestion, 0, min(len(words), SuggestLimit))
+	for i := 0; i < len(words); i += docLookupBatchSize {
+		end := min(i+docLookupBatchSize, len(words))
+		iter := s.scylladb.Session.Query(`SELECT word, total_occurrences FROM word_stats WHERE word IN ?`, words[i:end]).
+			WithContext(ctx).Iter()
+		var occurrences int
+		for iter.Scan(&word, &occurrences) {
+			// Words of deleted documents keep their prefix rows.
+			if occurrences > 0 {
+				suggestions = append(suggestions, Suggestion{Term: word, Occurrences: occurrences})
+			}
+		}
+		if err := iter.Close(); err != nil {
+			return nil, fmt.Errorf("failed to read word stats: %w", err)
+		}
+	}
+
+	sort.Slice(suggestions, func(i, j int) bool {
+		if suggestions[i].Occurrences != suggestions[j].Occurrences {
+			return suggestions[i].Occurrences > suggestions[j].Occurrences
+		}
+		return suggestions[i].Term < suggestions[j].Term
+	})
+	if len(suggestions) > SuggestLimit {
+		suggestions = suggestions[:SuggestLimit]
+	}
+	return &SuggestResponse{Prefix: prefix, Suggestions: suggestions}, nil
+}
@@ -0,0 +1,136 @@
+// Package apierror classifies errors by kind, so handlers can pick an HTTP
+// status without inspecting messages, and writes the JSON envelope shared by
+// every error response.
+package apierror
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Kinds of error. Services return errors wrapping one of these, usually
+// built with the constructors below; any other error is internal.
+var (
+	ErrInvalidInput = errors.New("invalid input")
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrForbidden    = errors.New("forbidden")
+	ErrNotFound     = errors.New("not found")
+	ErrConflict     = errors.New("conflict")
+)
+
+var statuses = []struct {
+	kind   error
+	status int
+}{
+	{ErrInvalidInput, http.StatusBadRequest},
+	{ErrUnauthorized, http.StatusUnauthorized},
+	{ErrForbidden, http.StatusForbidden},
+	{ErrNotFound, http.StatusNotFound},
+	{ErrConflict, http.StatusConflict},
+}
+
+var codes = map[int]string{
+	http.StatusBadRequest:      "invalid_input",
+	http.StatusUnauthorized:    "unauthorized",
+	http.StatusForbidden:       "forbidden",
+	http.StatusNotFound:        "not_found",
+	http.StatusConflict:        "conflict",
+	http.StatusTooManyRequests: "too_many_requests",
+}
+
+// Error is an error of a given kind whose message is safe to show clients.
+type Error struct {
+	Kind    error
+	Message string
+}
+
+func (e *Error) Error() string { return e.Message }
+
+func (e *Error) Unwrap() error { return e.Kind }
+
+func InvalidInput(format string, args ...any) error {
+	return &Error{Kind: ErrInvalidInput, Message: fmt.Sprintf(format, args...)}
+}
+
+func Unauthorized(format string, args ...any) error {
+	return &Error{Kind: ErrUnauthorized, Message: fmt.Sprintf(format, args...)}
+}
+
+func Forbidden(format string, args ...any) error {
+	return &Error{Kind: ErrForbidden, Message: fmt.Sprintf(format, args...)}
+}
+
+func NotFound(format string, args ...any) error {
+	return &Error{Kind: ErrNotFound, Message: fmt.Sprintf(format, args...)}
+}
+
+func Conflict(format string, args ...any) error {
+	return &Error{Kind: ErrConflict, Message: fmt.Sprintf(format, args...)}
+}
+
+// Status returns the HTTP status for err's kind, or 500 if it has none.
+func Status(err error) int {
+	for _, s := range statuses {
+		if errors.Is(err, s.kind) {
+			return s.status
+		}
+	}
+	return http.StatusInternalServerError
+}
+
+// Response is the JSON body of every error response. Code is a stable,
+// machine-readable name for the status, such as "not_found".
+type Response struct {
+	Error string `json:"error"`
+	Code  string `json:"code"`
+}
+
+// NewResponse builds the response body for status and message.
+func NewResponse(status int, message string) Response {
+	code, ok := codes[status]
+	if !ok {
+		code = "internal"
+	}
+	return Response{Error: message, Code: code}
+}
+
+// FromError returns the status and response body for err. Errors of a known
+// kind show their own message; internal ones show fallback instead, so
+// their details don't leak to clients.
+func FromError(err error, fallback string) (int, Response) {
+	status := Status(err)
+	if status == http.StatusInternalServerError {
+		return status, NewResponse(status, fallback)
+	}
+
+	message := err.Error()
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		message = apiErr.Message
+	}
+	return status, NewResponse(status, message)
+}
+
+// Write writes an error response with status and message.
+func Write(c *gin.Context, status int, message string) {
+	c.JSON(status, NewResponse(status, message))
+}
+
+// Abort is Write for middleware: it also stops the handler chain.
+func Abort(c *gin.Context, status int, message string) {
+	c.AbortWithStatusJSON(status, NewResponse(status, message))
+}
+
+// Respond writes err as an error response, see FromError. Internal errors
+// are logged with fallback as the message.
+func Respond(c *gin.Context, err error, fallback string) {
+	status, resp := FromError(err, fallback)
+	if status == http.StatusInternalServerError {
+		slog.ErrorContext(c.Request.Context(), fallback, "error", err)
+	}
+	c.JSON(status, resp)
+}
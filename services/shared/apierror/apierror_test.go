@@ -0,0 +1,79 @@
+package apierror
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestFromError(t *testing.T) {
+	tests := []struct {
+		name        string
+		err         error
+		wantStatus  int
+		wantMessage string
+		wantCode    string
+	}{
+		{"invalid input", InvalidInput("limit %d too large", 500), http.StatusBadRequest, "limit 500 too large", "invalid_input"},
+		{"unauthorized", Unauthorized("invalid credentials"), http.StatusUnauthorized, "invalid credentials", "unauthorized"},
+		{"forbidden", Forbidden("admins only"), http.StatusForbidden, "admins only", "forbidden"},
+		{"not found", NotFound("file %q not found", "a.pdf"), http.StatusNotFound, `file "a.pdf" not found`, "not_found"},
+		{"conflict", Conflict("email taken"), http.StatusConflict, "email taken", "conflict"},
+		{"wrapped keeps kind and message", fmt.Errorf("loading user: %w", NotFound("user not found")),
+			http.StatusNotFound, "user not found", "not_found"},
+		{"bare kind", fmt.Errorf("%w: token expired", ErrUnauthorized), http.StatusUnauthorized, "unauthorized: token expired", "unauthorized"},
+		{"internal hides details", errors.New("dial tcp 10.0.0.5:9042: refused"),
+			http.StatusInternalServerError, "failed to search", "internal"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, resp := FromError(tt.err, "failed to search")
+			if status != tt.wantStatus {
+				t.Errorf("status = %d, want %d", status, tt.wantStatus)
+			}
+			if resp.Error != tt.wantMessage || resp.Code != tt.wantCode {
+				t.Errorf("response = %+v, want {%q %q}", resp, tt.wantMessage, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestRespond(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	rec := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(rec)
+	c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+
+	Respond(c, NotFound("document not found"), "failed to get document")
+
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+	var body Response
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if want := (Response{Error: "document not found", Code: "not_found"}); body != want {
+		t.Errorf("body = %+v, want %+v", body, want)
+	}
+}
+
+func TestNewResponseCodes(t *testing.T) {
+	tests := []struct {
+		status int
+		want   string
+	}{
+		{http.StatusTooManyRequests, "too_many_requests"},
+		{http.StatusServiceUnavailable, "internal"},
+	}
+	for _, tt := range tests {
+		if got := NewResponse(tt.status, "msg").Code; got != tt.want {
+			t.Errorf("NewResponse(%d).Code = %q, want %q", tt.status, got, tt.want)
+		}
+	}
+}
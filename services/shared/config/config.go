@@ -0,0 +1,183 @@
+// Package config reads service configuration from environment variables,
+// either one value at a time with the typed loaders or into a struct with
+// Bind.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// String returns the value of key, or defaultValue if it is unset.
+func String(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+// Int returns key parsed as an int. An unset or invalid value yields
+// defaultValue, the latter with a warning.
+func Int(key string, defaultValue int) int {
+	return load(key, defaultValue, strconv.Atoi)
+}
+
+// Float is Int for float64 values.
+func Float(key string, defaultValue float64) float64 {
+	return load(key, defaultValue, func(value string) (float64, error) {
+		return strconv.ParseFloat(value, 64)
+	})
+}
+
+// Bool is Int for bool values, in any form strconv.ParseBool accepts.
+func Bool(key string, defaultValue bool) bool {
+	return load(key, defaultValue, strconv.ParseBool)
+}
+
+// Duration is Int for durations such as "5s". Durations must be positive.
+func Duration(key string, defaultValue time.Duration) time.Duration {
+	return load(key, defaultValue, parseDuration)
+}
+
+// List returns key split as a comma-separated list, or defaultValue if it
+// is unset.
+func List(key string, defaultValue []string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	return SplitList(value)
+}
+
+// SplitList splits a comma-separated value, skipping empty entries. It
+// returns nil if there are none.
+func SplitList(value string) []string {
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+func load[T any](key string, defaultValue T, parse func(string) (T, error)) T {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := parse(value)
+	if err != nil {
+		slog.Warn("Invalid environment variable, using default", "key", key, "value", value, "default", defaultValue)
+		return defaultValue
+	}
+	return parsed
+}
+
+func parseDuration(value string) (time.Duration, error) {
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, err
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("must be positive")
+	}
+	return d, nil
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// Bind sets the fields of the struct dst points to from the environment.
+// Each field to bind is tagged with its variable, and optionally a default:
+//
+//	Port  string        `env:"PORT" default:":8080"`
+//	Hosts []string      `env:"HOSTS,required"`
+//	Wait  time.Duration `env:"WAIT"`
+//
+// A field whose variable is unset gets its default, or keeps its value if it
+// has none, so defaults may also be set on dst beforehand. Fields marked
+// required must be set. Supported types are string, bool, int, int64,
+// float64, time.Duration and []string (comma-separated). Unlike the typed
+// loaders, Bind doesn't fall back on invalid values: it returns an error
+// listing every invalid or missing variable.
+func Bind(dst any) error {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Pointer || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("config: Bind needs a pointer to a struct, got %T", dst)
+	}
+	v = v.Elem()
+
+	var errs []error
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		tag, ok := field.Tag.Lookup("env")
+		if !ok || !field.IsExported() {
+			continue
+		}
+		key, opts, _ := strings.Cut(tag, ",")
+		required := opts == "required"
+
+		value := os.Getenv(key)
+		if value == "" {
+			if required {
+				errs = append(errs, fmt.Errorf("%s is required", key))
+				continue
+			}
+			if value, ok = field.Tag.Lookup("default"); !ok {
+				continue
+			}
+		}
+		if err := setField(v.Field(i), value); err != nil {
+			errs = append(errs, fmt.Errorf("invalid %s=%q: %w", key, value, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func setField(field reflect.Value, value string) error {
+	if field.Type() == durationType {
+		d, err := parseDuration(value)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(d))
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return err
+		}
+		field.SetInt(n)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+	case reflect.Slice:
+		if field.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported field type %s", field.Type())
+		}
+		field.Set(reflect.ValueOf(SplitList(value)))
+	default:
+		return fmt.Errorf("unsupported field type %s", field.Type())
+	}
+	return nil
+}
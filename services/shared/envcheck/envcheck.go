@@ -0,0 +1,48 @@
+// Package envcheck keeps services from running in production on the
+// development defaults their configuration falls back to.
+package envcheck
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// IsProduction reports whether ENV is "production".
+func IsProduction() bool {
+	return strings.EqualFold(strings.TrimSpace(os.Getenv("ENV")), "production")
+}
+
+// Required reads configuration values that have a development default but
+// must be set explicitly in production, such as secrets and credentials.
+type Required struct {
+	production bool
+	missing    []string
+}
+
+func NewRequired() *Required {
+	return &Required{production: IsProduction()}
+}
+
+// Get returns the value of the environment variable key, or devDefault if
+// it is unset. In production an unset key, or one still set to devDefault,
+// is recorded as missing and reported by Err.
+func (r *Required) Get(key, devDefault string) string {
+	value := os.Getenv(key)
+	if r.production && (value == "" || value == devDefault) {
+		r.missing = append(r.missing, key)
+	}
+	if value == "" {
+		return devDefault
+	}
+	return value
+}
+
+// Err returns an error listing every missing key, or nil if there are none.
+func (r *Required) Err() error {
+	if len(r.missing) == 0 {
+		return nil
+	}
+	return fmt.Errorf("ENV=production requires these to be set to non-default values: %s",
+		strings.Join(r.missing, ", "))
+}
@@ -0,0 +1,47 @@
+package envcheck
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRequired(t *testing.T) {
+	const devSecret = "very-secret-key"
+
+	tests := []struct {
+		name        string
+		env         string
+		secret      string
+		wantValue   string
+		wantMissing bool
+	}{
+		{"development, unset", "development", "", devSecret, false},
+		{"development, default kept", "", devSecret, devSecret, false},
+		{"production, set", "production", "rotated-secret", "rotated-secret", false},
+		{"production, unset", "production", "", devSecret, true},
+		{"production, default kept", " Production ", devSecret, devSecret, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("ENV", tt.env)
+			t.Setenv("JWT_SECRET_KEY", tt.secret)
+			t.Setenv("DATABASE_URL", "postgres://prod")
+
+			required := NewRequired()
+			if got := required.Get("JWT_SECRET_KEY", devSecret); got != tt.wantValue {
+				t.Errorf("value = %q, want %q", got, tt.wantValue)
+			}
+			required.Get("DATABASE_URL", "postgres://localhost")
+
+			err := required.Err()
+			if (err != nil) != tt.wantMissing {
+				t.Fatalf("err = %v, want missing %v", err, tt.wantMissing)
+			}
+			if err != nil {
+				if !strings.Contains(err.Error(), "JWT_SECRET_KEY") || strings.Contains(err.Error(), "DATABASE_URL") {
+					t.Errorf("err = %v, want only JWT_SECRET_KEY listed", err)
+				}
+			}
+		})
+	}
+}
@@ -1,56 +1,212 @@
 package jwt
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/hex"
 	"fmt"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// Claims are the access token claims. RegisteredClaims.ID carries the jti
+// used to revoke a single token.
 type Claims struct {
 	UserID string `json:"user_id"`
 	Email  string `json:"email"`
+	// Role is RoleUser or RoleAdmin. Tokens issued before roles existed
+	// have none, which grants no more than RoleUser.
+	Role string `json:"role,omitempty"`
 	jwt.RegisteredClaims
 }
 
+// Roles a user can hold.
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+// RevocationStore records revoked token IDs until the tokens expire, and the
+// time before which each user's tokens are no longer accepted.
+type RevocationStore interface {
+	Revoke(ctx context.Context, jti string, expiresAt time.Time) error
+	IsRevoked(ctx context.Context, jti string) (bool, error)
+	// TokensValidAfter returns the user's cutoff, or the zero time if
+	// every unexpired token is accepted.
+	TokensValidAfter(ctx context.Context, userID string) (time.Time, error)
+}
+
+const (
+	AlgorithmHS256 = "HS256"
+	AlgorithmRS256 = "RS256"
+)
+
 type Service struct {
+	algorithm      string
 	secretKey      []byte
 	accessTokenTTL time.Duration
+	revocations    RevocationStore
+
+	// RS256 only. signingKey is nil for verify-only services.
+	signingKey *rsa.PrivateKey
+	signingKID string
+	keys       KeySource
 }
 
+// NewService returns an HS256 service that signs and validates tokens with a
+// shared secret.
 func NewService(secretKey string, accessTokenTTL time.Duration) *Service {
 	return &Service{
+		algorithm:      AlgorithmHS256,
 		secretKey:      []byte(secretKey),
 		accessTokenTTL: accessTokenTTL,
 	}
 }
 
-func (s *Service) GenerateAccessToken(userID, email string) (string, error) {
+// NewRS256Service returns a service that signs tokens with signingKey,
+// stamping kid in the token header. Tokens are validated against keys, which
+// should include signingKey's public half and any keys still in rotation.
+func NewRS256Service(signingKey *rsa.PrivateKey, kid string, keys *KeySet, accessTokenTTL time.Duration) *Service {
+	keys.Add(kid, &signingKey.PublicKey)
+	return &Service{
+		algorithm:      AlgorithmRS256,
+		accessTokenTTL: accessTokenTTL,
+		signingKey:     signingKey,
+		signingKID:     kid,
+		keys:           keys,
+	}
+}
+
+// NewRS256Verifier returns a service that only validates RS256 tokens, so it
+// never holds a signing secret.
+func NewRS256Verifier(keys KeySource) *Service {
+	return &Service{
+		algorithm: AlgorithmRS256,
+		keys:      keys,
+	}
+}
+
+// UseRevocationStore makes ValidateToken reject tokens revoked in store, and
+// tokens issued before their user's cutoff. Without a store, tokens are valid
+// until they expire.
+func (s *Service) UseRevocationStore(store RevocationStore) {
+	s.revocations = store
+}
+
+// JWKS returns the public keys tokens are validated against. It is empty
+// for HS256 services and for verifiers backed by a remote key set.
+func (s *Service) JWKS() JWKS {
+	if keys, ok := s.keys.(*KeySet); ok {
+		return keys.JWKS()
+	}
+	return JWKS{Keys: []JWK{}}
+}
+
+func (s *Service) GenerateAccessToken(userID, email, role string) (string, error) {
+	jti, err := newTokenID()
+	if err != nil {
+		return "", fmt.Errorf("failed to generate token id: %w", err)
+	}
+
 	claims := Claims{
 		UserID: userID,
 		Email:  email,
+		Role:   role,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(s.accessTokenTTL)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
 	}
 
+	if s.algorithm == AlgorithmRS256 {
+		if s.signingKey == nil {
+			return "", fmt.Errorf("service cannot sign tokens: no signing key")
+		}
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+		token.Header["kid"] = s.signingKID
+		return token.SignedString(s.signingKey)
+	}
+
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	return token.SignedString(s.secretKey)
 }
 
 func (s *Service) ValidateToken(tokenString string) (*Claims, error) {
+	return s.ValidateTokenContext(context.Background(), tokenString)
+}
+
+// ValidateTokenContext validates the signature and expiry of tokenString and,
+// when a revocation store is configured, that it has not been revoked and was
+// issued no earlier than its user's cutoff. iat has second precision, so the
+// cutoff is truncated to the second: a token whose iat falls in the same
+// second as the cutoff is still accepted, and only tokens from earlier
+// seconds are rejected. A store failure rejects the token.
+func (s *Service) ValidateTokenContext(ctx context.Context, tokenString string) (*Claims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(t *jwt.Token) (any, error) {
-		return s.secretKey, nil
-	})
+		if s.algorithm == AlgorithmHS256 {
+			return s.secretKey, nil
+		}
+		kid, _ := t.Header["kid"].(string)
+		if kid == "" {
+			return nil, fmt.Errorf("token has no kid")
+		}
+		return s.keys.PublicKey(ctx, kid)
+	}, jwt.WithValidMethods([]string{s.algorithm}))
 
 	if err != nil {
 		return nil, err
 	}
 
-	if claims, ok := token.Claims.(*Claims); ok && token.Valid {
-		return claims, nil
+	claims, ok := token.Claims.(*Claims)
+	if !ok || !token.Valid {
+		return nil, fmt.Errorf("invalid token")
+	}
+
+	if s.revocations != nil && claims.ID != "" {
+		revoked, err := s.revocations.IsRevoked(ctx, claims.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check token revocation: %w", err)
+		}
+		if revoked {
+			return nil, fmt.Errorf("token has been revoked")
+		}
+	}
+	if s.revocations != nil && claims.UserID != "" {
+		cutoff, err := s.revocations.TokensValidAfter(ctx, claims.UserID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check token revocation: %w", err)
+		}
+		if !cutoff.IsZero() && (claims.IssuedAt == nil || claims.IssuedAt.Time.Before(cutoff.Truncate(time.Second))) {
+			return nil, fmt.Errorf("token has been revoked")
+		}
+	}
+
+	return claims, nil
+}
+
+// Revoke denylists the token described by claims until it expires.
+func (s *Service) Revoke(ctx context.Context, claims *Claims) error {
+	if s.revocations == nil {
+		return fmt.Errorf("token revocation is not configured")
+	}
+	if claims.ID == "" {
+		return fmt.Errorf("token has no jti and cannot be revoked")
 	}
 
-	return nil, fmt.Errorf("invalid token")
+	expiresAt := time.Now().Add(s.accessTokenTTL)
+	if claims.ExpiresAt != nil {
+		expiresAt = claims.ExpiresAt.Time
+	}
+	return s.revocations.Revoke(ctx, claims.ID, expiresAt)
+}
+
+func newTokenID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
 }
@@ -0,0 +1,101 @@
+package jwt
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// cutoffStore is a RevocationStore that revokes nothing by jti and holds a
+// single user's cutoff.
+type cutoffStore struct {
+	userID string
+	cutoff time.Time
+}
+
+func (cutoffStore) Revoke(ctx context.Context, jti string, expiresAt time.Time) error {
+	return nil
+}
+
+func (cutoffStore) IsRevoked(ctx context.Context, jti string) (bool, error) {
+	return false, nil
+}
+
+func (s cutoffStore) TokensValidAfter(ctx context.Context, userID string) (time.Time, error) {
+	if userID != s.userID {
+		return time.Time{}, nil
+	}
+	return s.cutoff, nil
+}
+
+func TestValidateTokenUserCutoff(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name    string
+		userID  string
+		cutoff  time.Time
+		wantErr bool
+	}{
+		{"no cutoff", "user-1", time.Time{}, false},
+		{"cutoff before issue", "user-1", now.Add(-time.Minute), false},
+		{"cutoff in issue second", "user-1", now.Truncate(time.Second), false},
+		{"cutoff after issue", "user-1", now.Add(time.Minute), true},
+		{"other user's cutoff", "user-2", now.Add(time.Minute), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := NewService("test-secret", time.Hour)
+			service.UseRevocationStore(cutoffStore{userID: tt.userID, cutoff: tt.cutoff})
+
+			token, err := service.GenerateAccessToken("user-1", "user@example.com", RoleUser)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = service.ValidateTokenContext(context.Background(), token)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateTokenContext() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateTokenCutoffSecond(t *testing.T) {
+	const secret = "test-secret"
+	// A cutoff part-way through a second, as a logout would set it.
+	second := time.Now().Truncate(time.Second).Add(-time.Minute)
+	cutoff := second.Add(700 * time.Millisecond)
+
+	tests := []struct {
+		name     string
+		issuedAt time.Time
+		wantErr  bool
+	}{
+		{"issued in the cutoff second", second, false},
+		{"issued the second before", second.Add(-time.Second), true},
+		{"issued the second after", second.Add(time.Second), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := NewService(secret, time.Hour)
+			service.UseRevocationStore(cutoffStore{userID: "user-1", cutoff: cutoff})
+
+			token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, Claims{
+				UserID: "user-1",
+				RegisteredClaims: jwt.RegisteredClaims{
+					ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+					IssuedAt:  jwt.NewNumericDate(tt.issuedAt),
+				},
+			}).SignedString([]byte(secret))
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = service.ValidateTokenContext(context.Background(), token)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateTokenContext() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+// Package logging sets up the structured logger shared by the trawl
+// services. Code logs through log/slog; Setup installs the handler.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// Output formats.
+const (
+	// FormatJSON writes one JSON object per line, for log aggregators.
+	FormatJSON = "json"
+	// FormatText writes key=value lines, for reading in a terminal.
+	FormatText = "text"
+)
+
+type Config struct {
+	// Level is the lowest level logged: debug, info, warn or error.
+	Level  string
+	Format string
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Level:  "info",
+		Format: FormatJSON,
+	}
+}
+
+// Setup makes a logger for config the slog default and returns it. Output of
+// the standard log package goes through it too, at info level. Invalid
+// settings fall back to the defaults with a warning.
+func Setup(config Config) *slog.Logger {
+	return setup(os.Stderr, config)
+}
+
+func setup(w io.Writer, config Config) *slog.Logger {
+	var warnings []string
+
+	level, err := ParseLevel(config.Level)
+	if err != nil {
+		warnings = append(warnings, err.Error())
+		level = slog.LevelInfo
+	}
+
+	opts := &slog.HandlerOptions{Level: level}
+	var handler slog.Handler
+	switch strings.ToLower(config.Format) {
+	case FormatText:
+		handler = slog.NewTextHandler(w, opts)
+	case FormatJSON, "":
+		handler = slog.NewJSONHandler(w, opts)
+	default:
+		warnings = append(warnings, fmt.Sprintf("invalid log format %q", config.Format))
+		handler = slog.NewJSONHandler(w, opts)
+	}
+
+	logger := slog.New(contextHandler{handler})
+	slog.SetDefault(logger)
+	for _, warning := range warnings {
+		logger.Warn("Invalid logging config, using defaults", "error", warning)
+	}
+	return logger
+}
+
+// Fatal logs msg at error level with args through the default logger and
+// exits with status 1. Like log.Fatal, it skips deferred calls, so it is only
+// for failures during startup.
+func Fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
+
+type requestIDKey struct{}
+
+// WithRequestID returns a copy of ctx carrying the request ID id. Lines
+// logged with the context then include it.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFromContext returns the request ID carried by ctx, or "".
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// contextHandler adds the request ID of the context to each record.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if id := RequestIDFromContext(ctx); id != "" {
+		record.AddAttrs(slog.String("request_id", id))
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
+
+// ParseLevel parses a level name, case-insensitively. "warning" is accepted
+// for warn.
+func ParseLevel(name string) (slog.Level, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "info", "":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	}
+	return slog.LevelInfo, fmt.Errorf("invalid log level %q", name)
+}
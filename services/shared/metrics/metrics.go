@@ -0,0 +1,167 @@
+// Package metrics holds the Prometheus metrics shared by the trawl services.
+// Each service registers the groups it uses and serves Handler on /metrics.
+package metrics
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "trawl"
+
+// Indexing metrics, registered by RegisterIndexing.
+var (
+	DocumentsIndexed = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "indexing",
+		Name:      "documents_indexed_total",
+		Help:      "Documents successfully indexed.",
+	})
+
+	JobDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "indexing",
+		Name:      "job_duration_seconds",
+		Help:      "Time spent processing one indexing job, by outcome.",
+		Buckets:   []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60},
+	}, []string{"status"})
+
+	TokensPerDocument = prometheus.NewSummary(prometheus.SummaryOpts{
+		Namespace:  namespace,
+		Subsystem:  "indexing",
+		Name:       "tokens_per_document",
+		Help:       "Tokens extracted from each indexed document.",
+		Objectives: map[float64]float64{0.5: 0.05, 0.9: 0.01, 0.99: 0.001},
+	})
+
+	WorkerConcurrency = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "indexing",
+		Name:      "worker_concurrency",
+		Help:      "Configured number of concurrent indexing workers.",
+	})
+
+	JobsPublished = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "indexing",
+		Name:      "jobs_published_total",
+		Help:      "Indexing jobs published to the queue, by outcome.",
+	}, []string{"status"})
+
+	PublishDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "indexing",
+		Name:      "publish_duration_seconds",
+		Help:      "Time from publishing an indexing job to the broker confirming it.",
+		Buckets:   []float64{0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5},
+	})
+)
+
+// Search metrics, registered by RegisterSearch.
+var (
+	SearchLatency = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "search",
+		Name:      "request_duration_seconds",
+		Help:      "Time spent answering one search request.",
+		Buckets:   prometheus.DefBuckets,
+	})
+
+	SearchZeroResults = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "search",
+		Name:      "zero_results_total",
+		Help:      "Search requests that matched no documents.",
+	})
+)
+
+// ScyllaDB metrics, registered by RegisterScylla and recorded by the
+// scylladb package's session observers. Queries are labelled by the CQL
+// operation (select, insert, ...) and the table they name.
+var (
+	ScyllaQueryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "scylla",
+		Name:      "query_duration_seconds",
+		Help:      "Time spent on one ScyllaDB query attempt, by operation and table.",
+		Buckets:   []float64{0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5},
+	}, []string{"operation", "table"})
+
+	ScyllaQueryErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "scylla",
+		Name:      "query_errors_total",
+		Help:      "ScyllaDB query attempts that failed, by operation and table.",
+	}, []string{"operation", "table"})
+
+	ScyllaBatchDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "scylla",
+		Name:      "batch_duration_seconds",
+		Help:      "Time spent on one ScyllaDB batch attempt, by table.",
+		Buckets:   []float64{0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5},
+	}, []string{"table"})
+
+	ScyllaBatchFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "scylla",
+		Name:      "batch_failures_total",
+		Help:      "ScyllaDB batch attempts that failed, by table.",
+	}, []string{"table"})
+
+	ScyllaConnectFailures = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "scylla",
+		Name:      "connect_failures_total",
+		Help:      "Failed attempts to open a connection to a ScyllaDB host.",
+	})
+)
+
+var (
+	indexingOnce sync.Once
+	searchOnce   sync.Once
+	scyllaOnce   sync.Once
+)
+
+// RegisterIndexing registers the indexing metrics with the default registry.
+func RegisterIndexing() {
+	indexingOnce.Do(func() {
+		prometheus.MustRegister(DocumentsIndexed, JobDuration, TokensPerDocument, WorkerConcurrency,
+			JobsPublished, PublishDuration)
+	})
+}
+
+// RegisterSearch registers the search metrics with the default registry.
+func RegisterSearch() {
+	searchOnce.Do(func() {
+		prometheus.MustRegister(SearchLatency, SearchZeroResults)
+	})
+}
+
+// RegisterScylla registers the ScyllaDB metrics with the default registry.
+func RegisterScylla() {
+	scyllaOnce.Do(func() {
+		prometheus.MustRegister(ScyllaQueryDuration, ScyllaQueryErrors, ScyllaBatchDuration,
+			ScyllaBatchFailures, ScyllaConnectFailures)
+	})
+}
+
+// RegisterGaugeFunc registers a gauge whose value is read from fn at scrape
+// time, e.g. a queue depth. subsystem and name follow the trawl_ prefix.
+func RegisterGaugeFunc(subsystem, name, help string, fn func() float64) {
+	prometheus.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      name,
+		Help:      help,
+	}, fn))
+}
+
+// Handler serves every registered metric, plus the Go runtime and process
+// metrics, in the Prometheus text format.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
@@ -1,10 +1,11 @@
 package middleware
 
 import (
-	"fmt"
+	"log/slog"
 	"net/http"
 	"strings"
 
+	"github.com/amrrdev/trawl/services/shared/apierror"
 	"github.com/amrrdev/trawl/services/shared/jwt"
 	"github.com/gin-gonic/gin"
 )
@@ -23,41 +24,49 @@ func (m *AuthMiddleware) RequireAuth() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"error": "Authorization header required",
-			})
-			c.Abort()
+			apierror.Abort(c, http.StatusUnauthorized, "Authorization header required")
 			return
 		}
 
 		parts := strings.Split(authHeader, " ")
 		if len(parts) != 2 || parts[0] != "Bearer" {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"error": "Invalid authorization format. Use: Bearer <token>",
-			})
-			c.Abort()
+			apierror.Abort(c, http.StatusUnauthorized, "Invalid authorization format. Use: Bearer <token>")
 			return
 		}
 
 		token := parts[1]
-		fmt.Println("token ->>>>>>>>>>>>>", token)
-		claims, err := m.jwtService.ValidateToken(token)
+		claims, err := m.jwtService.ValidateTokenContext(c.Request.Context(), token)
 		if err != nil {
-			fmt.Println("error->>>>>>>>>>>", err)
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"error": "Invalid or expired token",
-			})
-			c.Abort()
+			slog.DebugContext(c.Request.Context(), "Rejected token", "error", err)
+			apierror.Abort(c, http.StatusUnauthorized, "Invalid or expired token")
 			return
 		}
 
 		c.Set("user_id", claims.UserID)
 		c.Set("email", claims.Email)
+		c.Set("claims", claims)
 
 		c.Next()
 	}
 }
 
+// RequireRole returns middleware that lets through only callers whose token
+// carries role, answering 403 otherwise. RequireAuth has to run before it.
+func RequireRole(role string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims := GetClaims(c)
+		if claims == nil {
+			apierror.Abort(c, http.StatusUnauthorized, "Authentication required")
+			return
+		}
+		if claims.Role != role {
+			apierror.Abort(c, http.StatusForbidden, "Insufficient permissions")
+			return
+		}
+		c.Next()
+	}
+}
+
 func GetUserID(c *gin.Context) string {
 	userID, exists := c.Get("user_id")
 	if !exists {
@@ -73,3 +82,12 @@ func GetUserEmail(c *gin.Context) string {
 	}
 	return email.(string)
 }
+
+// GetClaims returns the validated token claims set by RequireAuth.
+func GetClaims(c *gin.Context) *jwt.Claims {
+	claims, exists := c.Get("claims")
+	if !exists {
+		return nil
+	}
+	return claims.(*jwt.Claims)
+}
@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/amrrdev/trawl/services/shared/jwt"
+	"github.com/gin-gonic/gin"
+)
+
+// memoryRevocations is an in-memory jwt.RevocationStore.
+type memoryRevocations struct {
+	jtis    map[string]bool
+	cutoffs map[string]time.Time
+}
+
+func (m memoryRevocations) Revoke(ctx context.Context, jti string, expiresAt time.Time) error {
+	m.jtis[jti] = true
+	return nil
+}
+
+func (m memoryRevocations) IsRevoked(ctx context.Context, jti string) (bool, error) {
+	return m.jtis[jti], nil
+}
+
+func (m memoryRevocations) TokensValidAfter(ctx context.Context, userID string) (time.Time, error) {
+	return m.cutoffs[userID], nil
+}
+
+func TestRequireAuthRejectsRevokedTokens(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	service := jwt.NewService("test-secret", time.Hour)
+	store := memoryRevocations{jtis: map[string]bool{}, cutoffs: map[string]time.Time{}}
+	service.UseRevocationStore(store)
+
+	valid, err := service.GenerateAccessToken("user-1", "user@example.com", jwt.RoleUser)
+	if err != nil {
+		t.Fatal(err)
+	}
+	revoked, err := service.GenerateAccessToken("user-1", "user@example.com", jwt.RoleUser)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := service.ValidateToken(revoked)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := service.Revoke(context.Background(), claims); err != nil {
+		t.Fatal(err)
+	}
+	signedOut, err := service.GenerateAccessToken("user-2", "other@example.com", jwt.RoleUser)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.cutoffs["user-2"] = time.Now().Add(time.Minute)
+
+	router := gin.New()
+	router.GET("/", NewAuthMiddleware(service).RequireAuth(), func(c *gin.Context) {
+		c.String(http.StatusOK, GetUserID(c))
+	})
+
+	tests := []struct {
+		name   string
+		header string
+		want   int
+	}{
+		{"valid token", "Bearer " + valid, http.StatusOK},
+		{"revoked token", "Bearer " + revoked, http.StatusUnauthorized},
+		{"token before user cutoff", "Bearer " + signedOut, http.StatusUnauthorized},
+		{"missing header", "", http.StatusUnauthorized},
+		{"malformed header", valid, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
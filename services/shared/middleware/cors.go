@@ -0,0 +1,104 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CORSConfig sets which browser origins may call an API.
+type CORSConfig struct {
+	// AllowedOrigins are origins such as "https://app.example.com". "*"
+	// allows any origin, which is meant for development; an empty list
+	// refuses all cross-origin requests.
+	AllowedOrigins []string
+	AllowedMethods []string
+	AllowedHeaders []string
+	// AllowCredentials lets browsers send cookies and read responses to
+	// credentialed requests.
+	AllowCredentials bool
+	// MaxAge is how long browsers may cache a preflight response.
+	MaxAge time.Duration
+}
+
+// DefaultCORSConfig allows the methods and headers the APIs use, but no
+// origins.
+func DefaultCORSConfig() CORSConfig {
+	return CORSConfig{
+		AllowedMethods: []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowedHeaders: []string{"Authorization", "Content-Type"},
+		MaxAge:         12 * time.Hour,
+	}
+}
+
+// CORS returns middleware adding CORS headers for allowed origins. Preflight
+// requests are answered directly, without reaching the routes.
+func CORS(config CORSConfig) gin.HandlerFunc {
+	allowAll := false
+	origins := make(map[string]bool)
+	for _, origin := range config.AllowedOrigins {
+		origin = strings.TrimSuffix(strings.TrimSpace(origin), "/")
+		switch origin {
+		case "":
+		case "*":
+			allowAll = true
+		default:
+			origins[strings.ToLower(origin)] = true
+		}
+	}
+	if allowAll && config.AllowCredentials {
+		slog.Warn("CORS allows credentialed requests from any origin; list the origins in production")
+	}
+
+	methods := strings.Join(config.AllowedMethods, ", ")
+	headers := strings.Join(config.AllowedHeaders, ", ")
+	maxAge := strconv.Itoa(int(config.MaxAge.Seconds()))
+
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		if origin == "" {
+			c.Next()
+			return
+		}
+
+		header := c.Writer.Header()
+		header.Add("Vary", "Origin")
+		preflight := c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != ""
+		if !allowAll && !origins[strings.ToLower(origin)] {
+			if preflight {
+				c.AbortWithStatus(http.StatusForbidden)
+				return
+			}
+			// Without the headers the browser withholds the response.
+			c.Next()
+			return
+		}
+
+		// A wildcard can't be combined with credentials, so the origin is
+		// echoed back instead.
+		if allowAll && !config.AllowCredentials {
+			header.Set("Access-Control-Allow-Origin", "*")
+		} else {
+			header.Set("Access-Control-Allow-Origin", origin)
+		}
+		if config.AllowCredentials {
+			header.Set("Access-Control-Allow-Credentials", "true")
+		}
+
+		if preflight {
+			header.Set("Access-Control-Allow-Methods", methods)
+			header.Set("Access-Control-Allow-Headers", headers)
+			if config.MaxAge > 0 {
+				header.Set("Access-Control-Max-Age", maxAge)
+			}
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+		header.Set("Access-Control-Expose-Headers", RequestIDHeader)
+		c.Next()
+	}
+}
@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"log/slog"
+	"net/http"
+
+	"github.com/amrrdev/trawl/services/shared/apierror"
+	"github.com/gin-gonic/gin"
+)
+
+// WebhookAuth returns middleware admitting only requests that carry token
+// the way MinIO sends a webhook target's auth_token: in the Authorization
+// header, as "Bearer <token>" or as is. Other requests get 401. An empty
+// token rejects every request, so webhooks stay closed until one is set.
+func WebhookAuth(token string) gin.HandlerFunc {
+	if token == "" {
+		slog.Warn("No webhook auth token configured, rejecting all webhook requests")
+	}
+	bearer := []byte("Bearer " + token)
+	raw := []byte(token)
+
+	return func(c *gin.Context) {
+		header := []byte(c.GetHeader("Authorization"))
+		valid := token != "" &&
+			(subtle.ConstantTimeCompare(header, bearer) == 1 || subtle.ConstantTimeCompare(header, raw) == 1)
+		if !valid {
+			slog.WarnContext(c.Request.Context(), "Rejected webhook request", "client_ip", c.ClientIP())
+			apierror.Abort(c, http.StatusUnauthorized, "invalid webhook credentials")
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,19 @@
+package queue
+
+import "fmt"
+
+// Default names of the indexing queues. The API, the worker and the queue
+// tools all declare or read these queues, and RabbitMQ refuses to redeclare a
+// queue with different dead-letter arguments, so every process takes its
+// defaults from here.
+const (
+	IndexingQueue = "indexing_queue"
+	// IndexingDLQ receives the jobs that exhausted their retries.
+	IndexingDLQ = IndexingQueue + "_dlq"
+)
+
+// RetryQueue returns the name of the queue that holds jobs of queueName
+// waiting for their attempt-th retry.
+func RetryQueue(queueName string, attempt int) string {
+	return fmt.Sprintf("%s.retry.%d", queueName, attempt)
+}
@@ -1,67 +1,512 @@
 package queue
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
 
 	amqp "github.com/rabbitmq/amqp091-go"
 )
 
+const (
+	reconnectBaseDelay = time.Second
+	reconnectMaxDelay  = 30 * time.Second
+	// reconnectWait is how long an operation waits for a lost connection to
+	// come back before it fails.
+	reconnectWait = 30 * time.Second
+)
+
+// ErrClosed is returned by operations on a RabbitMQ that has been closed.
+var ErrClosed = errors.New("rabbitmq client closed")
+
+// amqpConnection and amqpChannel are the parts of the amqp091 connection and
+// channel the client uses, so tests can stand in for a broker.
+type amqpConnection interface {
+	Channel() (amqpChannel, error)
+	NotifyClose(receiver chan *amqp.Error) chan *amqp.Error
+	IsClosed() bool
+	Close() error
+}
+
+type amqpChannel interface {
+	QueueDeclare(name string, durable, autoDelete, exclusive, noWait bool, args amqp.Table) (amqp.Queue, error)
+	QueueDeclarePassive(name string, durable, autoDelete, exclusive, noWait bool, args amqp.Table) (amqp.Queue, error)
+	Qos(prefetchCount, prefetchSize int, global bool) error
+	Confirm(noWait bool) error
+	Publish(exchange, key string, mandatory, immediate bool, msg amqp.Publishing) error
+	PublishWithDeferredConfirmWithContext(ctx context.Context, exchange, key string, mandatory, immediate bool, msg amqp.Publishing) (*amqp.DeferredConfirmation, error)
+	Get(queue string, autoAck bool) (amqp.Delivery, bool, error)
+	Consume(queue, consumer string, autoAck, exclusive, noLocal, noWait bool, args amqp.Table) (<-chan amqp.Delivery, error)
+	NotifyClose(receiver chan *amqp.Error) chan *amqp.Error
+	IsClosed() bool
+	Close() error
+}
+
+// dialedConnection adapts *amqp.Connection to amqpConnection.
+type dialedConnection struct {
+	*amqp.Connection
+}
+
+func (c dialedConnection) Channel() (amqpChannel, error) {
+	channel, err := c.Connection.Channel()
+	if err != nil {
+		return nil, err
+	}
+	return channel, nil
+}
+
+func dialAMQP(url string) (amqpConnection, error) {
+	conn, err := amqp.Dial(url)
+	if err != nil {
+		return nil, err
+	}
+	return dialedConnection{conn}, nil
+}
+
+// RabbitMQ is a connection and channel to a broker that recover on their own.
+// When either closes, it re-dials with backoff, opens a new channel and
+// replays the queue declarations, QoS and confirm mode set up so far.
+// Meanwhile operations wait for the new channel instead of failing.
 type RabbitMQ struct {
-	Conn    *amqp.Connection
-	Channel *amqp.Channel
+	url  string
+	dial func(url string) (amqpConnection, error)
+	done chan struct{}
+
+	mu      sync.Mutex
+	conn    amqpConnection
+	channel amqpChannel
+	// ready is closed while conn and channel are usable, and replaced by an
+	// open one when they are lost.
+	ready  chan struct{}
+	closed bool
+	// topology is applied to every new channel, in order.
+	topology []func(amqpChannel) error
+	// prefetch is the latest QoS limit; qosSet whether topology applies it.
+	prefetch int
+	qosSet   bool
 }
 
 func NewRabbitMQ(url string) (*RabbitMQ, error) {
-	conn, err := amqp.Dial(url)
+	return newRabbitMQ(url, dialAMQP)
+}
+
+func newRabbitMQ(url string, dial func(url string) (amqpConnection, error)) (*RabbitMQ, error) {
+	r := &RabbitMQ{
+		url:   url,
+		dial:  dial,
+		done:  make(chan struct{}),
+		ready: make(chan struct{}),
+	}
+	if err := r.connect(); err != nil {
+		return nil, err
+	}
+	go r.watch()
+	return r, nil
+}
+
+func (r *RabbitMQ) connect() error {
+	conn, err := r.dial(r.url)
+	if err != nil {
+		return fmt.Errorf("failed to connect to RabbitMQ: %w", err)
+	}
+	channel, err := r.openChannel(conn)
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to RabbitMQ: %s", err)
+		conn.Close()
+		return err
 	}
+	r.setConnected(conn, channel)
+	return nil
+}
 
+func (r *RabbitMQ) openChannel(conn amqpConnection) (amqpChannel, error) {
 	channel, err := conn.Channel()
 	if err != nil {
-		return nil, fmt.Errorf("failed to open a RabbitMQ channel: %s", err)
+		return nil, fmt.Errorf("failed to open a RabbitMQ channel: %w", err)
+	}
+
+	r.mu.Lock()
+	topology := slices.Clone(r.topology)
+	r.mu.Unlock()
+	for _, apply := range topology {
+		if err := apply(channel); err != nil {
+			channel.Close()
+			return nil, err
+		}
+	}
+	return channel, nil
+}
+
+func (r *RabbitMQ) setConnected(conn amqpConnection, channel amqpChannel) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.conn = conn
+	r.channel = channel
+	close(r.ready)
+}
+
+// markLost makes operations wait for a new channel once channel is found
+// closed. It is a no-op if channel has already been replaced.
+func (r *RabbitMQ) markLost(channel amqpChannel) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.channel != channel {
+		return
+	}
+	select {
+	case <-r.ready:
+		r.ready = make(chan struct{})
+	default:
+	}
+}
+
+// watch recovers the connection and channel whenever either closes, until
+// Close is called.
+func (r *RabbitMQ) watch() {
+	for {
+		r.mu.Lock()
+		conn, channel := r.conn, r.channel
+		r.mu.Unlock()
+
+		var reason *amqp.Error
+		select {
+		case <-r.done:
+			return
+		case reason = <-conn.NotifyClose(make(chan *amqp.Error, 1)):
+		case reason = <-channel.NotifyClose(make(chan *amqp.Error, 1)):
+		}
+		if r.isClosed() {
+			return
+		}
+
+		slog.Warn("RabbitMQ connection lost, reconnecting", "error", reason)
+		r.markLost(channel)
+		if !r.recover(conn) {
+			return
+		}
+		slog.Info("RabbitMQ connection recovered")
+	}
+}
+
+// recover re-establishes the channel, on conn if it is still open and on a
+// new connection otherwise. It returns false if Close was called first.
+func (r *RabbitMQ) recover(conn amqpConnection) bool {
+	// A channel the broker closed on its own, e.g. after a failed passive
+	// declare, only needs replacing.
+	if !conn.IsClosed() {
+		channel, err := r.openChannel(conn)
+		if err == nil {
+			r.setConnected(conn, channel)
+			return true
+		}
+		conn.Close()
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := r.connect()
+		if err == nil {
+			return true
+		}
+		delay := reconnectDelay(attempt)
+		slog.Warn("RabbitMQ reconnect failed", "attempt", attempt, "retry_in", delay, "error", err)
+		select {
+		case <-r.done:
+			return false
+		case <-time.After(delay):
+		}
+	}
+}
+
+// reconnectDelay doubles from reconnectBaseDelay up to reconnectMaxDelay.
+func reconnectDelay(attempt int) time.Duration {
+	if attempt > 10 {
+		return reconnectMaxDelay
+	}
+	return min(reconnectBaseDelay<<(attempt-1), reconnectMaxDelay)
+}
+
+func (r *RabbitMQ) isClosed() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.closed
+}
+
+// currentChannel returns the open channel, waiting for a lost one to be
+// replaced until ctx is done.
+func (r *RabbitMQ) currentChannel(ctx context.Context) (amqpChannel, error) {
+	for {
+		r.mu.Lock()
+		ready, channel, closed := r.ready, r.channel, r.closed
+		r.mu.Unlock()
+		if closed {
+			return nil, ErrClosed
+		}
+
+		select {
+		case <-ready:
+			if !channel.IsClosed() {
+				return channel, nil
+			}
+			r.markLost(channel)
+		case <-r.done:
+			return nil, ErrClosed
+		case <-ctx.Done():
+			return nil, fmt.Errorf("RabbitMQ connection unavailable: %w", ctx.Err())
+		}
 	}
+}
 
-	return &RabbitMQ{
-		Conn:    conn,
-		Channel: channel,
-	}, nil
+// withChannel runs fn on the open channel. If the channel turns out to be
+// closed, fn is retried once on its replacement.
+func (r *RabbitMQ) withChannel(ctx context.Context, fn func(amqpChannel) error) error {
+	ctx, cancel := context.WithTimeout(ctx, reconnectWait)
+	defer cancel()
+
+	for attempt := 0; ; attempt++ {
+		channel, err := r.currentChannel(ctx)
+		if err != nil {
+			return err
+		}
+		err = fn(channel)
+		if attempt > 0 || !errors.Is(err, amqp.ErrClosed) {
+			return err
+		}
+		r.markLost(channel)
+	}
+}
+
+// addTopology applies fn to the open channel and to every channel opened
+// after a reconnect.
+func (r *RabbitMQ) addTopology(fn func(amqpChannel) error) error {
+	if err := r.withChannel(context.Background(), fn); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	r.topology = append(r.topology, fn)
+	r.mu.Unlock()
+	return nil
 }
 
 func (r *RabbitMQ) DeclareQueue(name string, durable bool, args amqp.Table) error {
-	_, err := r.Channel.QueueDeclare(name, durable, false, false, false, args)
+	err := r.addTopology(func(channel amqpChannel) error {
+		_, err := channel.QueueDeclare(name, durable, false, false, false, args)
+		return err
+	})
 	if err != nil {
 		return fmt.Errorf("failed to declare a %s queue: %s", name, err)
 	}
 	return nil
 }
 
+// Qos limits how many unacknowledged messages the broker delivers at once.
+// It may be called again to change the limit. The limit is channel-wide, so
+// unlike a per-consumer one a change also applies to consumers already
+// running.
+func (r *RabbitMQ) Qos(prefetchCount int) error {
+	r.mu.Lock()
+	update := r.qosSet
+	r.prefetch, r.qosSet = prefetchCount, true
+	r.mu.Unlock()
+
+	apply := func(channel amqpChannel) error {
+		r.mu.Lock()
+		prefetch := r.prefetch
+		r.mu.Unlock()
+		return channel.Qos(prefetch, 0, true)
+	}
+
+	var err error
+	if update {
+		err = r.withChannel(context.Background(), apply)
+	} else {
+		err = r.addTopology(apply)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to set QoS: %w", err)
+	}
+	return nil
+}
+
 func (r *RabbitMQ) Publish(queueName string, data []byte) error {
-	err := r.Channel.Publish("", queueName, false, false, amqp.Publishing{
+	return r.PublishWithHeaders(queueName, data, nil)
+}
+
+// PublishWithHeaders publishes data to queueName with the given message
+// headers, such as trace context.
+func (r *RabbitMQ) PublishWithHeaders(queueName string, data []byte, headers amqp.Table) error {
+	err := r.PublishMessage(queueName, amqp.Publishing{
 		ContentType:  "application/json",
 		Body:         data,
+		Headers:      headers,
 		DeliveryMode: amqp.Persistent,
 	})
 	if err != nil {
-		return fmt.Errorf("failed to publish message in queue: %s", err)
+		return fmt.Errorf("failed to publish message in queue: %w", err)
+	}
+	return nil
+}
+
+// PublishMessage publishes msg to queueName as is.
+func (r *RabbitMQ) PublishMessage(queueName string, msg amqp.Publishing) error {
+	return r.withChannel(context.Background(), func(channel amqpChannel) error {
+		return channel.Publish("", queueName, false, false, msg)
+	})
+}
+
+// EnableConfirms puts the channel into publisher-confirm mode. It must be
+// called before PublishConfirmed.
+func (r *RabbitMQ) EnableConfirms() error {
+	err := r.addTopology(func(channel amqpChannel) error {
+		return channel.Confirm(false)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to enable publisher confirms: %w", err)
+	}
+	return nil
+}
+
+// PublishConfirmed publishes msg to queueName and waits until the broker
+// confirms it has taken responsibility for the message.
+func (r *RabbitMQ) PublishConfirmed(ctx context.Context, queueName string, msg amqp.Publishing) error {
+	var confirmation *amqp.DeferredConfirmation
+	err := r.withChannel(ctx, func(channel amqpChannel) error {
+		var err error
+		confirmation, err = channel.PublishWithDeferredConfirmWithContext(ctx, "", queueName, false, false, msg)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to publish message in queue: %w", err)
+	}
+	if confirmation == nil {
+		return fmt.Errorf("channel is not in confirm mode")
+	}
+
+	acked, err := confirmation.WaitContext(ctx)
+	if err != nil {
+		return fmt.Errorf("failed waiting for publish confirm: %w", err)
+	}
+	if !acked {
+		return fmt.Errorf("broker nacked message for queue %s", queueName)
 	}
 	return nil
 }
 
-func (r *RabbitMQ) Consume(queueName string) (<-chan amqp.Delivery, error) {
-	delivery, err := r.Channel.Consume(queueName, "", false, false, false, false, nil)
+// QueueDepth returns the number of ready messages in an existing queue. The
+// queue is inspected on a short-lived channel, as a failed passive declare
+// closes its channel.
+func (r *RabbitMQ) QueueDepth(name string) (int, error) {
+	var q amqp.Queue
+	err := r.withChannel(context.Background(), func(amqpChannel) error {
+		r.mu.Lock()
+		conn := r.conn
+		r.mu.Unlock()
+		channel, err := conn.Channel()
+		if err != nil {
+			return err
+		}
+		defer channel.Close()
+		q, err = channel.QueueDeclarePassive(name, true, false, false, false, nil)
+		return err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to inspect %s queue: %w", name, err)
+	}
+	return q.Messages, nil
+}
+
+// Get fetches one message from queueName, if there is one, without waiting.
+func (r *RabbitMQ) Get(queueName string, autoAck bool) (amqp.Delivery, bool, error) {
+	var (
+		msg amqp.Delivery
+		ok  bool
+	)
+	err := r.withChannel(context.Background(), func(channel amqpChannel) error {
+		var err error
+		msg, ok, err = channel.Get(queueName, autoAck)
+		return err
+	})
+	return msg, ok, err
+}
+
+// Consume delivers messages from queueName. The returned channel outlives
+// reconnects, as consumption resumes on every new channel, and is closed by
+// Close. Deliveries from before a reconnect can no longer be acknowledged;
+// the broker redelivers them.
+func (r *RabbitMQ) Consume(queueName, consumerTag string) (<-chan amqp.Delivery, error) {
+	deliveries, err := r.consume(context.Background(), queueName, consumerTag)
 	if err != nil {
-		return nil, fmt.Errorf("failed to consume from %s queue: %s", queueName, err)
+		return nil, fmt.Errorf("failed to consume from %s queue: %w", queueName, err)
 	}
 
-	return delivery, nil
+	out := make(chan amqp.Delivery)
+	go func() {
+		defer close(out)
+		for {
+			for delivery := range deliveries {
+				select {
+				case out <- delivery:
+				case <-r.done:
+					return
+				}
+			}
+			deliveries, err = r.resume(queueName, consumerTag)
+			if err != nil {
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+func (r *RabbitMQ) consume(ctx context.Context, queueName, consumerTag string) (<-chan amqp.Delivery, error) {
+	var deliveries <-chan amqp.Delivery
+	err := r.withChannel(ctx, func(channel amqpChannel) error {
+		var err error
+		deliveries, err = channel.Consume(queueName, consumerTag, false, false, false, false, nil)
+		return err
+	})
+	return deliveries, err
+}
+
+// resume consumes queueName again once the channel has been replaced. It only
+// gives up when the client is closed.
+func (r *RabbitMQ) resume(queueName, consumerTag string) (<-chan amqp.Delivery, error) {
+	for {
+		deliveries, err := r.consume(context.Background(), queueName, consumerTag)
+		if err == nil {
+			slog.Info("Resumed consuming", "queue", queueName)
+			return deliveries, nil
+		}
+		if errors.Is(err, ErrClosed) {
+			return nil, err
+		}
+		slog.Warn("Failed to resume consuming", "queue", queueName, "error", err)
+		select {
+		case <-r.done:
+			return nil, ErrClosed
+		case <-time.After(reconnectBaseDelay):
+		}
+	}
 }
 
 func (r *RabbitMQ) Close() error {
-	if err := r.Channel.Close(); err != nil {
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return nil
+	}
+	r.closed = true
+	close(r.done)
+	conn, channel := r.conn, r.channel
+	r.mu.Unlock()
+
+	if err := channel.Close(); err != nil && !errors.Is(err, amqp.ErrClosed) {
 		return fmt.Errorf("failed to close channel: %w", err)
 	}
-	if err := r.Conn.Close(); err != nil {
+	if err := conn.Close(); err != nil && !errors.Is(err, amqp.ErrClosed) {
 		return fmt.Errorf("failed to close connection: %w", err)
 	}
 	return nil
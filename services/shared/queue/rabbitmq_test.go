@@ -0,0 +1,479 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// closeNotifier reports a fake connection or channel closing the way amqp091
+// does: listeners get the reason, if any, and are then closed.
+type closeNotifier struct {
+	notifyMu  sync.Mutex
+	closed    bool
+	listeners []chan *amqp.Error
+}
+
+func (n *closeNotifier) NotifyClose(receiver chan *amqp.Error) chan *amqp.Error {
+	n.notifyMu.Lock()
+	defer n.notifyMu.Unlock()
+	if n.closed {
+		close(receiver)
+		return receiver
+	}
+	n.listeners = append(n.listeners, receiver)
+	return receiver
+}
+
+func (n *closeNotifier) IsClosed() bool {
+	n.notifyMu.Lock()
+	defer n.notifyMu.Unlock()
+	return n.closed
+}
+
+// shut closes n and reports whether it was open. reason is nil for a clean
+// close.
+func (n *closeNotifier) shut(reason *amqp.Error) bool {
+	n.notifyMu.Lock()
+	defer n.notifyMu.Unlock()
+	if n.closed {
+		return false
+	}
+	n.closed = true
+	for _, l := range n.listeners {
+		if reason != nil {
+			l <- reason
+		}
+		close(l)
+	}
+	return true
+}
+
+// fakeBroker dials fake connections and keeps every channel opened on them.
+// While gate is set, dialing waits for it to be closed.
+type fakeBroker struct {
+	mu       sync.Mutex
+	gate     chan struct{}
+	conns    []*fakeConnection
+	channels []*fakeChannel
+}
+
+func (b *fakeBroker) dial(url string) (amqpConnection, error) {
+	b.mu.Lock()
+	gate := b.gate
+	b.mu.Unlock()
+	if gate != nil {
+		<-gate
+	}
+
+	conn := &fakeConnection{broker: b}
+	b.mu.Lock()
+	b.conns = append(b.conns, conn)
+	b.mu.Unlock()
+	return conn, nil
+}
+
+func (b *fakeBroker) conn(i int) *fakeConnection {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.conns[i]
+}
+
+func (b *fakeBroker) channel(i int) *fakeChannel {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.channels[i]
+}
+
+func (b *fakeBroker) dials() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.conns)
+}
+
+type fakeConnection struct {
+	closeNotifier
+	broker *fakeBroker
+
+	mu       sync.Mutex
+	channels []*fakeChannel
+}
+
+func (c *fakeConnection) Channel() (amqpChannel, error) {
+	if c.IsClosed() {
+		return nil, amqp.ErrClosed
+	}
+	channel := &fakeChannel{}
+	c.mu.Lock()
+	c.channels = append(c.channels, channel)
+	c.mu.Unlock()
+	c.broker.mu.Lock()
+	c.broker.channels = append(c.broker.channels, channel)
+	c.broker.mu.Unlock()
+	return channel, nil
+}
+
+func (c *fakeConnection) Close() error {
+	c.drop(nil)
+	return nil
+}
+
+// drop closes the connection and its channels with reason, as a broker
+// restart would.
+func (c *fakeConnection) drop(reason *amqp.Error) {
+	if !c.shut(reason) {
+		return
+	}
+	c.mu.Lock()
+	channels := slices.Clone(c.channels)
+	c.mu.Unlock()
+	for _, channel := range channels {
+		channel.drop(reason)
+	}
+}
+
+// fakeChannel records the topology calls and publishes made on it.
+type fakeChannel struct {
+	closeNotifier
+
+	mu         sync.Mutex
+	calls      []string
+	published  []string
+	deliveries chan amqp.Delivery
+}
+
+func (c *fakeChannel) record(call string) error {
+	if c.IsClosed() {
+		return amqp.ErrClosed
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.calls = append(c.calls, call)
+	return nil
+}
+
+func (c *fakeChannel) recorded() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return slices.Clone(c.calls)
+}
+
+func (c *fakeChannel) QueueDeclare(name string, durable, autoDelete, exclusive, noWait bool, args amqp.Table) (amqp.Queue, error) {
+	return amqp.Queue{Name: name}, c.record("declare " + name)
+}
+
+func (c *fakeChannel) QueueDeclarePassive(name string, durable, autoDelete, exclusive, noWait bool, args amqp.Table) (amqp.Queue, error) {
+	return amqp.Queue{Name: name, Messages: 7}, c.record("inspect " + name)
+}
+
+func (c *fakeChannel) Qos(prefetchCount, prefetchSize int, global bool) error {
+	return c.record(fmt.Sprintf("qos %d", prefetchCount))
+}
+
+func (c *fakeChannel) Confirm(noWait bool) error {
+	return c.record("confirm")
+}
+
+func (c *fakeChannel) Publish(exchange, key string, mandatory, immediate bool, msg amqp.Publishing) error {
+	if c.IsClosed() {
+		return amqp.ErrClosed
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.published = append(c.published, key+":"+string(msg.Body))
+	return nil
+}
+
+// PublishWithDeferredConfirmWithContext publishes like a channel that isn't
+// in confirm mode; the fake can't produce confirmations.
+func (c *fakeChannel) PublishWithDeferredConfirmWithContext(ctx context.Context, exchange, key string, mandatory, immediate bool, msg amqp.Publishing) (*amqp.DeferredConfirmation, error) {
+	return nil, c.Publish(exchange, key, mandatory, immediate, msg)
+}
+
+func (c *fakeChannel) Get(queue string, autoAck bool) (amqp.Delivery, bool, error) {
+	return amqp.Delivery{}, false, c.record("get " + queue)
+}
+
+func (c *fakeChannel) Consume(queue, consumer string, autoAck, exclusive, noLocal, noWait bool, args amqp.Table) (<-chan amqp.Delivery, error) {
+	if err := c.record("consume " + queue); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.deliveries = make(chan amqp.Delivery, 1)
+	return c.deliveries, nil
+}
+
+func (c *fakeChannel) deliver(body string) {
+	c.mu.Lock()
+	deliveries := c.deliveries
+	c.mu.Unlock()
+	deliveries <- amqp.Delivery{Body: []byte(body)}
+}
+
+func (c *fakeChannel) Close() error {
+	c.drop(nil)
+	return nil
+}
+
+func (c *fakeChannel) drop(reason *amqp.Error) {
+	if !c.shut(reason) {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.deliveries != nil {
+		close(c.deliveries)
+	}
+}
+
+func newFakeRabbitMQ(t *testing.T) (*RabbitMQ, *fakeBroker) {
+	t.Helper()
+	broker := &fakeBroker{}
+	r, err := newRabbitMQ("amqp://fake", broker.dial)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { r.Close() })
+	return r, broker
+}
+
+// eventually fails the test if cond doesn't hold within a second.
+func eventually(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestRabbitMQRecoversTopology(t *testing.T) {
+	forced := &amqp.Error{Code: amqp.ConnectionForced, Reason: "broker restart"}
+
+	tests := []struct {
+		name      string
+		lose      func(b *fakeBroker)
+		wantDials int
+	}{
+		{"connection lost", func(b *fakeBroker) { b.conn(0).drop(forced) }, 2},
+		{"channel closed by the broker", func(b *fakeBroker) { b.channel(0).drop(&amqp.Error{Code: amqp.NotFound}) }, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, broker := newFakeRabbitMQ(t)
+			if err := r.DeclareQueue("jobs", true, nil); err != nil {
+				t.Fatal(err)
+			}
+			if err := r.DeclareQueue("jobs_dlq", true, nil); err != nil {
+				t.Fatal(err)
+			}
+			if err := r.Qos(5); err != nil {
+				t.Fatal(err)
+			}
+			if err := r.EnableConfirms(); err != nil {
+				t.Fatal(err)
+			}
+			// A later limit replaces the first on replay rather than adding
+			// a step.
+			if err := r.Qos(10); err != nil {
+				t.Fatal(err)
+			}
+
+			tt.lose(broker)
+			if err := r.Publish("jobs", []byte("after")); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := broker.dials(); got != tt.wantDials {
+				t.Errorf("dials = %d, want %d", got, tt.wantDials)
+			}
+			recovered := broker.channel(1)
+			want := []string{"declare jobs", "declare jobs_dlq", "qos 10", "confirm"}
+			if got := recovered.recorded(); !slices.Equal(got, want) {
+				t.Errorf("replayed %v, want %v", got, want)
+			}
+			if want := []string{"jobs:after"}; !slices.Equal(recovered.published, want) {
+				t.Errorf("published on the new channel %v, want %v", recovered.published, want)
+			}
+		})
+	}
+}
+
+func TestRabbitMQPublishWaitsForReconnect(t *testing.T) {
+	r, broker := newFakeRabbitMQ(t)
+
+	gate := make(chan struct{})
+	broker.mu.Lock()
+	broker.gate = gate
+	broker.mu.Unlock()
+	broker.conn(0).drop(&amqp.Error{Code: amqp.ConnectionForced})
+
+	published := make(chan error, 1)
+	go func() { published <- r.Publish("jobs", []byte("queued")) }()
+	select {
+	case err := <-published:
+		t.Fatalf("publish returned during the outage: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(gate)
+	select {
+	case err := <-published:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("publish not resumed after reconnecting")
+	}
+	if got := broker.channel(1).published; !slices.Equal(got, []string{"jobs:queued"}) {
+		t.Errorf("published %v on the new channel", got)
+	}
+}
+
+func TestRabbitMQConsumeResumes(t *testing.T) {
+	r, broker := newFakeRabbitMQ(t)
+
+	deliveries, err := r.Consume("jobs", "worker")
+	if err != nil {
+		t.Fatal(err)
+	}
+	receive := func() string {
+		t.Helper()
+		select {
+		case d := <-deliveries:
+			return string(d.Body)
+		case <-time.After(time.Second):
+			t.Fatal("no delivery")
+			return ""
+		}
+	}
+
+	broker.channel(0).deliver("before")
+	if got := receive(); got != "before" {
+		t.Errorf("got %q, want %q", got, "before")
+	}
+
+	broker.conn(0).drop(&amqp.Error{Code: amqp.ConnectionForced})
+	eventually(t, "consumption to resume", func() bool {
+		return broker.dials() == 2 && slices.Contains(broker.channel(1).recorded(), "consume jobs")
+	})
+	broker.channel(1).deliver("after")
+	if got := receive(); got != "after" {
+		t.Errorf("got %q, want %q", got, "after")
+	}
+
+	r.Close()
+	select {
+	case _, ok := <-deliveries:
+		if ok {
+			t.Error("delivery after Close")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("deliveries not closed by Close")
+	}
+}
+
+func TestRabbitMQQosUpdate(t *testing.T) {
+	r, broker := newFakeRabbitMQ(t)
+	for _, n := range []int{4, 8, 2} {
+		if err := r.Qos(n); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Each change applies to the running channel at once.
+	want := []string{"qos 4", "qos 8", "qos 2"}
+	if got := broker.channel(0).recorded(); !slices.Equal(got, want) {
+		t.Errorf("calls = %v, want %v", got, want)
+	}
+}
+
+func TestPublishConfirmedFailures(t *testing.T) {
+	tests := []struct {
+		name    string
+		outage  bool
+		wantErr error
+	}{
+		{"channel not in confirm mode", false, nil},
+		{"broker down past the deadline", true, context.DeadlineExceeded},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, broker := newFakeRabbitMQ(t)
+			if tt.outage {
+				broker.mu.Lock()
+				broker.gate = make(chan struct{})
+				broker.mu.Unlock()
+				broker.conn(0).drop(&amqp.Error{Code: amqp.ConnectionForced})
+				t.Cleanup(func() { close(broker.gate) })
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+			defer cancel()
+			err := r.PublishConfirmed(ctx, "jobs", amqp.Publishing{Body: []byte("job")})
+			if err == nil {
+				t.Fatal("unconfirmed publish reported as delivered")
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("err = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRabbitMQQueueDepthUsesOwnChannel(t *testing.T) {
+	r, broker := newFakeRabbitMQ(t)
+
+	depth, err := r.QueueDepth("jobs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if depth != 7 {
+		t.Errorf("depth = %d, want 7", depth)
+	}
+	if calls := broker.channel(0).recorded(); len(calls) != 0 {
+		t.Errorf("inspected on the shared channel: %v", calls)
+	}
+	if inspector := broker.channel(1); !inspector.IsClosed() {
+		t.Error("inspection channel left open")
+	}
+}
+
+func TestRabbitMQClosed(t *testing.T) {
+	r, _ := newFakeRabbitMQ(t)
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Publish("jobs", []byte("late")); !errors.Is(err, ErrClosed) {
+		t.Errorf("Publish after Close = %v, want %v", err, ErrClosed)
+	}
+	if err := r.Close(); err != nil {
+		t.Errorf("second Close = %v", err)
+	}
+}
+
+func TestReconnectDelay(t *testing.T) {
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{5, 16 * time.Second},
+		{6, reconnectMaxDelay},
+		{64, reconnectMaxDelay},
+	}
+	for _, tt := range tests {
+		if got := reconnectDelay(tt.attempt); got != tt.want {
+			t.Errorf("reconnectDelay(%d) = %v, want %v", tt.attempt, got, tt.want)
+		}
+	}
+}
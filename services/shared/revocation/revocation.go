@@ -0,0 +1,92 @@
+// Package revocation is the Postgres-backed jwt.RevocationStore. The auth
+// service owns the revoked_tokens table and the users.tokens_valid_after
+// cutoffs and writes to them; every service that validates access tokens
+// reads them through the same Store, so a revoked token is rejected
+// everywhere at once.
+package revocation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Store records revoked token IDs in revoked_tokens. Rows outlive their
+// token only until the next revocation prunes them.
+type Store struct {
+	pool *pgxpool.Pool
+	// owned is set when Connect opened pool, so Close closes it.
+	owned bool
+}
+
+// NewStore returns a store using pool, which the caller keeps closing.
+func NewStore(pool *pgxpool.Pool) *Store {
+	return &Store{pool: pool}
+}
+
+// Connect opens a small pool on databaseURL, for services that don't use the
+// auth database otherwise. Close releases it.
+func Connect(ctx context.Context, databaseURL string) (*Store, error) {
+	config, err := pgxpool.ParseConfig(databaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("revocation: unable to parse database URL: %w", err)
+	}
+	config.MaxConns = 10
+
+	pool, err := pgxpool.NewWithConfig(ctx, config)
+	if err != nil {
+		return nil, fmt.Errorf("revocation: unable to create connection pool: %w", err)
+	}
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("revocation: unable to reach database: %w", err)
+	}
+	return &Store{pool: pool, owned: true}, nil
+}
+
+// Close closes the pool opened by Connect. It does nothing for stores built
+// with NewStore.
+func (s *Store) Close() {
+	if s.owned {
+		s.pool.Close()
+	}
+}
+
+func (s *Store) Revoke(ctx context.Context, jti string, expiresAt time.Time) error {
+	if _, err := s.pool.Exec(ctx, `DELETE FROM revoked_tokens WHERE expires_at < $1`, time.Now().UTC()); err != nil {
+		slog.WarnContext(ctx, "Failed to prune expired revoked tokens", "error", err)
+	}
+
+	_, err := s.pool.Exec(ctx, `
+		INSERT INTO revoked_tokens (jti, expires_at)
+		VALUES ($1, $2)
+		ON CONFLICT (jti) DO NOTHING
+	`, jti, expiresAt.UTC())
+	return err
+}
+
+func (s *Store) IsRevoked(ctx context.Context, jti string) (bool, error) {
+	var revoked bool
+	err := s.pool.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM revoked_tokens WHERE jti = $1)`, jti).Scan(&revoked)
+	return revoked, err
+}
+
+func (s *Store) TokensValidAfter(ctx context.Context, userID string) (time.Time, error) {
+	var cutoff *time.Time
+	err := s.pool.QueryRow(ctx, `SELECT tokens_valid_after FROM users WHERE user_id = $1`, userID).Scan(&cutoff)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	if cutoff == nil {
+		return time.Time{}, nil
+	}
+	return *cutoff, nil
+}
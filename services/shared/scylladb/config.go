@@ -0,0 +1,92 @@
+package scylladb
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/gocql/gocql"
+)
+
+// Config describes how to reach the cluster. The env tags let services
+// read it with config.Bind.
+type Config struct {
+	Hosts    []string `env:"SCYLLADB_HOSTS"`
+	Keyspace string   `env:"SCYLLADB_KEYSPACE"`
+	// Consistency is a level such as ONE, QUORUM or LOCAL_QUORUM.
+	Consistency string `env:"SCYLLADB_CONSISTENCY"`
+	// Username and Password enable password authentication when Username
+	// is set.
+	Username string `env:"SCYLLADB_USERNAME"`
+	Password string `env:"SCYLLADB_PASSWORD"`
+	// ConnectTimeout bounds each attempt's connection setup.
+	ConnectTimeout time.Duration `env:"SCYLLADB_CONNECT_TIMEOUT"`
+	// MaxRetries is how many times a failed connection is retried, waiting
+	// RetryDelay at first and twice as long after each further failure, up
+	// to MaxRetryDelay.
+	MaxRetries    int           `env:"SCYLLADB_CONNECT_RETRIES"`
+	RetryDelay    time.Duration `env:"SCYLLADB_RETRY_DELAY"`
+	MaxRetryDelay time.Duration `env:"SCYLLADB_MAX_RETRY_DELAY"`
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Hosts:          []string{"127.0.0.1:9042"},
+		Keyspace:       "searchflow",
+		Consistency:    "ONE",
+		ConnectTimeout: 10 * time.Second,
+		MaxRetries:     10,
+		RetryDelay:     time.Second,
+		MaxRetryDelay:  30 * time.Second,
+	}
+}
+
+// newSession opens a session, retrying failed attempts as cfg allows. It
+// gives up early if ctx is done.
+func newSession(ctx context.Context, cfg Config) (*gocql.Session, error) {
+	if len(cfg.Hosts) == 0 {
+		return nil, fmt.Errorf("scylladb: no hosts configured")
+	}
+	consistency, err := gocql.ParseConsistencyWrapper(cfg.Consistency)
+	if err != nil {
+		return nil, fmt.Errorf("scylladb: invalid consistency %q", cfg.Consistency)
+	}
+
+	cluster := gocql.NewCluster(cfg.Hosts...)
+	cluster.Keyspace = cfg.Keyspace
+	cluster.Consistency = consistency
+	if cfg.ConnectTimeout > 0 {
+		cluster.ConnectTimeout = cfg.ConnectTimeout
+	}
+	cluster.QueryObserver = observer{}
+	cluster.BatchObserver = observer{}
+	cluster.ConnectObserver = observer{}
+	if cfg.Username != "" {
+		cluster.Authenticator = gocql.PasswordAuthenticator{
+			Username: cfg.Username,
+			Password: cfg.Password,
+		}
+	}
+
+	delay := cfg.RetryDelay
+	for attempt := 0; ; attempt++ {
+		session, err := cluster.CreateSession()
+		if err == nil {
+			return session, nil
+		}
+		if attempt >= cfg.MaxRetries {
+			return nil, fmt.Errorf("scylladb: failed to connect to %v after %d attempts: %w", cfg.Hosts, attempt+1, err)
+		}
+
+		slog.Warn("ScyllaDB not reachable, retrying", "attempt", attempt+1, "retry_in", delay, "error", err)
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("scylladb: gave up connecting: %w", ctx.Err())
+		case <-time.After(delay):
+		}
+		if delay *= 2; cfg.MaxRetryDelay > 0 && delay > cfg.MaxRetryDelay {
+			delay = cfg.MaxRetryDelay
+		}
+	}
+}
@@ -0,0 +1,274 @@
+package scylladb
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gocql/gocql"
+)
+
+// Migrations are CQL files named like golang-migrate's:
+// NNNNNN_name.up.cql applies a version and NNNNNN_name.down.cql reverts it.
+// Statements are separated by semicolons, which therefore can't appear in
+// string literals; lines starting with -- are comments.
+//
+//go:embed migrations/*.cql
+var migrationFiles embed.FS
+
+const (
+	keyspaceQuery = `
+		CREATE KEYSPACE IF NOT EXISTS searchflow
+		WITH REPLICATION = {
+			'class': 'SimpleStrategy',
+			'replication_factor': 1
+		}
+	`
+	migrationsTableQuery = `
+		CREATE TABLE IF NOT EXISTS searchflow.schema_migrations (
+			version bigint PRIMARY KEY,
+			name text,
+			dirty boolean,
+			applied_at timestamp
+		)
+	`
+)
+
+type migration struct {
+	version  int64
+	name     string
+	up, down string
+}
+
+// appliedMigration is a schema_migrations row. A dirty migration was
+// started but didn't finish, leaving the schema in between versions.
+type appliedMigration struct {
+	version int64
+	dirty   bool
+}
+
+// MigrateUp applies every migration newer than the latest one applied,
+// creating the keyspace and the schema_migrations table if needed.
+func (s *ScyllaDB) MigrateUp() error {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return err
+	}
+	applied, err := s.appliedMigrations()
+	if err != nil {
+		return err
+	}
+
+	done := make(map[int64]bool, len(applied))
+	for _, a := range applied {
+		done[a.version] = true
+	}
+
+	count := 0
+	for _, m := range migrations {
+		if done[m.version] {
+			continue
+		}
+		if err := s.runMigration(m, m.up, true); err != nil {
+			return err
+		}
+		count++
+	}
+
+	slog.Info("ScyllaDB migrations applied", "count", count)
+	return nil
+}
+
+// MigrateDown reverts the latest steps applied migrations, newest first.
+func (s *ScyllaDB) MigrateDown(steps int) error {
+	if steps <= 0 {
+		return fmt.Errorf("scylladb: steps must be positive, got %d", steps)
+	}
+	migrations, err := loadMigrations()
+	if err != nil {
+		return err
+	}
+	applied, err := s.appliedMigrations()
+	if err != nil {
+		return err
+	}
+
+	byVersion := make(map[int64]migration, len(migrations))
+	for _, m := range migrations {
+		byVersion[m.version] = m
+	}
+
+	for i := len(applied) - 1; i >= 0 && steps > 0; i, steps = i-1, steps-1 {
+		m, ok := byVersion[applied[i].version]
+		if !ok {
+			return fmt.Errorf("scylladb: applied migration %d has no migration file", applied[i].version)
+		}
+		if m.down == "" {
+			return fmt.Errorf("scylladb: migration %d_%s has no down file", m.version, m.name)
+		}
+		if err := s.runMigration(m, m.down, false); err != nil {
+			return err
+		}
+	}
+
+	slog.Info("ScyllaDB migrations rolled back")
+	return nil
+}
+
+// appliedMigrations returns the applied migrations in version order. It
+// fails if one of them is dirty, since the schema then needs fixing by hand
+// and its schema_migrations row deleting before migrating further.
+func (s *ScyllaDB) appliedMigrations() ([]appliedMigration, error) {
+	if err := s.Session.Query(keyspaceQuery).Exec(); err != nil {
+		return nil, fmt.Errorf("scylladb: failed to create keyspace: %w", err)
+	}
+	if err := s.Session.Query(migrationsTableQuery).Exec(); err != nil {
+		return nil, fmt.Errorf("scylladb: failed to create schema_migrations: %w", err)
+	}
+
+	var applied []appliedMigration
+	iter := s.Session.Query(`SELECT version, dirty FROM searchflow.schema_migrations`).Iter()
+	var a appliedMigration
+	for iter.Scan(&a.version, &a.dirty) {
+		applied = append(applied, a)
+	}
+	if err := iter.Close(); err != nil {
+		return nil, fmt.Errorf("scylladb: failed to read schema_migrations: %w", err)
+	}
+
+	sort.Slice(applied, func(i, j int) bool { return applied[i].version < applied[j].version })
+	for _, a := range applied {
+		if a.dirty {
+			return nil, fmt.Errorf("scylladb: migration %d is dirty", a.version)
+		}
+	}
+	return applied, nil
+}
+
+// runMigration runs the statements of one direction of m, marking it dirty
+// in schema_migrations until they have all succeeded. CQL has no
+// transactions, so a failure leaves it dirty.
+func (s *ScyllaDB) runMigration(m migration, script string, up bool) error {
+	mark := `INSERT INTO searchflow.schema_migrations (version, name, dirty, applied_at) VALUES (?, ?, ?, ?)`
+	if err := s.Session.Query(mark, m.version, m.name, true, time.Now()).Exec(); err != nil {
+		return fmt.Errorf("scylladb: failed to mark migration %d: %w", m.version, err)
+	}
+
+	for _, stmt := range splitStatements(script) {
+		if err := s.Session.Query(stmt).Exec(); err != nil && !alreadyApplied(err) {
+			return fmt.Errorf("scylladb: migration %d_%s failed: %w", m.version, m.name, err)
+		}
+	}
+
+	var err error
+	if up {
+		err = s.Session.Query(mark, m.version, m.name, false, time.Now()).Exec()
+	} else {
+		err = s.Session.Query(`DELETE FROM searchflow.schema_migrations WHERE version = ?`, m.version).Exec()
+	}
+	if err != nil {
+		return fmt.Errorf("scylladb: failed to record migration %d: %w", m.version, err)
+	}
+
+	slog.Info("ScyllaDB migration done", "version", m.version, "name", m.name, "up", up)
+	return nil
+}
+
+// alreadyApplied reports whether err is from adding a column that exists or
+// dropping one that doesn't. CQL's ALTER TABLE has no IF [NOT] EXISTS, and
+// clusters set up before migrations may already have some of their changes.
+func alreadyApplied(err error) bool {
+	var reqErr gocql.RequestError
+	if !errors.As(err, &reqErr) {
+		return false
+	}
+	msg := reqErr.Message()
+	return strings.Contains(msg, "conflicts with an existing column") ||
+		strings.Contains(msg, "already exists") ||
+		strings.Contains(msg, "was not found in table")
+}
+
+func loadMigrations() ([]migration, error) {
+	entries, err := fs.ReadDir(migrationFiles, "migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*migration)
+	for _, entry := range entries {
+		name := entry.Name()
+		version, title, direction, ok := parseMigrationName(name)
+		if !ok {
+			return nil, fmt.Errorf("scylladb: invalid migration file name %q", name)
+		}
+
+		data, err := migrationFiles.ReadFile(path.Join("migrations", name))
+		if err != nil {
+			return nil, err
+		}
+
+		m := byVersion[version]
+		if m == nil {
+			m = &migration{version: version, name: title}
+			byVersion[version] = m
+		}
+		if direction == "up" {
+			m.up = string(data)
+		} else {
+			m.down = string(data)
+		}
+	}
+
+	migrations := make([]migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.up == "" {
+			return nil, fmt.Errorf("scylladb: migration %d_%s has no up file", m.version, m.name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].version < migrations[j].version })
+	return migrations, nil
+}
+
+// parseMigrationName splits a name like 000001_create_tables.up.cql.
+func parseMigrationName(name string) (version int64, title, direction string, ok bool) {
+	base, direction, ok := strings.Cut(strings.TrimSuffix(name, ".cql"), ".")
+	if !ok || (direction != "up" && direction != "down") {
+		return 0, "", "", false
+	}
+	versionStr, title, ok := strings.Cut(base, "_")
+	if !ok {
+		return 0, "", "", false
+	}
+	version, err := strconv.ParseInt(versionStr, 10, 64)
+	if err != nil {
+		return 0, "", "", false
+	}
+	return version, title, direction, true
+}
+
+// splitStatements splits a migration script into its statements, dropping
+// comments and blank lines.
+func splitStatements(script string) []string {
+	var lines []string
+	for _, line := range strings.Split(script, "\n") {
+		if trimmed := strings.TrimSpace(line); trimmed != "" && !strings.HasPrefix(trimmed, "--") {
+			lines = append(lines, line)
+		}
+	}
+
+	var stmts []string
+	for _, stmt := range strings.Split(strings.Join(lines, "\n"), ";") {
+		if stmt = strings.TrimSpace(stmt); stmt != "" {
+			stmts = append(stmts, stmt)
+		}
+	}
+	return stmts
+}
@@ -0,0 +1,63 @@
+package scylladb
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"github.com/amrrdev/trawl/services/shared/metrics"
+	"github.com/gocql/gocql"
+)
+
+// observer records every query, batch and connection attempt of a session in
+// the metrics package's ScyllaDB metrics.
+type observer struct{}
+
+func (observer) ObserveQuery(_ context.Context, q gocql.ObservedQuery) {
+	operation, table := describeStatement(q.Statement)
+	metrics.ScyllaQueryDuration.WithLabelValues(operation, table).Observe(q.End.Sub(q.Start).Seconds())
+	if q.Err != nil {
+		metrics.ScyllaQueryErrors.WithLabelValues(operation, table).Inc()
+	}
+}
+
+// ObserveBatch labels a batch by the table of its first statement; the
+// services only batch writes to a single table.
+func (observer) ObserveBatch(_ context.Context, b gocql.ObservedBatch) {
+	table := ""
+	if len(b.Statements) > 0 {
+		_, table = describeStatement(b.Statements[0])
+	}
+	metrics.ScyllaBatchDuration.WithLabelValues(table).Observe(b.End.Sub(b.Start).Seconds())
+	if b.Err != nil {
+		metrics.ScyllaBatchFailures.WithLabelValues(table).Inc()
+	}
+}
+
+func (observer) ObserveConnect(c gocql.ObservedConnect) {
+	if c.Err != nil {
+		metrics.ScyllaConnectFailures.Inc()
+	}
+}
+
+var statementTable = regexp.MustCompile(`(?i)\b(?:FROM|INTO|UPDATE|TABLE(?:\s+IF\s+(?:NOT\s+)?EXISTS)?)\s+([\w.]+)`)
+
+// describeStatement returns the lower-cased operation of a CQL statement,
+// such as "select", and the table it names without its keyspace, or "" if
+// there is none.
+func describeStatement(stmt string) (operation, table string) {
+	fields := strings.Fields(stmt)
+	if len(fields) == 0 {
+		return "", ""
+	}
+	operation = strings.ToLower(fields[0])
+
+	if m := statementTable.FindStringSubmatch(stmt); m != nil {
+		table = m[1]
+		if i := strings.LastIndexByte(table, '.'); i >= 0 {
+			table = table[i+1:]
+		}
+		table = strings.ToLower(table)
+	}
+	return operation, table
+}
@@ -0,0 +1,85 @@
+// Package scylladb connects the indexing and search services to ScyllaDB,
+// retrying while the cluster is still starting up, and owns the schema of
+// the searchflow keyspace they share.
+package scylladb
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/gocql/gocql"
+)
+
+type ScyllaDB struct {
+	Session *gocql.Session
+}
+
+// CollectionStatsRow is the collection_stats row holding the corpus totals.
+const CollectionStatsRow = "global"
+
+// Words are listed in term_prefixes under each of their prefixes from
+// TermPrefixMinLen to TermPrefixMaxLen runes long.
+const (
+	TermPrefixMinLen = 2
+	TermPrefixMaxLen = 10
+)
+
+// Fields a bigram_index row can name, matching the search service's match
+// fields.
+const (
+	FieldBody  = "body"
+	FieldTitle = "title"
+)
+
+// WordPair is the bigram_index key of two words found at adjacent
+// positions.
+func WordPair(first, second string) string {
+	return first + " " + second
+}
+
+type options struct {
+	createSchema bool
+}
+
+// Option changes what Connect does once connected.
+type Option func(*options)
+
+// WithSchema makes Connect apply pending migrations, see MigrateUp. Failing
+// to do so is logged rather than returned, so a service can still start
+// against a schema someone else manages.
+func WithSchema() Option {
+	return func(o *options) {
+		o.createSchema = true
+	}
+}
+
+// Connect opens a session as cfg describes.
+func Connect(ctx context.Context, cfg Config, opts ...Option) (*ScyllaDB, error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	session, err := newSession(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	scylla := &ScyllaDB{
+		Session: session,
+	}
+
+	if o.createSchema {
+		if err := scylla.MigrateUp(); err != nil {
+			slog.Warn("Failed to migrate schema", "error", err)
+		}
+	}
+
+	return scylla, nil
+}
+
+func (s *ScyllaDB) Close() {
+	if s.Session != nil {
+		s.Session.Close()
+	}
+}
@@ -2,15 +2,24 @@ package storage
 
 import (
 	"context"
+	"encoding/base64"
+	"errors"
 	"fmt"
 	"net/url"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/amrrdev/trawl/services/shared/apierror"
 	"github.com/gin-gonic/gin"
 	"github.com/minio/minio-go/v7"
 	"github.com/minio/minio-go/v7/pkg/credentials"
 )
 
+// ErrObjectNotFound is returned for objects missing from the bucket.
+var ErrObjectNotFound = errors.New("object not found")
+
 type Storage struct {
 	Client *minio.Client
 	Bucket string
@@ -68,8 +77,18 @@ func (s *Storage) GetUploadUrl(ctx context.Context, userID, filename string, dur
 	return presignedUrl.String(), nil
 }
 
+// GetDownloadUrl presigns a GET for userID's file. Presigning doesn't touch
+// the bucket, so the object is checked first; a missing one returns
+// ErrObjectNotFound rather than a URL that would fail.
 func (s *Storage) GetDownloadUrl(ctx context.Context, userID, filename string, duration time.Duration) (string, error) {
 	objectName := GetObjectName(userID, filename)
+	if _, err := s.Client.StatObject(ctx, s.Bucket, objectName, minio.StatObjectOptions{}); err != nil {
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			return "", ErrObjectNotFound
+		}
+		return "", err
+	}
+
 	presignedUrl, err := s.Client.PresignedGetObject(
 		ctx,
 		s.Bucket,
@@ -84,27 +103,166 @@ func (s *Storage) GetDownloadUrl(ctx context.Context, userID, filename string, d
 	return presignedUrl.String(), nil
 }
 
-func (s *Storage) ListFiles(ctx context.Context, userID string) ([]map[string]any, error) {
-	objects := s.Client.ListObjects(
-		ctx,
-		s.Bucket,
-		minio.ListObjectsOptions{Prefix: userID + "/"},
-	)
+// Orders ListFiles can return files in.
+const (
+	SortByName     = "name"
+	SortBySize     = "size"
+	SortByModified = "modified"
+)
+
+// ListOptions selects a page of ListFiles results.
+type ListOptions struct {
+	// Limit caps the files returned; zero returns them all.
+	Limit int
+	// Cursor is the NextCursor of the previous page, empty for the first.
+	Cursor string
+	// Sort is one of the SortBy orders; empty sorts by name.
+	Sort string
+}
+
+// FileList is a page of ListFiles results. NextCursor is empty on the last
+// page.
+type FileList struct {
+	Files      []map[string]any
+	NextCursor string
+}
 
-	var files []map[string]any
-	for obj := range objects {
+type fileEntry struct {
+	key      string
+	size     int64
+	modified time.Time
+}
+
+// ListFiles lists the files under userID's prefix. Sorted by name, pages come
+// straight from MinIO, which lists keys in order; other orders have to list
+// the whole prefix before the first page can be cut.
+func (s *Storage) ListFiles(ctx context.Context, userID string, opts ListOptions) (*FileList, error) {
+	sortBy := opts.Sort
+	if sortBy == "" {
+		sortBy = SortByName
+	}
+	if sortBy != SortByName && sortBy != SortBySize && sortBy != SortByModified {
+		return nil, apierror.InvalidInput("invalid sort %q", opts.Sort)
+	}
+	if opts.Limit < 0 {
+		return nil, apierror.InvalidInput("invalid limit %d", opts.Limit)
+	}
+	var after *fileEntry
+	if opts.Cursor != "" {
+		entry, err := decodeCursor(opts.Cursor, sortBy)
+		if err != nil {
+			return nil, err
+		}
+		after = &entry
+	}
+
+	// Cancelling stops MinIO's listing goroutine when we return before
+	// draining the channel.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	listOpts := minio.ListObjectsOptions{Prefix: userID + "/"}
+	// A name-sorted page needs one entry past the limit to know whether
+	// another page follows.
+	want := 0
+	if sortBy == SortByName {
+		if after != nil {
+			listOpts.StartAfter = after.key
+		}
+		if opts.Limit > 0 {
+			want = opts.Limit + 1
+		}
+	}
+
+	var entries []fileEntry
+	for obj := range s.Client.ListObjects(ctx, s.Bucket, listOpts) {
 		if obj.Err != nil {
 			return nil, obj.Err
 		}
+		entries = append(entries, fileEntry{key: obj.Key, size: obj.Size, modified: obj.LastModified})
+		if want > 0 && len(entries) == want {
+			break
+		}
+	}
 
-		files = append(files, gin.H{
-			"name":     obj.Key,
-			"size":     obj.Size,
-			"modified": obj.LastModified,
+	if sortBy != SortByName {
+		sort.Slice(entries, func(i, j int) bool {
+			return lessEntry(entries[i], entries[j], sortBy)
 		})
+		if after != nil {
+			first := sort.Search(len(entries), func(i int) bool {
+				return lessEntry(*after, entries[i], sortBy)
+			})
+			entries = entries[first:]
+		}
 	}
 
-	return files, nil
+	list := &FileList{}
+	if opts.Limit > 0 && len(entries) > opts.Limit {
+		entries = entries[:opts.Limit]
+		list.NextCursor = encodeCursor(entries[len(entries)-1], sortBy)
+	}
+	for _, e := range entries {
+		list.Files = append(list.Files, gin.H{
+			"name":     e.key,
+			"size":     e.size,
+			"modified": e.modified,
+		})
+	}
+	return list, nil
+}
+
+// lessEntry orders files by sortBy, breaking ties by key so that every file
+// has a fixed place to resume after.
+func lessEntry(a, b fileEntry, sortBy string) bool {
+	switch sortBy {
+	case SortBySize:
+		if a.size != b.size {
+			return a.size < b.size
+		}
+	case SortByModified:
+		if !a.modified.Equal(b.modified) {
+			return a.modified.Before(b.modified)
+		}
+	}
+	return a.key < b.key
+}
+
+// encodeCursor records the last file of a page as "<sort value>\x00<key>".
+func encodeCursor(e fileEntry, sortBy string) string {
+	value := ""
+	switch sortBy {
+	case SortBySize:
+		value = strconv.FormatInt(e.size, 10)
+	case SortByModified:
+		value = strconv.FormatInt(e.modified.UnixNano(), 10)
+	}
+	return base64.RawURLEncoding.EncodeToString([]byte(value + "\x00" + e.key))
+}
+
+func decodeCursor(cursor, sortBy string) (fileEntry, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return fileEntry{}, apierror.InvalidInput("invalid cursor")
+	}
+	value, key, ok := strings.Cut(string(raw), "\x00")
+	if !ok || key == "" {
+		return fileEntry{}, apierror.InvalidInput("invalid cursor")
+	}
+
+	entry := fileEntry{key: key}
+	switch sortBy {
+	case SortBySize:
+		entry.size, err = strconv.ParseInt(value, 10, 64)
+	case SortByModified:
+		var nanos int64
+		nanos, err = strconv.ParseInt(value, 10, 64)
+		entry.modified = time.Unix(0, nanos)
+	}
+	if err != nil {
+		return fileEntry{}, apierror.InvalidInput("invalid cursor for sort %q", sortBy)
+	}
+	return entry, nil
 }
 
 func GetObjectName(userID string, filename string) string {
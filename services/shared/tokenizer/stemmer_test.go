@@ -0,0 +1,44 @@
+package tokenizer
+
+import "testing"
+
+func TestStem(t *testing.T) {
+	// Expected stems are from Porter's reference vocabulary.
+	tests := []struct {
+		word string
+		want string
+	}{
+		{"caresses", "caress"},
+		{"ponies", "poni"},
+		{"ties", "ti"},
+		{"cats", "cat"},
+		{"feed", "feed"},
+		{"agreed", "agre"},
+		{"plastered", "plaster"},
+		{"motoring", "motor"},
+		{"sing", "sing"},
+		{"running", "run"},
+		{"hopping", "hop"},
+		{"falling", "fall"},
+		{"filing", "file"},
+		{"happy", "happi"},
+		{"relational", "relat"},
+		{"conditional", "condit"},
+		{"generalization", "gener"},
+		{"hopefulness", "hope"},
+		{"electrical", "electr"},
+		{"adjustable", "adjust"},
+		{"controlling", "control"},
+		{"is", "is"},
+		{"go", "go"},
+		{"café", "café"},
+		{"данные", "данные"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.word, func(t *testing.T) {
+			if got := Stem(tt.word); got != tt.want {
+				t.Errorf("Stem(%q) = %q, want %q", tt.word, got, tt.want)
+			}
+		})
+	}
+}
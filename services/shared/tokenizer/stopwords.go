@@ -0,0 +1,111 @@
+package tokenizer
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Built-in stop-word lists by ISO 639-1 language code.
+var builtinStopWords = map[string][]string{
+	"en": {
+		"a", "an", "and", "are", "as", "at", "be", "by", "for", "from",
+		"has", "he", "in", "is", "it", "its", "of", "on", "that", "the",
+		"to", "was", "will", "with",
+	},
+	"fr": {
+		"au", "aux", "avec", "ce", "ces", "dans", "de", "des", "du", "elle",
+		"en", "est", "et", "il", "je", "la", "le", "les", "leur", "lui",
+		"mais", "ne", "nous", "on", "ou", "par", "pas", "pour", "qu", "que",
+		"qui", "sa", "se", "ses", "son", "sur", "un", "une", "vous",
+	},
+	"de": {
+		"auf", "aus", "bei", "das", "dem", "den", "der", "des", "die", "ein",
+		"eine", "einem", "einen", "einer", "es", "für", "ich", "im", "in", "ist",
+		"mit", "nicht", "oder", "sich", "sie", "und", "von", "war", "wie", "zu",
+		"zum", "zur",
+	},
+	"es": {
+		"al", "como", "con", "de", "del", "el", "en", "es", "la", "las",
+		"lo", "los", "más", "no", "para", "pero", "por", "que", "se", "su",
+		"sus", "un", "una", "uno", "y",
+	},
+}
+
+// StopWordsLanguages lists the languages with a built-in stop-word list.
+func StopWordsLanguages() []string {
+	langs := make([]string, 0, len(builtinStopWords))
+	for lang := range builtinStopWords {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// StopWordsFor returns the built-in stop-word list for a language code.
+// "none" returns an empty list, which disables stop-word filtering.
+func StopWordsFor(language string) (map[string]bool, error) {
+	language = strings.ToLower(strings.TrimSpace(language))
+	if language == "none" {
+		return map[string]bool{}, nil
+	}
+	words, ok := builtinStopWords[language]
+	if !ok {
+		return nil, fmt.Errorf("invalid stop-word language %q: available %s, none",
+			language, strings.Join(StopWordsLanguages(), ", "))
+	}
+	return toSet(words), nil
+}
+
+// LoadStopWords reads a stop-word list from a file with one word per line.
+// Blank lines and lines starting with # are ignored.
+func LoadStopWords(path string) (map[string]bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open stop-word file: %w", err)
+	}
+	defer f.Close()
+
+	var words []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		words = append(words, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read stop-word file: %w", err)
+	}
+	return toSet(words), nil
+}
+
+// Config selects the stop-word list. A StopWordsFile takes precedence over
+// Language.
+type Config struct {
+	Language      string
+	StopWordsFile string
+}
+
+func DefaultConfig() Config {
+	return Config{Language: "en"}
+}
+
+// StopWords resolves the configured stop-word list.
+func (c Config) StopWords() (map[string]bool, error) {
+	if c.StopWordsFile != "" {
+		return LoadStopWords(c.StopWordsFile)
+	}
+	return StopWordsFor(c.Language)
+}
+
+func toSet(words []string) map[string]bool {
+	set := make(map[string]bool, len(words))
+	for _, w := range words {
+		set[strings.ToLower(w)] = true
+	}
+	return set
+}
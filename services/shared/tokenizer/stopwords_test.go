@@ -0,0 +1,63 @@
+package tokenizer
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestConfigStopWords(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "stopwords.txt")
+	if err := os.WriteFile(file, []byte("# custom list\nFoo\n\n  bar  \n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		config  Config
+		stop    []string
+		keep    []string
+		wantErr bool
+	}{
+		{"default English", DefaultConfig(), []string{"the", "and"}, []string{"und", "le"}, false},
+		{"language code normalized", Config{Language: " DE "}, []string{"und", "für"}, []string{"the"}, false},
+		{"none disables filtering", Config{Language: "none"}, nil, []string{"the", "und"}, false},
+		{"file wins over language", Config{Language: "en", StopWordsFile: file}, []string{"foo", "bar"}, []string{"the", "# custom list"}, false},
+		{"unknown language", Config{Language: "xx"}, nil, nil, true},
+		{"missing file", Config{StopWordsFile: filepath.Join(t.TempDir(), "missing")}, nil, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			words, err := tt.config.StopWords()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %v", err, tt.wantErr)
+			}
+			for _, w := range tt.stop {
+				if !words[w] {
+					t.Errorf("%q is not a stop word", w)
+				}
+			}
+			for _, w := range tt.keep {
+				if words[w] {
+					t.Errorf("%q is a stop word", w)
+				}
+			}
+		})
+	}
+}
+
+func TestTokenizerStopWords(t *testing.T) {
+	german, err := StopWordsFor("de")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var words []string
+	for _, tok := range NewTokenizerWithStopWords(german).Tokenize("die Suche und the index") {
+		words = append(words, tok.Word)
+	}
+	if want := []string{"such", "the", "index"}; !slices.Equal(words, want) {
+		t.Errorf("words = %v, want %v", words, want)
+	}
+}
@@ -0,0 +1,83 @@
+package tokenizer
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type Tokenizer struct {
+	stopWords map[string]bool
+}
+
+type Token struct {
+	Word     string
+	Position int
+	// Start and End are the byte offsets of the word in the text passed to
+	// Tokenize.
+	Start int
+	End   int
+}
+
+// NewTokenizer returns a tokenizer with the English stop-word list.
+func NewTokenizer() *Tokenizer {
+	stopWords, _ := StopWordsFor("en")
+	return NewTokenizerWithStopWords(stopWords)
+}
+
+// NewTokenizerWithStopWords returns a tokenizer that drops the given words.
+// A nil or empty list disables stop-word filtering.
+func NewTokenizerWithStopWords(stopWords map[string]bool) *Tokenizer {
+	return &Tokenizer{stopWords: stopWords}
+}
+
+// Tokenize splits text into words made of Unicode letters, digits and
+// combining marks, lowercases them, drops stopwords and single characters,
+// and stems what is left. Positions count only the kept tokens, so words
+// separated by a stopword are adjacent. Indexing and search both call this;
+// the index only matches queries tokenized the same way.
+func (t *Tokenizer) Tokenize(text string) []Token {
+	tokens := make([]Token, 0)
+	position := 0
+
+	start := -1
+	for i, r := range text {
+		if isWordRune(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 {
+			if token, ok := t.token(text, start, i, position); ok {
+				tokens = append(tokens, token)
+				position++
+			}
+			start = -1
+		}
+	}
+	if start >= 0 {
+		if token, ok := t.token(text, start, len(text), position); ok {
+			tokens = append(tokens, token)
+		}
+	}
+
+	return tokens
+}
+
+func (t *Tokenizer) token(text string, start, end, position int) (Token, bool) {
+	word := strings.ToLower(text[start:end])
+	if utf8.RuneCountInString(word) < 2 || t.stopWords[word] {
+		return Token{}, false
+	}
+	return Token{
+		Word:     Stem(word),
+		Position: position,
+		Start:    start,
+		End:      end,
+	}, true
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsNumber(r) || unicode.IsMark(r)
+}
@@ -0,0 +1,80 @@
+package tokenizer
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		name      string
+		text      string
+		words     []string
+		positions []int
+	}{
+		{"stems and lowercases", "Running Dogs", []string{"run", "dog"}, []int{0, 1}},
+		{"stopwords leave no gap", "state of the art", []string{"state", "art"}, []int{0, 1}},
+		{"punctuation splits words", "search-engine,indexing!", []string{"search", "engin", "index"}, []int{0, 1, 2}},
+		{"single characters dropped", "a b go", []string{"go"}, []int{0}},
+		{"empty", "", nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens := NewTokenizer().Tokenize(tt.text)
+			var words []string
+			var positions []int
+			for _, tok := range tokens {
+				words = append(words, tok.Word)
+				positions = append(positions, tok.Position)
+			}
+			if !slices.Equal(words, tt.words) {
+				t.Errorf("words = %v, want %v", words, tt.words)
+			}
+			if !slices.Equal(positions, tt.positions) {
+				t.Errorf("positions = %v, want %v", positions, tt.positions)
+			}
+		})
+	}
+}
+
+func TestTokenizeOffsets(t *testing.T) {
+	text := "The Quick, brown fox"
+	want := []string{"Quick", "brown", "fox"}
+
+	tokens := NewTokenizer().Tokenize(text)
+	if len(tokens) != len(want) {
+		t.Fatalf("got %d tokens, want %d", len(tokens), len(want))
+	}
+	for i, tok := range tokens {
+		if got := text[tok.Start:tok.End]; got != want[i] {
+			t.Errorf("token %d spans %q, want %q", i, got, want[i])
+		}
+	}
+}
+
+func TestTokenizeUnicode(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		words []string
+	}{
+		{"accented Latin", "Élan café", []string{"élan", "café"}},
+		{"Cyrillic", "Поиск данных", []string{"поиск", "данных"}},
+		{"Greek", "Αναζήτηση κειμένου", []string{"αναζήτηση", "κειμένου"}},
+		{"CJK run is one word", "検索エンジン", []string{"検索エンジン"}},
+		{"combining marks stay in the word", "cafe\u0301 menu", []string{"cafe\u0301", "menu"}},
+		{"digits", "Go 1.25 release", []string{"go", "25", "releas"}},
+		{"non-ASCII punctuation splits", "alpha—beta«gamma»", []string{"alpha", "beta", "gamma"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var words []string
+			for _, tok := range NewTokenizer().Tokenize(tt.text) {
+				words = append(words, tok.Word)
+			}
+			if !slices.Equal(words, tt.words) {
+				t.Errorf("words = %q, want %q", words, tt.words)
+			}
+		})
+	}
+}